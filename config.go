@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/mathutils"
+	"root.challenge/output"
+)
+
+// pipelineConfig is the contents of the JSON file named by the -config flag, which describes a whole
+// pipeline -- where its events come from, how they're processed, and which reports are written where:
+//
+//	{
+//	  "sources": [{"path": "trips.txt"}, {"path": "rides.jsonl", "format": "json"}],
+//	  "handlers": {"trip": {"min_speed_mph": 1, "max_speed_mph": 120}},
+//	  "processing": {"ignore_case": true, "dedup_window": 1000},
+//	  "errors": {"circuit_breaker": 3},
+//	  "store": {"strict": true, "park_trips": 100, "save": "store.json"},
+//	  "reports": [{"output": "report.txt"}, {"output": "top10.json", "format": "json", "top": 10}]
+//	}
+//
+// Every field is optional, and most have a flag of the same meaning (see `flagValues`) -- flags given
+// explicitly on the command line override the file.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// Scalar fields are pointers, so that fields left out of the file can be told apart from fields set to their
+// zero value (only the former defer to the defaults of their flags). When adding a field with a flag of its
+// own, add it to `flagValues` (and `validate`) too.
+type pipelineConfig struct {
+	// Sources are read (in order) into the same store, unless an input file is given on the command line.
+	Sources    []*sourceConfig   `json:"sources"`
+	Handlers   *handlersConfig   `json:"handlers"`
+	Processing *processingConfig `json:"processing"`
+	Errors     *errorsConfig     `json:"errors"`
+	Store      *storeConfig      `json:"store"`
+	Metrics    *metricsConfig    `json:"metrics"`
+	// Reports are each written to their own output (by the subcommands that write reports).
+	Reports []*reportConfig `json:"reports"`
+}
+
+type sourceConfig struct {
+	Path string `json:"path"`
+	// Format is the decoder of the source (see the -format flag).
+	Format *string `json:"format"`
+}
+
+type handlersConfig struct {
+	Trip *tripConfig `json:"trip"`
+}
+
+type tripConfig struct {
+	MinSpeedMph *float64 `json:"min_speed_mph"`
+	MaxSpeedMph *float64 `json:"max_speed_mph"`
+}
+
+type processingConfig struct {
+	IgnoreCase  *bool    `json:"ignore_case"`
+	DedupWindow *int     `json:"dedup_window"`
+	DedupFile   *string  `json:"dedup_file"`
+	LogEvents   *bool    `json:"log_events"`
+	RateLimit   *float64 `json:"rate_limit"`
+}
+
+type errorsConfig struct {
+	CircuitBreaker *int `json:"circuit_breaker"`
+}
+
+type storeConfig struct {
+	Strict *bool `json:"strict"`
+	// ParkTrips is a number of events (see -park-trips).
+	ParkTrips *int `json:"park_trips"`
+	// Load is only used by the "replay" subcommand (see its -store flag).
+	Load *string `json:"load"`
+	Save *string `json:"save"`
+}
+
+type metricsConfig struct {
+	Addr *string `json:"addr"`
+	File *string `json:"file"`
+}
+
+type reportConfig struct {
+	// Output is the file the report is written to (standard output if empty).
+	Output   string  `json:"output"`
+	Format   *string `json:"format"`
+	By       *string `json:"by"`
+	Units    *string `json:"units"`
+	Inactive *string `json:"inactive"`
+	Sort     *string `json:"sort"`
+	Top      *int    `json:"top"`
+}
+
+func addConfigFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", "",
+		"read the pipeline (sources, settings and reports) from this JSON file, which flags given explicitly override")
+}
+
+// loadConfig reads and validates the `pipelineConfig` in the file at `path`.
+func loadConfig(path string) (*pipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Misspelled fields would otherwise be silently ignored.
+	decoder.DisallowUnknownFields()
+
+	var cfg pipelineConfig
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, describeJSONError(data, err))
+	}
+
+	if problems := cfg.validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config file %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+
+	return &cfg, nil
+}
+
+// describeJSONError adds the line and column that `err` (from decoding `data`) arose at, if known.
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	line, column := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// validate returns a description of every problem with `cfg` (named by the path to its field), so that they
+// can all be fixed in one go.
+func (cfg *pipelineConfig) validate() []string {
+	var problems []string
+	problemf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for i, sc := range cfg.Sources {
+		if sc == nil {
+			problemf("sources[%d]: missing", i)
+			continue
+		}
+		if sc.Path == "" {
+			problemf("sources[%d].path: missing", i)
+		}
+		if sc.Format != nil {
+			if _, err := eventReader(*sc.Format); err != nil {
+				problemf("sources[%d].format: expecting 'text' or 'json', got '%s'", i, *sc.Format)
+			}
+		}
+	}
+
+	if cfg.Handlers != nil && cfg.Handlers.Trip != nil {
+		if err := cfg.Handlers.Trip.speedBounds().Validate(); err != nil {
+			problemf("handlers.trip: %s", err)
+		}
+	}
+
+	if pc := cfg.Processing; pc != nil {
+		if pc.DedupWindow != nil && *pc.DedupWindow < 0 {
+			problemf("processing.dedup_window: expecting a non-negative number, got %d", *pc.DedupWindow)
+		}
+		if pc.DedupWindow != nil && *pc.DedupWindow > 0 && pc.DedupFile != nil && *pc.DedupFile != "" {
+			problemf("processing: dedup_window and dedup_file are mutually exclusive")
+		}
+		if pc.RateLimit != nil && *pc.RateLimit < 0 {
+			problemf("processing.rate_limit: expecting a non-negative number, got %g", *pc.RateLimit)
+		}
+	}
+
+	if ec := cfg.Errors; ec != nil && ec.CircuitBreaker != nil && *ec.CircuitBreaker < 0 {
+		problemf("errors.circuit_breaker: expecting a non-negative number, got %d", *ec.CircuitBreaker)
+	}
+
+	// Whether park_trips applies depends on strict, which may be given on the command line instead -- so that's
+	// only checked once both are merged into the flags (see `processingFlags.newPipeline`).
+	if sc := cfg.Store; sc != nil && sc.ParkTrips != nil && *sc.ParkTrips < 0 {
+		problemf("store.park_trips: expecting a non-negative number, got %d", *sc.ParkTrips)
+	}
+
+	outputs := make(map[string]int)
+	for i, rc := range cfg.Reports {
+		if rc == nil {
+			problemf("reports[%d]: missing", i)
+			continue
+		}
+
+		if j, ok := outputs[rc.Output]; ok {
+			problemf("reports[%d].output: same output as reports[%d] ('%s')", i, j, rc.Output)
+		}
+		outputs[rc.Output] = i
+
+		if rc.Format != nil {
+			if _, err := output.ParseReportFormat(*rc.Format); err != nil {
+				problemf("reports[%d].format: %s", i, err)
+			}
+		}
+		if rc.By != nil {
+			if _, err := parseBy(*rc.By); err != nil {
+				problemf("reports[%d].by: %s", i, err)
+			}
+		}
+		if rc.Units != nil {
+			if _, err := mathutils.ParseDistanceUnit(*rc.Units); err != nil {
+				problemf("reports[%d].units: %s", i, err)
+			}
+		}
+		if rc.Inactive != nil {
+			if _, err := output.ParseInactiveDriverPolicy(*rc.Inactive); err != nil {
+				problemf("reports[%d].inactive: %s", i, err)
+			}
+		}
+		if rc.Sort != nil {
+			if _, err := output.ParseSortOrder(*rc.Sort); err != nil {
+				problemf("reports[%d].sort: %s", i, err)
+			}
+		}
+		if rc.Top != nil && *rc.Top < 0 {
+			problemf("reports[%d].top: expecting a non-negative number, got %d", i, *rc.Top)
+		}
+	}
+
+	return problems
+}
+
+// speedBounds returns the `trip.SpeedBounds` described by `tc`, falling back to `trip.DefaultSpeedBounds`
+// for whichever bound it leaves out.
+func (tc *tripConfig) speedBounds() trip.SpeedBounds {
+	speedBounds := trip.DefaultSpeedBounds
+	if tc.MinSpeedMph != nil {
+		speedBounds.MinMph = *tc.MinSpeedMph
+	}
+	if tc.MaxSpeedMph != nil {
+		speedBounds.MaxMph = *tc.MaxSpeedMph
+	}
+
+	return speedBounds
+}
+
+// flagValues returns the value of every flag that `cfg` sets, in the syntax of the flag.
+func (cfg *pipelineConfig) flagValues() map[string]string {
+	flagValues := make(map[string]string)
+	setString := func(name string, value *string) {
+		if value != nil {
+			flagValues[name] = *value
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			flagValues[name] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			flagValues[name] = strconv.Itoa(*value)
+		}
+	}
+	setFloat := func(name string, value *float64) {
+		if value != nil {
+			flagValues[name] = strconv.FormatFloat(*value, 'g', -1, 64)
+		}
+	}
+
+	if cfg.Handlers != nil && cfg.Handlers.Trip != nil {
+		setFloat("min-speed", cfg.Handlers.Trip.MinSpeedMph)
+		setFloat("max-speed", cfg.Handlers.Trip.MaxSpeedMph)
+	}
+
+	if pc := cfg.Processing; pc != nil {
+		setBool("ignore-case", pc.IgnoreCase)
+		setInt("dedup-window", pc.DedupWindow)
+		setString("dedup-file", pc.DedupFile)
+		setBool("log-events", pc.LogEvents)
+		setFloat("rate-limit", pc.RateLimit)
+	}
+
+	if cfg.Errors != nil {
+		setInt("circuit-breaker", cfg.Errors.CircuitBreaker)
+	}
+
+	if sc := cfg.Store; sc != nil {
+		setBool("strict", sc.Strict)
+		setInt("park-trips", sc.ParkTrips)
+		setString("store", sc.Load)
+		setString("save-store", sc.Save)
+	}
+
+	if mc := cfg.Metrics; mc != nil {
+		setString("metrics-addr", mc.Addr)
+		setString("metrics-file", mc.File)
+	}
+
+	return flagValues
+}
+
+// applyConfig loads the `pipelineConfig` in the file at `path` (if not empty), and uses it to set every flag
+// in `flags` that wasn't given explicitly on the command line (flags that `flags` doesn't have are ignored,
+// since not every setting applies to every subcommand).
+//
+// It returns nil (and no `error`) if `path` is empty.
+func applyConfig(flags *flag.FlagSet, path string) (*pipelineConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := explicitFlags(flags)
+	for name, value := range cfg.flagValues() {
+		if flags.Lookup(name) == nil || explicit[name] {
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("error applying config file %s: %w", path, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// explicitFlags returns the names of the flags in `flags` that were given explicitly on the command line.
+func explicitFlags(flags *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	return explicit
+}
@@ -0,0 +1,29 @@
+package eventhandler
+
+import (
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+	"root.challenge/mathutils"
+)
+
+// EventContext describes how to interpret the args of an event, as declared by the input it came from (see
+// `input.Directives`).
+//
+// The zero value describes an input that declares nothing, which is how every event was interpreted before
+// inputs could declare anything.
+type EventContext struct {
+	// DistanceUnit is the unit of distances that aren't explicitly suffixed with one.
+	DistanceUnit mathutils.DistanceUnit
+	// Logger is scoped to the event (see `eventprocessor.WithLogger`), and is nil (which discards everything)
+	// unless the framework was given one.
+	Logger *logging.Logger
+}
+
+// ContextAwareHandler is an optional extension of `Interface` for handlers whose interpretation of an
+// event depends on the `EventContext` it came with.
+//
+// The framework invokes `HandleWithContext`() instead of `Handle`() for such handlers -- `Handle`() is
+// still required, and should behave like `HandleWithContext`() with the zero `EventContext`.
+type ContextAwareHandler interface {
+	HandleWithContext(EventContext, EventArgs, *eventstore.EventStore) error
+}
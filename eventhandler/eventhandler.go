@@ -14,18 +14,15 @@ type EventArgs []string
 //
 // ============================================== Maintainer Notes ==============================================
 //
-// As the handling of each event gets more complex, the list of these operations should be expanded (for example,
-// to provide:
+// As the handling of each event gets more complex, the list of these operations should be expanded -- the
+// one-time per-run Setup()/Teardown() hooks and the CheckPreconditions() hook (which implements the Template Method
+// design pattern (https://en.wikipedia.org/wiki/Template_method_pattern) by allowing each handler to provide quick,
+// light sanity checks that are invoked by the framework before the potentially more-expensive-to-spin-up `Handle`()
+// method) already exist as optional extensions of this interface (see hooks.go), so that handlers which have no use
+// for them aren't burdened with them.
 //
-// a) a one-time system-wide Setup() and Teardown() hook for each handler,
-// b) a CheckPreconditions() hook to implement the Template Method design pattern
-//    (https://en.wikipedia.org/wiki/Template_method_pattern) that allows each handler to provide quick, light
-//    sanity checks that can be invoked by the framework before invoking the potentially more-expensive-to-spin-up
-//    `Handle`() method,
-// c) observability hooks that are invoked every so often by the framework to collect telemetry (and free each
-//    handler from having to worry about the infrastructure setup for said observability),
-//
-// etc.)
+// Observability hooks that are invoked every so often by the framework to collect telemetry (and free each handler
+// from having to worry about the infrastructure setup for said observability) are the natural next addition.
 type Interface interface {
 	// `Handle`() is invoked at runtime for each new event of the corresponding `EventType` that enters the system.
 	//
@@ -9,24 +9,59 @@ import (
 
 const eventType eventhandler.EventType = "Driver"
 
+// argSchema declares the driver's name, along with an optional "id=<ID>" keyword arg -- when the latter is
+// omitted (as is the case with every legacy "Driver <first name>" event), the name doubles up as the ID.
+var argSchema = eventhandler.NewSchema(
+	// Drivers registered without an ID are identified by their name.
+	eventhandler.StringField("name", "name").As(eventhandler.DriverRole),
+	eventhandler.StringField("id", "ID").Keyword().As(eventhandler.DriverRole),
+)
+
+type args struct {
+	Name string `arg:"name"`
+	ID   string `arg:"id"`
+}
+
 // EventHandler is an implementation of `eventhandler.Interface` for the "Driver" EventType.
 type EventHandler struct{}
 
 func init() {
-	if err := eventhandler.GlobalRegistry().RegisterEventHandler(eventType, &EventHandler{}); err != nil {
+	if err := Register(eventhandler.GlobalRegistry()); err != nil {
 		panic(err)
 	}
 }
 
+// Register registers this package's handlers with `r` (which this package does with
+// `eventhandler.GlobalRegistry`() at load time).
+func Register(r *eventhandler.Registry) error {
+	return r.RegisterEventHandler(eventType, &EventHandler{})
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *EventHandler) Description() string {
+	return "Registers a driver by name (which doubles up as their ID, unless one is given)."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *EventHandler) ArgSchema() *eventhandler.Schema {
+	return argSchema
+}
+
 // Conforms to `eventhandler.Interface`.
 func (eh *EventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
-	if len(eventArgs) != 1 {
-		return fmt.Errorf("expecting exactly 1 arg (first name) to Driver event %v; got %d",
-			eventArgs, len(eventArgs))
+	var a args
+	if err := argSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse Driver event %v: %w", eventArgs, err)
+	}
+
+	driverID := a.ID
+	if driverID == "" {
+		driverID = a.Name
 	}
 
 	if err := eventStore.RegisterDriver(&eventstore.DriverInfo{
-		FirstName: eventArgs[0],
+		ID:          driverID,
+		DisplayName: a.Name,
 	}); err != nil {
 		return fmt.Errorf("failed to register Driver event %v with EventStore: %w", eventArgs, err)
 	}
@@ -1,6 +1,7 @@
 package driver_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -25,10 +26,32 @@ func TestDriverEventHandler(t *testing.T) {
 			input:       eventhandler.EventArgs{"DriverA", "SomeLastName"},
 			expectError: true,
 		},
+		"TooManyArgsWithID": {
+			input:       eventhandler.EventArgs{"DriverA", "SomeLastName", "id=d1"},
+			expectError: true,
+		},
+		"OnlyID": {
+			input:       eventhandler.EventArgs{"id=d1"},
+			expectError: true,
+		},
+		"UnknownKeywordArg": {
+			input:       eventhandler.EventArgs{"DriverA", "last=SomeLastName"},
+			expectError: true,
+		},
+		"CorrectArgsWithID": {
+			input: eventhandler.EventArgs{"DriverA", "id=d1"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "d1",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 0 * time.Second,
+				TotalMilesDriven:    0.0,
+			},
+		},
 		"CorrectArgs": {
 			input: eventhandler.EventArgs{"DriverA"},
 			expectedOutput: eventstore.VisitableEntity{
-				DriverFirstName:     "DriverA",
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
 				TotalDurationDriven: 0 * time.Second,
 				TotalMilesDriven:    0.0,
 			},
@@ -40,6 +63,11 @@ func TestDriverEventHandler(t *testing.T) {
 			es := eventstore.New()
 			deh := &driver.EventHandler{}
 
+			// Every malformed event should already be caught by the (cheaper) schema validation.
+			if err := deh.ArgSchema().Validate(tc.input); (err != nil) != tc.expectError {
+				t.Fatalf("expected: precondition error=%v, got: %v", tc.expectError, err)
+			}
+
 			err := deh.Handle(tc.input, es)
 			switch {
 			case tc.expectError && err != nil:
@@ -58,7 +86,7 @@ func TestDriverEventHandler(t *testing.T) {
 			}
 
 			actualOutput := r.Entities[0]
-			if actualOutput != tc.expectedOutput {
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
 				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
 			}
 		})
@@ -0,0 +1,191 @@
+// Package driverlifecycle handles the events that change a driver after their registration (which is handled
+// by the sibling `driver` package).
+package driverlifecycle
+
+import (
+	"fmt"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+)
+
+const (
+	deactivationEventType eventhandler.EventType = "DriverDeactivate"
+	reactivationEventType eventhandler.EventType = "DriverReactivate"
+	renameEventType       eventhandler.EventType = "DriverRename"
+	mergeEventType        eventhandler.EventType = "DriverMerge"
+)
+
+// driverIDField references a driver by ID.
+var driverIDField = eventhandler.StringField("driver", "driver ID").As(eventhandler.DriverRole)
+
+var (
+	// The "DriverDeactivate" and "DriverReactivate" EventTypes share this schema.
+	activationArgSchema = eventhandler.NewSchema(driverIDField)
+	renameArgSchema     = eventhandler.NewSchema(
+		driverIDField,
+		eventhandler.StringField("name", "new display name"),
+	)
+	mergeArgSchema = eventhandler.NewSchema(
+		// A merge is about the driver merged away, whose later events (by their old ID) it must precede.
+		eventhandler.StringField("driver", "ID of driver to merge").As(eventhandler.DriverRole),
+		eventhandler.StringField("into", "ID of driver to merge into"),
+	)
+)
+
+type activationArgs struct {
+	DriverID string `arg:"driver"`
+}
+
+type renameArgs struct {
+	DriverID       string `arg:"driver"`
+	NewDisplayName string `arg:"name"`
+}
+
+type mergeArgs struct {
+	DriverID     string `arg:"driver"`
+	IntoDriverID string `arg:"into"`
+}
+
+// DeactivationEventHandler is an implementation of `eventhandler.Interface` for the "DriverDeactivate" EventType.
+type DeactivationEventHandler struct{}
+
+// ReactivationEventHandler is an implementation of `eventhandler.Interface` for the "DriverReactivate" EventType.
+type ReactivationEventHandler struct{}
+
+// RenameEventHandler is an implementation of `eventhandler.Interface` for the "DriverRename" EventType.
+type RenameEventHandler struct{}
+
+// MergeEventHandler is an implementation of `eventhandler.Interface` for the "DriverMerge" EventType.
+type MergeEventHandler struct{}
+
+func init() {
+	if err := Register(eventhandler.GlobalRegistry()); err != nil {
+		panic(err)
+	}
+}
+
+// Register registers this package's handlers with `r` (which this package does with
+// `eventhandler.GlobalRegistry`() at load time).
+func Register(r *eventhandler.Registry) error {
+	for eventType, eventHandler := range map[eventhandler.EventType]eventhandler.Interface{
+		deactivationEventType: &DeactivationEventHandler{},
+		reactivationEventType: &ReactivationEventHandler{},
+		renameEventType:       &RenameEventHandler{},
+		mergeEventType:        &MergeEventHandler{},
+	} {
+		if err := r.RegisterEventHandler(eventType, eventHandler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *DeactivationEventHandler) Description() string {
+	return "Marks a driver as inactive."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *DeactivationEventHandler) ArgSchema() *eventhandler.Schema {
+	return activationArgSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *DeactivationEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a activationArgs
+	if err := activationArgSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse DriverDeactivate event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.DeactivateDriver(&eventstore.DriverInfo{
+		ID: a.DriverID,
+	}); err != nil {
+		return fmt.Errorf("failed to deactivate driver for DriverDeactivate event %v with EventStore: %w",
+			eventArgs, err)
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *ReactivationEventHandler) Description() string {
+	return "Marks an inactive driver as active again."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *ReactivationEventHandler) ArgSchema() *eventhandler.Schema {
+	return activationArgSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *ReactivationEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a activationArgs
+	if err := activationArgSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse DriverReactivate event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.ReactivateDriver(&eventstore.DriverInfo{
+		ID: a.DriverID,
+	}); err != nil {
+		return fmt.Errorf("failed to reactivate driver for DriverReactivate event %v with EventStore: %w",
+			eventArgs, err)
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *RenameEventHandler) Description() string {
+	return "Changes the display name of a driver."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *RenameEventHandler) ArgSchema() *eventhandler.Schema {
+	return renameArgSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *RenameEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a renameArgs
+	if err := renameArgSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse DriverRename event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.RenameDriver(&eventstore.DriverRenameInfo{
+		DriverID:       a.DriverID,
+		NewDisplayName: a.NewDisplayName,
+	}); err != nil {
+		return fmt.Errorf("failed to rename driver for DriverRename event %v with EventStore: %w", eventArgs, err)
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *MergeEventHandler) Description() string {
+	return "Merges a duplicate driver (along with their trips) into another driver."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *MergeEventHandler) ArgSchema() *eventhandler.Schema {
+	return mergeArgSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *MergeEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a mergeArgs
+	if err := mergeArgSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse DriverMerge event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.MergeDrivers(&eventstore.DriverMergeInfo{
+		DriverID:     a.DriverID,
+		IntoDriverID: a.IntoDriverID,
+	}); err != nil {
+		return fmt.Errorf("failed to merge drivers for DriverMerge event %v with EventStore: %w", eventArgs, err)
+	}
+
+	return nil
+}
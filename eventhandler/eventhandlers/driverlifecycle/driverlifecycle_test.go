@@ -0,0 +1,131 @@
+package driverlifecycle_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/driverlifecycle"
+	"root.challenge/eventstore"
+)
+
+func TestDriverLifecycleEventHandlers(t *testing.T) {
+	tests := map[string]struct {
+		eventHandler eventhandler.Interface
+		input        eventhandler.EventArgs
+		// For when `Handle`() returns an error.
+		expectError bool
+		// `expectedOutput` is mutually exclusive with `expectError`.
+		expectedOutput []eventstore.VisitableEntity
+	}{
+		"DeactivateTooFewArgs": {
+			eventHandler: &driverlifecycle.DeactivationEventHandler{},
+			input:        eventhandler.EventArgs{},
+			expectError:  true,
+		},
+		"DeactivateTooManyArgs": {
+			eventHandler: &driverlifecycle.DeactivationEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA", "DriverB"},
+			expectError:  true,
+		},
+		"DeactivateUnknownDriver": {
+			eventHandler: &driverlifecycle.DeactivationEventHandler{},
+			input:        eventhandler.EventArgs{"DriverC"},
+			expectError:  true,
+		},
+		"Deactivate": {
+			eventHandler: &driverlifecycle.DeactivationEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA"},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0, DriverInactive: true},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+		},
+		"ReactivateTooFewArgs": {
+			eventHandler: &driverlifecycle.ReactivationEventHandler{},
+			input:        eventhandler.EventArgs{},
+			expectError:  true,
+		},
+		"ReactivateUnknownDriver": {
+			eventHandler: &driverlifecycle.ReactivationEventHandler{},
+			input:        eventhandler.EventArgs{"DriverC"},
+			expectError:  true,
+		},
+		"ReactivateActiveDriver": {
+			eventHandler: &driverlifecycle.ReactivationEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA"},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+		},
+		"RenameTooFewArgs": {
+			eventHandler: &driverlifecycle.RenameEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA"},
+			expectError:  true,
+		},
+		"RenameUnknownDriver": {
+			eventHandler: &driverlifecycle.RenameEventHandler{},
+			input:        eventhandler.EventArgs{"DriverC", "NameC"},
+			expectError:  true,
+		},
+		"Rename": {
+			eventHandler: &driverlifecycle.RenameEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA", "NameA"},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "NameA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0,
+					DriverPreviousNames: []string{"DriverA"}},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+		},
+		"MergeTooManyArgs": {
+			eventHandler: &driverlifecycle.MergeEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA", "DriverB", "DriverC"},
+			expectError:  true,
+		},
+		"MergeIntoUnknownDriver": {
+			eventHandler: &driverlifecycle.MergeEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA", "DriverC"},
+			expectError:  true,
+		},
+		"Merge": {
+			eventHandler: &driverlifecycle.MergeEventHandler{},
+			input:        eventhandler.EventArgs{"DriverA", "DriverB"},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 2 * time.Hour, TotalMilesDriven: 50.0,
+					DriverPreviousNames: []string{"DriverA"}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			es := eventstore.New()
+			es.RecordTrip(&eventstore.TripInfo{DriverID: "DriverA", TripDuration: 1 * time.Hour, TripMileage: 20.0})
+			es.RecordTrip(&eventstore.TripInfo{DriverID: "DriverB", TripDuration: 1 * time.Hour, TripMileage: 30.0})
+
+			err := tc.eventHandler.Handle(tc.input, es)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			r := eventstore.NewRecorder()
+			es.Visit(r)
+
+			actualOutput := r.Entities
+			sort.Slice(actualOutput, func(i, j int) bool {
+				return actualOutput[i].DriverID < actualOutput[j].DriverID
+			})
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
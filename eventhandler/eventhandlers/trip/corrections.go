@@ -0,0 +1,159 @@
+package trip
+
+import (
+	"fmt"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+)
+
+const (
+	cancellationEventType eventhandler.EventType = "TripCancel"
+	amendmentEventType    eventhandler.EventType = "TripAmend"
+)
+
+// tripIDField references a trip by the ID it was given in its "Trip" event.
+var tripIDField = eventhandler.StringField("trip", "trip ID").As(eventhandler.TripRole)
+
+var cancellationArgSchema = eventhandler.NewSchema(tripIDField)
+
+type cancellationArgs struct {
+	TripID string `arg:"trip"`
+}
+
+var amendmentArgSchema = eventhandler.NewSchema(
+	tripIDField,
+	startField,
+	stopField,
+	distanceField,
+	vehicleField,
+)
+
+type amendmentArgs struct {
+	TripID    string    `arg:"trip"`
+	Start     time.Time `arg:"start"`
+	Stop      time.Time `arg:"stop"`
+	Distance  distance  `arg:"distance"`
+	VehicleID string    `arg:"vehicle"`
+}
+
+// CancellationEventHandler is an implementation of `eventhandler.Interface` for the "TripCancel" EventType.
+//
+// The event takes the ID of a prior trip (as provided to the "Trip" event via its "id=<ID>" keyword arg).
+type CancellationEventHandler struct{}
+
+// AmendmentEventHandler is an implementation of `eventhandler.Interface` for the "TripAmend" EventType.
+//
+// The event takes the ID of a prior trip, followed by the corrected start time, stop time, distance driven
+// and (optionally) vehicle of that trip -- the trip remains attributed to its original driver.
+//
+// Its zero value discards amended trips as per `DefaultSpeedBounds` (see `NewAmendmentEventHandler` to
+// customize that).
+type AmendmentEventHandler struct {
+	settings
+}
+
+// NewAmendmentEventHandler creates a new `AmendmentEventHandler`, customized by `opts`.
+func NewAmendmentEventHandler(opts ...Option) (*AmendmentEventHandler, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AmendmentEventHandler{settings: s}, nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *CancellationEventHandler) Description() string {
+	return "Cancels a previously recorded trip."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *CancellationEventHandler) ArgSchema() *eventhandler.Schema {
+	return cancellationArgSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *CancellationEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a cancellationArgs
+	if err := cancellationArgSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse TripCancel event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.CancelTrip(&eventstore.TripCancelInfo{
+		TripID: a.TripID,
+	}); err != nil {
+		return fmt.Errorf("failed to cancel trip for TripCancel event %v with EventStore: %w", eventArgs, err)
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *AmendmentEventHandler) Description() string {
+	return "Corrects the measurements of a previously recorded trip."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *AmendmentEventHandler) ArgSchema() *eventhandler.Schema {
+	return amendmentArgSchema
+}
+
+// Conforms to `eventhandler.PreconditionChecker`.
+//
+// This checks the constraints between fields that `amendmentArgSchema` can't express on its own.
+func (eh *AmendmentEventHandler) CheckPreconditions(eventArgs eventhandler.EventArgs) error {
+	return checkTripTimes(amendmentArgSchema, eventArgs)
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *AmendmentEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	return eh.HandleWithContext(eventhandler.EventContext{}, eventArgs, eventStore)
+}
+
+// Conforms to `eventhandler.ContextAwareHandler`.
+func (eh *AmendmentEventHandler) HandleWithContext(eventContext eventhandler.EventContext,
+	eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	tripInfo, err := parseAmendmentArgs(eventArgs, eventContext)
+	if err != nil {
+		return err
+	}
+
+	// A trip amended to be unusable is treated exactly like an unusable trip in the first place -- it's
+	// discarded (and can thus be amended again).
+	if !eh.isUsableTripSample(tripInfo) {
+		if err := eventStore.DiscardTripAmendment(tripInfo); err != nil {
+			return fmt.Errorf("failed to discard trip for TripAmend event %v with EventStore: %w", eventArgs, err)
+		}
+
+		eh.discarded(eventContext, amendmentEventType, tripInfo)
+		return nil
+	}
+
+	if err := eventStore.AmendTrip(tripInfo); err != nil {
+		return fmt.Errorf("failed to amend trip for TripAmend event %v with EventStore: %w", eventArgs, err)
+	}
+
+	return nil
+}
+
+// parseAmendmentArgs parses the args of a TripAmend event into an `eventstore.TripInfo` (with an empty
+// `DriverID`, since amended trips stay attributed to their original driver).
+func parseAmendmentArgs(eventArgs eventhandler.EventArgs,
+	eventContext eventhandler.EventContext) (*eventstore.TripInfo, error) {
+	var a amendmentArgs
+	if err := amendmentArgSchema.Decode(eventArgs, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse TripAmend event %v: %w", eventArgs, err)
+	}
+
+	tripInfo, err := computeTripInfo(a.Start, a.Stop, a.Distance, eventContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TripAmend event %v: %w", eventArgs, err)
+	}
+
+	tripInfo.VehicleID = a.VehicleID
+	tripInfo.TripID = a.TripID
+
+	return tripInfo, nil
+}
@@ -0,0 +1,192 @@
+package trip_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventstore"
+)
+
+func TestTripCorrectionEventHandlers(t *testing.T) {
+	tests := map[string]struct {
+		eventHandler eventhandler.Interface
+		input        eventhandler.EventArgs
+		// For when `Handle`() returns an error.
+		expectError bool
+		// `expectedOutput` is mutually exclusive with `expectError`.
+		expectedOutput eventstore.VisitableEntity
+	}{
+		"CancelTooFewArgs": {
+			eventHandler: &trip.CancellationEventHandler{},
+			input:        eventhandler.EventArgs{},
+			expectError:  true,
+		},
+		"CancelTooManyArgs": {
+			eventHandler: &trip.CancellationEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "TripB"},
+			expectError:  true,
+		},
+		"CancelUnknownTrip": {
+			eventHandler: &trip.CancellationEventHandler{},
+			input:        eventhandler.EventArgs{"TripC"},
+			expectError:  true,
+		},
+		"Cancel": {
+			eventHandler: &trip.CancellationEventHandler{},
+			input:        eventhandler.EventArgs{"TripA"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0,
+			},
+		},
+		"AmendTooFewArgs": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "01:00", "02:00"},
+			expectError:  true,
+		},
+		"AmendUnknownTrip": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripC", "01:00", "02:00", "25.5"},
+			expectError:  true,
+		},
+		"AmendWithUnknownKeywordArg": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "01:00", "02:00", "25.5", "id=TripB"},
+			expectError:  true,
+		},
+		"AmendWronglyFormattedStartTime": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "01:00:45", "02:00", "25.5"},
+			expectError:  true,
+		},
+		"Amend": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "01:00", "03:00", "40km"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 54.854848,
+			},
+		},
+		"AmendToUnusableTripDiscardsIt": {
+			eventHandler: &trip.AmendmentEventHandler{},
+			input:        eventhandler.EventArgs{"TripA", "01:00", "02:00", "200"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			es := eventstore.New()
+			for _, eventArgs := range []eventhandler.EventArgs{
+				{"DriverA", "01:00", "02:00", "20", "id=TripA"},
+				{"DriverA", "03:00", "04:00", "30", "id=TripB"},
+			} {
+				if err := (&trip.EventHandler{}).Handle(eventArgs, es); err != nil {
+					t.Fatalf("failed to set up Trip %v: %v", eventArgs, err)
+				}
+			}
+
+			err := tc.eventHandler.Handle(tc.input, es)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			r := eventstore.NewRecorder()
+			es.Visit(r)
+
+			if len(r.Entities) != 1 {
+				t.Fatalf("expected exactly 1 VisitableEntity in EventStore, got %v", len(r.Entities))
+			}
+
+			if actualOutput := r.Entities[0]; !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+// Trips discarded for their speed (either in the first place, or after an amendment) must remain correctable.
+func TestTripCorrectionsOfDiscardedTrips(t *testing.T) {
+	type event struct {
+		eventHandler eventhandler.Interface
+		eventArgs    eventhandler.EventArgs
+		// For when `Handle`() returns an error.
+		expectError bool
+	}
+
+	tests := map[string]struct {
+		events         []event
+		expectedOutput []eventstore.VisitableEntity
+	}{
+		"AmendDiscardedTripToUsableTrip": {
+			events: []event{
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "500", "id=TripA"}},
+				{eventHandler: &trip.AmendmentEventHandler{}, eventArgs: eventhandler.EventArgs{"TripA", "01:00", "02:00", "30"}},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+		},
+		"AmendTripToUnusableTripAndBack": {
+			events: []event{
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "30", "id=TripA"}},
+				{eventHandler: &trip.AmendmentEventHandler{}, eventArgs: eventhandler.EventArgs{"TripA", "01:00", "02:00", "500"}},
+				{eventHandler: &trip.AmendmentEventHandler{}, eventArgs: eventhandler.EventArgs{"TripA", "01:00", "03:00", "40"}},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 2 * time.Hour, TotalMilesDriven: 40.0},
+			},
+		},
+		"CancelDiscardedTrip": {
+			events: []event{
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "30", "id=TripA"}},
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "03:00", "04:00", "500", "id=TripB"}},
+				{eventHandler: &trip.CancellationEventHandler{}, eventArgs: eventhandler.EventArgs{"TripB"}},
+				// Cancelled trips are gone for good.
+				{eventHandler: &trip.AmendmentEventHandler{}, eventArgs: eventhandler.EventArgs{"TripB", "03:00", "04:00", "30"},
+					expectError: true},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+		},
+		"DiscardedTripIDCantBeReused": {
+			events: []event{
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "500", "id=TripA"}},
+				{eventHandler: &trip.EventHandler{}, eventArgs: eventhandler.EventArgs{"DriverA", "03:00", "04:00", "30", "id=TripA"},
+					expectError: true},
+			},
+			expectedOutput: []eventstore.VisitableEntity{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			es := eventstore.New()
+			for i, e := range tc.events {
+				err := e.eventHandler.Handle(e.eventArgs, es)
+				switch {
+				case !e.expectError && err != nil:
+					t.Fatalf("event %d expected: no error, got: %v", i, err)
+				case e.expectError && err == nil:
+					t.Fatalf("event %d expected: error, got: no error", i)
+				}
+			}
+
+			r := eventstore.NewRecorder()
+			es.Visit(r)
+
+			if !reflect.DeepEqual(r.Entities, tc.expectedOutput) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, r.Entities)
+			}
+		})
+	}
+}
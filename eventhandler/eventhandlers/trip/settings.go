@@ -0,0 +1,111 @@
+package trip
+
+import (
+	"fmt"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+	"root.challenge/mathutils"
+)
+
+// SpeedBounds are the average speeds (in miles/hour) outside of which trips are considered too anomalous to
+// use in our dataset, and are thus discarded (by both "Trip" and "TripAmend" events).
+type SpeedBounds struct {
+	MinMph float64
+	MaxMph float64
+}
+
+// DefaultSpeedBounds are the `SpeedBounds` of every handler not customized by `WithSpeedBounds`.
+var DefaultSpeedBounds = SpeedBounds{MinMph: 5, MaxMph: 100}
+
+// Validate returns an `error` if `sb` can't be satisfied by any trip.
+func (sb SpeedBounds) Validate() error {
+	if sb.MinMph < 0 {
+		return fmt.Errorf("minimum speed %gmph is negative", sb.MinMph)
+	}
+
+	if sb.MaxMph < sb.MinMph {
+		return fmt.Errorf("maximum speed %gmph is below minimum speed %gmph", sb.MaxMph, sb.MinMph)
+	}
+
+	return nil
+}
+
+// Option customizes the handlers created by `NewEventHandler` and `NewAmendmentEventHandler` (or registered
+// by `Register`).
+type Option func(*settings)
+
+// WithSpeedBounds makes handlers discard trips as per `speedBounds`, rather than as per
+// `DefaultSpeedBounds`.
+func WithSpeedBounds(speedBounds SpeedBounds) Option {
+	return func(s *settings) {
+		s.speedBounds = &speedBounds
+	}
+}
+
+// WithDiscardObserver makes handlers pass every trip they discard as per their `SpeedBounds` to `observe`,
+// along with the `eventhandler.EventType` of the event that held it.
+func WithDiscardObserver(observe func(eventType eventhandler.EventType, tripInfo *eventstore.TripInfo)) Option {
+	return func(s *settings) {
+		s.observeDiscard = observe
+	}
+}
+
+// settings holds the customizations (made via `Option`s) shared by the handlers of this package.
+//
+// Its zero value stands for the defaults, so that zero-value handlers (like `&EventHandler{}`) keep working.
+type settings struct {
+	// speedBounds is nil for `DefaultSpeedBounds`.
+	speedBounds *SpeedBounds
+	// observeDiscard is nil unless set by `WithDiscardObserver`.
+	observeDiscard func(eventType eventhandler.EventType, tripInfo *eventstore.TripInfo)
+}
+
+func newSettings(opts []Option) (settings, error) {
+	var s settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if err := s.bounds().Validate(); err != nil {
+		return settings{}, fmt.Errorf("invalid speed bounds: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s settings) bounds() SpeedBounds {
+	if s.speedBounds == nil {
+		return DefaultSpeedBounds
+	}
+
+	return *s.speedBounds
+}
+
+// isUsableTripSample returns whether the average speed of `tripInfo` is within `s.bounds`().
+func (s settings) isUsableTripSample(tripInfo *eventstore.TripInfo) bool {
+	tripSpeedMph := computeTripSpeedMph(tripInfo)
+
+	speedBounds := s.bounds()
+	return tripSpeedMph >= speedBounds.MinMph && tripSpeedMph <= speedBounds.MaxMph
+}
+
+func computeTripSpeedMph(tripInfo *eventstore.TripInfo) float64 {
+	return mathutils.ComputeSpeedMph64(mathutils.ConvertDistance64(
+		mathutils.WidenFloat32(tripInfo.TripMileage), tripInfo.TripDistanceUnit, mathutils.Miles), tripInfo.TripDuration)
+}
+
+// discarded notes that `tripInfo` (held by an event of `eventType`, that came with `eventContext`) was
+// discarded for not being a usable trip sample.
+func (s settings) discarded(eventContext eventhandler.EventContext, eventType eventhandler.EventType,
+	tripInfo *eventstore.TripInfo) {
+	// Discarding trips is routine (that's what `SpeedBounds` are for), so it's only worth an info entry.
+	eventContext.Logger.Info("discarded trip with implausible speed",
+		logging.String("trip_id", tripInfo.TripID),
+		logging.Float("speed_mph", computeTripSpeedMph(tripInfo)))
+
+	if s.observeDiscard != nil {
+		s.observeDiscard(eventType, tripInfo)
+	}
+}
@@ -0,0 +1,142 @@
+package trip_test
+
+import (
+	"reflect"
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventstore"
+)
+
+func TestSpeedBounds(t *testing.T) {
+	tests := map[string]struct {
+		opts           []trip.Option
+		input          eventhandler.EventArgs
+		expectRecorded bool
+	}{
+		"DefaultBoundsDiscardFastTrip": {
+			input:          eventhandler.EventArgs{"DriverA", "01:00", "02:00", "150"},
+			expectRecorded: false,
+		},
+		"WiderBoundsKeepFastTrip": {
+			opts:           []trip.Option{trip.WithSpeedBounds(trip.SpeedBounds{MinMph: 1, MaxMph: 200})},
+			input:          eventhandler.EventArgs{"DriverA", "01:00", "02:00", "150"},
+			expectRecorded: true,
+		},
+		"WiderBoundsKeepSlowTrip": {
+			opts:           []trip.Option{trip.WithSpeedBounds(trip.SpeedBounds{MinMph: 1, MaxMph: 200})},
+			input:          eventhandler.EventArgs{"DriverA", "01:00", "02:00", "2"},
+			expectRecorded: true,
+		},
+		"NarrowerBoundsDiscardTrip": {
+			opts:           []trip.Option{trip.WithSpeedBounds(trip.SpeedBounds{MinMph: 30, MaxMph: 60})},
+			input:          eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25"},
+			expectRecorded: false,
+		},
+		"BoundsAreInclusive": {
+			opts:           []trip.Option{trip.WithSpeedBounds(trip.SpeedBounds{MinMph: 25, MaxMph: 25})},
+			input:          eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25"},
+			expectRecorded: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			teh, err := trip.NewEventHandler(tc.opts...)
+			if err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+
+			es := eventstore.New()
+			if err := teh.Handle(tc.input, es); err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+
+			r := eventstore.NewRecorder()
+			es.Visit(r)
+			if recorded := len(r.Entities) == 1; recorded != tc.expectRecorded {
+				t.Fatalf("expected: trip recorded=%v, got: %#v", tc.expectRecorded, r.Entities)
+			}
+		})
+	}
+}
+
+func TestAmendmentSpeedBounds(t *testing.T) {
+	opts := []trip.Option{trip.WithSpeedBounds(trip.SpeedBounds{MinMph: 1, MaxMph: 200})}
+
+	teh, err := trip.NewEventHandler(opts...)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	aeh, err := trip.NewAmendmentEventHandler(opts...)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	es := eventstore.New()
+	if err := teh.Handle(eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25", "id=t1"}, es); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// An amendment to 150mph would discard the trip with the default bounds, but not with these.
+	if err := aeh.Handle(eventhandler.EventArgs{"t1", "01:00", "02:00", "150"}, es); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	r := eventstore.NewRecorder()
+	es.Visit(r)
+	if len(r.Entities) != 1 || r.Entities[0].TotalMilesDriven != 150 {
+		t.Fatalf("expected: 1 driver with 150 miles, got: %#v", r.Entities)
+	}
+}
+
+func TestInvalidSpeedBounds(t *testing.T) {
+	for name, speedBounds := range map[string]trip.SpeedBounds{
+		"NegativeMinimum":     {MinMph: -1, MaxMph: 100},
+		"MaximumBelowMinimum": {MinMph: 50, MaxMph: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := trip.NewEventHandler(trip.WithSpeedBounds(speedBounds)); err == nil {
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if err := trip.Register(eventhandler.NewRegistry(), trip.WithSpeedBounds(speedBounds)); err == nil {
+				t.Fatalf("expected: error, got: no error")
+			}
+		})
+	}
+}
+
+func TestDiscardObserver(t *testing.T) {
+	var discarded []eventhandler.EventType
+	opts := []trip.Option{trip.WithDiscardObserver(func(eventType eventhandler.EventType, _ *eventstore.TripInfo) {
+		discarded = append(discarded, eventType)
+	})}
+
+	teh, err := trip.NewEventHandler(opts...)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	aeh, err := trip.NewAmendmentEventHandler(opts...)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	es := eventstore.New()
+	for _, input := range []eventhandler.EventArgs{
+		{"DriverA", "01:00", "02:00", "150"},
+		{"DriverA", "01:00", "02:00", "25", "id=t1"},
+	} {
+		if err := teh.Handle(input, es); err != nil {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+	}
+	if err := aeh.Handle(eventhandler.EventArgs{"t1", "01:00", "02:00", "2"}, es); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	if expected := []eventhandler.EventType{"Trip", "TripAmend"}; !reflect.DeepEqual(expected, discarded) {
+		t.Fatalf("expected: %v, got: %v", expected, discarded)
+	}
+}
@@ -3,6 +3,7 @@ package trip
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"root.challenge/eventhandler"
@@ -12,88 +13,265 @@ import (
 
 const eventType eventhandler.EventType = "Trip"
 
+// The fields shared by the Trip event and its corrections (which replace every measurement of a trip).
+var (
+	startField    = eventhandler.ClockField("start", "start time")
+	stopField     = eventhandler.ClockField("stop", "stop time")
+	distanceField = eventhandler.CustomField("distance", "distance driven", "number[mi|km]",
+		func(s string) (interface{}, error) {
+			return parseDistance(s)
+		})
+	// vehicleField attributes a trip to a particular vehicle.
+	vehicleField = eventhandler.StringField("vehicle", "vehicle ID").Keyword().As(eventhandler.VehicleRole)
+)
+
+var argSchema = eventhandler.NewSchema(
+	eventhandler.StringField("driver", "driver ID").As(eventhandler.DriverRole),
+	startField,
+	stopField,
+	distanceField,
+	vehicleField,
+	// The trip ID allows a trip to be referenced by later corrections.
+	eventhandler.StringField("id", "trip ID").Keyword().As(eventhandler.TripRole),
+)
+
+type args struct {
+	DriverID  string    `arg:"driver"`
+	Start     time.Time `arg:"start"`
+	Stop      time.Time `arg:"stop"`
+	Distance  distance  `arg:"distance"`
+	VehicleID string    `arg:"vehicle"`
+	TripID    string    `arg:"id"`
+}
+
+// distance is the distance driven in a trip, in the unit it was expressed in.
+type distance struct {
+	mileage float32
+	// unit is only meaningful if unitGiven is true -- otherwise, the distance is in the
+	// `eventhandler.EventContext.DistanceUnit` of its event.
+	unit      mathutils.DistanceUnit
+	unitGiven bool
+}
+
 // EventHandler is an implementation of `eventhandler.Interface` for the "Trip" EventType.
-type EventHandler struct{}
+//
+// Trips reference their driver by ID (which, for drivers registered via legacy "Driver <first name>"
+// events, is their first name).
+//
+// Its zero value discards trips as per `DefaultSpeedBounds` (see `NewEventHandler` to customize that).
+type EventHandler struct {
+	settings
+}
+
+// NewEventHandler creates a new `EventHandler`, customized by `opts`.
+func NewEventHandler(opts ...Option) (*EventHandler, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventHandler{settings: s}, nil
+}
 
 func init() {
-	if err := eventhandler.GlobalRegistry().RegisterEventHandler(eventType, &EventHandler{}); err != nil {
+	if err := Register(eventhandler.GlobalRegistry()); err != nil {
 		panic(err)
 	}
 }
 
+// Register registers this package's handlers (customized by `opts`) with `r` (which this package does with
+// `eventhandler.GlobalRegistry`() at load time, without any `opts`).
+func Register(r *eventhandler.Registry, opts ...Option) error {
+	s, err := newSettings(opts)
+	if err != nil {
+		return err
+	}
+
+	for eventType, eventHandler := range map[eventhandler.EventType]eventhandler.Interface{
+		eventType:             &EventHandler{settings: s},
+		cancellationEventType: &CancellationEventHandler{},
+		amendmentEventType:    &AmendmentEventHandler{settings: s},
+	} {
+		if err := r.RegisterEventHandler(eventType, eventHandler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *EventHandler) Description() string {
+	speedBounds := eh.bounds()
+	return fmt.Sprintf("Records a trip by a driver (discarding trips slower than %gmph or faster than %gmph).",
+		speedBounds.MinMph, speedBounds.MaxMph)
+}
+
+// Conforms to `eventhandler.Aliaser`.
+//
+// "Ride" is what some of our partner feeds call trips.
+func (eh *EventHandler) Aliases() []eventhandler.EventType {
+	return []eventhandler.EventType{"Ride"}
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *EventHandler) ArgSchema() *eventhandler.Schema {
+	return argSchema
+}
+
+// Conforms to `eventhandler.PreconditionChecker`.
+//
+// This checks the constraints between fields that `argSchema` can't express on its own.
+func (eh *EventHandler) CheckPreconditions(eventArgs eventhandler.EventArgs) error {
+	return checkTripTimes(argSchema, eventArgs)
+}
+
 // Conforms to `eventhandler.Interface`.
 func (eh *EventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
-	if len(eventArgs) != 4 {
-		return fmt.Errorf(
-			"expecting exactly 4 args (first name, start time, stop time, miles driven) to Trip event %v; got %d",
-			eventArgs, len(eventArgs))
+	return eh.HandleWithContext(eventhandler.EventContext{}, eventArgs, eventStore)
+}
+
+// Conforms to `eventhandler.ContextAwareHandler`.
+//
+// Distances without a unit are taken to be in the `DistanceUnit` of `eventContext`.
+func (eh *EventHandler) HandleWithContext(eventContext eventhandler.EventContext, eventArgs eventhandler.EventArgs,
+	eventStore *eventstore.EventStore) error {
+	tripInfo, err := parseArgs(eventArgs, eventContext)
+	if err != nil {
+		return err
 	}
 
-	driverFirstName, startTimeStr, stopTimeStr, tripMileageStr :=
-		eventArgs[0], eventArgs[1], eventArgs[2], eventArgs[3]
+	if !eh.isUsableTripSample(tripInfo) {
+		// Discarded trips remain known to the `eventstore.EventStore`, so that they can still be amended.
+		if err := eventStore.DiscardTrip(tripInfo); err != nil {
+			return fmt.Errorf("failed to discard trip for Trip event %v with EventStore: %w", eventArgs, err)
+		}
 
-	tripDuration, err := computeTripDuration(startTimeStr, stopTimeStr)
-	if err != nil {
-		return fmt.Errorf("failed to compute trip duration for Trip event %v: %w", eventArgs, err)
+		eh.discarded(eventContext, eventType, tripInfo)
+		return nil
 	}
 
-	tripMileage, err := computeTripMileage(tripMileageStr)
-	if err != nil {
-		return fmt.Errorf("failed to compute trip mileage for Trip event %v: %w", eventArgs, err)
+	if err := eventStore.RecordTrip(tripInfo); err != nil {
+		return fmt.Errorf("failed to record Trip event %v with EventStore: %w", eventArgs, err)
 	}
 
-	if isUsableTripSample(tripMileage, tripDuration) {
-		if err := eventStore.RecordTrip(&eventstore.TripInfo{
-			DriverFirstName: driverFirstName,
-			TripDuration:    tripDuration,
-			TripMileage:     tripMileage,
-		}); err != nil {
-			return fmt.Errorf("failed to record Trip event %v with EventStore: %w", eventArgs, err)
-		}
+	return nil
+}
+
+// Conforms to `eventhandler.TeardownHook`.
+//
+// Trips can only be parked (see `eventstore.WithParkedTrips`) by this handler, so it's responsible for
+// giving up on the ones whose drivers were never registered by the end of the processing run.
+func (eh *EventHandler) Teardown(eventStore *eventstore.EventStore) error {
+	if errs := eventStore.FlushParkedTrips(); len(errs) > 0 {
+		return parkedTripErrors(errs)
 	}
 
 	return nil
 }
 
-func computeTripDuration(startTimeStr, stopTimeStr string) (time.Duration, error) {
-	const expectedTimeFormatStr string = "15:04"
+// parseArgs parses the args of a Trip event (that came with `eventContext`) into an `eventstore.TripInfo`.
+func parseArgs(eventArgs eventhandler.EventArgs, eventContext eventhandler.EventContext) (*eventstore.TripInfo, error) {
+	var a args
+	if err := argSchema.Decode(eventArgs, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse Trip event %v: %w", eventArgs, err)
+	}
 
-	startTime, err := time.Parse(expectedTimeFormatStr, startTimeStr)
+	tripInfo, err := computeTripInfo(a.Start, a.Stop, a.Distance, eventContext)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse start time %s (likely doesn't conform to %s format): %w",
-			startTimeStr, expectedTimeFormatStr, err)
+		return nil, fmt.Errorf("failed to parse Trip event %v: %w", eventArgs, err)
 	}
 
-	stopTime, err := time.Parse(expectedTimeFormatStr, stopTimeStr)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse stop time %s (likely doesn't conform to %s format): %w",
-			stopTimeStr, expectedTimeFormatStr, err)
+	tripInfo.DriverID = a.DriverID
+	tripInfo.VehicleID = a.VehicleID
+	tripInfo.TripID = a.TripID
+
+	return tripInfo, nil
+}
+
+// parkedTripErrors collects the `error`s of every parked trip given up on by `Teardown`.
+type parkedTripErrors []error
+
+// Conforms to `error`.
+func (pte parkedTripErrors) Error() string {
+	errStrs := make([]string, 0, len(pte))
+	for _, err := range pte {
+		errStrs = append(errStrs, err.Error())
 	}
 
+	return fmt.Sprintf("failed to record %d parked trips: %s", len(pte), strings.Join(errStrs, "; "))
+}
+
+// tripTimes is all that the preconditions of a trip (or of its amendment) are about.
+type tripTimes struct {
+	Start time.Time `arg:"start"`
+	Stop  time.Time `arg:"stop"`
+}
+
+// checkTripTimes checks that the start time of `eventArgs` comes before its stop time -- `eventArgs` are
+// otherwise taken to conform to `schema` (which has "start" and "stop" fields), so no other field is parsed.
+func checkTripTimes(schema *eventhandler.Schema, eventArgs eventhandler.EventArgs) error {
+	var tt tripTimes
+	if err := schema.DecodeValidated(eventArgs, &tt); err != nil {
+		return err
+	}
+
+	return checkStartBeforeStop(tt.Start, tt.Stop)
+}
+
+func checkStartBeforeStop(startTime, stopTime time.Time) error {
 	if !startTime.Before(stopTime) {
-		return 0, fmt.Errorf("start time %s doesn't come before stop time %s",
-			startTimeStr, stopTimeStr)
+		return fmt.Errorf("start time %s doesn't come before stop time %s",
+			startTime.Format("15:04"), stopTime.Format("15:04"))
 	}
 
-	return stopTime.Sub(startTime), nil
+	return nil
 }
 
-func computeTripMileage(tripMileageStr string) (float32, error) {
-	tripMileage64, err := strconv.ParseFloat(tripMileageStr, 32)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse miles driven %s: %w", tripMileageStr, err)
+// computeTripInfo fills in the measurements of a trip (and nothing else) in a new `eventstore.TripInfo`.
+//
+// Distances without a unit are taken to be in the `DistanceUnit` of `eventContext`.
+func computeTripInfo(startTime, stopTime time.Time, tripDistance distance,
+	eventContext eventhandler.EventContext) (*eventstore.TripInfo, error) {
+	if err := checkStartBeforeStop(startTime, stopTime); err != nil {
+		return nil, err
+	}
+
+	tripDistanceUnit := tripDistance.unit
+	if !tripDistance.unitGiven {
+		tripDistanceUnit = eventContext.DistanceUnit
 	}
 
-	return float32(tripMileage64), nil
+	return &eventstore.TripInfo{
+		TripDuration:     stopTime.Sub(startTime),
+		TripMileage:      tripDistance.mileage,
+		TripDistanceUnit: tripDistanceUnit,
+	}, nil
 }
 
-func isUsableTripSample(tripMileage float32, tripDuration time.Duration) bool {
-	tripSpeedMph := mathutils.ComputeSpeedMph32(tripMileage, tripDuration)
+// parseDistance parses the distance driven, which is a number optionally suffixed with the
+// `mathutils.DistanceUnit` it's expressed in (for example, "17.3", "17.3mi", or "27.8km") -- a bare
+// number is in the `eventhandler.EventContext.DistanceUnit` of its event, which is miles unless the input
+// declares otherwise (as is the case for every input predating unit support).
+func parseDistance(tripMileageStr string) (distance, error) {
+	numberStr := strings.TrimRightFunc(tripMileageStr, func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	})
 
-	// Speeds < 5mph or > 100mph are considered too anomalous to use in our dataset.
-	if tripSpeedMph < 5.0 || tripSpeedMph > 100.0 {
-		return false
+	var tripDistance distance
+	if unitStr := tripMileageStr[len(numberStr):]; unitStr != "" {
+		var err error
+		if tripDistance.unit, err = mathutils.ParseDistanceUnit(unitStr); err != nil {
+			return distance{}, fmt.Errorf("failed to parse unit: %w", err)
+		}
+		tripDistance.unitGiven = true
+	}
+
+	tripMileage64, err := strconv.ParseFloat(numberStr, 32)
+	if err != nil {
+		return distance{}, fmt.Errorf("failed to parse number: %w", err)
 	}
+	tripDistance.mileage = float32(tripMileage64)
 
-	return true
+	return tripDistance, nil
 }
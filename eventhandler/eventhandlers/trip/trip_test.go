@@ -1,23 +1,29 @@
 package trip_test
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
 	"root.challenge/eventhandler"
 	"root.challenge/eventhandler/eventhandlers/trip"
 	"root.challenge/eventstore"
+	"root.challenge/mathutils"
 )
 
 func TestTripEventHandler(t *testing.T) {
 	tests := map[string]struct {
 		input eventhandler.EventArgs
+		// Left as the zero value for events from inputs that declare nothing.
+		eventContext eventhandler.EventContext
 		// For when `Handle`() returns an error.
 		expectError bool
 		// For when the event is discarded.
 		expectNoOp bool
 		// `expectedOutput` is mutually exclusive with `expectError` and `expectNoOp`.
 		expectedOutput eventstore.VisitableEntity
+		// Left nil for trips that aren't attributed to a vehicle.
+		expectedVehicleOutput *eventstore.VisitableVehicleEntity
 	}{
 		"TooFewArgs": {
 			input:       eventhandler.EventArgs{},
@@ -50,7 +56,8 @@ func TestTripEventHandler(t *testing.T) {
 		"MileageFormattedAsInt": {
 			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25"},
 			expectedOutput: eventstore.VisitableEntity{
-				DriverFirstName:     "DriverA",
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
 				TotalDurationDriven: 1 * time.Hour,
 				TotalMilesDriven:    25.0,
 			},
@@ -58,11 +65,97 @@ func TestTripEventHandler(t *testing.T) {
 		"CorrectArgs": {
 			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5"},
 			expectedOutput: eventstore.VisitableEntity{
-				DriverFirstName:     "DriverA",
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
 				TotalDurationDriven: 1 * time.Hour,
 				TotalMilesDriven:    25.5,
 			},
 		},
+		"MileageWithMilesSuffix": {
+			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5mi"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    25.5,
+			},
+		},
+		"MileageWithKilometersSuffix": {
+			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "40km"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    24.854848,
+			},
+		},
+		"MileageWithUnknownSuffix": {
+			input:       eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5ft"},
+			expectError: true,
+		},
+		"MileageWithOnlySuffix": {
+			input:       eventhandler.EventArgs{"DriverA", "01:00", "02:00", "km"},
+			expectError: true,
+		},
+		"DiscardTripWithSpeedLessThan5MphInKilometers": {
+			input:      eventhandler.EventArgs{"DriverA", "01:00", "02:00", "8km"},
+			expectNoOp: true,
+		},
+		"KeepTripWithSpeedGreaterThan100KphButLessThan100Mph": {
+			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "120km"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    74.564543,
+			},
+		},
+		"BareDistanceInUnitOfEventContext": {
+			input:        eventhandler.EventArgs{"DriverA", "01:00", "02:00", "120"},
+			eventContext: eventhandler.EventContext{DistanceUnit: mathutils.Kilometers},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    74.564543,
+			},
+		},
+		"ExplicitUnitOverridesUnitOfEventContext": {
+			input:        eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5mi"},
+			eventContext: eventhandler.EventContext{DistanceUnit: mathutils.Kilometers},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    25.5,
+			},
+		},
+		"TripWithVehicle": {
+			input: eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5", "vehicle=VehicleA"},
+			expectedOutput: eventstore.VisitableEntity{
+				DriverID:            "DriverA",
+				DriverDisplayName:   "DriverA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    25.5,
+			},
+			expectedVehicleOutput: &eventstore.VisitableVehicleEntity{
+				VehicleID:           "VehicleA",
+				TotalDurationDriven: 1 * time.Hour,
+				TotalMilesDriven:    25.5,
+			},
+		},
+		"TripWithUnknownKeywordArg": {
+			input:       eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5", "car=VehicleA"},
+			expectError: true,
+		},
+		"TripWithDuplicateVehicle": {
+			input:       eventhandler.EventArgs{"DriverA", "01:00", "02:00", "25.5", "vehicle=VehicleA", "vehicle=VehicleB"},
+			expectError: true,
+		},
+		"TripWithVehicleButTooFewPositionalArgs": {
+			input:       eventhandler.EventArgs{"DriverA", "01:00", "02:00", "vehicle=VehicleA"},
+			expectError: true,
+		},
 		"DiscardTripWithSpeedLessThan5Mph": {
 			input:      eventhandler.EventArgs{"DriverA", "01:00", "02:00", "4.9"},
 			expectNoOp: true,
@@ -78,7 +171,17 @@ func TestTripEventHandler(t *testing.T) {
 			es := eventstore.New()
 			teh := &trip.EventHandler{}
 
-			err := teh.Handle(tc.input, es)
+			// Every malformed event should already be caught by the (cheaper) schema validation and precondition
+			// check, which the framework runs in that order.
+			err := teh.ArgSchema().Validate(tc.input)
+			if err == nil {
+				err = teh.CheckPreconditions(tc.input)
+			}
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected: precondition error=%v, got: %v", tc.expectError, err)
+			}
+
+			err = teh.HandleWithContext(tc.eventContext, tc.input, es)
 			switch {
 			case tc.expectError && err != nil:
 				return
@@ -90,6 +193,7 @@ func TestTripEventHandler(t *testing.T) {
 
 			r := eventstore.NewRecorder()
 			es.Visit(r)
+			es.VisitVehicles(r)
 
 			if tc.expectNoOp {
 				if len(r.Entities) == 0 {
@@ -104,9 +208,60 @@ func TestTripEventHandler(t *testing.T) {
 			}
 
 			actualOutput := r.Entities[0]
-			if actualOutput != tc.expectedOutput {
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
 				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
 			}
+
+			if tc.expectedVehicleOutput == nil {
+				if len(r.VehicleEntities) != 0 {
+					t.Fatalf("expected: 0 VisitableVehicleEntities in EventStore, got %#v", r.VehicleEntities)
+				}
+				return
+			}
+
+			if len(r.VehicleEntities) != 1 {
+				t.Fatalf("expected exactly 1 VisitableVehicleEntity in EventStore, got %v", len(r.VehicleEntities))
+			}
+
+			if actualVehicleOutput := r.VehicleEntities[0]; actualVehicleOutput != *tc.expectedVehicleOutput {
+				t.Fatalf("expected: %v, got: %v", *tc.expectedVehicleOutput, actualVehicleOutput)
+			}
 		})
 	}
 }
+
+func TestTripEventHandlerTeardown(t *testing.T) {
+	es := eventstore.New(eventstore.WithParkedTrips(10))
+	teh := &trip.EventHandler{}
+
+	for _, eventArgs := range []eventhandler.EventArgs{
+		{"DriverA", "01:00", "02:00", "25.5"},
+		{"DriverB", "01:00", "02:00", "25.5"},
+	} {
+		if err := teh.Handle(eventArgs, es); err != nil {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+	}
+
+	// Trips still waiting on their drivers at the end of the run are given up on.
+	if err := teh.Teardown(es); err == nil {
+		t.Fatalf("expected: error, got: no error")
+	}
+
+	if err := es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA", DisplayName: "DriverA"}); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	r := eventstore.NewRecorder()
+	es.Visit(r)
+
+	expectedOutput := []eventstore.VisitableEntity{{DriverID: "DriverA", DriverDisplayName: "DriverA"}}
+	if !reflect.DeepEqual(r.Entities, expectedOutput) {
+		t.Fatalf("expected: %v, got: %v", expectedOutput, r.Entities)
+	}
+
+	// There's nothing left to give up on.
+	if err := teh.Teardown(es); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package vehicle
+
+import (
+	"fmt"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+)
+
+const eventType eventhandler.EventType = "Vehicle"
+
+var argSchema = eventhandler.NewSchema(
+	eventhandler.StringField("id", "vehicle ID").As(eventhandler.VehicleRole),
+	eventhandler.StringField("class", "class"),
+	eventhandler.StringField("fuel", "fuel type"),
+)
+
+type args struct {
+	ID       string `arg:"id"`
+	Class    string `arg:"class"`
+	FuelType string `arg:"fuel"`
+}
+
+// EventHandler is an implementation of `eventhandler.Interface` for the "Vehicle" EventType.
+type EventHandler struct{}
+
+func init() {
+	if err := Register(eventhandler.GlobalRegistry()); err != nil {
+		panic(err)
+	}
+}
+
+// Register registers this package's handlers with `r` (which this package does with
+// `eventhandler.GlobalRegistry`() at load time).
+func Register(r *eventhandler.Registry) error {
+	return r.RegisterEventHandler(eventType, &EventHandler{})
+}
+
+// Conforms to `eventhandler.Describer`.
+func (eh *EventHandler) Description() string {
+	return "Registers a vehicle (or updates its attributes)."
+}
+
+// Conforms to `eventhandler.SchemaProvider`.
+func (eh *EventHandler) ArgSchema() *eventhandler.Schema {
+	return argSchema
+}
+
+// Conforms to `eventhandler.Interface`.
+func (eh *EventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	var a args
+	if err := argSchema.Decode(eventArgs, &a); err != nil {
+		return fmt.Errorf("failed to parse Vehicle event %v: %w", eventArgs, err)
+	}
+
+	if err := eventStore.RegisterVehicle(&eventstore.VehicleInfo{
+		ID:       a.ID,
+		Class:    a.Class,
+		FuelType: a.FuelType,
+	}); err != nil {
+		return fmt.Errorf("failed to register Vehicle event %v with EventStore: %w", eventArgs, err)
+	}
+
+	return nil
+}
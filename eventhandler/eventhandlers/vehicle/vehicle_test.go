@@ -0,0 +1,65 @@
+package vehicle_test
+
+import (
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/vehicle"
+	"root.challenge/eventstore"
+)
+
+func TestVehicleEventHandler(t *testing.T) {
+	tests := map[string]struct {
+		input eventhandler.EventArgs
+		// For when `Handle`() returns an error.
+		expectError bool
+		// `expectedOutput` is mutually exclusive with `expectError`.
+		expectedOutput eventstore.VisitableVehicleEntity
+	}{
+		"TooFewArgs": {
+			input:       eventhandler.EventArgs{"VehicleA", "Sedan"},
+			expectError: true,
+		},
+		"TooManyArgs": {
+			input:       eventhandler.EventArgs{"VehicleA", "Sedan", "Petrol", "SomeUnwantedInfo"},
+			expectError: true,
+		},
+		"CorrectArgs": {
+			input: eventhandler.EventArgs{"VehicleA", "Sedan", "Petrol"},
+			expectedOutput: eventstore.VisitableVehicleEntity{
+				VehicleID:       "VehicleA",
+				VehicleClass:    "Sedan",
+				VehicleFuelType: "Petrol",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			es := eventstore.New()
+			veh := &vehicle.EventHandler{}
+
+			err := veh.Handle(tc.input, es)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			r := eventstore.NewRecorder()
+			es.VisitVehicles(r)
+
+			if len(r.VehicleEntities) != 1 {
+				t.Fatalf("expected exactly 1 VisitableVehicleEntity in EventStore, got %v", len(r.VehicleEntities))
+			}
+
+			actualOutput := r.VehicleEntities[0]
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package eventhandler
+
+import "fmt"
+
+// Describer is an optional extension of `Interface` for handlers that can describe (in a sentence or so)
+// what their events mean, for the benefit of `Registry.ListEventHandlers`.
+type Describer interface {
+	Description() string
+}
+
+// HandlerInfo describes a registered `Interface` implementation, as returned by
+// `Registry.ListEventHandlers`.
+type HandlerInfo struct {
+	EventType EventType
+	// Aliases holds the other names the handler's events are accepted under (see `Aliaser` and
+	// `Registry.RegisterAlias`), and is nil for handlers that have none.
+	Aliases []EventType
+	// HandlerType is the Go type of the handler (for example, "*trip.EventHandler").
+	HandlerType string
+	// Description is empty for handlers that don't implement `Describer`.
+	Description string
+	// Hooks names the optional extensions of `Interface` (from hooks.go and context.go) that the handler
+	// implements.
+	Hooks []string
+	// Usage and Fields document the args of the handler's events, and are empty for handlers that don't
+	// implement `SchemaProvider`.
+	Usage  string
+	Fields []FieldInfo
+}
+
+func describeHandler(eventType EventType, eventHandler Interface) HandlerInfo {
+	handlerInfo := HandlerInfo{
+		EventType:   eventType,
+		HandlerType: fmt.Sprintf("%T", eventHandler),
+		Hooks:       make([]string, 0),
+	}
+
+	if describer, ok := eventHandler.(Describer); ok {
+		handlerInfo.Description = describer.Description()
+	}
+
+	if schemaProvider, ok := eventHandler.(SchemaProvider); ok {
+		handlerInfo.Usage = schemaProvider.ArgSchema().Usage(eventType)
+		handlerInfo.Fields = schemaProvider.ArgSchema().Fields()
+	}
+
+	if _, ok := eventHandler.(PreconditionChecker); ok {
+		handlerInfo.Hooks = append(handlerInfo.Hooks, "CheckPreconditions")
+	}
+	if _, ok := eventHandler.(ContextAwareHandler); ok {
+		handlerInfo.Hooks = append(handlerInfo.Hooks, "HandleWithContext")
+	}
+	if _, ok := eventHandler.(SetupHook); ok {
+		handlerInfo.Hooks = append(handlerInfo.Hooks, "Setup")
+	}
+	if _, ok := eventHandler.(TeardownHook); ok {
+		handlerInfo.Hooks = append(handlerInfo.Hooks, "Teardown")
+	}
+
+	return handlerInfo
+}
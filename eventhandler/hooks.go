@@ -0,0 +1,50 @@
+package eventhandler
+
+import "root.challenge/eventstore"
+
+// SetupHook is an optional extension of `Interface` for handlers that need to prepare for a processing run
+// before handling any of its events.
+//
+// The framework detects it with a type assertion, and invokes `Setup`() at most once per processing run for
+// each `EventType` the handler is registered for -- right before the first event of that `EventType` in the
+// run -- so handlers with no events in a run are never set up. A handler whose `Setup`() fails is not
+// invoked for the rest of that run.
+type SetupHook interface {
+	Setup(*eventstore.EventStore) error
+}
+
+// TeardownHook is an optional extension of `Interface` for handlers that need to wrap up at the end of a
+// processing run.
+//
+// The framework invokes `Teardown`() exactly once at the end of each processing run for every handler that
+// was (successfully) set up in it -- where a handler that doesn't implement `SetupHook` counts as set up
+// as soon as it's routed its first event -- in the reverse order of setup.
+type TeardownHook interface {
+	Teardown(*eventstore.EventStore) error
+}
+
+// PreconditionChecker is an optional extension of `Interface` for handlers that can cheaply sanity-check
+// an event (typically its shape, without consulting `eventstore.EventStore`) before `Handle`() is invoked.
+//
+// Events that fail `CheckPreconditions`() are never passed to `Handle`(), and their failures are reported
+// separately from failures in `Handle`() -- so implementations of `Handle`() should still be prepared to
+// reject malformed events when invoked directly.
+//
+// `CheckPreconditions`() must be free of side effects, since it's also what validation-only runs (see
+// `eventprocessor.EventProcessor.Validate`) check events with -- as must `SchemaProvider.ArgSchema`().
+//
+// The framework only checks the preconditions of events that conform to the `Schema` of their handler (if
+// any), so `CheckPreconditions`() only needs to check what the `Schema` can't express -- which it can get
+// at cheaply via `Schema.DecodeValidated`.
+type PreconditionChecker interface {
+	CheckPreconditions(EventArgs) error
+}
+
+// Aliaser is an optional extension of `Interface` for handlers that also handle events under names other
+// than the `EventType` they're registered for (for example, "Ride" for "Trip").
+//
+// `Registry.RegisterEventHandler` registers the aliases alongside the `EventType`, and fails if any of them
+// collide with a name that's already registered.
+type Aliaser interface {
+	Aliases() []EventType
+}
@@ -0,0 +1,56 @@
+package eventhandler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitKeywordArgs separates the trailing optional "key=value" args of an event from its leading
+// positional args.
+//
+// Keyword args must all come after every positional arg, may each appear at most once, and must use one
+// of `allowedKeywords` -- any violation of those rules results in an `error`.
+//
+// `Schema` takes care of this for handlers that declare their args -- this is for the rest.
+func SplitKeywordArgs(eventArgs EventArgs, allowedKeywords ...string) (EventArgs, map[string]string, error) {
+	numPositionalArgs := len(eventArgs)
+	for numPositionalArgs > 0 && strings.Contains(eventArgs[numPositionalArgs-1], "=") {
+		numPositionalArgs--
+	}
+
+	keywordArgs := make(map[string]string)
+	for _, arg := range eventArgs[numPositionalArgs:] {
+		keyword, value := splitKeywordArg(arg)
+
+		if !containsString(allowedKeywords, keyword) {
+			return nil, nil, fmt.Errorf("unexpected keyword arg '%s' (expecting one of %v)", arg, allowedKeywords)
+		}
+
+		if _, dup := keywordArgs[keyword]; dup {
+			return nil, nil, fmt.Errorf("keyword arg '%s' is specified more than once", keyword)
+		}
+
+		if value == "" {
+			return nil, nil, fmt.Errorf("keyword arg '%s' is missing a value", keyword)
+		}
+
+		keywordArgs[keyword] = value
+	}
+
+	return eventArgs[:numPositionalArgs], keywordArgs, nil
+}
+
+func splitKeywordArg(arg string) (string, string) {
+	i := strings.IndexByte(arg, '=')
+	return arg[:i], arg[i+1:]
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+
+	return false
+}
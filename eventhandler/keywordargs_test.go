@@ -0,0 +1,85 @@
+package eventhandler_test
+
+import (
+	"reflect"
+	"testing"
+
+	"root.challenge/eventhandler"
+)
+
+func TestSplitKeywordArgs(t *testing.T) {
+	tests := map[string]struct {
+		input                  eventhandler.EventArgs
+		allowedKeywords        []string
+		expectError            bool
+		expectedPositionalArgs eventhandler.EventArgs
+		expectedKeywordArgs    map[string]string
+	}{
+		"NoArgs": {
+			input:                  eventhandler.EventArgs{},
+			expectedPositionalArgs: eventhandler.EventArgs{},
+			expectedKeywordArgs:    map[string]string{},
+		},
+		"OnlyPositionalArgs": {
+			input:                  eventhandler.EventArgs{"A", "B"},
+			allowedKeywords:        []string{"k"},
+			expectedPositionalArgs: eventhandler.EventArgs{"A", "B"},
+			expectedKeywordArgs:    map[string]string{},
+		},
+		"PositionalAndKeywordArgs": {
+			input:                  eventhandler.EventArgs{"A", "B", "k1=v1", "k2=v=2"},
+			allowedKeywords:        []string{"k1", "k2"},
+			expectedPositionalArgs: eventhandler.EventArgs{"A", "B"},
+			expectedKeywordArgs:    map[string]string{"k1": "v1", "k2": "v=2"},
+		},
+		"OnlyKeywordArgs": {
+			input:                  eventhandler.EventArgs{"k1=v1"},
+			allowedKeywords:        []string{"k1"},
+			expectedPositionalArgs: eventhandler.EventArgs{},
+			expectedKeywordArgs:    map[string]string{"k1": "v1"},
+		},
+		"KeywordArgBeforePositionalArg": {
+			input:                  eventhandler.EventArgs{"k1=v1", "A"},
+			allowedKeywords:        []string{"k1"},
+			expectedPositionalArgs: eventhandler.EventArgs{"k1=v1", "A"},
+			expectedKeywordArgs:    map[string]string{},
+		},
+		"DisallowedKeyword": {
+			input:           eventhandler.EventArgs{"A", "k3=v3"},
+			allowedKeywords: []string{"k1"},
+			expectError:     true,
+		},
+		"DuplicateKeyword": {
+			input:           eventhandler.EventArgs{"A", "k1=v1", "k1=v2"},
+			allowedKeywords: []string{"k1"},
+			expectError:     true,
+		},
+		"MissingValue": {
+			input:           eventhandler.EventArgs{"A", "k1="},
+			allowedKeywords: []string{"k1"},
+			expectError:     true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualPositionalArgs, actualKeywordArgs, err := eventhandler.SplitKeywordArgs(tc.input, tc.allowedKeywords...)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if !reflect.DeepEqual(actualPositionalArgs, tc.expectedPositionalArgs) {
+				t.Fatalf("expected positional args: %#v, got: %#v", tc.expectedPositionalArgs, actualPositionalArgs)
+			}
+
+			if !reflect.DeepEqual(actualKeywordArgs, tc.expectedKeywordArgs) {
+				t.Fatalf("expected keyword args: %#v, got: %#v", tc.expectedKeywordArgs, actualKeywordArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package eventhandler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Overlay composes `layers` into a single `HandlerLookup` that finds the handler for each `EventType` in
+// the first of `layers` that has one -- so earlier layers override later ones, and later layers act as
+// fallbacks for earlier ones.
+//
+// For example, `Overlay(overrides, GlobalRegistry())` replaces (or adds to) a few of the default handlers
+// without affecting the rest, and without touching `GlobalRegistry`() itself.
+//
+// Layers are consulted at lookup time, so handlers registered with any of them after the composition are
+// still found.
+func Overlay(layers ...HandlerLookup) HandlerLookup {
+	return overlay(layers)
+}
+
+type overlay []HandlerLookup
+
+// Conforms to `HandlerLookup`.
+func (o overlay) GetHandlerForEvent(eventType EventType) (Interface, error) {
+	_, eventHandler, err := o.LookupEventHandler(eventType)
+	return eventHandler, err
+}
+
+// Conforms to `CanonicalLookup`.
+func (o overlay) LookupEventHandler(eventType EventType) (EventType, Interface, error) {
+	for _, layer := range o {
+		canonicalEventType, eventHandler, err := Lookup(layer, eventType)
+		if err == nil {
+			return canonicalEventType, eventHandler, nil
+		}
+
+		if !errors.Is(err, ErrUnknownEventType) {
+			return "", nil, err
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w for EventType '%s' in any layer", ErrUnknownEventType, eventType)
+}
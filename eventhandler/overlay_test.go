@@ -0,0 +1,58 @@
+package eventhandler_test
+
+import (
+	"errors"
+	"testing"
+
+	"root.challenge/eventhandler"
+)
+
+func TestOverlay(t *testing.T) {
+	top := eventhandler.NewRegistry()
+	bottom := eventhandler.NewRegistry()
+
+	topEventHandler := newTestEventHandler()
+	bottomEventHandler := newTestEventHandler()
+	bottomOnlyEventHandler := newTestEventHandler()
+
+	for _, registration := range []struct {
+		r            *eventhandler.Registry
+		eventType    eventhandler.EventType
+		eventHandler eventhandler.Interface
+	}{
+		{top, "Shared", topEventHandler},
+		{bottom, "Shared", bottomEventHandler},
+		{bottom, "BottomOnly", bottomOnlyEventHandler},
+	} {
+		if err := registration.r.RegisterEventHandler(registration.eventType, registration.eventHandler); err != nil {
+			t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+		}
+	}
+
+	o := eventhandler.Overlay(top, bottom)
+
+	// Layers registered with after composition are still consulted.
+	lateEventHandler := newTestEventHandler()
+	if err := top.RegisterEventHandler("Late", lateEventHandler); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+
+	for eventType, expectedEventHandler := range map[eventhandler.EventType]*testEventHandler{
+		"Shared":     topEventHandler,
+		"BottomOnly": bottomOnlyEventHandler,
+		"Late":       lateEventHandler,
+	} {
+		retrievedEventHandler, err := o.GetHandlerForEvent(eventType)
+		if err != nil {
+			t.Fatalf("GetHandlerForEvent(%s) expected: no error, got: %v", eventType, err)
+		}
+
+		if actualId := retrievedEventHandler.(*testEventHandler).id; actualId != expectedEventHandler.id {
+			t.Fatalf("GetHandlerForEvent(%s) expected id: %v, got id: %v", eventType, expectedEventHandler.id, actualId)
+		}
+	}
+
+	if _, err := o.GetHandlerForEvent("Unknown"); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("GetHandlerForEvent() expected: ErrUnknownEventType, got: %v", err)
+	}
+}
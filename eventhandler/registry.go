@@ -1,29 +1,100 @@
 package eventhandler
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 )
 
+// ErrUnknownEventType is the `error` (possibly wrapped) returned by `HandlerLookup.GetHandlerForEvent`
+// for `EventType`s that have no registered handler.
+var ErrUnknownEventType = errors.New("no EventHandler registered")
+
+// HandlerLookup is the read side of `Registry`, which is all that's needed to route events to their
+// handlers -- it allows `Registry`s to be composed (see `Overlay`) without the composition having to
+// support registration.
+type HandlerLookup interface {
+	// GetHandlerForEvent returns the `Interface` implementation for `eventType`, or an `error` wrapping
+	// `ErrUnknownEventType` if there is none.
+	GetHandlerForEvent(eventType EventType) (Interface, error)
+}
+
+// CanonicalLookup is an optional extension of `HandlerLookup` for lookups that match `EventType`s loosely
+// (for example, case-insensitively, or by alias), and can thus route events of several different spellings
+// to the same handler.
+//
+// Everything that tracks state per `EventType` (like handler setup, or circuit breaking) should key that
+// state by the canonical `EventType` it returns, rather than by the spelling in the event -- see `Lookup`.
+type CanonicalLookup interface {
+	HandlerLookup
+
+	// LookupEventHandler is `GetHandlerForEvent`, that also returns the `EventType` that the handler was
+	// registered for.
+	LookupEventHandler(eventType EventType) (EventType, Interface, error)
+}
+
+// Lookup returns the canonical `EventType` for `eventType` along with its handler in `l`, where every
+// `EventType` is canonical for `HandlerLookup`s that aren't `CanonicalLookup`s.
+func Lookup(l HandlerLookup, eventType EventType) (EventType, Interface, error) {
+	if canonicalLookup, ok := l.(CanonicalLookup); ok {
+		return canonicalLookup.LookupEventHandler(eventType)
+	}
+
+	eventHandler, err := l.GetHandlerForEvent(eventType)
+	return eventType, eventHandler, err
+}
+
 // Registry stores the concrete `eventhandler.Interface` implementations registered with the system.
 type Registry struct {
 	mutex    sync.RWMutex
 	registry map[EventType]Interface
+	// names maps every name an `EventType` can be looked up by (the `EventType` itself and its aliases,
+	// normalized by `normalize`) to that `EventType`.
+	names map[EventType]EventType
+	// aliases holds the aliases of every `EventType` that has any, in the order they were registered.
+	aliases map[EventType][]EventType
+
+	caseInsensitive bool
+}
+
+// RegistryOption configures a `Registry` created by `NewRegistry`.
+type RegistryOption func(*Registry)
+
+// WithCaseInsensitiveEventTypes makes the `Registry` match `EventType`s (and their aliases)
+// case-insensitively -- so events of type "trip" or "TRIP" are routed to the handler for "Trip", and
+// registering both "Trip" and "trip" is a collision.
+func WithCaseInsensitiveEventTypes() RegistryOption {
+	return func(r *Registry) {
+		r.caseInsensitive = true
+	}
 }
 
 // NewRegistry creates a new `Registry` object.
 //
-// This should typically only be used for testing or for niche scenarios that require isolation in the
-// face of multi-tenancy -- the expected production scenario is to just use `GlobalRegistry`().
-func NewRegistry() *Registry {
-	return &Registry{
+// This is meant for scenarios that require isolation from the handlers registered with `GlobalRegistry`()
+// -- for example, tests, or multiple pipelines with different sets of handlers in a single binary -- while
+// the expected production scenario is to just use `GlobalRegistry`() (which every handler package
+// registers itself with at load time).
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{
 		registry: make(map[EventType]Interface),
+		names:    make(map[EventType]EventType),
+		aliases:  make(map[EventType][]EventType),
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
 var globalRegistry = NewRegistry()
 
-// GlobalRegistry provides access to the one shared global `Registry` for the entire system to use.
+// GlobalRegistry provides access to the one shared global `Registry` for the entire system to use by
+// default.
 func GlobalRegistry() *Registry {
 	return globalRegistry
 }
@@ -31,8 +102,10 @@ func GlobalRegistry() *Registry {
 // RegisterEventHandler registers an `eventhandler.Interface` implementation to be invoked at runtime
 // for all events of a particular `EventType`.
 //
-// It returns `error` if a nil implementation is provided, or if this method has already previously been
-// called for the same `EventType`.
+// Handlers that implement `Aliaser` are registered under their aliases too, all or nothing.
+//
+// It returns `error` if a nil implementation is provided, or if the `EventType` (or any of the aliases)
+// collides with an `EventType` or alias that's already registered.
 //
 // The call to this method is typically expected to be made in each handler package's `init`() function.
 func (r *Registry) RegisterEventHandler(eventType EventType, eventHandler Interface) error {
@@ -43,26 +116,191 @@ func (r *Registry) RegisterEventHandler(eventType EventType, eventHandler Interf
 		return fmt.Errorf("nil EventHandler provided for EventType '%s'", eventType)
 	}
 
-	if _, dup := r.registry[eventType]; dup {
-		return fmt.Errorf("EventHandler for EventType '%s' is already registered", eventType)
+	names := []EventType{eventType}
+	if aliaser, ok := eventHandler.(Aliaser); ok {
+		names = append(names, aliaser.Aliases()...)
+	}
+
+	// Check every name up front (including against each other), so that a collision leaves no trace.
+	seen := make(map[EventType]bool)
+	for _, name := range names {
+		if err := r.checkNameIsFree(name); err != nil {
+			return fmt.Errorf("failed to register EventHandler for EventType '%s': %w", eventType, err)
+		}
+
+		if seen[r.normalize(name)] {
+			return fmt.Errorf("failed to register EventHandler for EventType '%s': name '%s' is given more than once",
+				eventType, name)
+		}
+		seen[r.normalize(name)] = true
 	}
 
 	r.registry[eventType] = eventHandler
+	r.names[r.normalize(eventType)] = eventType
+	for _, alias := range names[1:] {
+		r.addAlias(alias, eventType)
+	}
+
+	return nil
+}
+
+// RegisterAlias makes events of type `alias` route to the handler registered for `eventType` (for example,
+// to accept a partner feed's name for one of our `EventType`s), on top of any aliases the handler declares
+// as an `Aliaser`.
+//
+// It returns an `error` wrapping `ErrUnknownEventType` if no handler is registered for `eventType`, and an
+// `error` if `alias` collides with an `EventType` or alias that's already registered.
+func (r *Registry) RegisterAlias(alias, eventType EventType) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	canonicalEventType, ok := r.names[r.normalize(eventType)]
+	if !ok {
+		return fmt.Errorf("%w for EventType '%s' to alias", ErrUnknownEventType, eventType)
+	}
+
+	if err := r.checkNameIsFree(alias); err != nil {
+		return fmt.Errorf("failed to register alias for EventType '%s': %w", canonicalEventType, err)
+	}
+
+	r.addAlias(alias, canonicalEventType)
 	return nil
 }
 
 // GetHandlerForEvent returns the previously-registered `eventhandler.Interface` implementation for
 // a particular `EventType`.
 //
-// It returns `error` if no prior call to `RegisterEventHandler`() was made for the `EventType`.
+// It returns an `error` wrapping `ErrUnknownEventType` if no prior call to `RegisterEventHandler`() was
+// made for the `EventType`.
+//
+// Conforms to `HandlerLookup`.
 func (r *Registry) GetHandlerForEvent(eventType EventType) (Interface, error) {
+	_, eventHandler, err := r.LookupEventHandler(eventType)
+	return eventHandler, err
+}
+
+// LookupEventHandler resolves `eventType` (which may be an alias, or differ in case from the registered
+// `EventType` for `Registry`s created `WithCaseInsensitiveEventTypes`) to the `EventType` its handler was
+// registered for.
+//
+// Conforms to `CanonicalLookup`.
+func (r *Registry) LookupEventHandler(eventType EventType) (EventType, Interface, error) {
 	r.mutex.RLock()
-	eventHandler, ok := r.registry[eventType]
-	r.mutex.RUnlock()
+	defer r.mutex.RUnlock()
+
+	canonicalEventType, ok := r.names[r.normalize(eventType)]
+	if !ok {
+		return "", nil, fmt.Errorf("%w for EventType '%s'", ErrUnknownEventType, eventType)
+	}
+
+	return canonicalEventType, r.registry[canonicalEventType], nil
+}
+
+// ReplaceEventHandler atomically replaces the `eventhandler.Interface` implementation registered for a
+// particular `EventType` (for example, to hot-upgrade it while events are being processed), and returns the
+// replaced implementation so that the caller can wind it down.
+//
+// The aliases of the `EventType` are kept as they are (even if the replacement is an `Aliaser` that
+// declares different ones), so that hot-upgrades never change how events are routed.
+//
+// It returns `error` if a nil implementation is provided, and an `error` wrapping `ErrUnknownEventType` if
+// no prior call to `RegisterEventHandler`() was made for the `EventType`.
+func (r *Registry) ReplaceEventHandler(eventType EventType, eventHandler Interface) (Interface, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if eventHandler == nil {
+		return nil, fmt.Errorf("nil EventHandler provided for EventType '%s'", eventType)
+	}
+
+	canonicalEventType, ok := r.names[r.normalize(eventType)]
+	if !ok {
+		return nil, fmt.Errorf("%w for EventType '%s' to replace", ErrUnknownEventType, eventType)
+	}
+
+	replacedEventHandler := r.registry[canonicalEventType]
+	r.registry[canonicalEventType] = eventHandler
+	return replacedEventHandler, nil
+}
+
+// UnregisterEventHandler removes the `eventhandler.Interface` implementation registered for a particular
+// `EventType`, after which events of that `EventType` (and of its aliases) are no longer recognized.
+//
+// It returns an `error` wrapping `ErrUnknownEventType` if no prior call to `RegisterEventHandler`() was
+// made for the `EventType`.
+func (r *Registry) UnregisterEventHandler(eventType EventType) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
+	canonicalEventType, ok := r.names[r.normalize(eventType)]
 	if !ok {
-		return nil, fmt.Errorf("no EventHandler registered for EventType '%s'", eventType)
+		return fmt.Errorf("%w for EventType '%s' to unregister", ErrUnknownEventType, eventType)
 	}
 
-	return eventHandler, nil
+	for _, alias := range r.aliases[canonicalEventType] {
+		delete(r.names, r.normalize(alias))
+	}
+	delete(r.aliases, canonicalEventType)
+	delete(r.names, r.normalize(canonicalEventType))
+	delete(r.registry, canonicalEventType)
+	return nil
+}
+
+// ListEventHandlers describes every registered `eventhandler.Interface` implementation, ordered by
+// `EventType`.
+func (r *Registry) ListEventHandlers() []HandlerInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	handlerInfos := make([]HandlerInfo, 0, len(r.registry))
+	for eventType, eventHandler := range r.registry {
+		handlerInfo := describeHandler(eventType, eventHandler)
+		if aliases := r.aliases[eventType]; len(aliases) > 0 {
+			handlerInfo.Aliases = append([]EventType{}, aliases...)
+		}
+		handlerInfos = append(handlerInfos, handlerInfo)
+	}
+
+	sort.Slice(handlerInfos, func(i, j int) bool {
+		return handlerInfos[i].EventType < handlerInfos[j].EventType
+	})
+
+	return handlerInfos
+}
+
+// normalize maps `name` to the key it's stored under in `names`.
+func (r *Registry) normalize(name EventType) EventType {
+	if r.caseInsensitive {
+		return EventType(strings.ToLower(string(name)))
+	}
+
+	return name
+}
+
+// checkNameIsFree returns an `error` if `name` already resolves to a registered `EventType` (as itself or
+// as an alias).
+//
+// It must be called with `mutex` held.
+func (r *Registry) checkNameIsFree(name EventType) error {
+	if name == "" {
+		return fmt.Errorf("empty name")
+	}
+
+	canonicalEventType, taken := r.names[r.normalize(name)]
+	switch {
+	case !taken:
+		return nil
+	case r.normalize(name) == r.normalize(canonicalEventType):
+		return fmt.Errorf("name '%s' collides with EventType '%s', which is already registered", name,
+			canonicalEventType)
+	default:
+		return fmt.Errorf("name '%s' collides with an alias of EventType '%s', which is already registered", name,
+			canonicalEventType)
+	}
+}
+
+// addAlias must be called with `mutex` held, after `checkNameIsFree`(`alias`).
+func (r *Registry) addAlias(alias, eventType EventType) {
+	r.names[r.normalize(alias)] = eventType
+	r.aliases[eventType] = append(r.aliases[eventType], alias)
 }
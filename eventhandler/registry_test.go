@@ -1,7 +1,9 @@
 package eventhandler_test
 
 import (
+	"errors"
 	"math/rand"
+	"reflect"
 	"testing"
 
 	"root.challenge/eventhandler"
@@ -85,3 +87,259 @@ func TestRetrievalWithoutRegistration(t *testing.T) {
 		t.Fatalf("GetHandlerForEvent() expected: error, got: no error")
 	}
 }
+
+func TestReplaceEventHandler(t *testing.T) {
+	r := eventhandler.NewRegistry()
+
+	if _, err := r.ReplaceEventHandler(testEventType, newTestEventHandler()); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("ReplaceEventHandler() expected: ErrUnknownEventType, got: %v", err)
+	}
+
+	originalEventHandler := newTestEventHandler()
+	if err := r.RegisterEventHandler(testEventType, originalEventHandler); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+
+	if _, err := r.ReplaceEventHandler(testEventType, nil); err == nil {
+		t.Fatalf("ReplaceEventHandler() expected: error, got: no error")
+	}
+
+	replacementEventHandler := newTestEventHandler()
+	replacedEventHandler, err := r.ReplaceEventHandler(testEventType, replacementEventHandler)
+	if err != nil {
+		t.Fatalf("ReplaceEventHandler() expected: no error, got: %v", err)
+	}
+
+	if actualId := replacedEventHandler.(*testEventHandler).id; actualId != originalEventHandler.id {
+		t.Fatalf("ReplaceEventHandler() expected id: %v, got id: %v", originalEventHandler.id, actualId)
+	}
+
+	retrievedEventHandler, err := r.GetHandlerForEvent(testEventType)
+	if err != nil {
+		t.Fatalf("GetHandlerForEvent() expected: no error, got: %v", err)
+	}
+
+	if actualId := retrievedEventHandler.(*testEventHandler).id; actualId != replacementEventHandler.id {
+		t.Fatalf("GetHandlerForEvent() expected id: %v, got id: %v", replacementEventHandler.id, actualId)
+	}
+}
+
+func TestUnregisterEventHandler(t *testing.T) {
+	r := eventhandler.NewRegistry()
+
+	if err := r.UnregisterEventHandler(testEventType); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("UnregisterEventHandler() expected: ErrUnknownEventType, got: %v", err)
+	}
+
+	if err := r.RegisterEventHandler(testEventType, newTestEventHandler()); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+
+	if err := r.UnregisterEventHandler(testEventType); err != nil {
+		t.Fatalf("UnregisterEventHandler() expected: no error, got: %v", err)
+	}
+
+	if _, err := r.GetHandlerForEvent(testEventType); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("GetHandlerForEvent() expected: ErrUnknownEventType, got: %v", err)
+	}
+
+	// The `EventType` is free to be registered again.
+	if err := r.RegisterEventHandler(testEventType, newTestEventHandler()); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+}
+
+// describedTestEventHandler is a `testEventHandler` that implements some of the optional extensions of
+// `eventhandler.Interface`.
+type describedTestEventHandler struct {
+	testEventHandler
+}
+
+func (dteh *describedTestEventHandler) Description() string {
+	return "Does nothing."
+}
+
+func (dteh *describedTestEventHandler) CheckPreconditions(eventhandler.EventArgs) error {
+	return nil
+}
+
+func (dteh *describedTestEventHandler) Teardown(*eventstore.EventStore) error {
+	return nil
+}
+
+func TestListEventHandlers(t *testing.T) {
+	r := eventhandler.NewRegistry()
+
+	if handlerInfos := r.ListEventHandlers(); len(handlerInfos) != 0 {
+		t.Fatalf("ListEventHandlers() expected: no handlers, got: %v", handlerInfos)
+	}
+
+	if err := r.RegisterEventHandler("B", &describedTestEventHandler{}); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+	if err := r.RegisterEventHandler("A", newTestEventHandler()); err != nil {
+		t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+	}
+
+	expectedHandlerInfos := []eventhandler.HandlerInfo{
+		{
+			EventType:   "A",
+			HandlerType: "*eventhandler_test.testEventHandler",
+			Hooks:       []string{},
+		},
+		{
+			EventType:   "B",
+			HandlerType: "*eventhandler_test.describedTestEventHandler",
+			Description: "Does nothing.",
+			Hooks:       []string{"CheckPreconditions", "Teardown"},
+		},
+	}
+
+	if handlerInfos := r.ListEventHandlers(); !reflect.DeepEqual(handlerInfos, expectedHandlerInfos) {
+		t.Fatalf("ListEventHandlers() expected: %#v, got: %#v", expectedHandlerInfos, handlerInfos)
+	}
+}
+
+// `aliasedTestEventHandler` is an implementation of `eventhandler.Interface` that declares aliases.
+type aliasedTestEventHandler struct {
+	testEventHandler
+	aliases []eventhandler.EventType
+}
+
+func (ateh *aliasedTestEventHandler) Aliases() []eventhandler.EventType {
+	return ateh.aliases
+}
+
+func TestEventTypeMatching(t *testing.T) {
+	tests := map[string]struct {
+		opts []eventhandler.RegistryOption
+		// Every handler is registered for "Trip", with these aliases.
+		aliases        []eventhandler.EventType
+		extraAliases   []eventhandler.EventType
+		expectedFound  map[eventhandler.EventType]bool
+		expectRegError bool
+	}{
+		"ExactMatchByDefault": {
+			expectedFound: map[eventhandler.EventType]bool{"Trip": true, "trip": false, "TRIP": false},
+		},
+		"CaseInsensitive": {
+			opts:          []eventhandler.RegistryOption{eventhandler.WithCaseInsensitiveEventTypes()},
+			expectedFound: map[eventhandler.EventType]bool{"Trip": true, "trip": true, "TRIP": true, "Ride": false},
+		},
+		"DeclaredAliases": {
+			aliases:       []eventhandler.EventType{"Ride"},
+			expectedFound: map[eventhandler.EventType]bool{"Trip": true, "Ride": true, "ride": false},
+		},
+		"CaseInsensitiveAliases": {
+			opts:          []eventhandler.RegistryOption{eventhandler.WithCaseInsensitiveEventTypes()},
+			aliases:       []eventhandler.EventType{"Ride"},
+			extraAliases:  []eventhandler.EventType{"Journey"},
+			expectedFound: map[eventhandler.EventType]bool{"ride": true, "JOURNEY": true, "Drive": false},
+		},
+		"AliasCollidesWithEventType": {
+			aliases:        []eventhandler.EventType{"Trip"},
+			expectRegError: true,
+		},
+		"AliasesCollideWithEachOther": {
+			aliases:        []eventhandler.EventType{"Ride", "Ride"},
+			expectRegError: true,
+		},
+		"AliasesCollideCaseInsensitively": {
+			opts:           []eventhandler.RegistryOption{eventhandler.WithCaseInsensitiveEventTypes()},
+			aliases:        []eventhandler.EventType{"TRIP"},
+			expectRegError: true,
+		},
+		"AliasesDontCollideCaseSensitively": {
+			aliases:       []eventhandler.EventType{"TRIP"},
+			expectedFound: map[eventhandler.EventType]bool{"Trip": true, "TRIP": true, "trip": false},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := eventhandler.NewRegistry(tc.opts...)
+
+			err := r.RegisterEventHandler("Trip", &aliasedTestEventHandler{aliases: tc.aliases})
+			if (err != nil) != tc.expectRegError {
+				t.Fatalf("RegisterEventHandler() expected: error=%v, got: %v", tc.expectRegError, err)
+			}
+			if tc.expectRegError {
+				// A failed registration leaves no trace.
+				if _, err := r.GetHandlerForEvent("Trip"); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+					t.Fatalf("GetHandlerForEvent() expected: ErrUnknownEventType, got: %v", err)
+				}
+				return
+			}
+
+			for _, alias := range tc.extraAliases {
+				if err := r.RegisterAlias(alias, "Trip"); err != nil {
+					t.Fatalf("RegisterAlias() expected: no error, got: %v", err)
+				}
+			}
+
+			for eventType, expectFound := range tc.expectedFound {
+				canonicalEventType, _, err := eventhandler.Lookup(r, eventType)
+				if (err == nil) != expectFound {
+					t.Fatalf("Lookup(%s) expected: found=%v, got: %v", eventType, expectFound, err)
+				}
+				if expectFound && canonicalEventType != "Trip" {
+					t.Fatalf("Lookup(%s) expected: Trip, got: %s", eventType, canonicalEventType)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterAlias(t *testing.T) {
+	r := eventhandler.NewRegistry()
+
+	if err := r.RegisterAlias("Ride", "Trip"); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("RegisterAlias() expected: ErrUnknownEventType, got: %v", err)
+	}
+
+	for _, eventType := range []eventhandler.EventType{"Trip", "Driver"} {
+		if err := r.RegisterEventHandler(eventType, newTestEventHandler()); err != nil {
+			t.Fatalf("RegisterEventHandler() expected: no error, got: %v", err)
+		}
+	}
+
+	if err := r.RegisterAlias("Ride", "Trip"); err != nil {
+		t.Fatalf("RegisterAlias() expected: no error, got: %v", err)
+	}
+
+	// Aliases can't be taken twice, nor shadow an `EventType` -- and neither can an `EventType` shadow an alias.
+	if err := r.RegisterAlias("Ride", "Driver"); err == nil {
+		t.Fatalf("RegisterAlias() expected: error, got: no error")
+	}
+	if err := r.RegisterAlias("Driver", "Trip"); err == nil {
+		t.Fatalf("RegisterAlias() expected: error, got: no error")
+	}
+	if err := r.RegisterEventHandler("Ride", newTestEventHandler()); err == nil {
+		t.Fatalf("RegisterEventHandler() expected: error, got: no error")
+	}
+
+	// Aliases can be used in place of their `EventType` everywhere.
+	replacementEventHandler := newTestEventHandler()
+	if _, err := r.ReplaceEventHandler("Ride", replacementEventHandler); err != nil {
+		t.Fatalf("ReplaceEventHandler() expected: no error, got: %v", err)
+	}
+	if retrievedEventHandler, err := r.GetHandlerForEvent("Trip"); err != nil || retrievedEventHandler != replacementEventHandler {
+		t.Fatalf("GetHandlerForEvent() expected: replacement handler, got: %v (%v)", retrievedEventHandler, err)
+	}
+
+	handlerInfos := r.ListEventHandlers()
+	if expectedAliases := []eventhandler.EventType{"Ride"}; !reflect.DeepEqual(handlerInfos[1].Aliases, expectedAliases) {
+		t.Fatalf("ListEventHandlers() expected aliases: %v, got: %v", expectedAliases, handlerInfos[1].Aliases)
+	}
+
+	// Unregistering an `EventType` frees up its aliases.
+	if err := r.UnregisterEventHandler("Trip"); err != nil {
+		t.Fatalf("UnregisterEventHandler() expected: no error, got: %v", err)
+	}
+	if _, err := r.GetHandlerForEvent("Ride"); !errors.Is(err, eventhandler.ErrUnknownEventType) {
+		t.Fatalf("GetHandlerForEvent() expected: ErrUnknownEventType, got: %v", err)
+	}
+	if err := r.RegisterAlias("Ride", "Driver"); err != nil {
+		t.Fatalf("RegisterAlias() expected: no error, got: %v", err)
+	}
+}
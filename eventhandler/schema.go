@@ -0,0 +1,430 @@
+package eventhandler
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaProvider is an optional extension of `Interface` for handlers that declare the args of their events
+// with a `Schema`.
+//
+// The framework validates every event against the `Schema` of its handler before invoking anything else on
+// the handler (reporting failures just like failed `PreconditionChecker`s), so `Handle`() only needs to
+// `Schema.Decode` the args -- and `Registry.ListEventHandlers` generates usage docs from it.
+type SchemaProvider interface {
+	ArgSchema() *Schema
+}
+
+// Schema declares the args of an event as a sequence of named, typed `Field`s, which it validates and
+// decodes `EventArgs` against -- producing uniform `*ArgError`s across every handler.
+type Schema struct {
+	positionalFields []Field
+	keywordFields    []Field
+}
+
+// NewSchema creates a `Schema` out of `fields`, where positional fields appear in the order they're
+// declared, and keyword fields (see `Field.Keyword`) can be declared anywhere.
+//
+// Since schemas are static declarations, it panics on invalid ones (like a required positional field
+// following an optional one, or duplicate field names) rather than returning an `error`.
+func NewSchema(fields ...Field) *Schema {
+	s := &Schema{}
+	names := make(map[string]bool)
+	for _, f := range fields {
+		if names[f.name] {
+			panic(fmt.Sprintf("duplicate field '%s' in Schema", f.name))
+		}
+		names[f.name] = true
+
+		if f.keyword {
+			s.keywordFields = append(s.keywordFields, f)
+			continue
+		}
+
+		if n := len(s.positionalFields); n > 0 && s.positionalFields[n-1].optional && !f.optional {
+			panic(fmt.Sprintf("required positional field '%s' follows optional positional field '%s' in Schema",
+				f.name, s.positionalFields[n-1].name))
+		}
+		s.positionalFields = append(s.positionalFields, f)
+	}
+
+	return s
+}
+
+// Field is a single named, typed arg in a `Schema`.
+type Field struct {
+	// name identifies the field in struct tags (and is the keyword of keyword fields), while description
+	// identifies it to humans (in usage docs and `*ArgError`s).
+	name        string
+	description string
+	typeName    string
+	parse       func(string) (interface{}, error)
+
+	optional     bool
+	keyword      bool
+	defaultValue *string
+	role         Role
+}
+
+// Role identifies what the value of a `Field` refers to, for the parts of the system that need to know what
+// an event is about without knowing its handler (like partitioners, which keep every event about a driver in
+// the same partition) -- see `Schema.RoleArgs`.
+type Role int
+
+const (
+	// NoRole is the `Role` of every `Field` not given another one by `Field.As`.
+	NoRole Role = iota
+	// DriverRole is the `Role` of a `Field` holding the ID of the driver an event is about.
+	DriverRole
+	// TripRole is the `Role` of a `Field` holding the ID of the trip an event is about.
+	TripRole
+	// VehicleRole is the `Role` of a `Field` holding the ID of the vehicle an event is about.
+	VehicleRole
+)
+
+// StringField declares a `Field` that decodes into a `string` as-is.
+func StringField(name, description string) Field {
+	return CustomField(name, description, "string", func(s string) (interface{}, error) {
+		return s, nil
+	})
+}
+
+// ClockField declares a `Field` holding a time of day in the 24-hour "HH:MM" format, that decodes into a
+// `time.Time` (on the zero date).
+func ClockField(name, description string) Field {
+	const expectedTimeFormatStr string = "15:04"
+
+	return CustomField(name, description, "HH:MM", func(s string) (interface{}, error) {
+		t, err := time.Parse(expectedTimeFormatStr, s)
+		if err != nil {
+			return nil, fmt.Errorf("doesn't conform to %s format: %w", expectedTimeFormatStr, err)
+		}
+
+		return t, nil
+	})
+}
+
+// FloatField declares a `Field` holding a number, that decodes into a `float64` (or a `float32`).
+func FloatField(name, description string) Field {
+	return CustomField(name, description, "number", func(s string) (interface{}, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// CustomField declares a `Field` of a type not covered by the other constructors, that's parsed by
+// `parse` -- `typeName` describes the expected format in usage docs.
+func CustomField(name, description, typeName string, parse func(string) (interface{}, error)) Field {
+	return Field{
+		name:        name,
+		description: description,
+		typeName:    typeName,
+		parse:       parse,
+	}
+}
+
+// Optional marks `f` as optional -- it's left as the zero value of its type when omitted.
+func (f Field) Optional() Field {
+	f.optional = true
+	return f
+}
+
+// Default marks `f` as optional, with `value` being parsed in its place when omitted.
+func (f Field) Default(value string) Field {
+	f.optional = true
+	f.defaultValue = &value
+	return f
+}
+
+// Keyword makes `f` a (always optional) "<name>=<value>" keyword arg, instead of a positional one.
+func (f Field) Keyword() Field {
+	f.optional = true
+	f.keyword = true
+	return f
+}
+
+// As gives `f` the `Role` of `role`, which only `StringField`s can have.
+func (f Field) As(role Role) Field {
+	f.role = role
+	return f
+}
+
+// FieldInfo describes a `Field`, for generating docs.
+type FieldInfo struct {
+	Name        string
+	Description string
+	Type        string
+	Optional    bool
+	Keyword     bool
+	// Default is empty for fields without a default value.
+	Default string
+	Role    Role
+}
+
+// Fields describes the fields of `s`, positional fields first.
+func (s *Schema) Fields() []FieldInfo {
+	fieldInfos := make([]FieldInfo, 0, len(s.positionalFields)+len(s.keywordFields))
+	for _, f := range append(append([]Field{}, s.positionalFields...), s.keywordFields...) {
+		fieldInfo := FieldInfo{
+			Name:        f.name,
+			Description: f.description,
+			Type:        f.typeName,
+			Optional:    f.optional,
+			Keyword:     f.keyword,
+			Role:        f.role,
+		}
+		if f.defaultValue != nil {
+			fieldInfo.Default = *f.defaultValue
+		}
+
+		fieldInfos = append(fieldInfos, fieldInfo)
+	}
+
+	return fieldInfos
+}
+
+// Usage renders a synopsis of events of `eventType` that conform to `s` (for example,
+// "Driver <name> [id=<ID>]").
+func (s *Schema) Usage(eventType EventType) string {
+	usage := []string{string(eventType)}
+	for _, f := range s.positionalFields {
+		if f.optional {
+			usage = append(usage, fmt.Sprintf("[<%s>]", f.description))
+		} else {
+			usage = append(usage, fmt.Sprintf("<%s>", f.description))
+		}
+	}
+
+	for _, f := range s.keywordFields {
+		usage = append(usage, fmt.Sprintf("[%s=<%s>]", f.name, f.description))
+	}
+
+	return strings.Join(usage, " ")
+}
+
+// ArgError is the `error` that results from `EventArgs` not conforming to a `Schema`.
+type ArgError struct {
+	// Field is the description of the offending field, and is empty for errors that aren't specific to a
+	// single field (like a wrong number of args).
+	Field string
+	// Value is the offending arg, if any.
+	Value string
+	Err   error
+}
+
+// Conforms to `error`.
+func (ae *ArgError) Error() string {
+	switch {
+	case ae.Field != "":
+		return fmt.Sprintf("invalid %s '%s': %v", ae.Field, ae.Value, ae.Err)
+	case ae.Value != "":
+		return fmt.Sprintf("invalid arg '%s': %v", ae.Value, ae.Err)
+	default:
+		return ae.Err.Error()
+	}
+}
+
+// Unwrap allows `errors.Is` and `errors.As` to see through `ae`.
+func (ae *ArgError) Unwrap() error {
+	return ae.Err
+}
+
+// Validate returns an `*ArgError` if `eventArgs` doesn't conform to `s`.
+func (s *Schema) Validate(eventArgs EventArgs) error {
+	_, err := s.parse(eventArgs, nil)
+	return err
+}
+
+// Decode validates `eventArgs` against `s`, and stores the decoded value of every field in the field of
+// the struct pointed to by `target` that's tagged with its name (for example, `arg:"start"`) -- fields of
+// `s` with no such struct field are validated but otherwise ignored.
+//
+// It returns an `*ArgError` if `eventArgs` doesn't conform to `s`, and a plain `error` if `target` can't
+// hold the decoded values (which is a bug in the handler, rather than a problem with its events).
+func (s *Schema) Decode(eventArgs EventArgs, target interface{}) error {
+	return s.decode(eventArgs, target, false)
+}
+
+// DecodeValidated is `Decode` for `eventArgs` that are already known to conform to `s` (typically because
+// they passed `Validate`) -- it only parses the fields of `s` that `target` has struct fields for, rather
+// than validating every field all over again.
+//
+// This is the cheap way for a `PreconditionChecker` to get at the few fields it checks, since the framework
+// validates events against their `Schema` before checking their preconditions.
+func (s *Schema) DecodeValidated(eventArgs EventArgs, target interface{}) error {
+	return s.decode(eventArgs, target, true)
+}
+
+// RoleArgs validates `eventArgs` against `s` (like `Validate`, but without parsing the fields that have no
+// `Role`), and returns the args of the fields with a `Role` that are present in it (or defaulted), keyed by
+// `Role`. If several fields have the same `Role`, the last one present wins (keyword fields coming after
+// positional ones) -- so a "Driver <name> [id=<ID>]" event is about the driver with the given ID, if any, and
+// about the driver whose ID is its name otherwise.
+func (s *Schema) RoleArgs(eventArgs EventArgs) (map[Role]string, error) {
+	values, err := s.parse(eventArgs, func(f Field) bool {
+		return f.role != NoRole
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roleArgs := make(map[Role]string)
+	for _, f := range append(append([]Field{}, s.positionalFields...), s.keywordFields...) {
+		if arg, ok := values[f.name].(string); ok && f.role != NoRole {
+			roleArgs[f.role] = arg
+		}
+	}
+
+	return roleArgs, nil
+}
+
+// decode implements `Decode` (and `DecodeValidated`, if `onlyTargetFields` is true).
+func (s *Schema) decode(eventArgs EventArgs, target interface{}, onlyTargetFields bool) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expecting a pointer to a struct to decode into; got %T", target)
+	}
+
+	// structFieldIndices maps the name of every field of `s` to the index of the struct field tagged with it.
+	structValue := targetValue.Elem()
+	structFieldIndices := make(map[string]int)
+	for i := 0; i < structValue.NumField(); i++ {
+		if name, ok := structValue.Type().Field(i).Tag.Lookup("arg"); ok {
+			structFieldIndices[name] = i
+		}
+	}
+
+	var isWanted func(f Field) bool
+	if onlyTargetFields {
+		isWanted = func(f Field) bool {
+			_, ok := structFieldIndices[f.name]
+			return ok
+		}
+	}
+
+	values, err := s.parse(eventArgs, isWanted)
+	if err != nil {
+		return err
+	}
+
+	for name, i := range structFieldIndices {
+		value, ok := values[name]
+		if !ok {
+			// Omitted optional fields stay as their zero values.
+			continue
+		}
+
+		structField := structValue.Type().Field(i)
+		decodedValue := reflect.ValueOf(value)
+		switch {
+		case decodedValue.Type().AssignableTo(structField.Type):
+			structValue.Field(i).Set(decodedValue)
+		case decodedValue.Type().ConvertibleTo(structField.Type):
+			structValue.Field(i).Set(decodedValue.Convert(structField.Type))
+		default:
+			return fmt.Errorf("can't decode field '%s' of type %s into struct field %s of type %s",
+				name, decodedValue.Type(), structField.Name, structField.Type)
+		}
+	}
+
+	return nil
+}
+
+// parse validates `eventArgs` against `s`, and returns the parsed values of the fields present in it (or
+// defaulted), keyed by field name -- only the fields that `isWanted` reports are parsed, unless it's nil.
+func (s *Schema) parse(eventArgs EventArgs, isWanted func(f Field) bool) (map[string]interface{}, error) {
+	keywords := make([]string, 0, len(s.keywordFields))
+	for _, f := range s.keywordFields {
+		keywords = append(keywords, f.name)
+	}
+
+	positionalArgs, keywordArgs, err := SplitKeywordArgs(eventArgs, keywords...)
+	if err != nil {
+		return nil, &ArgError{Err: err}
+	}
+
+	if err := s.checkNumPositionalArgs(len(positionalArgs)); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	parseField := func(f Field, arg string, present bool) error {
+		if isWanted != nil && !isWanted(f) {
+			return nil
+		}
+
+		if !present {
+			if f.defaultValue == nil {
+				return nil
+			}
+			arg = *f.defaultValue
+		}
+
+		value, err := f.parse(arg)
+		if err != nil {
+			return &ArgError{Field: f.description, Value: arg, Err: unwrapParseError(err)}
+		}
+
+		values[f.name] = value
+		return nil
+	}
+
+	for i, f := range s.positionalFields {
+		present := i < len(positionalArgs)
+		arg := ""
+		if present {
+			arg = positionalArgs[i]
+		}
+
+		if err := parseField(f, arg, present); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range s.keywordFields {
+		arg, present := keywordArgs[f.name]
+		if err := parseField(f, arg, present); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (s *Schema) checkNumPositionalArgs(numPositionalArgs int) error {
+	minNumPositionalArgs := 0
+	descriptions := make([]string, 0, len(s.positionalFields))
+	for _, f := range s.positionalFields {
+		if !f.optional {
+			minNumPositionalArgs++
+		}
+		descriptions = append(descriptions, f.description)
+	}
+	maxNumPositionalArgs := len(s.positionalFields)
+
+	if numPositionalArgs >= minNumPositionalArgs && numPositionalArgs <= maxNumPositionalArgs {
+		return nil
+	}
+
+	expected := fmt.Sprintf("exactly %d", maxNumPositionalArgs)
+	if minNumPositionalArgs != maxNumPositionalArgs {
+		expected = fmt.Sprintf("between %d and %d", minNumPositionalArgs, maxNumPositionalArgs)
+	}
+
+	return &ArgError{
+		Err: fmt.Errorf("expecting %s positional args (%s); got %d",
+			expected, strings.Join(descriptions, ", "), numPositionalArgs),
+	}
+}
+
+// unwrapParseError strips the redundant context that `strconv` adds to the `error`s it returns directly
+// (the function name and the input, which `ArgError` already includes).
+func unwrapParseError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err
+	}
+
+	return err
+}
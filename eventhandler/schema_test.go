@@ -0,0 +1,288 @@
+package eventhandler_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+)
+
+var testSchema = eventhandler.NewSchema(
+	eventhandler.StringField("name", "name"),
+	eventhandler.ClockField("start", "start time"),
+	eventhandler.FloatField("ratio", "ratio").Default("0.5"),
+	eventhandler.StringField("note", "note").Optional(),
+	eventhandler.StringField("tag", "tag").Keyword(),
+)
+
+type testArgs struct {
+	Name  string    `arg:"name"`
+	Start time.Time `arg:"start"`
+	Ratio float32   `arg:"ratio"`
+	Note  string    `arg:"note"`
+	Tag   string    `arg:"tag"`
+	// Untagged fields are left alone.
+	Untouched string
+}
+
+func TestSchemaDecode(t *testing.T) {
+	tests := map[string]struct {
+		input          eventhandler.EventArgs
+		expectError    bool
+		expectedOutput testArgs
+	}{
+		"RequiredArgsOnly": {
+			input: eventhandler.EventArgs{"A", "07:15"},
+			expectedOutput: testArgs{
+				Name:  "A",
+				Start: time.Date(0, 1, 1, 7, 15, 0, 0, time.UTC),
+				Ratio: 0.5,
+			},
+		},
+		"AllArgs": {
+			input: eventhandler.EventArgs{"A", "07:15", "0.25", "hello", "tag=x"},
+			expectedOutput: testArgs{
+				Name:  "A",
+				Start: time.Date(0, 1, 1, 7, 15, 0, 0, time.UTC),
+				Ratio: 0.25,
+				Note:  "hello",
+				Tag:   "x",
+			},
+		},
+		"TooFewArgs": {
+			input:       eventhandler.EventArgs{"A"},
+			expectError: true,
+		},
+		"TooManyArgs": {
+			input:       eventhandler.EventArgs{"A", "07:15", "0.25", "hello", "extra"},
+			expectError: true,
+		},
+		"MalformedClock": {
+			input:       eventhandler.EventArgs{"A", "7.15"},
+			expectError: true,
+		},
+		"MalformedFloat": {
+			input:       eventhandler.EventArgs{"A", "07:15", "half"},
+			expectError: true,
+		},
+		"UnknownKeyword": {
+			input:       eventhandler.EventArgs{"A", "07:15", "color=red"},
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := testSchema.Validate(tc.input); (err != nil) != tc.expectError {
+				t.Fatalf("expected: validation error=%v, got: %v", tc.expectError, err)
+			}
+
+			actualOutput := testArgs{Untouched: "untouched"}
+			err := testSchema.Decode(tc.input, &actualOutput)
+			switch {
+			case tc.expectError && err != nil:
+				var argErr *eventhandler.ArgError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("expected: *eventhandler.ArgError, got: %T (%v)", err, err)
+				}
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			tc.expectedOutput.Untouched = "untouched"
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %+v, got: %+v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestSchemaDecodeValidated(t *testing.T) {
+	type startOnly struct {
+		Start time.Time `arg:"start"`
+	}
+
+	tests := map[string]struct {
+		input          eventhandler.EventArgs
+		expectError    bool
+		expectedOutput startOnly
+	}{
+		"WellFormed": {
+			input:          eventhandler.EventArgs{"A", "07:15", "0.25"},
+			expectedOutput: startOnly{Start: time.Date(0, 1, 1, 7, 15, 0, 0, time.UTC)},
+		},
+		// Only the fields that are decoded are parsed.
+		"MalformedFieldNotDecoded": {
+			input:          eventhandler.EventArgs{"A", "07:15", "half"},
+			expectedOutput: startOnly{Start: time.Date(0, 1, 1, 7, 15, 0, 0, time.UTC)},
+		},
+		"MalformedFieldDecoded": {
+			input:       eventhandler.EventArgs{"A", "7.15"},
+			expectError: true,
+		},
+		"TooFewArgs": {
+			input:       eventhandler.EventArgs{"A"},
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var actualOutput startOnly
+			err := testSchema.DecodeValidated(tc.input, &actualOutput)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %+v, got: %+v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestSchemaRoleArgs(t *testing.T) {
+	roleSchema := eventhandler.NewSchema(
+		eventhandler.StringField("name", "name").As(eventhandler.DriverRole),
+		eventhandler.ClockField("start", "start time"),
+		eventhandler.StringField("vehicle", "vehicle ID").Optional().As(eventhandler.VehicleRole),
+		eventhandler.StringField("id", "ID").Keyword().As(eventhandler.DriverRole),
+	)
+
+	tests := map[string]struct {
+		input eventhandler.EventArgs
+		// For when `RoleArgs`() returns an error.
+		expectError    bool
+		expectedOutput map[eventhandler.Role]string
+	}{
+		"OnlyRequiredFields": {
+			input:          eventhandler.EventArgs{"Dan", "07:15"},
+			expectedOutput: map[eventhandler.Role]string{eventhandler.DriverRole: "Dan"},
+		},
+		"LastFieldOfRoleWins": {
+			input: eventhandler.EventArgs{"Dan", "07:15", "V1", "id=D1"},
+			expectedOutput: map[eventhandler.Role]string{
+				eventhandler.DriverRole: "D1", eventhandler.VehicleRole: "V1",
+			},
+		},
+		// Fields without a role aren't parsed.
+		"MalformedFieldWithoutRole": {
+			input:          eventhandler.EventArgs{"Dan", "7 o'clock"},
+			expectedOutput: map[eventhandler.Role]string{eventhandler.DriverRole: "Dan"},
+		},
+		"TooFewArgs": {
+			input:       eventhandler.EventArgs{"Dan"},
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := roleSchema.RoleArgs(tc.input)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestSchemaArgError(t *testing.T) {
+	err := testSchema.Validate(eventhandler.EventArgs{"A", "07:15", "half"})
+
+	var argErr *eventhandler.ArgError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected: *eventhandler.ArgError, got: %T (%v)", err, err)
+	}
+
+	if argErr.Field != "ratio" || argErr.Value != "half" {
+		t.Fatalf("expected: field 'ratio' with value 'half', got: field '%s' with value '%s'", argErr.Field, argErr.Value)
+	}
+
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected: error wrapping %v, got: %v", strconv.ErrSyntax, err)
+	}
+
+	expectedErrStr := "invalid ratio 'half': invalid syntax"
+	if err.Error() != expectedErrStr {
+		t.Fatalf("expected: %q, got: %q", expectedErrStr, err.Error())
+	}
+}
+
+func TestSchemaDecodeIntoIncompatibleTarget(t *testing.T) {
+	var target struct {
+		Start string `arg:"start"`
+	}
+
+	err := testSchema.Decode(eventhandler.EventArgs{"A", "07:15"}, &target)
+	if err == nil {
+		t.Fatalf("expected: error, got: no error")
+	}
+
+	var argErr *eventhandler.ArgError
+	if errors.As(err, &argErr) {
+		t.Fatalf("expected: plain error for a handler bug, got: *eventhandler.ArgError (%v)", err)
+	}
+}
+
+func TestSchemaUsageAndFields(t *testing.T) {
+	expectedUsage := "TestEvent <name> <start time> [<ratio>] [<note>] [tag=<tag>]"
+	if actualUsage := testSchema.Usage(testEventType); actualUsage != expectedUsage {
+		t.Fatalf("expected: %q, got: %q", expectedUsage, actualUsage)
+	}
+
+	expectedFields := []eventhandler.FieldInfo{
+		{Name: "name", Description: "name", Type: "string"},
+		{Name: "start", Description: "start time", Type: "HH:MM"},
+		{Name: "ratio", Description: "ratio", Type: "number", Optional: true, Default: "0.5"},
+		{Name: "note", Description: "note", Type: "string", Optional: true},
+		{Name: "tag", Description: "tag", Type: "string", Optional: true, Keyword: true},
+	}
+	if actualFields := testSchema.Fields(); !reflect.DeepEqual(actualFields, expectedFields) {
+		t.Fatalf("expected: %+v, got: %+v", expectedFields, actualFields)
+	}
+}
+
+func TestNewSchemaPanicsOnInvalidDeclarations(t *testing.T) {
+	tests := map[string][]eventhandler.Field{
+		"DuplicateField": {
+			eventhandler.StringField("a", "a"),
+			eventhandler.StringField("a", "another a").Keyword(),
+		},
+		"RequiredFieldAfterOptionalField": {
+			eventhandler.StringField("a", "a").Optional(),
+			eventhandler.StringField("b", "b"),
+		},
+	}
+
+	for name, fields := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected: panic, got: no panic")
+				}
+			}()
+
+			eventhandler.NewSchema(fields...)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package eventprocessor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Deduplicator tracks the IDs of the events that have already been processed, so that re-deliveries of
+// those events (which at-least-once upstreams are prone to) are dropped instead of being applied twice.
+//
+// Implementations must be safe for concurrent use, since a single `EventProcessor` can `Process` multiple
+// input streams concurrently.
+type Deduplicator interface {
+	// CheckAndRecord reports whether an event with `eventID` has been seen before, and records it as seen
+	// otherwise.
+	CheckAndRecord(eventID string) (duplicate bool, err error)
+	// NumDuplicates is the number of times `CheckAndRecord` has reported a duplicate.
+	NumDuplicates() int
+}
+
+// LookupDeduplicator is a `Deduplicator` that can also tell whether an event ID has been seen before,
+// without recording it.
+type LookupDeduplicator interface {
+	Deduplicator
+	// Seen reports whether an event with `eventID` has been seen before.
+	Seen(eventID string) bool
+}
+
+// WindowedDeduplicator is a `Deduplicator` that remembers only the most recent event IDs it has seen,
+// bounding its memory usage at the cost of missing re-deliveries that arrive too late.
+type WindowedDeduplicator struct {
+	mu            sync.Mutex
+	windowSize    int
+	seenIDs       map[string]struct{}
+	seenIDsInAge  []string
+	numDuplicates int
+}
+
+// NewWindowedDeduplicator creates a `WindowedDeduplicator` that remembers the last `windowSize` event IDs.
+func NewWindowedDeduplicator(windowSize int) (*WindowedDeduplicator, error) {
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("expecting a positive deduplication window size; got %d", windowSize)
+	}
+
+	return &WindowedDeduplicator{
+		windowSize:   windowSize,
+		seenIDs:      make(map[string]struct{}, windowSize),
+		seenIDsInAge: make([]string, 0, windowSize),
+	}, nil
+}
+
+// Conforms to `Deduplicator`.
+func (wd *WindowedDeduplicator) CheckAndRecord(eventID string) (bool, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if _, ok := wd.seenIDs[eventID]; ok {
+		wd.numDuplicates++
+		return true, nil
+	}
+
+	// Forget the oldest event ID to make room for this one.
+	if len(wd.seenIDsInAge) == wd.windowSize {
+		delete(wd.seenIDs, wd.seenIDsInAge[0])
+		wd.seenIDsInAge = wd.seenIDsInAge[1:]
+	}
+
+	wd.seenIDs[eventID] = struct{}{}
+	wd.seenIDsInAge = append(wd.seenIDsInAge, eventID)
+
+	return false, nil
+}
+
+// Conforms to `LookupDeduplicator`.
+func (wd *WindowedDeduplicator) Seen(eventID string) bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	_, ok := wd.seenIDs[eventID]
+	return ok
+}
+
+// Conforms to `Deduplicator`.
+func (wd *WindowedDeduplicator) NumDuplicates() int {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	return wd.numDuplicates
+}
+
+// PersistentDeduplicator is a `Deduplicator` that remembers every event ID it has ever seen, across runs,
+// by appending them to a file -- which makes it possible to safely replay an entire input that was
+// (partially or wholly) processed before.
+//
+// Maintainer Notes:
+//
+// The seen-set grows without bound (both on disk and in memory), which is fine for the volumes this
+// program deals with today; a key-value store with TTLs is the natural replacement once that stops being
+// the case, and can slot in behind `Deduplicator` without any change to `EventProcessor`.
+type PersistentDeduplicator struct {
+	mu            sync.Mutex
+	seenIDs       map[string]struct{}
+	seenIDsFile   *os.File
+	numDuplicates int
+}
+
+// OpenPersistentDeduplicator creates a `PersistentDeduplicator` backed by the file at `path` (which is
+// created if it doesn't exist yet), seeded with the event IDs recorded there by previous runs.
+//
+// `Close` must be called once the `PersistentDeduplicator` is no longer needed.
+func OpenPersistentDeduplicator(path string) (*PersistentDeduplicator, error) {
+	seenIDsFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening seen event IDs file %s: %w", path, err)
+	}
+
+	seenIDs := make(map[string]struct{})
+	scanner := bufio.NewScanner(seenIDsFile)
+	for scanner.Scan() {
+		if eventID := strings.TrimSpace(scanner.Text()); eventID != "" {
+			seenIDs[eventID] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		seenIDsFile.Close()
+		return nil, fmt.Errorf("error reading seen event IDs file %s: %w", path, err)
+	}
+
+	return &PersistentDeduplicator{
+		seenIDs:     seenIDs,
+		seenIDsFile: seenIDsFile,
+	}, nil
+}
+
+// Conforms to `Deduplicator`.
+func (pd *PersistentDeduplicator) CheckAndRecord(eventID string) (bool, error) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	if _, ok := pd.seenIDs[eventID]; ok {
+		pd.numDuplicates++
+		return true, nil
+	}
+
+	// Persist before remembering, so that a failure to persist doesn't leave the file lagging behind
+	// memory.
+	if _, err := fmt.Fprintln(pd.seenIDsFile, eventID); err != nil {
+		return false, fmt.Errorf("error recording event ID %s as seen: %w", eventID, err)
+	}
+
+	pd.seenIDs[eventID] = struct{}{}
+
+	return false, nil
+}
+
+// Conforms to `LookupDeduplicator`.
+func (pd *PersistentDeduplicator) Seen(eventID string) bool {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	_, ok := pd.seenIDs[eventID]
+	return ok
+}
+
+// Conforms to `Deduplicator`.
+func (pd *PersistentDeduplicator) NumDuplicates() int {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	return pd.numDuplicates
+}
+
+// Close releases the file backing `pd`.
+func (pd *PersistentDeduplicator) Close() error {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	return pd.seenIDsFile.Close()
+}
+
+// StagedDeduplicator is a `Deduplicator` that stages the event IDs it hasn't seen before, rather than
+// recording them with the `LookupDeduplicator` it wraps right away -- they're only recorded there once
+// they're committed (see `Commit`), which is meant to happen once whatever the events were processed into
+// has been saved, so that events whose effects were lost are never dropped as duplicates when redelivered.
+//
+// Staged event IDs count as seen (by `CheckAndRecord`) until they're discarded (see `Discard`).
+type StagedDeduplicator struct {
+	mu               sync.Mutex
+	deduplicator     LookupDeduplicator
+	stagedIDs        map[string]struct{}
+	stagedIDsInOrder []string
+	numDuplicates    int
+}
+
+// NewStagedDeduplicator creates a `StagedDeduplicator` that commits event IDs to `deduplicator`.
+func NewStagedDeduplicator(deduplicator LookupDeduplicator) *StagedDeduplicator {
+	return &StagedDeduplicator{
+		deduplicator: deduplicator,
+		stagedIDs:    make(map[string]struct{}),
+	}
+}
+
+// Conforms to `Deduplicator`.
+func (sd *StagedDeduplicator) CheckAndRecord(eventID string) (bool, error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	if _, ok := sd.stagedIDs[eventID]; ok || sd.deduplicator.Seen(eventID) {
+		sd.numDuplicates++
+		return true, nil
+	}
+
+	sd.stagedIDs[eventID] = struct{}{}
+	sd.stagedIDsInOrder = append(sd.stagedIDsInOrder, eventID)
+
+	return false, nil
+}
+
+// Conforms to `Deduplicator`.
+func (sd *StagedDeduplicator) NumDuplicates() int {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	return sd.numDuplicates
+}
+
+// Commit records every staged event ID with the wrapped `LookupDeduplicator` (in the order they were
+// staged in). If that fails, the event IDs that weren't recorded yet stay staged, to be committed again by
+// the next call.
+func (sd *StagedDeduplicator) Commit() error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	for len(sd.stagedIDsInOrder) > 0 {
+		eventID := sd.stagedIDsInOrder[0]
+		if _, err := sd.deduplicator.CheckAndRecord(eventID); err != nil {
+			return fmt.Errorf("error committing event ID %s: %w", eventID, err)
+		}
+
+		delete(sd.stagedIDs, eventID)
+		sd.stagedIDsInOrder = sd.stagedIDsInOrder[1:]
+	}
+
+	return nil
+}
+
+// Discard forgets every staged event ID, so that those events are processed again when redelivered.
+func (sd *StagedDeduplicator) Discard() {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.stagedIDs = make(map[string]struct{})
+	sd.stagedIDsInOrder = nil
+}
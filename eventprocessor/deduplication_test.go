@@ -0,0 +1,196 @@
+package eventprocessor_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+const deduplicationTestEventType eventhandler.EventType = "DeduplicationTestEvent"
+
+func TestWindowedDeduplicator(t *testing.T) {
+	if _, err := eventprocessor.NewWindowedDeduplicator(0); err == nil {
+		t.Fatalf("expected: error for empty window, got: no error")
+	}
+
+	wd, err := eventprocessor.NewWindowedDeduplicator(2)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	for i, step := range []struct {
+		eventID           string
+		expectedDuplicate bool
+	}{
+		{"e1", false},
+		{"e1", true},
+		{"e2", false},
+		{"e1", true},
+		// Evicts "e1" from the window.
+		{"e3", false},
+		{"e1", false},
+		{"e3", true},
+	} {
+		duplicate, err := wd.CheckAndRecord(step.eventID)
+		if err != nil {
+			t.Fatalf("step %d: expected: no error, got: %v", i, err)
+		}
+
+		if duplicate != step.expectedDuplicate {
+			t.Fatalf("step %d: expected: duplicate=%v for %s, got: duplicate=%v",
+				i, step.expectedDuplicate, step.eventID, duplicate)
+		}
+	}
+
+	if numDuplicates := wd.NumDuplicates(); numDuplicates != 3 {
+		t.Fatalf("expected: 3 duplicates, got: %d", numDuplicates)
+	}
+}
+
+func TestPersistentDeduplicator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen")
+
+	pd, err := eventprocessor.OpenPersistentDeduplicator(path)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	for _, eventID := range []string{"e1", "e2", "e1"} {
+		if _, err := pd.CheckAndRecord(eventID); err != nil {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+	}
+
+	if numDuplicates := pd.NumDuplicates(); numDuplicates != 1 {
+		t.Fatalf("expected: 1 duplicate, got: %d", numDuplicates)
+	}
+
+	if err := pd.Close(); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// A later run remembers what earlier runs saw.
+	pd, err = eventprocessor.OpenPersistentDeduplicator(path)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	defer pd.Close()
+
+	for eventID, expectedDuplicate := range map[string]bool{"e1": true, "e2": true, "e3": false} {
+		duplicate, err := pd.CheckAndRecord(eventID)
+		if err != nil {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+
+		if duplicate != expectedDuplicate {
+			t.Fatalf("expected: duplicate=%v for %s, got: duplicate=%v", expectedDuplicate, eventID, duplicate)
+		}
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	if expectedContents := "e1\ne2\ne3\n"; string(contents) != expectedContents {
+		t.Fatalf("expected: %q, got: %q", expectedContents, string(contents))
+	}
+}
+
+func TestStagedDeduplicator(t *testing.T) {
+	wd, err := eventprocessor.NewWindowedDeduplicator(10)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	sd := eventprocessor.NewStagedDeduplicator(wd)
+
+	checkAndRecord := func(eventID string, expectedDuplicate bool) {
+		t.Helper()
+
+		duplicate, err := sd.CheckAndRecord(eventID)
+		if err != nil {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+
+		if duplicate != expectedDuplicate {
+			t.Fatalf("expected: duplicate=%v for %s, got: duplicate=%v", expectedDuplicate, eventID, duplicate)
+		}
+	}
+
+	// Staged event IDs are seen, but not recorded until they're committed.
+	checkAndRecord("e1", false)
+	checkAndRecord("e1", true)
+	if wd.Seen("e1") {
+		t.Fatalf("expected: e1 not recorded before Commit, got: recorded")
+	}
+
+	if err := sd.Commit(); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	if !wd.Seen("e1") {
+		t.Fatalf("expected: e1 recorded after Commit, got: not recorded")
+	}
+	checkAndRecord("e1", true)
+
+	// Discarded event IDs are forgotten.
+	checkAndRecord("e2", false)
+	sd.Discard()
+	if err := sd.Commit(); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	if wd.Seen("e2") {
+		t.Fatalf("expected: e2 not recorded after Discard, got: recorded")
+	}
+	checkAndRecord("e2", false)
+
+	if numDuplicates := sd.NumDuplicates(); numDuplicates != 2 {
+		t.Fatalf("expected: 2 duplicates, got: %d", numDuplicates)
+	}
+}
+
+func TestProcessWithDeduplicator(t *testing.T) {
+	teh := &testEventHandler{}
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler(deduplicationTestEventType, teh); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	teh.setup(false)
+	defer teh.teardown()
+
+	wd, err := eventprocessor.NewWindowedDeduplicator(10)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	ep := eventprocessor.New(eventprocessor.WithDeduplicator(wd), eventprocessor.WithRegistry(r))
+
+	// Replaying the same input (as a separate stream) is the canonical re-delivery.
+	for i := 0; i < 2; i++ {
+		eventC := make(chan *input.EventEnvelope, 4)
+		eventC <- input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("DeduplicationTestEvent A"), "e1")
+		eventC <- input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("DeduplicationTestEvent B"), "e2")
+		eventC <- input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("DeduplicationTestEvent A"), "e1")
+		// Events without IDs can't be deduplicated.
+		eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("DeduplicationTestEvent C"))
+		close(eventC)
+
+		for err := range ep.Process(eventC, eventstore.New()) {
+			t.Fatalf("expected: no error, got: %v", err)
+		}
+	}
+
+	expectedOutput := []eventhandler.EventArgs{{"A"}, {"B"}, {"C"}, {"C"}}
+	if !reflect.DeepEqual(teh.recordedEventArgs, expectedOutput) {
+		t.Fatalf("expected: %#v, got: %#v", expectedOutput, teh.recordedEventArgs)
+	}
+
+	if numDuplicates := wd.NumDuplicates(); numDuplicates != 4 {
+		t.Fatalf("expected: 4 duplicates, got: %d", numDuplicates)
+	}
+}
@@ -0,0 +1,103 @@
+package eventprocessor
+
+import (
+	"errors"
+	"fmt"
+
+	"root.challenge/eventhandler"
+)
+
+// ErrorClass classifies the `error`s emitted by `Process` by the stage of processing they arose in, so that
+// callers can tell (say) malformed input apart from failures of the handlers themselves.
+type ErrorClass int
+
+const (
+	// InputError is for `error`s retrieving events from the input stream, and for malformed events.
+	InputError ErrorClass = iota
+	// DeduplicationError is for `error`s from the `Deduplicator` (see `WithDeduplicator`).
+	DeduplicationError
+	// UnknownEventTypeError is for events with no registered handler.
+	UnknownEventTypeError
+	// PreconditionError is for events rejected by `eventhandler.PreconditionChecker`.
+	PreconditionError
+	// SetupError is for `error`s from `eventhandler.SetupHook`.
+	SetupError
+	// HandlerError is for `error`s from `eventhandler.Interface.Handle` (or any `Middleware` wrapping it).
+	HandlerError
+	// HandlerPanic is for panics in `eventhandler.Interface.Handle`, `eventhandler.PreconditionChecker` or
+	// any `Middleware` (see `PanicError`) -- panics in `eventhandler.SetupHook` and `eventhandler.TeardownHook`
+	// are classified as `SetupError` and `TeardownError` respectively.
+	HandlerPanic
+	// CircuitOpenError is for events rejected because their handler panicked repeatedly (see
+	// `WithCircuitBreaker`).
+	CircuitOpenError
+	// TeardownError is for `error`s from `eventhandler.TeardownHook`.
+	TeardownError
+	// ParkedTripError is for trips that expired while waiting on their drivers to be registered.
+	ParkedTripError
+)
+
+// String returns a human-readable name for `ec`.
+func (ec ErrorClass) String() string {
+	switch ec {
+	case InputError:
+		return "input"
+	case DeduplicationError:
+		return "deduplication"
+	case UnknownEventTypeError:
+		return "unknown event type"
+	case PreconditionError:
+		return "precondition"
+	case SetupError:
+		return "setup"
+	case HandlerError:
+		return "handler"
+	case HandlerPanic:
+		return "handler panic"
+	case CircuitOpenError:
+		return "circuit open"
+	case TeardownError:
+		return "teardown"
+	case ParkedTripError:
+		return "parked trip"
+	default:
+		return "unknown"
+	}
+}
+
+// ProcessingError is the `error` emitted by `Process` (and `Validate`).
+type ProcessingError struct {
+	Class ErrorClass
+	// EventType and EventArgs are empty for `error`s that aren't specific to a single event.
+	EventType eventhandler.EventType
+	EventArgs eventhandler.EventArgs
+	// Line is the line of the input that the event came from, and is 0 when unknown (or when the `error`
+	// isn't specific to a single event).
+	Line int
+	Err  error
+}
+
+// Conforms to `error`.
+func (pe *ProcessingError) Error() string {
+	if pe.Line > 0 {
+		return fmt.Sprintf("line %d: %s", pe.Line, pe.Err)
+	}
+
+	return pe.Err.Error()
+}
+
+// Unwrap allows `errors.Is` and `errors.As` to see through `pe`.
+func (pe *ProcessingError) Unwrap() error {
+	return pe.Err
+}
+
+// ClassOf returns the `ErrorClass` of an `error` emitted by `Process` (even if it has since been wrapped),
+// and false for any other `error`.
+func ClassOf(err error) (ErrorClass, bool) {
+	var pe *ProcessingError
+	if !errors.As(err, &pe) {
+		return 0, false
+	}
+
+	return pe.Class, true
+}
@@ -1,14 +1,17 @@
 package eventprocessor
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 
 	"root.challenge/eventhandler"
-	_ "root.challenge/eventhandler/eventhandlers/driver"
-	_ "root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventhandler/eventhandlers/driver"
+	"root.challenge/eventhandler/eventhandlers/driverlifecycle"
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventhandler/eventhandlers/vehicle"
 	"root.challenge/eventstore"
 	"root.challenge/input"
+	"root.challenge/logging"
 )
 
 // EventProcessor is the central entity responsible for orchestrating the processing of events that
@@ -17,11 +20,81 @@ import (
 // It is expected that only one of these is typically created in the system, with multiple disparate
 // calls to `Process`() (for example, if there are multiple input sources to process concurrently,
 // each potentially using a separate `eventstore.EventStore`) made as needed.
-type EventProcessor struct{}
+type EventProcessor struct {
+	registry eventhandler.HandlerLookup
+	// deduplicator is nil when deduplication is disabled.
+	deduplicator Deduplicator
+	middlewares  []Middleware
+	// circuitBreaker is nil when circuit breaking is disabled.
+	circuitBreaker *circuitBreaker
+	// handle is the invocation of `eventhandler.Interface.Handle` wrapped in `middlewares`.
+	handle HandleFunc
+	// observer is nil unless set by `WithObserver`.
+	observer Observer
+	// logger is nil unless set by `WithLogger`.
+	logger *logging.Logger
+}
+
+// Option configures an `EventProcessor` created by `New`.
+type Option func(*EventProcessor)
+
+// WithRegistry makes the `EventProcessor` route events to the handlers in `registry` (which can be a
+// single `*eventhandler.Registry`, or a composition of them -- see `eventhandler.Overlay`), instead of the
+// handlers in `eventhandler.GlobalRegistry`().
+func WithRegistry(registry eventhandler.HandlerLookup) Option {
+	return func(ep *EventProcessor) {
+		ep.registry = registry
+	}
+}
+
+// RegisterBuiltinHandlers registers every handler that ships with the system (which are the handlers in
+// `eventhandler.GlobalRegistry`() by default) with `r`.
+//
+// The trip handlers are customized by `tripOpts` (they're the only builtin handlers with any settings).
+func RegisterBuiltinHandlers(r *eventhandler.Registry, tripOpts ...trip.Option) error {
+	for _, register := range []func(*eventhandler.Registry) error{
+		driver.Register,
+		driverlifecycle.Register,
+		func(r *eventhandler.Registry) error {
+			return trip.Register(r, tripOpts...)
+		},
+		vehicle.Register,
+	} {
+		if err := register(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithDeduplicator makes the `EventProcessor` drop events whose IDs `deduplicator` has seen before (events
+// without IDs are always processed).
+//
+// Events are recorded as seen before they're handled, so an event that fails to be handled isn't retried
+// when re-delivered -- its failure stems from its content, which a re-delivery doesn't change.
+//
+// Unlike the rest of the state involved in processing, `deduplicator` is deliberately shared by every call
+// to `Process`, since re-deliveries of an event typically arrive on a different input stream than the
+// original.
+func WithDeduplicator(deduplicator Deduplicator) Option {
+	return func(ep *EventProcessor) {
+		ep.deduplicator = deduplicator
+	}
+}
 
 // New creates a new `EventProcessor`.
-func New() *EventProcessor {
-	return &EventProcessor{}
+func New(opts ...Option) *EventProcessor {
+	ep := &EventProcessor{
+		registry: eventhandler.GlobalRegistry(),
+	}
+	for _, opt := range opts {
+		opt(ep)
+	}
+
+	ep.handle = buildHandleChain(ep.middlewares)
+
+	return ep
 }
 
 // Process receives a stream of `input.EventEnvelope` objects and processes them in the background as
@@ -32,44 +105,353 @@ func New() *EventProcessor {
 // here -- and not in `New`() -- to allow a single `EventProcessor` to own the processing of every input
 // stream entering the system; that, in turn, makes this method stateless, and thus amenable to being
 // hosted on serverless/FaaS technology stacks.
-func (ep *EventProcessor) Process(eventC <-chan *input.EventEnvelope, eventStore *eventstore.EventStore) <-chan error {
+//
+// The run can be customized by `opts` (like naming the input stream with `WithSource`).
+func (ep *EventProcessor) Process(eventC <-chan *input.EventEnvelope, eventStore *eventstore.EventStore,
+	opts ...ProcessOption) <-chan error {
+	var po processOptions
+	for _, opt := range opts {
+		opt(&po)
+	}
+
+	// Every entry logged while processing the run comes with the name of its input stream (if known).
+	logger := ep.logger
+	if po.source != "" {
+		logger = logger.With(logging.String("source", po.source))
+	}
+
 	errC := make(chan error)
 
+	emit := func(err error) {
+		if ep.observer != nil {
+			ep.observer.Error(err)
+		}
+		errC <- err
+	}
+
 	go func() {
 		defer close(errC)
 
-		for eventEnvelope := range eventC {
-			if eventEnvelope.Err != nil {
-				errC <- fmt.Errorf("error retrieving next EventEnvelope from channel: %w", eventEnvelope.Err)
-				continue
+		lifecycle := newHandlerLifecycle()
+		defer func() {
+			for _, err := range lifecycle.teardown(eventStore) {
+				emit(err)
 			}
+		}()
 
-			if eventEnvelope.Body == nil {
-				errC <- fmt.Errorf("retrieved malformed EventEnvelope with nil Body but nil Err as well")
-				continue
+		for eventEnvelope := range eventC {
+			eventType := ep.processEnvelope(eventEnvelope, eventStore, lifecycle, logger, emit)
+			if ep.observer != nil {
+				ep.observer.EventProcessed(eventType)
 			}
+		}
+	}()
 
-			parsedEvent := strings.Fields(string(*eventEnvelope.Body))
-			if len(parsedEvent) == 0 {
-				// Skip over empty events.
-				continue
-			}
-			eventType := eventhandler.EventType(parsedEvent[0])
-			eventArgs := eventhandler.EventArgs(parsedEvent[1:])
+	return errC
+}
+
+// processEnvelope is the part of `Process` that processes a single `input.EventEnvelope` (logging to
+// `logger`, the `logging.Logger` of the run), passing every `error` along the way to `emit` -- and returns the
+// `eventhandler.EventType` of its event (if any).
+func (ep *EventProcessor) processEnvelope(eventEnvelope *input.EventEnvelope, eventStore *eventstore.EventStore,
+	lifecycle *handlerLifecycle, logger *logging.Logger, emit func(error)) eventhandler.EventType {
+	event, err := parseEventEnvelope(eventEnvelope)
+	if err != nil {
+		emit(err)
+		return ""
+	}
+
+	if event == nil {
+		// Skip over empty (and commented-out) events.
+		return ""
+	}
+
+	// Events are reported by the `eventhandler.EventType` their handler was registered for (duplicates
+	// included), and those without a handler not at all -- their spellings are up to the input, and would
+	// make for an unbounded set of metric labels.
+	eventType := ep.canonicalEventType(event.eventType)
+
+	if eventEnvelope.ID != "" && ep.deduplicator != nil {
+		duplicate, err := ep.deduplicator.CheckAndRecord(eventEnvelope.ID)
+		if err != nil {
+			emit(&ProcessingError{
+				Class: DeduplicationError,
+				Line:  event.line,
+				Err:   fmt.Errorf("error deduplicating event with ID %s: %w", eventEnvelope.ID, err),
+			})
+			return eventType
+		}
+
+		if duplicate {
+			// Re-deliveries are expected, so they're only counted (by `ep.deduplicator`), not reported.
+			logger.Debug("dropped duplicate event", logging.String("event_type", string(event.eventType)),
+				logging.Int("line", event.line), logging.String("event_id", eventEnvelope.ID))
+			return eventType
+		}
+	}
+
+	if err := ep.handleEvent(event, eventStore, lifecycle, logger); err != nil {
+		emit(err)
+	}
 
-			eventHandler, err := eventhandler.GlobalRegistry().GetHandlerForEvent(eventType)
+	// Count the event towards how long parked trips have been waiting on their drivers, and give up on those
+	// that have waited for too long (this is a no-op unless `eventStore` was created with
+	// `eventstore.WithParkedTrips`).
+	for _, err := range eventStore.ExpireParkedTrips() {
+		emit(&ProcessingError{
+			Class: ParkedTripError,
+			Err:   fmt.Errorf("error recording parked trip: %w", err),
+		})
+	}
+
+	return eventType
+}
+
+// Validate is the dry-run counterpart of `Process` -- it runs every event in `eventC` through the stages of
+// processing that have no side effects (parsing it, finding its handler, and validating its args against
+// the handler's `eventhandler.Schema` and `eventhandler.PreconditionChecker`), and emits the same `error`s
+// on the returned channel that `Process` would for those stages.
+//
+// It never touches an `eventstore.EventStore`, nor does it deduplicate events (which would record their
+// IDs), set handlers up, invoke middlewares or trip the circuit breaker -- so it's safe to run on input that
+// is later passed to `Process`. Since those stages are skipped, a clean validation doesn't guarantee clean
+// processing (for example, trips of drivers that are never registered only fail with
+// `eventstore.WithStrictDriverRegistration`).
+func (ep *EventProcessor) Validate(eventC <-chan *input.EventEnvelope) <-chan error {
+	errC := make(chan error)
+
+	go func() {
+		defer close(errC)
+
+		for eventEnvelope := range eventC {
+			event, err := parseEventEnvelope(eventEnvelope)
 			if err != nil {
-				errC <- fmt.Errorf("error retrieving handler for eventType %s: %w", eventType, err)
+				errC <- err
 				continue
 			}
 
-			if err := eventHandler.Handle(eventArgs, eventStore); err != nil {
-				errC <- fmt.Errorf("error handling eventType %s with args %v: %w",
-					eventType, eventArgs, err)
+			if event == nil {
 				continue
 			}
+
+			if err := ep.validateEvent(event); err != nil {
+				errC <- err
+			}
 		}
 	}()
 
 	return errC
 }
+
+// event is a single event extracted from an `input.EventEnvelope`.
+type event struct {
+	eventType    eventhandler.EventType
+	eventArgs    eventhandler.EventArgs
+	eventContext eventhandler.EventContext
+	line         int
+}
+
+// newError creates a `*ProcessingError` of `class` for `e`.
+func (e *event) newError(class ErrorClass, err error) error {
+	return &ProcessingError{
+		Class:     class,
+		EventType: e.eventType,
+		EventArgs: e.eventArgs,
+		Line:      e.line,
+		Err:       err,
+	}
+}
+
+// parseEventEnvelope extracts the event in `eventEnvelope`, and returns a nil `*event` (along with a nil
+// `error`) for envelopes that don't hold one (like blank lines).
+func parseEventEnvelope(eventEnvelope *input.EventEnvelope) (*event, error) {
+	if eventEnvelope.Err != nil {
+		return nil, &ProcessingError{
+			Class: InputError,
+			Line:  eventEnvelope.Line,
+			Err:   fmt.Errorf("error retrieving next EventEnvelope from channel: %w", eventEnvelope.Err),
+		}
+	}
+
+	if eventEnvelope.Body == nil {
+		return nil, &ProcessingError{
+			Class: InputError,
+			Line:  eventEnvelope.Line,
+			Err:   fmt.Errorf("retrieved malformed EventEnvelope with nil Body but nil Err as well"),
+		}
+	}
+
+	tokens, err := eventEnvelope.Tokens()
+	if err != nil {
+		return nil, &ProcessingError{
+			Class: InputError,
+			Line:  eventEnvelope.Line,
+			Err:   fmt.Errorf("error tokenizing event: %w", err),
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	return &event{
+		eventType:    eventhandler.EventType(tokens[0]),
+		eventArgs:    eventhandler.EventArgs(tokens[1:]),
+		eventContext: newEventContext(eventEnvelope.Directives),
+		line:         eventEnvelope.Line,
+	}, nil
+}
+
+// handleEvent routes a single event through the stages of handling it -- finding its handler, checking its
+// preconditions, setting its handler up (once per run), and finally handling it -- and returns a
+// `*ProcessingError` classified by the stage that failed, if any.
+func (ep *EventProcessor) handleEvent(e *event, eventStore *eventstore.EventStore, lifecycle *handlerLifecycle,
+	logger *logging.Logger) error {
+	eventHandler, err := ep.lookupEventHandler(e)
+	if err != nil {
+		return err
+	}
+
+	if !ep.circuitBreaker.allow(e.eventType) {
+		return e.newError(CircuitOpenError,
+			fmt.Errorf("handling of eventType %s is disabled after its handler panicked repeatedly", e.eventType))
+	}
+
+	// Only the outcome of invoking the handler counts towards the circuit breaker -- events that are turned
+	// away before that (for being malformed, say) are merely released.
+	invoked, panicked := false, false
+	defer func() {
+		if invoked {
+			ep.circuitBreaker.record(e.eventType, panicked)
+		} else {
+			ep.circuitBreaker.release(e.eventType)
+		}
+	}()
+
+	// Args are validated before setup, so that a run made up entirely of malformed events doesn't spin up
+	// any handlers.
+	if err := checkEventArgs(e, eventHandler); err != nil {
+		// A panic in `CheckPreconditions`() is the handler misbehaving just as much as one in `Handle`().
+		invoked = isPanic(err)
+		panicked = invoked
+		return err
+	}
+
+	// The args are known to be well-formed by now, so the schema of `eventHandler` can pick the driver out of
+	// them.
+	e.eventContext.Logger = eventLogger(logger, e, eventHandler)
+
+	// Panics in `Setup`() are treated like any other setup failure, since the handler won't be invoked again
+	// in this run either way.
+	if err := lifecycle.ensureSetup(e.eventType, eventHandler, eventStore); err != nil {
+		return e.newError(SetupError, err)
+	}
+
+	invoked = true
+	if err := recoverPanics(e.eventType, func() error {
+		return ep.handle(&Invocation{
+			EventType:    e.eventType,
+			EventArgs:    e.eventArgs,
+			EventContext: e.eventContext,
+			EventStore:   eventStore,
+			eventHandler: eventHandler,
+		})
+	}); err != nil {
+		class := HandlerError
+		if panicked = isPanic(err); panicked {
+			class = HandlerPanic
+		}
+
+		return e.newError(class,
+			fmt.Errorf("error handling eventType %s with args %v: %w", e.eventType, e.eventArgs, err))
+	}
+
+	return nil
+}
+
+// validateEvent routes a single event through the side-effect-free stages of `handleEvent`.
+func (ep *EventProcessor) validateEvent(e *event) error {
+	eventHandler, err := ep.lookupEventHandler(e)
+	if err != nil {
+		return err
+	}
+
+	return checkEventArgs(e, eventHandler)
+}
+
+// lookupEventHandler finds the handler for `e`, and switches `e` over to the `EventType` that the handler
+// was registered for (rather than whichever alias or spelling `e` arrived with), so that per-`EventType`
+// state -- like setup and circuit breaking -- is shared by every spelling.
+func (ep *EventProcessor) lookupEventHandler(e *event) (eventhandler.Interface, error) {
+	canonicalEventType, eventHandler, err := eventhandler.Lookup(ep.registry, e.eventType)
+	if err != nil {
+		return nil, e.newError(UnknownEventTypeError,
+			fmt.Errorf("error retrieving handler for eventType %s: %w", e.eventType, err))
+	}
+
+	e.eventType = canonicalEventType
+	return eventHandler, nil
+}
+
+// canonicalEventType returns the `eventhandler.EventType` that the handler for `eventType` was registered
+// for, or "" if there's no such handler.
+func (ep *EventProcessor) canonicalEventType(eventType eventhandler.EventType) eventhandler.EventType {
+	canonicalEventType, _, err := eventhandler.Lookup(ep.registry, eventType)
+	if err != nil {
+		return ""
+	}
+
+	return canonicalEventType
+}
+
+// checkEventArgs validates the args of `e` against the schema of `eventHandler`, and then its
+// preconditions -- both of which are side-effect free.
+func checkEventArgs(e *event, eventHandler eventhandler.Interface) error {
+	if schemaProvider, ok := eventHandler.(eventhandler.SchemaProvider); ok {
+		if err := recoverPanics(e.eventType, func() error {
+			return schemaProvider.ArgSchema().Validate(e.eventArgs)
+		}); err != nil {
+			if isPanic(err) {
+				return e.newError(HandlerPanic, fmt.Errorf("error validating args for eventType %s with args %v: %w",
+					e.eventType, e.eventArgs, err))
+			}
+
+			return e.newError(PreconditionError,
+				fmt.Errorf("invalid args for eventType %s with args %v: %w", e.eventType, e.eventArgs, err))
+		}
+	}
+
+	if preconditionChecker, ok := eventHandler.(eventhandler.PreconditionChecker); ok {
+		if err := recoverPanics(e.eventType, func() error {
+			return preconditionChecker.CheckPreconditions(e.eventArgs)
+		}); err != nil {
+			if isPanic(err) {
+				return e.newError(HandlerPanic, fmt.Errorf("error checking preconditions for eventType %s with args %v: %w",
+					e.eventType, e.eventArgs, err))
+			}
+
+			return e.newError(PreconditionError,
+				fmt.Errorf("failed preconditions for eventType %s with args %v: %w", e.eventType, e.eventArgs, err))
+		}
+	}
+
+	return nil
+}
+
+// newEventContext translates the `input.Directives` of an event (which may be nil) into the
+// `eventhandler.EventContext` that its handler interprets it in.
+func newEventContext(directives *input.Directives) eventhandler.EventContext {
+	if directives == nil {
+		return eventhandler.EventContext{}
+	}
+
+	return eventhandler.EventContext{
+		DistanceUnit: directives.DistanceUnit,
+	}
+}
+
+// isPanic reports whether `err` is the outcome of `recoverPanics` recovering from a panic.
+func isPanic(err error) bool {
+	var pe *PanicError
+	return errors.As(err, &pe)
+}
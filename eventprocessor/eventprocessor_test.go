@@ -1,8 +1,11 @@
 package eventprocessor_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"root.challenge/eventhandler"
@@ -134,10 +137,14 @@ func TestTripEventHandler(t *testing.T) {
 		},
 	}
 
-	// Register `testEventHandler` one time for the entire test case, akin to how packages are initialized exactly
-	// once at load time.
+	// Register `testEventHandler` with an `eventhandler.Registry` private to this test (so as to leave
+	// `eventhandler.GlobalRegistry`() untouched), one time for the entire test case, akin to how packages are
+	// initialized exactly once at load time.
 	teh := &testEventHandler{}
-	eventhandler.GlobalRegistry().RegisterEventHandler(testEventType, teh)
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler(testEventType, teh); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -154,7 +161,7 @@ func TestTripEventHandler(t *testing.T) {
 
 			// Record the `error`s emitted by `Process`() to make the test easier to debug.
 			actualErrors := make([]error, 0)
-			for err := range eventprocessor.New().Process(eventC, eventstore.New()) {
+			for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, eventstore.New()) {
 				actualErrors = append(actualErrors, err)
 			}
 
@@ -174,3 +181,142 @@ func TestTripEventHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestWithRegistry(t *testing.T) {
+	builtins := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(builtins); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// Override the builtin handler for "Driver" events, leaving every other builtin handler in place.
+	teh := &testEventHandler{}
+	teh.setup(false)
+	overrides := eventhandler.NewRegistry()
+	if err := overrides.RegisterEventHandler("Driver", teh); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	eventC := make(chan *input.EventEnvelope, 3)
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("Driver Dan"))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("Trip Dan 07:15 07:45 17.3"))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("TestEvent Arg1"))
+	close(eventC)
+
+	es := eventstore.New()
+	actualErrors := make([]error, 0)
+	for err := range eventprocessor.New(eventprocessor.WithRegistry(eventhandler.Overlay(overrides, builtins))).Process(eventC, es) {
+		actualErrors = append(actualErrors, err)
+	}
+
+	// Neither layer has a handler for "TestEvent".
+	if len(actualErrors) != 1 {
+		t.Fatalf("expected: 1 error, got: %v", actualErrors)
+	}
+	if errorClass, _ := eventprocessor.ClassOf(actualErrors[0]); errorClass != eventprocessor.UnknownEventTypeError {
+		t.Fatalf("expected: error of class %v, got: %v", eventprocessor.UnknownEventTypeError, actualErrors[0])
+	}
+
+	if expectedOutput := []eventhandler.EventArgs{{"Dan"}}; !reflect.DeepEqual(teh.recordedEventArgs, expectedOutput) {
+		t.Fatalf("expected: %#v, got: %#v", expectedOutput, teh.recordedEventArgs)
+	}
+
+	// The builtin "Trip" handler recorded the trip (lazily registering its driver, since the overridden
+	// "Driver" handler didn't).
+	r := eventstore.NewRecorder()
+	es.Visit(r)
+	if len(r.Entities) != 1 || r.Entities[0].DriverID != "Dan" {
+		t.Fatalf("expected: exactly 1 VisitableEntity for Dan, got: %v", r.Entities)
+	}
+}
+
+func TestProcessValidatesArgSchemas(t *testing.T) {
+	r := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(r); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	eventC := make(chan *input.EventEnvelope, 3)
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("Driver Dan"))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("Trip Dan 7.15 07:45 17.3"))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("Vehicle V1 sedan"))
+	close(eventC)
+
+	actualErrors := make([]error, 0)
+	for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, eventstore.New()) {
+		actualErrors = append(actualErrors, err)
+	}
+
+	if len(actualErrors) != 2 {
+		t.Fatalf("expected: 2 errors, got: %v", actualErrors)
+	}
+
+	for _, err := range actualErrors {
+		if errorClass, _ := eventprocessor.ClassOf(err); errorClass != eventprocessor.PreconditionError {
+			t.Fatalf("expected: error of class %v, got: %v", eventprocessor.PreconditionError, err)
+		}
+
+		var argErr *eventhandler.ArgError
+		if !errors.As(err, &argErr) {
+			t.Fatalf("expected: error wrapping *eventhandler.ArgError, got: %v", err)
+		}
+	}
+}
+
+func TestProcessTokenizesEvents(t *testing.T) {
+	r := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(r); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	eventC := make(chan *input.EventEnvelope, 4)
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(`Driver "Mary Ann" id=MA # a comment`))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(`# Driver Dan`))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(`Trip MA 07:15 07:45 17.3`))
+	eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(`Driver "Unterminated`))
+	close(eventC)
+
+	es := eventstore.New()
+	actualErrors := make([]error, 0)
+	for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, es) {
+		actualErrors = append(actualErrors, err)
+	}
+
+	if len(actualErrors) != 1 {
+		t.Fatalf("expected: 1 error, got: %v", actualErrors)
+	}
+	if errorClass, _ := eventprocessor.ClassOf(actualErrors[0]); errorClass != eventprocessor.InputError {
+		t.Fatalf("expected: error of class %v, got: %v", eventprocessor.InputError, actualErrors[0])
+	}
+
+	rec := eventstore.NewRecorder()
+	es.Visit(rec)
+	if len(rec.Entities) != 1 || rec.Entities[0].DriverID != "MA" || rec.Entities[0].DriverDisplayName != "Mary Ann" {
+		t.Fatalf("expected: exactly 1 VisitableEntity for Mary Ann, got: %v", rec.Entities)
+	}
+}
+
+func TestProcessWithDirectives(t *testing.T) {
+	r := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(r); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	const events = "#!format v1\n#!units km\nDriver Dan\nTrip Dan 07:00 08:00 120\nTrip Dan 08:00 09:00 10mi\n"
+
+	es := eventstore.New()
+	eventC := input.StartReading(io.NopCloser(strings.NewReader(events)))
+	for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, es) {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	rec := eventstore.NewRecorder()
+	es.Visit(rec)
+	if len(rec.Entities) != 1 {
+		t.Fatalf("expected: exactly 1 VisitableEntity, got: %v", rec.Entities)
+	}
+
+	// 120km (in the declared units) plus 10mi (in explicit units).
+	if expectedMiles := 84.564543; rec.Entities[0].TotalMilesDriven != expectedMiles {
+		t.Fatalf("expected: %v miles, got: %v", expectedMiles, rec.Entities[0].TotalMilesDriven)
+	}
+}
@@ -0,0 +1,107 @@
+package eventprocessor
+
+import (
+	"fmt"
+	"reflect"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+)
+
+// handlerLifecycle tracks the handlers that have been set up during a single processing run, so that
+// each of them is set up at most once and torn down exactly once.
+//
+// It's owned by a single call to `Process`, and is thus not safe for concurrent use.
+type handlerLifecycle struct {
+	// setups holds the outcome of setting up every handler of every `EventType` seen so far -- there's more
+	// than one handler per `EventType` when handlers are replaced mid-run.
+	setups map[eventhandler.EventType][]setupOutcome
+	// setupOrder holds the successfully set-up handlers, in the order they were set up.
+	setupOrder []setupHandler
+}
+
+type setupHandler struct {
+	eventType    eventhandler.EventType
+	eventHandler eventhandler.Interface
+}
+
+type setupOutcome struct {
+	eventHandler eventhandler.Interface
+	err          error
+}
+
+func newHandlerLifecycle() *handlerLifecycle {
+	return &handlerLifecycle{
+		setups: make(map[eventhandler.EventType][]setupOutcome),
+	}
+}
+
+// ensureSetup sets `eventHandler` up if this is its first event of `eventType` in the run, and returns the
+// (remembered) outcome of that setup otherwise.
+//
+// A handler that replaces another one mid-run (see `eventhandler.Registry.ReplaceEventHandler`) is set up
+// on its first event, just like the handler it replaced was -- and both are torn down at the end of the run.
+// Swapping a handler back in doesn't set it up again.
+func (hl *handlerLifecycle) ensureSetup(eventType eventhandler.EventType, eventHandler eventhandler.Interface,
+	eventStore *eventstore.EventStore) error {
+	for _, outcome := range hl.setups[eventType] {
+		if isSameHandler(outcome.eventHandler, eventHandler) {
+			return outcome.err
+		}
+	}
+
+	var err error
+	if setupHook, ok := eventHandler.(eventhandler.SetupHook); ok {
+		if setupErr := recoverPanics(eventType, func() error {
+			return setupHook.Setup(eventStore)
+		}); setupErr != nil {
+			err = fmt.Errorf("error setting up handler for eventType %s: %w", eventType, setupErr)
+		}
+	}
+
+	hl.setups[eventType] = append(hl.setups[eventType], setupOutcome{eventHandler, err})
+	if err == nil {
+		hl.setupOrder = append(hl.setupOrder, setupHandler{eventType, eventHandler})
+	}
+
+	return err
+}
+
+// teardown tears down every successfully set-up handler, in the reverse order of setup.
+func (hl *handlerLifecycle) teardown(eventStore *eventstore.EventStore) []error {
+	var errs []error
+	for i := len(hl.setupOrder) - 1; i >= 0; i-- {
+		sh := hl.setupOrder[i]
+
+		teardownHook, ok := sh.eventHandler.(eventhandler.TeardownHook)
+		if !ok {
+			continue
+		}
+
+		if err := recoverPanics(sh.eventType, func() error {
+			return teardownHook.Teardown(eventStore)
+		}); err != nil {
+			errs = append(errs, &ProcessingError{
+				Class:     TeardownError,
+				EventType: sh.eventType,
+				Err:       fmt.Errorf("error tearing down handler for eventType %s: %w", sh.eventType, err),
+			})
+		}
+	}
+
+	return errs
+}
+
+// isSameHandler reports whether `a` and `b` are the same handler -- handlers of types that can't be
+// compared are assumed to be the same, since there's no way to tell otherwise.
+func isSameHandler(a, b eventhandler.Interface) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+
+	if !reflect.TypeOf(a).Comparable() {
+		return true
+	}
+
+	return a == b
+}
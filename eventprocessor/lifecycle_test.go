@@ -0,0 +1,329 @@
+package eventprocessor_test
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+// `hookedTestEventHandler` is an implementation of `eventhandler.Interface` (and all of its optional
+// extensions) that logs its invocations into a log shared with other `hookedTestEventHandler`s, so that
+// the relative ordering of invocations across handlers can be inspected.
+type hookedTestEventHandler struct {
+	name            string
+	log             *[]string
+	setupErr        error
+	teardownErr     error
+	rejectedArg     string
+	handleShouldErr bool
+}
+
+func (hteh *hookedTestEventHandler) Setup(eventStore *eventstore.EventStore) error {
+	*hteh.log = append(*hteh.log, hteh.name+".Setup")
+	return hteh.setupErr
+}
+
+func (hteh *hookedTestEventHandler) Teardown(eventStore *eventstore.EventStore) error {
+	*hteh.log = append(*hteh.log, hteh.name+".Teardown")
+	return hteh.teardownErr
+}
+
+func (hteh *hookedTestEventHandler) CheckPreconditions(eventArgs eventhandler.EventArgs) error {
+	for _, eventArg := range eventArgs {
+		if eventArg == hteh.rejectedArg {
+			return fmt.Errorf("rejected arg %s", eventArg)
+		}
+	}
+
+	return nil
+}
+
+func (hteh *hookedTestEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	*hteh.log = append(*hteh.log, fmt.Sprintf("%s.Handle%v", hteh.name, eventArgs))
+	if hteh.handleShouldErr {
+		return fmt.Errorf("hookedTestEventHandler Handle error")
+	}
+
+	return nil
+}
+
+func TestProcessLifecycleHooks(t *testing.T) {
+	tests := map[string]struct {
+		input []string
+		// Configure `hookedTestEventHandler`s A and B respectively.
+		configureA, configureB func(*hookedTestEventHandler)
+		expectedErrorClasses   []eventprocessor.ErrorClass
+		expectedLog            []string
+	}{
+		"NoEvents": {
+			expectedLog: []string{},
+		},
+		"SetupAndTeardownOncePerRunInReverseOrder": {
+			input: []string{"HookedTestEventA 1", "HookedTestEventB 2", "HookedTestEventA 3"},
+			expectedLog: []string{
+				"A.Setup", "A.Handle[1]", "B.Setup", "B.Handle[2]", "A.Handle[3]", "B.Teardown", "A.Teardown",
+			},
+		},
+		"OnlyHandlersWithEventsAreSetUp": {
+			input:       []string{"HookedTestEventB 1"},
+			expectedLog: []string{"B.Setup", "B.Handle[1]", "B.Teardown"},
+		},
+		"FailedPreconditionsSkipHandling": {
+			input: []string{"HookedTestEventA bad", "HookedTestEventA 1"},
+			configureA: func(hteh *hookedTestEventHandler) {
+				hteh.rejectedArg = "bad"
+			},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.PreconditionError},
+			expectedLog:          []string{"A.Setup", "A.Handle[1]", "A.Teardown"},
+		},
+		"FailedPreconditionsDontSetUp": {
+			input: []string{"HookedTestEventA bad"},
+			configureA: func(hteh *hookedTestEventHandler) {
+				hteh.rejectedArg = "bad"
+			},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.PreconditionError},
+			expectedLog:          []string{},
+		},
+		"FailedSetupIsNotRetriedNorTornDown": {
+			input: []string{"HookedTestEventA 1", "HookedTestEventB 2", "HookedTestEventA 3"},
+			configureA: func(hteh *hookedTestEventHandler) {
+				hteh.setupErr = fmt.Errorf("setup error")
+			},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.SetupError, eventprocessor.SetupError},
+			expectedLog:          []string{"A.Setup", "B.Setup", "B.Handle[2]", "B.Teardown"},
+		},
+		"FailedTeardown": {
+			input: []string{"HookedTestEventA 1"},
+			configureA: func(hteh *hookedTestEventHandler) {
+				hteh.teardownErr = fmt.Errorf("teardown error")
+			},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.TeardownError},
+			expectedLog:          []string{"A.Setup", "A.Handle[1]", "A.Teardown"},
+		},
+		"FailedHandle": {
+			input: []string{"HookedTestEventA 1", "HookedTestEventC 2"},
+			configureA: func(hteh *hookedTestEventHandler) {
+				hteh.handleShouldErr = true
+			},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerError, eventprocessor.UnknownEventTypeError,
+			},
+			expectedLog: []string{"A.Setup", "A.Handle[1]", "A.Teardown"},
+		},
+	}
+
+	// Register the `hookedTestEventHandler`s with an `eventhandler.Registry` private to this test (so as to
+	// leave `eventhandler.GlobalRegistry`() untouched), one time for the entire test case, akin to how packages
+	// are initialized exactly once at load time.
+	var log []string
+	hehA := &hookedTestEventHandler{}
+	hehB := &hookedTestEventHandler{}
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler("HookedTestEventA", hehA); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	if err := r.RegisterEventHandler("HookedTestEventB", hehB); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			log = make([]string, 0)
+			*hehA = hookedTestEventHandler{name: "A", log: &log}
+			*hehB = hookedTestEventHandler{name: "B", log: &log}
+			if tc.configureA != nil {
+				tc.configureA(hehA)
+			}
+			if tc.configureB != nil {
+				tc.configureB(hehB)
+			}
+
+			eventC := make(chan *input.EventEnvelope, len(tc.input))
+			for _, event := range tc.input {
+				eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(event))
+			}
+			close(eventC)
+
+			actualErrorClasses := make([]eventprocessor.ErrorClass, 0)
+			for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, eventstore.New()) {
+				errorClass, ok := eventprocessor.ClassOf(err)
+				if !ok {
+					t.Fatalf("expected: classified error, got: %v", err)
+				}
+				actualErrorClasses = append(actualErrorClasses, errorClass)
+			}
+
+			if len(tc.expectedErrorClasses) == 0 {
+				tc.expectedErrorClasses = []eventprocessor.ErrorClass{}
+			}
+			if !reflect.DeepEqual(actualErrorClasses, tc.expectedErrorClasses) {
+				t.Fatalf("expected: errors of classes %v, got: %v", tc.expectedErrorClasses, actualErrorClasses)
+			}
+
+			if !reflect.DeepEqual(log, tc.expectedLog) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedLog, log)
+			}
+		})
+	}
+}
+
+func TestProcessWithHandlerReplacedMidRun(t *testing.T) {
+	var log []string
+	hehA := &hookedTestEventHandler{name: "A", log: &log}
+	hehB := &hookedTestEventHandler{name: "B", log: &log}
+
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler("HookedTestEventA", hehA); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// Hot-upgrade the handler right after the first event is handled.
+	replaceAfterFirstEvent := eventprocessor.Timing(func(*eventprocessor.Invocation, time.Duration, error) {
+		if len(log) == 2 {
+			if _, err := r.ReplaceEventHandler("HookedTestEventA", hehB); err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+		}
+	})
+
+	eventC := make(chan *input.EventEnvelope, 3)
+	for _, event := range []string{"HookedTestEventA 1", "HookedTestEventA 2", "HookedTestEventA 3"} {
+		eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(event))
+	}
+	close(eventC)
+
+	ep := eventprocessor.New(eventprocessor.WithRegistry(r), eventprocessor.WithMiddleware(replaceAfterFirstEvent))
+	for err := range ep.Process(eventC, eventstore.New()) {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	expectedLog := []string{
+		"A.Setup", "A.Handle[1]", "B.Setup", "B.Handle[2]", "B.Handle[3]", "B.Teardown", "A.Teardown",
+	}
+	if !reflect.DeepEqual(log, expectedLog) {
+		t.Fatalf("expected: %v, got: %v", expectedLog, log)
+	}
+}
+
+func TestProcessWithHandlerSwappedBackMidRun(t *testing.T) {
+	var log []string
+	hehA := &hookedTestEventHandler{name: "A", log: &log}
+	hehB := &hookedTestEventHandler{name: "B", log: &log}
+
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler("HookedTestEventA", hehA); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// Swap B in after the first event, and A back in after the second one.
+	swapAfterEvents := eventprocessor.Timing(func(invocation *eventprocessor.Invocation, _ time.Duration, _ error) {
+		replacements := map[string]eventhandler.Interface{"1": hehB, "2": hehA}
+		if replacement, ok := replacements[invocation.EventArgs[0]]; ok {
+			if _, err := r.ReplaceEventHandler("HookedTestEventA", replacement); err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+		}
+	})
+
+	eventC := make(chan *input.EventEnvelope, 3)
+	for _, event := range []string{"HookedTestEventA 1", "HookedTestEventA 2", "HookedTestEventA 3"} {
+		eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(event))
+	}
+	close(eventC)
+
+	ep := eventprocessor.New(eventprocessor.WithRegistry(r), eventprocessor.WithMiddleware(swapAfterEvents))
+	for err := range ep.Process(eventC, eventstore.New()) {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// A is only set up (and torn down) once, despite being swapped back in.
+	expectedLog := []string{
+		"A.Setup", "A.Handle[1]", "B.Setup", "B.Handle[2]", "A.Handle[3]", "B.Teardown", "A.Teardown",
+	}
+	if !reflect.DeepEqual(log, expectedLog) {
+		t.Fatalf("expected: %v, got: %v", expectedLog, log)
+	}
+}
+
+func TestProcessWithAliasedEventTypes(t *testing.T) {
+	var log []string
+	hehA := &hookedTestEventHandler{name: "A", log: &log}
+
+	r := eventhandler.NewRegistry(eventhandler.WithCaseInsensitiveEventTypes())
+	if err := r.RegisterEventHandler("HookedTestEventA", hehA); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	if err := r.RegisterAlias("AliasA", "HookedTestEventA"); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	eventC := make(chan *input.EventEnvelope, 3)
+	for _, event := range []string{"HookedTestEventA 1", "hookedtesteventa 2", "ALIASA 3"} {
+		eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString(event))
+	}
+	close(eventC)
+
+	for err := range eventprocessor.New(eventprocessor.WithRegistry(r)).Process(eventC, eventstore.New()) {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	// Every spelling of the `EventType` shares a single setup (and teardown) of its handler.
+	expectedLog := []string{"A.Setup", "A.Handle[1]", "A.Handle[2]", "A.Handle[3]", "A.Teardown"}
+	if !reflect.DeepEqual(log, expectedLog) {
+		t.Fatalf("expected: %v, got: %v", expectedLog, log)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var log []string
+	hehA := &hookedTestEventHandler{name: "A", log: &log, rejectedArg: "bad"}
+
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler("HookedTestEventA", hehA); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	deduplicator, err := eventprocessor.NewWindowedDeduplicator(10)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	const events = "@e1 HookedTestEventA 1\n@e1 HookedTestEventA 1\nHookedTestEventA bad\n\nUnknown 1\nHookedTestEventA \"2"
+	ep := eventprocessor.New(eventprocessor.WithRegistry(r), eventprocessor.WithDeduplicator(deduplicator))
+
+	summary := eventprocessor.NewErrorSummary()
+	for err := range ep.Validate(input.StartReading(io.NopCloser(strings.NewReader(events)))) {
+		summary.Add(err)
+	}
+
+	expectedLines := map[eventprocessor.ErrorClass][]int{
+		eventprocessor.InputError:            {6},
+		eventprocessor.UnknownEventTypeError: {5},
+		eventprocessor.PreconditionError:     {3},
+	}
+	if summary.Total() != len(expectedLines) {
+		t.Fatalf("expected: %d errors, got: %d", len(expectedLines), summary.Total())
+	}
+	for class, lines := range expectedLines {
+		if actualLines := summary.Lines(class); !reflect.DeepEqual(actualLines, lines) {
+			t.Fatalf("expected: %v errors on lines %v, got: %v", class, lines, actualLines)
+		}
+	}
+
+	// Validation has no side effects -- handlers are neither set up nor invoked, and no event IDs are
+	// recorded as seen.
+	if len(log) != 0 {
+		t.Fatalf("expected: no handler invocations, got: %v", log)
+	}
+	if duplicate, _ := deduplicator.CheckAndRecord("e1"); duplicate {
+		t.Fatalf("expected: event ID e1 to be unseen")
+	}
+}
@@ -0,0 +1,86 @@
+package eventprocessor
+
+import (
+	"errors"
+
+	"root.challenge/eventhandler"
+	"root.challenge/logging"
+)
+
+// WithLogger makes the `EventProcessor` hand every handler a `logging.Logger` (derived from `logger`) scoped
+// to the event it's handling, via the `Logger` of `eventhandler.EventContext` -- and log the events it drops
+// as duplicates (see `WithDeduplicator`) to `logger`, at `logging.LevelDebug`.
+//
+// Events themselves are only logged by the `Logging` middleware.
+//
+// Scoped `logging.Logger`s add the "source" of their event (if named, see `WithSource`), along with its
+// "event_type", "line" (if known) and "driver" (for events with a field of the `eventhandler.DriverRole`
+// in their `eventhandler.Schema`) to every entry.
+func WithLogger(logger *logging.Logger) Option {
+	return func(ep *EventProcessor) {
+		ep.logger = logger
+	}
+}
+
+// ProcessOption customizes a single run of `EventProcessor.Process`.
+type ProcessOption func(*processOptions)
+
+type processOptions struct {
+	source string
+}
+
+// WithSource names the input stream of the run (like the name of a file), which is added as the "source"
+// of every entry logged while processing it (see `WithLogger`).
+func WithSource(name string) ProcessOption {
+	return func(po *processOptions) {
+		po.source = name
+	}
+}
+
+// eventLogger returns the `logging.Logger` scoped to `e` (which is about to be handled by `eventHandler`),
+// derived from `logger` (the `logging.Logger` of the run, which may be nil).
+func eventLogger(logger *logging.Logger, e *event, eventHandler eventhandler.Interface) *logging.Logger {
+	if logger == nil {
+		return nil
+	}
+
+	fields := []logging.Field{logging.String("event_type", string(e.eventType))}
+	if e.line > 0 {
+		fields = append(fields, logging.Int("line", e.line))
+	}
+	if schemaProvider, ok := eventHandler.(eventhandler.SchemaProvider); ok {
+		// The args are known to conform to the schema by now, so they can only fail to parse if the schema
+		// itself is broken -- which doesn't get in the way of logging.
+		if roleArgs, err := schemaProvider.ArgSchema().RoleArgs(e.eventArgs); err == nil {
+			if driverID, ok := roleArgs[eventhandler.DriverRole]; ok {
+				fields = append(fields, logging.String("driver", driverID))
+			}
+		}
+	}
+
+	return logger.With(fields...)
+}
+
+// ErrorFields returns the `logging.Field`s that describe `err` (as emitted by `Process` or `Validate`) --
+// its "error", along with the "class", "event_type" and "line" of a `*ProcessingError`.
+func ErrorFields(err error) []logging.Field {
+	var processingErr *ProcessingError
+	if !errors.As(err, &processingErr) {
+		return []logging.Field{logging.Err(err)}
+	}
+
+	fields := []logging.Field{logging.String("class", processingErr.Class.String())}
+	if processingErr.EventType != "" {
+		fields = append(fields, logging.String("event_type", string(processingErr.EventType)))
+	}
+	if processingErr.Line > 0 {
+		fields = append(fields, logging.Int("line", processingErr.Line))
+	}
+
+	// The message of a `*ProcessingError` repeats its line, which already has a field of its own.
+	if err == error(processingErr) {
+		return append(fields, logging.Err(processingErr.Err))
+	}
+
+	return append(fields, logging.Err(err))
+}
@@ -0,0 +1,139 @@
+package eventprocessor_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/logging"
+)
+
+// loggingTestEventHandler logs every event it handles, to the `logging.Logger` that it's handed.
+type loggingTestEventHandler struct{}
+
+func (lteh *loggingTestEventHandler) Handle(eventhandler.EventArgs, *eventstore.EventStore) error {
+	return errors.New("expected: HandleWithContext to be invoked")
+}
+
+func (lteh *loggingTestEventHandler) HandleWithContext(eventContext eventhandler.EventContext,
+	eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	eventContext.Logger.Info("handled event")
+	return nil
+}
+
+// The driver of its events is a keyword arg, rather than their first arg.
+func (lteh *loggingTestEventHandler) ArgSchema() *eventhandler.Schema {
+	return eventhandler.NewSchema(
+		eventhandler.StringField("vehicle", "vehicle ID"),
+		eventhandler.StringField("driver", "driver ID").Keyword().As(eventhandler.DriverRole),
+	)
+}
+
+func TestWithLogger(t *testing.T) {
+	r := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(r); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+	if err := r.RegisterEventHandler("LoggingTestEvent", &loggingTestEventHandler{}); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	events := strings.Join([]string{
+		"Driver Dan",
+		"Trip Dan 07:15 07:20 50",
+		"Trip Dan 07:15 07:45 17.3",
+		"Bogus Dan",
+		"LoggingTestEvent V1 driver=Amy",
+		"LoggingTestEvent V2",
+	}, "\n")
+
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.LevelDebug, logging.WithClock(func() time.Time {
+		return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	}))
+
+	for range eventprocessor.New(eventprocessor.WithRegistry(r), eventprocessor.WithLogger(logger)).Process(
+		input.StartReading(ioutil.NopCloser(strings.NewReader(events))), eventstore.New(),
+		eventprocessor.WithSource("input.txt")) {
+	}
+
+	// Only the discarded trip is logged by a builtin handler, scoped to its event (and source) -- just like
+	// the events of other handlers are, whichever field their driver is in.
+	expectedLog := `{"time":"2021-06-01T12:00:00Z","level":"info","msg":"discarded trip with implausible speed",` +
+		`"source":"input.txt","event_type":"Trip","line":2,"driver":"Dan","trip_id":"","speed_mph":600}` + "\n" +
+		`{"time":"2021-06-01T12:00:00Z","level":"info","msg":"handled event",` +
+		`"source":"input.txt","event_type":"LoggingTestEvent","line":5,"driver":"Amy"}` + "\n" +
+		`{"time":"2021-06-01T12:00:00Z","level":"info","msg":"handled event",` +
+		`"source":"input.txt","event_type":"LoggingTestEvent","line":6}` + "\n"
+	if buf.String() != expectedLog {
+		t.Fatalf("expected: %q, got: %q", expectedLog, buf.String())
+	}
+}
+
+func TestWithLoggerWithoutSource(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.LevelDebug, logging.WithClock(func() time.Time {
+		return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	}))
+
+	for range eventprocessor.New(eventprocessor.WithLogger(logger)).Process(
+		input.StartReading(ioutil.NopCloser(strings.NewReader("Trip Dan 07:15 07:20 50"))), eventstore.New()) {
+	}
+
+	expectedLog := `{"time":"2021-06-01T12:00:00Z","level":"info","msg":"discarded trip with implausible speed",` +
+		`"event_type":"Trip","line":1,"driver":"Dan","trip_id":"","speed_mph":600}` + "\n"
+	if buf.String() != expectedLog {
+		t.Fatalf("expected: %q, got: %q", expectedLog, buf.String())
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	err := errors.New("boom")
+
+	tests := map[string]struct {
+		err      error
+		expected []logging.Field
+	}{
+		"ProcessingError": {
+			err: &eventprocessor.ProcessingError{
+				Class:     eventprocessor.HandlerError,
+				EventType: "Trip",
+				Line:      3,
+				Err:       err,
+			},
+			expected: []logging.Field{
+				logging.String("class", "handler"),
+				logging.String("event_type", "Trip"),
+				logging.Int("line", 3),
+				logging.String("error", "boom"),
+			},
+		},
+		"ProcessingErrorOfNoEvent": {
+			err: &eventprocessor.ProcessingError{Class: eventprocessor.ParkedTripError, Err: err},
+			expected: []logging.Field{
+				logging.String("class", "parked trip"),
+				logging.String("error", "boom"),
+			},
+		},
+		"OtherError": {
+			err:      err,
+			expected: []logging.Field{logging.String("error", "boom")},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actual := eventprocessor.ErrorFields(test.err); !reflect.DeepEqual(test.expected, actual) {
+				t.Fatalf("expected: %v, got: %v", test.expected, actual)
+			}
+		})
+	}
+}
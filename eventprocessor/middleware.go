@@ -0,0 +1,190 @@
+package eventprocessor
+
+import (
+	"sync"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+)
+
+// Invocation describes a single invocation of `eventhandler.Interface.Handle` as it passes through the
+// `Middleware` chain.
+type Invocation struct {
+	EventType    eventhandler.EventType
+	EventArgs    eventhandler.EventArgs
+	EventContext eventhandler.EventContext
+	EventStore   *eventstore.EventStore
+
+	eventHandler eventhandler.Interface
+}
+
+// HandleFunc performs (or continues) the handling of an `Invocation`.
+type HandleFunc func(*Invocation) error
+
+// Middleware wraps the handling of every event with cross-cutting behavior (timing, logging, etc.), by
+// returning a `HandleFunc` that's expected to call `next` -- or not, to short-circuit the handling of the
+// event.
+//
+// Middlewares only wrap `eventhandler.Interface.Handle` -- events that fail their preconditions (see
+// `eventhandler.PreconditionChecker`), or whose handlers fail to set up, never reach them.
+type Middleware func(next HandleFunc) HandleFunc
+
+// WithMiddleware wraps the handling of every event in `middlewares`.
+//
+// Middlewares are ordered like the layers of an onion, from the outside in -- the first one is outermost,
+// and is thus the first to see each `Invocation` and the last to see its outcome. Multiple uses of
+// `WithMiddleware` append to the same chain, in order.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(ep *EventProcessor) {
+		ep.middlewares = append(ep.middlewares, middlewares...)
+	}
+}
+
+// buildHandleChain wraps the invocation of `eventhandler.Interface.Handle` in `middlewares`.
+func buildHandleChain(middlewares []Middleware) HandleFunc {
+	handle := func(invocation *Invocation) error {
+		if contextAwareHandler, ok := invocation.eventHandler.(eventhandler.ContextAwareHandler); ok {
+			return contextAwareHandler.HandleWithContext(invocation.EventContext, invocation.EventArgs,
+				invocation.EventStore)
+		}
+
+		return invocation.eventHandler.Handle(invocation.EventArgs, invocation.EventStore)
+	}
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handle = middlewares[i](handle)
+	}
+
+	return handle
+}
+
+// Timing is a `Middleware` that measures how long each event takes to handle (including the middlewares
+// nested inside it), and reports that to `observe` along with the outcome of the handling.
+func Timing(observe func(invocation *Invocation, elapsed time.Duration, err error)) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(invocation *Invocation) error {
+			start := time.Now()
+			err := next(invocation)
+			observe(invocation, time.Since(start), err)
+
+			return err
+		}
+	}
+}
+
+// Logging is a `Middleware` that logs every event (and the outcome of handling it) to `logger`, at
+// `logging.LevelInfo` -- or `logging.LevelWarn` for events that failed to be handled.
+func Logging(logger *logging.Logger) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(invocation *Invocation) error {
+			err := next(invocation)
+
+			fields := []logging.Field{
+				logging.String("event_type", string(invocation.EventType)),
+				logging.Any("args", []string(invocation.EventArgs)),
+			}
+			if err != nil {
+				logger.Warn("failed to handle event", append(fields, logging.Err(err))...)
+			} else {
+				logger.Info("handled event", fields...)
+			}
+
+			return err
+		}
+	}
+}
+
+// RateLimit is a `Middleware` that throttles the handling of events to at most `eventsPerSecond` on average,
+// while allowing bursts of up to `burst` events (at least 1) -- events are delayed rather than dropped.
+//
+// The limit is shared by every call to `EventProcessor.Process`, and a non-positive `eventsPerSecond`
+// disables it.
+func RateLimit(eventsPerSecond float64, burst int) Middleware {
+	if eventsPerSecond <= 0 {
+		return func(next HandleFunc) HandleFunc {
+			return next
+		}
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	tb := &tokenBucket{
+		capacity:        float64(burst),
+		tokens:          float64(burst),
+		tokensPerSecond: eventsPerSecond,
+		lastRefill:      time.Now(),
+	}
+
+	return func(next HandleFunc) HandleFunc {
+		return func(invocation *Invocation) error {
+			time.Sleep(tb.take())
+
+			return next(invocation)
+		}
+	}
+}
+
+// tokenBucket implements the token bucket algorithm (https://en.wikipedia.org/wiki/Token_bucket) behind
+// `RateLimit`.
+type tokenBucket struct {
+	mu              sync.Mutex
+	capacity        float64
+	tokens          float64
+	tokensPerSecond float64
+	lastRefill      time.Time
+}
+
+// take takes a token from `tb`, and returns how long the caller must wait before that token is actually
+// available (tokens can be borrowed against the future, which is what makes waiting callers queue up
+// fairly).
+func (tb *tokenBucket) take() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens += now.Sub(tb.lastRefill).Seconds() * tb.tokensPerSecond
+	if tb.tokens > tb.capacity {
+		tb.tokens = tb.capacity
+	}
+	tb.lastRefill = now
+
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-tb.tokens / tb.tokensPerSecond * float64(time.Second))
+}
+
+// Sampled is a `Middleware` that applies `middleware` to only 1 in every `n` events (the first, the
+// (n+1)th, and so on), and lets every other event bypass it -- this is useful for expensive
+// observability middlewares (like `Logging`) on high-volume inputs.
+func Sampled(n int, middleware Middleware) Middleware {
+	if n < 1 {
+		n = 1
+	}
+
+	var mu sync.Mutex
+	numInvocations := 0
+
+	return func(next HandleFunc) HandleFunc {
+		sampledNext := middleware(next)
+
+		return func(invocation *Invocation) error {
+			mu.Lock()
+			sampled := numInvocations%n == 0
+			numInvocations++
+			mu.Unlock()
+
+			if sampled {
+				return sampledNext(invocation)
+			}
+
+			return next(invocation)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package eventprocessor_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/logging"
+)
+
+const middlewareTestEventType eventhandler.EventType = "MiddlewareTestEvent"
+
+// recordingMiddleware logs its own name into `log` before and after invoking the rest of the chain (or
+// instead of it, when `shortCircuit` is set).
+func recordingMiddleware(name string, log *[]string, shortCircuit bool) eventprocessor.Middleware {
+	return func(next eventprocessor.HandleFunc) eventprocessor.HandleFunc {
+		return func(invocation *eventprocessor.Invocation) error {
+			*log = append(*log, fmt.Sprintf("%s:before%v", name, invocation.EventArgs))
+			if shortCircuit {
+				return fmt.Errorf("%s short-circuited", name)
+			}
+
+			err := next(invocation)
+			*log = append(*log, fmt.Sprintf("%s:after%v", name, invocation.EventArgs))
+
+			return err
+		}
+	}
+}
+
+// processMiddlewareTestEvents runs each of `events` (as args to `middlewareTestEventType`) through a
+// new `eventprocessor.EventProcessor` that routes events to `r` and is configured with `opts`, and returns
+// the emitted `error`s.
+func processMiddlewareTestEvents(r *eventhandler.Registry, events []string, opts ...eventprocessor.Option) []error {
+	eventC := make(chan *input.EventEnvelope, len(events))
+	for _, event := range events {
+		eventC <- input.NewEventEnvelopeForBody(
+			input.NewEventFromString(string(middlewareTestEventType) + " " + event))
+	}
+	close(eventC)
+
+	errs := make([]error, 0)
+	for err := range eventprocessor.New(append(opts, eventprocessor.WithRegistry(r))...).Process(eventC, eventstore.New()) {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func TestMiddleware(t *testing.T) {
+	teh := &testEventHandler{}
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler(middlewareTestEventType, teh); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	t.Run("OrderedOutsideIn", func(t *testing.T) {
+		teh.setup(false)
+		defer teh.teardown()
+
+		var log []string
+		errs := processMiddlewareTestEvents(r, []string{"1"},
+			eventprocessor.WithMiddleware(recordingMiddleware("A", &log, false), recordingMiddleware("B", &log, false)),
+			eventprocessor.WithMiddleware(recordingMiddleware("C", &log, false)))
+		if len(errs) != 0 {
+			t.Fatalf("expected: no errors, got: %v", errs)
+		}
+
+		expectedLog := []string{"A:before[1]", "B:before[1]", "C:before[1]", "C:after[1]", "B:after[1]", "A:after[1]"}
+		if !reflect.DeepEqual(log, expectedLog) {
+			t.Fatalf("expected: %v, got: %v", expectedLog, log)
+		}
+
+		if expectedOutput := []eventhandler.EventArgs{{"1"}}; !reflect.DeepEqual(teh.recordedEventArgs, expectedOutput) {
+			t.Fatalf("expected: %v, got: %v", expectedOutput, teh.recordedEventArgs)
+		}
+	})
+
+	t.Run("ShortCircuit", func(t *testing.T) {
+		teh.setup(false)
+		defer teh.teardown()
+
+		var log []string
+		errs := processMiddlewareTestEvents(r, []string{"1"},
+			eventprocessor.WithMiddleware(recordingMiddleware("A", &log, false), recordingMiddleware("B", &log, true),
+				recordingMiddleware("C", &log, false)))
+		if len(errs) != 1 {
+			t.Fatalf("expected: 1 error, got: %v", errs)
+		}
+
+		if errorClass, _ := eventprocessor.ClassOf(errs[0]); errorClass != eventprocessor.HandlerError {
+			t.Fatalf("expected: error of class %v, got: %v", eventprocessor.HandlerError, errorClass)
+		}
+
+		expectedLog := []string{"A:before[1]", "B:before[1]", "A:after[1]"}
+		if !reflect.DeepEqual(log, expectedLog) {
+			t.Fatalf("expected: %v, got: %v", expectedLog, log)
+		}
+
+		if len(teh.recordedEventArgs) != 0 {
+			t.Fatalf("expected: no invocations of Handle, got: %v", teh.recordedEventArgs)
+		}
+	})
+
+	t.Run("Timing", func(t *testing.T) {
+		teh.setup(true)
+		defer teh.teardown()
+
+		var observed []string
+		processMiddlewareTestEvents(r, []string{"1", "2"},
+			eventprocessor.WithMiddleware(eventprocessor.Timing(
+				func(invocation *eventprocessor.Invocation, elapsed time.Duration, err error) {
+					observed = append(observed, fmt.Sprintf("%s%v:%v", invocation.EventType, invocation.EventArgs, err != nil))
+				})))
+
+		expectedObserved := []string{"MiddlewareTestEvent[1]:true", "MiddlewareTestEvent[2]:true"}
+		if !reflect.DeepEqual(observed, expectedObserved) {
+			t.Fatalf("expected: %v, got: %v", expectedObserved, observed)
+		}
+	})
+
+	t.Run("Logging", func(t *testing.T) {
+		teh.setup(false)
+		defer teh.teardown()
+
+		var buf bytes.Buffer
+		logger := logging.New(&buf, logging.LevelInfo, logging.WithClock(func() time.Time {
+			return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+		}))
+		processMiddlewareTestEvents(r, []string{"1"}, eventprocessor.WithMiddleware(eventprocessor.Logging(logger)))
+
+		expectedLog := `{"time":"2021-06-01T12:00:00Z","level":"info","msg":"handled event",` +
+			`"event_type":"MiddlewareTestEvent","args":["1"]}` + "\n"
+		if buf.String() != expectedLog {
+			t.Fatalf("expected: %q, got: %q", expectedLog, buf.String())
+		}
+	})
+
+	t.Run("LoggingFailures", func(t *testing.T) {
+		teh.setup(true)
+		defer teh.teardown()
+
+		// Failures are still logged when nothing quieter than a warning is.
+		var buf bytes.Buffer
+		logger := logging.New(&buf, logging.LevelWarn, logging.WithClock(func() time.Time {
+			return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+		}))
+		processMiddlewareTestEvents(r, []string{"1"}, eventprocessor.WithMiddleware(eventprocessor.Logging(logger)))
+
+		expectedLog := `{"time":"2021-06-01T12:00:00Z","level":"warn","msg":"failed to handle event",` +
+			`"event_type":"MiddlewareTestEvent","args":["1"],"error":"testEventHandler Handle error"}` + "\n"
+		if buf.String() != expectedLog {
+			t.Fatalf("expected: %q, got: %q", expectedLog, buf.String())
+		}
+	})
+
+	t.Run("Sampled", func(t *testing.T) {
+		teh.setup(false)
+		defer teh.teardown()
+
+		var log []string
+		processMiddlewareTestEvents(r, []string{"1", "2", "3", "4", "5"},
+			eventprocessor.WithMiddleware(eventprocessor.Sampled(2, recordingMiddleware("A", &log, false))))
+
+		expectedLog := []string{"A:before[1]", "A:after[1]", "A:before[3]", "A:after[3]", "A:before[5]", "A:after[5]"}
+		if !reflect.DeepEqual(log, expectedLog) {
+			t.Fatalf("expected: %v, got: %v", expectedLog, log)
+		}
+
+		// Events that bypass the sampled middleware are still handled.
+		if len(teh.recordedEventArgs) != 5 {
+			t.Fatalf("expected: 5 invocations of Handle, got: %v", teh.recordedEventArgs)
+		}
+	})
+
+	t.Run("RateLimit", func(t *testing.T) {
+		teh.setup(false)
+		defer teh.teardown()
+
+		// 1 event is let through immediately (as the burst), and the remaining 4 are spaced 10ms apart.
+		start := time.Now()
+		processMiddlewareTestEvents(r, strings.Fields("1 2 3 4 5"),
+			eventprocessor.WithMiddleware(eventprocessor.RateLimit(100, 1)))
+
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Fatalf("expected: at least 40ms to elapse, got: %v", elapsed)
+		}
+
+		if len(teh.recordedEventArgs) != 5 {
+			t.Fatalf("expected: 5 invocations of Handle, got: %v", teh.recordedEventArgs)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package eventprocessor
+
+import "root.challenge/eventhandler"
+
+// Observer is notified of the progress of `EventProcessor.Process` (see `WithObserver`) -- to keep metrics,
+// for example.
+//
+// Its methods are called from the goroutine of every call to `Process`, so an `Observer` shared by concurrent
+// calls must be safe for concurrent use.
+type Observer interface {
+	// EventProcessed is called once `Process` is done with every `input.EventEnvelope` it takes off its
+	// input channel (whatever the outcome, duplicates included), with the `eventhandler.EventType` of its
+	// event -- which is the `eventhandler.EventType` its handler was registered for, and empty if it has no
+	// handler (so that the raw spellings of unknown event types never reach metric labels) or if it held no
+	// event at all (like blank lines and malformed events).
+	EventProcessed(eventType eventhandler.EventType)
+	// Error is called with every `error` that `Process` emits, right before it's emitted.
+	Error(err error)
+}
+
+// WithObserver makes the `EventProcessor` notify `observer` of the progress of every call to `Process` (but
+// not of `Validate`, which processes nothing).
+func WithObserver(observer Observer) Option {
+	return func(ep *EventProcessor) {
+		ep.observer = observer
+	}
+}
@@ -0,0 +1,72 @@
+package eventprocessor_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+// recordingObserver is an `eventprocessor.Observer` that records what it's notified of.
+type recordingObserver struct {
+	eventTypes []eventhandler.EventType
+	classes    []eventprocessor.ErrorClass
+}
+
+// Conforms to `eventprocessor.Observer`.
+func (ro *recordingObserver) EventProcessed(eventType eventhandler.EventType) {
+	ro.eventTypes = append(ro.eventTypes, eventType)
+}
+
+// Conforms to `eventprocessor.Observer`.
+func (ro *recordingObserver) Error(err error) {
+	var processingErr *eventprocessor.ProcessingError
+	if errors.As(err, &processingErr) {
+		ro.classes = append(ro.classes, processingErr.Class)
+	}
+}
+
+func TestWithObserver(t *testing.T) {
+	events := strings.Join([]string{
+		"Driver Dan",
+		"",
+		"Ride Dan 07:15 07:45 17.3",
+		"Bogus Dan",
+		`Trip "Dan`,
+		"Trip Dan 07:15",
+		"@e1 Ride Dan 08:00 08:30 10",
+		"@e1 Ride Dan 08:00 08:30 10",
+		"@e2 Bogus Dan",
+		"@e2 Bogus Dan",
+	}, "\n")
+
+	wd, err := eventprocessor.NewWindowedDeduplicator(10)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	ro := &recordingObserver{}
+	numErrors := 0
+	for range eventprocessor.New(eventprocessor.WithObserver(ro), eventprocessor.WithDeduplicator(wd)).Process(
+		input.StartReading(ioutil.NopCloser(strings.NewReader(events))), eventstore.New()) {
+		numErrors++
+	}
+
+	// Every line is observed (aliases and duplicates by the event type they stand for, and unknown event
+	// types as no event type at all), and so is every error.
+	expectedEventTypes := []eventhandler.EventType{"Driver", "", "Trip", "", "", "Trip", "Trip", "Trip", "", ""}
+	if !reflect.DeepEqual(expectedEventTypes, ro.eventTypes) {
+		t.Fatalf("expected: %v, got: %v", expectedEventTypes, ro.eventTypes)
+	}
+
+	if expected := []eventprocessor.ErrorClass{eventprocessor.UnknownEventTypeError, eventprocessor.InputError,
+		eventprocessor.PreconditionError, eventprocessor.UnknownEventTypeError}; !reflect.DeepEqual(expected, ro.classes) || numErrors != len(expected) {
+		t.Fatalf("expected: %v, got: %v (of %d errors)", expected, ro.classes, numErrors)
+	}
+}
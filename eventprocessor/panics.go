@@ -0,0 +1,165 @@
+package eventprocessor
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"root.challenge/eventhandler"
+)
+
+// PanicError is the `error` that a panic in a handler (or in any `Middleware` wrapping it) is converted
+// into, so that a single misbehaving handler can't take down the processing of every other event.
+type PanicError struct {
+	EventType eventhandler.EventType
+	// Value is the value passed to `panic`().
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked, as of the panic.
+	Stack []byte
+}
+
+// Conforms to `error`.
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("handler for eventType %s panicked: %v", pe.EventType, pe.Value)
+}
+
+// recoverPanics invokes `f`, converting any panic in it into a `*PanicError`.
+func recoverPanics(eventType eventhandler.EventType, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				EventType: eventType,
+				Value:     r,
+				Stack:     debug.Stack(),
+			}
+		}
+	}()
+
+	return f()
+}
+
+// WithCircuitBreaker disables the handling of an `EventType` once its handler has panicked `threshold`
+// times in a row -- events of that `EventType` are then rejected (with a `CircuitOpenError`-classified
+// `error`) without being handled, while every other `EventType` is unaffected.
+//
+// After `cooldown`, a single event of a disabled `EventType` is let through on a trial basis: if it's
+// handled without panicking, the `EventType` is re-enabled, and it's disabled for another `cooldown`
+// otherwise. A `cooldown` of zero (or less) means there's no cooldown, so the `EventType` is disabled for good.
+//
+// A `threshold` of zero (or less) disables the circuit breaker itself, rather than the first `EventType` to
+// panic -- just like not passing this `Option` at all.
+//
+// Events that never reach their handler (like malformed ones, or ones whose handler failed to set up) don't
+// count either way -- they neither break a streak of panics, nor use up the trial of a disabled `EventType`.
+//
+// Since the handlers themselves are shared by every call to `Process`, so is the state of the circuit
+// breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(ep *EventProcessor) {
+		if threshold <= 0 {
+			ep.circuitBreaker = nil
+			return
+		}
+
+		if cooldown < 0 {
+			cooldown = 0
+		}
+
+		ep.circuitBreaker = &circuitBreaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+			circuits:  make(map[eventhandler.EventType]*circuit),
+		}
+	}
+}
+
+// circuitBreaker implements the circuit breaker design pattern
+// (https://en.wikipedia.org/wiki/Circuit_breaker_design_pattern) behind `WithCircuitBreaker`.
+//
+// A nil `*circuitBreaker` allows everything, which is the default.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[eventhandler.EventType]*circuit
+}
+
+// circuit is the state of a single `EventType` in a `circuitBreaker`.
+type circuit struct {
+	consecutivePanics int
+	open              bool
+	openedAt          time.Time
+	// trialInFlight is set while the single trial event allowed through an open circuit is being handled.
+	trialInFlight bool
+}
+
+// allow reports whether an event of `eventType` may be handled -- every allowed event must be followed by
+// a call to `record` with the outcome of invoking its handler, or by a call to `release` if the handler ends
+// up not being invoked (say, because the event is malformed).
+func (cb *circuitBreaker) allow(eventType eventhandler.EventType) bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[eventType]
+	if !ok || !c.open {
+		return true
+	}
+
+	if cb.cooldown == 0 || c.trialInFlight || time.Since(c.openedAt) < cb.cooldown {
+		return false
+	}
+
+	c.trialInFlight = true
+
+	return true
+}
+
+// record updates the state of `eventType` with the outcome of invoking the handler of an event allowed by
+// `allow`.
+func (cb *circuitBreaker) record(eventType eventhandler.EventType, panicked bool) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[eventType]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[eventType] = c
+	}
+
+	if !panicked {
+		*c = circuit{}
+		return
+	}
+
+	c.consecutivePanics++
+	c.trialInFlight = false
+	if c.open || c.consecutivePanics >= cb.threshold {
+		c.open = true
+		c.openedAt = time.Now()
+	}
+}
+
+// release gives back what `allow` handed out for an event of `eventType` whose handler ended up not being
+// invoked, leaving the state of `eventType` otherwise untouched -- such events say nothing about whether the
+// handler still panics.
+func (cb *circuitBreaker) release(eventType eventhandler.EventType) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if c, ok := cb.circuits[eventType]; ok {
+		c.trialInFlight = false
+	}
+}
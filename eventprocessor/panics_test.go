@@ -0,0 +1,245 @@
+package eventprocessor_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+// `panickingTestEventHandler` is an implementation of `eventhandler.Interface` (and all of its optional
+// extensions) that panics on demand.
+type panickingTestEventHandler struct {
+	setupPanics, teardownPanics bool
+	handledArgs                 []string
+}
+
+func (pteh *panickingTestEventHandler) Setup(eventStore *eventstore.EventStore) error {
+	if pteh.setupPanics {
+		panic("Setup panic")
+	}
+
+	return nil
+}
+
+func (pteh *panickingTestEventHandler) Teardown(eventStore *eventstore.EventStore) error {
+	if pteh.teardownPanics {
+		panic("Teardown panic")
+	}
+
+	return nil
+}
+
+func (pteh *panickingTestEventHandler) CheckPreconditions(eventArgs eventhandler.EventArgs) error {
+	switch eventArgs[0] {
+	case "precondition-panic":
+		panic("CheckPreconditions panic")
+	case "malformed":
+		return errors.New("malformed args")
+	}
+
+	return nil
+}
+
+func (pteh *panickingTestEventHandler) Handle(eventArgs eventhandler.EventArgs, eventStore *eventstore.EventStore) error {
+	if eventArgs[0] == "panic" {
+		panic("Handle panic")
+	}
+
+	pteh.handledArgs = append(pteh.handledArgs, eventArgs[0])
+
+	return nil
+}
+
+func TestPanicIsolation(t *testing.T) {
+	tests := map[string]struct {
+		input          []string
+		setupPanics    bool
+		teardownPanics bool
+		opts           []eventprocessor.Option
+		// Sleep for this long before the event at the same index is processed (for testing cooldowns).
+		sleepBefore          map[int]time.Duration
+		expectedErrorClasses []eventprocessor.ErrorClass
+		expectedHandledArgs  []string
+	}{
+		"PanicInHandle": {
+			input:                []string{"1", "panic", "2"},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.HandlerPanic},
+			expectedHandledArgs:  []string{"1", "2"},
+		},
+		"PanicInPreconditions": {
+			input:                []string{"precondition-panic", "1"},
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.HandlerPanic},
+			expectedHandledArgs:  []string{"1"},
+		},
+		"PanicInSetup": {
+			input:       []string{"1", "2"},
+			setupPanics: true,
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.SetupError, eventprocessor.SetupError,
+			},
+			expectedHandledArgs: []string{},
+		},
+		"PanicInTeardown": {
+			input:                []string{"1"},
+			teardownPanics:       true,
+			expectedErrorClasses: []eventprocessor.ErrorClass{eventprocessor.TeardownError},
+			expectedHandledArgs:  []string{"1"},
+		},
+		"PanicsWithoutCircuitBreaker": {
+			input: []string{"panic", "panic", "panic", "1"},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic, eventprocessor.HandlerPanic,
+			},
+			expectedHandledArgs: []string{"1"},
+		},
+		"CircuitBreakerWithoutThreshold": {
+			input: []string{"panic", "panic", "1"},
+			opts:  []eventprocessor.Option{eventprocessor.WithCircuitBreaker(0, 20*time.Millisecond)},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic,
+			},
+			expectedHandledArgs: []string{"1"},
+		},
+		"CircuitBreakerWithNegativeThreshold": {
+			input: []string{"panic", "panic", "1"},
+			opts:  []eventprocessor.Option{eventprocessor.WithCircuitBreaker(-1, 0)},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic,
+			},
+			expectedHandledArgs: []string{"1"},
+		},
+		"CircuitBreakerOpens": {
+			input: []string{"panic", "panic", "1", "2"},
+			opts:  []eventprocessor.Option{eventprocessor.WithCircuitBreaker(2, 0)},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic,
+				eventprocessor.CircuitOpenError, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{},
+		},
+		"CircuitBreakerCountsConsecutivePanics": {
+			input: []string{"panic", "1", "panic", "2"},
+			opts:  []eventprocessor.Option{eventprocessor.WithCircuitBreaker(2, 0)},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic,
+			},
+			expectedHandledArgs: []string{"1", "2"},
+		},
+		"CircuitBreakerIgnoresMalformedEvents": {
+			input: []string{"panic", "malformed", "panic", "malformed", "1"},
+			opts:  []eventprocessor.Option{eventprocessor.WithCircuitBreaker(2, 0)},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.PreconditionError, eventprocessor.HandlerPanic,
+				eventprocessor.CircuitOpenError, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{},
+		},
+		"CircuitBreakerStaysOpenAfterMalformedTrial": {
+			input:       []string{"panic", "panic", "malformed", "panic", "1"},
+			opts:        []eventprocessor.Option{eventprocessor.WithCircuitBreaker(2, 20*time.Millisecond)},
+			sleepBefore: map[int]time.Duration{2: 30 * time.Millisecond},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.HandlerPanic, eventprocessor.PreconditionError,
+				eventprocessor.HandlerPanic, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{},
+		},
+		"CircuitBreakerClosesAfterSuccessfulTrial": {
+			input:       []string{"panic", "1", "2", "3"},
+			opts:        []eventprocessor.Option{eventprocessor.WithCircuitBreaker(1, 20*time.Millisecond)},
+			sleepBefore: map[int]time.Duration{2: 30 * time.Millisecond},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{"2", "3"},
+		},
+		"CircuitBreakerWithNegativeCooldownStaysOpen": {
+			input:       []string{"panic", "1", "2"},
+			opts:        []eventprocessor.Option{eventprocessor.WithCircuitBreaker(1, -20*time.Millisecond)},
+			sleepBefore: map[int]time.Duration{2: 30 * time.Millisecond},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.CircuitOpenError, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{},
+		},
+		"CircuitBreakerReopensAfterFailedTrial": {
+			input:       []string{"panic", "1", "panic", "2"},
+			opts:        []eventprocessor.Option{eventprocessor.WithCircuitBreaker(1, 20*time.Millisecond)},
+			sleepBefore: map[int]time.Duration{2: 30 * time.Millisecond},
+			expectedErrorClasses: []eventprocessor.ErrorClass{
+				eventprocessor.HandlerPanic, eventprocessor.CircuitOpenError,
+				eventprocessor.HandlerPanic, eventprocessor.CircuitOpenError,
+			},
+			expectedHandledArgs: []string{},
+		},
+	}
+
+	// Register `panickingTestEventHandler` with an `eventhandler.Registry` private to this test (so as to leave
+	// `eventhandler.GlobalRegistry`() untouched), one time for the entire test case, akin to how packages are
+	// initialized exactly once at load time.
+	pteh := &panickingTestEventHandler{}
+	r := eventhandler.NewRegistry()
+	if err := r.RegisterEventHandler("PanickingTestEvent", pteh); err != nil {
+		t.Fatalf("expected: no error, got: %v", err)
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			*pteh = panickingTestEventHandler{
+				setupPanics:    tc.setupPanics,
+				teardownPanics: tc.teardownPanics,
+				handledArgs:    make([]string, 0),
+			}
+
+			// Feed events one at a time (instead of up-front, as other tests do) to be able to sleep between
+			// them.
+			eventC := make(chan *input.EventEnvelope)
+			go func() {
+				defer close(eventC)
+				for i, event := range tc.input {
+					time.Sleep(tc.sleepBefore[i])
+					eventC <- input.NewEventEnvelopeForBody(input.NewEventFromString("PanickingTestEvent " + event))
+				}
+			}()
+
+			actualErrorClasses := make([]eventprocessor.ErrorClass, 0)
+			ep := eventprocessor.New(append(tc.opts, eventprocessor.WithRegistry(r))...)
+			for err := range ep.Process(eventC, eventstore.New()) {
+				errorClass, _ := eventprocessor.ClassOf(err)
+				actualErrorClasses = append(actualErrorClasses, errorClass)
+
+				// Every setup and teardown failure here stems from a panic too.
+				var pe *eventprocessor.PanicError
+				switch errorClass {
+				case eventprocessor.HandlerPanic, eventprocessor.SetupError, eventprocessor.TeardownError:
+					if !errors.As(err, &pe) {
+						t.Fatalf("expected: PanicError, got: %v", err)
+					}
+
+					// The stack trace should lead back to the panic.
+					if !bytes.Contains(pe.Stack, []byte("panickingTestEventHandler")) {
+						t.Fatalf("expected: stack trace through panickingTestEventHandler, got: %s", pe.Stack)
+					}
+				}
+			}
+
+			if len(tc.expectedErrorClasses) == 0 {
+				tc.expectedErrorClasses = []eventprocessor.ErrorClass{}
+			}
+			if !reflect.DeepEqual(actualErrorClasses, tc.expectedErrorClasses) {
+				t.Fatalf("expected: errors of classes %v, got: %v", tc.expectedErrorClasses, actualErrorClasses)
+			}
+
+			if !reflect.DeepEqual(pteh.handledArgs, tc.expectedHandledArgs) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedHandledArgs, pteh.handledArgs)
+			}
+		})
+	}
+}
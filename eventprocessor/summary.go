@@ -0,0 +1,70 @@
+package eventprocessor
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrorSummary tallies the `error`s emitted by `Process` (or `Validate`) by `ErrorClass`, along with the
+// lines of the input that they arose on.
+//
+// It's not safe for concurrent use.
+type ErrorSummary struct {
+	total  int
+	counts map[ErrorClass]int
+	lines  map[ErrorClass][]int
+}
+
+// NewErrorSummary creates a new, empty `ErrorSummary`.
+func NewErrorSummary() *ErrorSummary {
+	return &ErrorSummary{
+		counts: make(map[ErrorClass]int),
+		lines:  make(map[ErrorClass][]int),
+	}
+}
+
+// Add tallies `err` -- `error`s that weren't emitted by `Process` (see `ClassOf`) only count towards
+// `Total`.
+func (es *ErrorSummary) Add(err error) {
+	es.total++
+
+	var pe *ProcessingError
+	if !errors.As(err, &pe) {
+		return
+	}
+
+	es.counts[pe.Class]++
+	if pe.Line > 0 {
+		es.lines[pe.Class] = append(es.lines[pe.Class], pe.Line)
+	}
+}
+
+// Total returns the number of `error`s tallied.
+func (es *ErrorSummary) Total() int {
+	return es.total
+}
+
+// Classes returns every `ErrorClass` with at least one `error` tallied, in the order they're declared in.
+func (es *ErrorSummary) Classes() []ErrorClass {
+	classes := make([]ErrorClass, 0, len(es.counts))
+	for class := range es.counts {
+		classes = append(classes, class)
+	}
+
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i] < classes[j]
+	})
+
+	return classes
+}
+
+// Count returns the number of `error`s of `class` tallied.
+func (es *ErrorSummary) Count(class ErrorClass) int {
+	return es.counts[class]
+}
+
+// Lines returns the lines of the input that `error`s of `class` arose on, in the order they were tallied
+// (which, for a single input, is ascending) -- `error`s without a line aren't included.
+func (es *ErrorSummary) Lines(class ErrorClass) []int {
+	return append([]int{}, es.lines[class]...)
+}
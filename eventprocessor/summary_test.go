@@ -0,0 +1,50 @@
+package eventprocessor_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"root.challenge/eventprocessor"
+)
+
+func TestErrorSummary(t *testing.T) {
+	summary := eventprocessor.NewErrorSummary()
+	for _, err := range []error{
+		&eventprocessor.ProcessingError{Class: eventprocessor.PreconditionError, Line: 7, Err: fmt.Errorf("A")},
+		&eventprocessor.ProcessingError{Class: eventprocessor.InputError, Line: 2, Err: fmt.Errorf("B")},
+		&eventprocessor.ProcessingError{Class: eventprocessor.PreconditionError, Line: 9, Err: fmt.Errorf("C")},
+		// Errors without lines are counted, but have no line to list.
+		&eventprocessor.ProcessingError{Class: eventprocessor.TeardownError, Err: fmt.Errorf("D")},
+		// Errors that aren't `*eventprocessor.ProcessingError`s only count towards the total.
+		fmt.Errorf("E"),
+	} {
+		summary.Add(err)
+	}
+
+	if summary.Total() != 5 {
+		t.Fatalf("expected: 5 errors, got: %d", summary.Total())
+	}
+
+	expectedClasses := []eventprocessor.ErrorClass{
+		eventprocessor.InputError, eventprocessor.PreconditionError, eventprocessor.TeardownError,
+	}
+	if actualClasses := summary.Classes(); !reflect.DeepEqual(actualClasses, expectedClasses) {
+		t.Fatalf("expected: %v, got: %v", expectedClasses, actualClasses)
+	}
+
+	if count := summary.Count(eventprocessor.PreconditionError); count != 2 {
+		t.Fatalf("expected: 2 precondition errors, got: %d", count)
+	}
+	if lines := summary.Lines(eventprocessor.PreconditionError); !reflect.DeepEqual(lines, []int{7, 9}) {
+		t.Fatalf("expected: precondition errors on lines [7 9], got: %v", lines)
+	}
+	if lines := summary.Lines(eventprocessor.TeardownError); len(lines) != 0 {
+		t.Fatalf("expected: no lines for teardown errors, got: %v", lines)
+	}
+
+	expectedErrStr := "line 7: A"
+	if errStr := (&eventprocessor.ProcessingError{Line: 7, Err: fmt.Errorf("A")}).Error(); errStr != expectedErrStr {
+		t.Fatalf("expected: %q, got: %q", expectedErrStr, errStr)
+	}
+}
@@ -1,7 +1,12 @@
 package eventstore
 
 import (
+	"errors"
+	"fmt"
 	"time"
+
+	"root.challenge/logging"
+	"root.challenge/mathutils"
 )
 
 // driverSummary represents the information about a driver that is pertinent to retain in `EventStore`.
@@ -11,25 +16,86 @@ import (
 // Note that this is a purely internal data structure, and is never exposed through any of the external
 // contracts of this package -- this provides the flexibility to change it at will without any impact to
 // clients of the package. Think of it as a database's storage format on disk.
+//
+// All distances are stored in the canonical unit of miles (regardless of the `mathutils.DistanceUnit` they
+// were provided in), leaving conversions for presentation to clients of `VisitorInterface`.
 type driverSummary struct {
+	// displayName is empty for drivers that have so far only been (lazily) registered by a trip, in which
+	// case their ID doubles up as their display name.
+	displayName string
+
 	totalDurationDriven time.Duration
-	// totalMilesDriven is a `float64` to avoid overflow (`TripInfo.TripMileage` is a `float32`, and all
-	// those `float32`s are aggregated into this field).
-	totalMilesDriven float64
+	// totalMilesDriven is a `mathutils.FixedDecimal` (rather than a floating-point number) so that aggregating
+	// any number of trips, in any order, is exact -- floating-point rounding drift would otherwise eventually
+	// change the (rounded) totals that are reported.
+	totalMilesDriven mathutils.FixedDecimal
+
+	// inactive is the inverse of what's exposed to clients so that the zero value represents the common case.
+	inactive bool
+	// previousNames lists, in chronological order, every display name this driver was previously known by
+	// (either via a rename, or via a merge of a duplicate driver record into this one).
+	previousNames []string
+}
+
+// vehicleSummary represents the information about a vehicle that is pertinent to retain in `EventStore`.
+//
+// See the Maintainer Notes on `driverSummary` -- they apply equally here.
+type vehicleSummary struct {
+	class    string
+	fuelType string
+
+	totalDurationDriven time.Duration
+	totalMilesDriven    mathutils.FixedDecimal
 }
 
 // EventStore is a repository of relevant information about all the events that have flowed into the system.
 //
-// The only way for clients to access any of the information stored within is via `VisitorInterface`.
+// The only way for clients to access any of the information stored within is via `VisitorInterface` (and
+// `VehicleVisitorInterface`).
 type EventStore struct {
-	driverSummaries map[string]*driverSummary
+	driverSummaries  map[string]*driverSummary
+	vehicleSummaries map[string]*vehicleSummary
+	// recordedTrips is keyed by `TripInfo.TripID`, and only tracks trips that have one.
+	recordedTrips map[string]*tripContribution
+	// discardedTrips maps the `TripInfo.TripID` of every trip discarded via `DiscardTrip` (or
+	// `DiscardTripAmendment`) to its `TripInfo.DriverID`.
+	discardedTrips map[string]string
+	// mergedDrivers maps the ID of every driver merged away via `MergeDrivers` to the ID of the (registered)
+	// driver they were merged into, so that events referencing the former keep applying to the latter.
+	mergedDrivers map[string]string
+
+	driverRegistrationPolicy driverRegistrationPolicy
+	// parkedTrips is only used with `WithParkedTrips`, and is keyed by `TripInfo.DriverID`.
+	parkedTrips map[string][]*parkedTrip
+	// maxParkedEvents is the number of events that trips stay parked for, at most.
+	maxParkedEvents int
+	numTripsParked  uint64
+	// numEventsExpired counts the calls to `ExpireParkedTrips`, which is how long trips have been parked for
+	// is measured.
+	numEventsExpired int
+	// logger is nil unless set by `WithLogger`.
+	logger *logging.Logger
 }
 
-// New creates a new `EventStore`.
-func New() *EventStore {
-	return &EventStore{
-		driverSummaries: make(map[string]*driverSummary),
+// Option customizes the behavior of an `EventStore`.
+type Option func(*EventStore)
+
+// New creates a new `EventStore`, customized by `opts`.
+func New(opts ...Option) *EventStore {
+	es := &EventStore{
+		driverSummaries:  make(map[string]*driverSummary),
+		vehicleSummaries: make(map[string]*vehicleSummary),
+		recordedTrips:    make(map[string]*tripContribution),
+		discardedTrips:   make(map[string]string),
+		mergedDrivers:    make(map[string]string),
+		parkedTrips:      make(map[string][]*parkedTrip),
 	}
+
+	for _, opt := range opts {
+		opt(es)
+	}
+
+	return es
 }
 
 // ============================================== Maintainer Notes ==============================================
@@ -43,58 +109,353 @@ func New() *EventStore {
 
 // DriverInfo encapsulates all the information about a driver that can be provided by clients of `EventStore`.
 type DriverInfo struct {
-	FirstName string
+	// ID uniquely (and permanently) identifies a driver.
+	//
+	// Drivers registered via the legacy "Driver <first name>" event have their first name as their ID.
+	ID string
+	// DisplayName defaults to `ID` when left empty.
+	DisplayName string
+}
+
+// DriverRenameInfo encapsulates all the information about renaming a driver that can be provided by clients
+// of `EventStore`.
+type DriverRenameInfo struct {
+	DriverID       string
+	NewDisplayName string
+}
+
+// DriverMergeInfo encapsulates all the information about merging two drivers that can be provided by clients
+// of `EventStore`.
+type DriverMergeInfo struct {
+	// DriverID identifies the (typically duplicate) driver that ceases to exist after the merge.
+	DriverID string
+	// IntoDriverID identifies the driver that survives the merge, absorbing everything recorded against the
+	// driver identified by `DriverID`.
+	IntoDriverID string
+}
+
+// VehicleInfo encapsulates all the information about a vehicle that can be provided by clients of `EventStore`.
+type VehicleInfo struct {
+	ID       string
+	Class    string
+	FuelType string
 }
 
 // TripInfo encapsulates all the information about a trip that can be provided by clients of `EventStore`.
 type TripInfo struct {
-	DriverFirstName string
-	TripDuration    time.Duration
-	TripMileage     float32
+	DriverID     string
+	TripDuration time.Duration
+	// TripMileage is expressed in `TripDistanceUnit`.
+	TripMileage float32
+	// TripDistanceUnit defaults to `mathutils.Miles` when left unset.
+	TripDistanceUnit mathutils.DistanceUnit
+	// VehicleID is optional, and is left empty for trips that aren't attributed to any particular vehicle.
+	VehicleID string
+	// TripID is optional, but trips can only be referenced later on (for example, by `CancelTrip`) if they
+	// have one.
+	TripID string
+}
+
+// TripCancelInfo encapsulates all the information about cancelling a trip that can be provided by clients
+// of `EventStore`.
+type TripCancelInfo struct {
+	TripID string
 }
 
 // RegisterDriver stores information about a new driver in the system.
 //
 // It's perfectly fine if multiple calls to this method are made with the same `DriverInfo` -- the
-// implementation is guaranteed to be idempotent.
+// implementation is guaranteed to be idempotent. Subsequent calls for the same `DriverInfo.ID` with a
+// different `DriverInfo.DisplayName` are ignored (use `RenameDriver` instead), except when the driver
+// was lazily registered by `RecordTrip` (and thus had no display name of their own so far). Registering a
+// driver that was merged away (see `MergeDrivers`) is a no-op too, since they're the driver they were merged
+// into.
 func (es *EventStore) RegisterDriver(driverInfo *DriverInfo) error {
-	if _, exists := es.driverSummaries[driverInfo.FirstName]; !exists {
-		es.registerDriver(driverInfo)
+	if driverInfo.ID == "" {
+		return fmt.Errorf("can't register a driver without an ID (display name: '%s')", driverInfo.DisplayName)
 	}
 
+	if _, merged := es.mergedDrivers[driverInfo.ID]; merged {
+		return nil
+	}
+
+	if driverSummary, exists := es.driverSummaries[driverInfo.ID]; exists {
+		if driverSummary.displayName == "" {
+			driverSummary.displayName = driverInfo.DisplayName
+		}
+		return nil
+	}
+
+	driverSummary := es.registerDriver(driverInfo)
+	es.unparkTrips(driverInfo.ID, driverSummary)
+
 	return nil
 }
 
 // registerDriver contains the core of what it takes to register a new driver -- all the public
 // methods that invoke it should wholly delegate all the relevant functionality to this method.
 func (es *EventStore) registerDriver(driverInfo *DriverInfo) *driverSummary {
-	newDriverSummary := &driverSummary{}
+	newDriverSummary := &driverSummary{
+		displayName: driverInfo.DisplayName,
+	}
 
-	es.driverSummaries[driverInfo.FirstName] = newDriverSummary
+	es.driverSummaries[driverInfo.ID] = newDriverSummary
 
 	return newDriverSummary
 }
 
+// ErrUnknownDriver is returned (wrapped) by operations that reference a driver that isn't registered.
+var ErrUnknownDriver = errors.New("unknown driver")
+
+// DeactivateDriver marks a registered driver (identified by `DriverInfo.ID`) as inactive (for example,
+// when they leave).
+//
+// Everything recorded against the driver so far is retained (as is the ability to record further trips
+// against them), and deactivating an already-inactive driver is a no-op.
+func (es *EventStore) DeactivateDriver(driverInfo *DriverInfo) error {
+	driverSummary, err := es.lookupDriver(driverInfo.ID)
+	if err != nil {
+		return err
+	}
+
+	driverSummary.inactive = true
+
+	return nil
+}
+
+// ReactivateDriver undoes `DeactivateDriver` (for example, when a driver comes back), and is a no-op for
+// drivers that are already active.
+func (es *EventStore) ReactivateDriver(driverInfo *DriverInfo) error {
+	driverSummary, err := es.lookupDriver(driverInfo.ID)
+	if err != nil {
+		return err
+	}
+
+	driverSummary.inactive = false
+
+	return nil
+}
+
+// RenameDriver changes the display name of a registered driver, retaining everything recorded against them
+// so far (along with a history of their previous display names).
+//
+// A driver's ID never changes, so trips continue to be recorded against them exactly as before the rename.
+func (es *EventStore) RenameDriver(driverRenameInfo *DriverRenameInfo) error {
+	driverSummary, err := es.lookupDriver(driverRenameInfo.DriverID)
+	if err != nil {
+		return err
+	}
+
+	if driverRenameInfo.NewDisplayName == "" {
+		return fmt.Errorf("can't rename driver '%s' to an empty display name", driverRenameInfo.DriverID)
+	}
+
+	currentDisplayName := driverSummary.displayNameOr(driverRenameInfo.DriverID)
+	if driverRenameInfo.NewDisplayName == currentDisplayName {
+		return nil
+	}
+
+	driverSummary.previousNames = append(driverSummary.previousNames, currentDisplayName)
+	driverSummary.displayName = driverRenameInfo.NewDisplayName
+
+	return nil
+}
+
+// MergeDrivers folds everything recorded against one registered driver into another registered driver, and
+// then removes the former (for example, when the same driver was registered twice under different IDs).
+//
+// The surviving driver retains their own ID, display name and active/inactive status, and their history of
+// previous display names is extended with the display name (and the history of previous display names) of
+// the driver merged into them.
+//
+// The ID of the driver merged away remains an alias of the surviving driver, so that every later reference to
+// it (like a trip recorded with `RecordTrip`) applies to the surviving driver instead.
+func (es *EventStore) MergeDrivers(driverMergeInfo *DriverMergeInfo) error {
+	if es.resolveDriverID(driverMergeInfo.DriverID) == es.resolveDriverID(driverMergeInfo.IntoDriverID) {
+		return fmt.Errorf("can't merge driver '%s' into themselves", driverMergeInfo.DriverID)
+	}
+
+	mergedDriverSummary, err := es.lookupDriver(driverMergeInfo.DriverID)
+	if err != nil {
+		return err
+	}
+
+	survivingDriverSummary, err := es.lookupDriver(driverMergeInfo.IntoDriverID)
+	if err != nil {
+		return err
+	}
+
+	mergedDriverID := es.resolveDriverID(driverMergeInfo.DriverID)
+	survivingDriverID := es.resolveDriverID(driverMergeInfo.IntoDriverID)
+
+	survivingDriverSummary.totalDurationDriven += mergedDriverSummary.totalDurationDriven
+	survivingDriverSummary.totalMilesDriven += mergedDriverSummary.totalMilesDriven
+	survivingDriverSummary.previousNames = append(survivingDriverSummary.previousNames,
+		mergedDriverSummary.previousNames...)
+	survivingDriverSummary.previousNames = append(survivingDriverSummary.previousNames,
+		mergedDriverSummary.displayNameOr(mergedDriverID))
+
+	delete(es.driverSummaries, mergedDriverID)
+	es.reattributeTrips(mergedDriverID, survivingDriverID)
+
+	// Aliases are kept resolved to a registered driver (rather than chained), so they never need more than
+	// a single lookup.
+	for aliasID, driverID := range es.mergedDrivers {
+		if driverID == mergedDriverID {
+			es.mergedDrivers[aliasID] = survivingDriverID
+		}
+	}
+	es.mergedDrivers[mergedDriverID] = survivingDriverID
+
+	return nil
+}
+
+// resolveDriverID returns the ID of the driver that `driverID` was merged into (see `MergeDrivers`), or
+// `driverID` itself if it wasn't merged away.
+func (es *EventStore) resolveDriverID(driverID string) string {
+	if survivingDriverID, merged := es.mergedDrivers[driverID]; merged {
+		return survivingDriverID
+	}
+
+	return driverID
+}
+
+// resolveTripDriver returns `tripInfo`, or a copy of it that references the driver that its
+// `TripInfo.DriverID` was merged into (see `resolveDriverID`).
+func (es *EventStore) resolveTripDriver(tripInfo *TripInfo) *TripInfo {
+	driverID := es.resolveDriverID(tripInfo.DriverID)
+	if driverID == tripInfo.DriverID {
+		return tripInfo
+	}
+
+	resolvedTripInfo := *tripInfo
+	resolvedTripInfo.DriverID = driverID
+	return &resolvedTripInfo
+}
+
+// lookupDriver returns the `driverSummary` of a registered driver (resolving drivers that were merged away,
+// see `MergeDrivers`), or an `error` wrapping `ErrUnknownDriver`.
+func (es *EventStore) lookupDriver(driverID string) (*driverSummary, error) {
+	driverSummary, exists := es.driverSummaries[es.resolveDriverID(driverID)]
+	if !exists {
+		return nil, fmt.Errorf("driver '%s': %w", driverID, ErrUnknownDriver)
+	}
+
+	return driverSummary, nil
+}
+
+// displayNameOr returns the display name of the driver, falling back to `driverID` if they don't have one.
+func (ds *driverSummary) displayNameOr(driverID string) string {
+	if ds.displayName == "" {
+		return driverID
+	}
+
+	return ds.displayName
+}
+
 // RecordTrip stores information about a new trip in the system.
 //
-// While it's currently perfectly fine for `TripInfo.DriverFirstName` to reference a driver that
+// While it's currently perfectly fine for `TripInfo.DriverID` to reference a driver that
 // hasn't previously been registered via a call to `RegisterDriver`, that's not behavior that clients
 // should come to depend upon -- this method currently takes care of performing the registration
 // lazily because all the information that's needed for that operation is present in `TripInfo`, but
 // that will almost certainly change when `DriverInfo` expands to become richer, at which time,
 // this lazy-registration behavior of this method will cease to work, and will result in an error
 // instead.
+//
+// Clients can opt in to that stricter behavior ahead of time via `WithStrictDriverRegistration` (or
+// `WithParkedTrips`).
+//
+// Trips of drivers that were merged away (see `MergeDrivers`) are recorded against the surviving driver.
 func (es *EventStore) RecordTrip(tripInfo *TripInfo) error {
-	// Register the Driver lazily, if needed.
-	driverSummary := es.driverSummaries[tripInfo.DriverFirstName]
+	tripInfo = es.resolveTripDriver(tripInfo)
+	if tripInfo.TripID != "" && es.isKnownTrip(tripInfo.TripID) {
+		return fmt.Errorf("trip '%s' is already recorded", tripInfo.TripID)
+	}
+
+	tc, err := newTripContribution(tripInfo)
+	if err != nil {
+		return err
+	}
+
+	driverSummary := es.driverSummaries[tripInfo.DriverID]
 	if driverSummary == nil {
+		switch es.driverRegistrationPolicy {
+		case rejectUnregisteredDrivers:
+			return &UnregisteredDriverError{
+				DriverID: tripInfo.DriverID,
+			}
+		case parkUnregisteredDrivers:
+			es.parkTrip(tripInfo, tc)
+			return nil
+		}
+
+		// Register the Driver lazily.
 		driverSummary = es.registerDriver(&DriverInfo{
-			FirstName: tripInfo.DriverFirstName,
+			ID: tripInfo.DriverID,
 		})
+		es.logger.Debug("registered driver lazily", logging.String("driver", tripInfo.DriverID))
+	}
+
+	es.recordTrip(driverSummary, tripInfo.TripID, tc)
+
+	return nil
+}
+
+// recordTrip contains the core of what it takes to record a trip (with the given ID, if any) against a
+// registered driver.
+func (es *EventStore) recordTrip(driverSummary *driverSummary, tripID string, tc *tripContribution) {
+	es.applyTripContribution(driverSummary, tc)
+
+	if tripID != "" {
+		es.recordedTrips[tripID] = tc
+	}
+}
+
+// RegisterVehicle stores information about a vehicle in the system.
+//
+// Multiple calls to this method for the same `VehicleInfo.ID` are perfectly fine -- the attributes of the
+// vehicle are updated to those provided in the latest call, while everything recorded against the vehicle
+// so far is retained.
+func (es *EventStore) RegisterVehicle(vehicleInfo *VehicleInfo) error {
+	if vehicleSummary, exists := es.vehicleSummaries[vehicleInfo.ID]; exists {
+		vehicleSummary.class = vehicleInfo.Class
+		vehicleSummary.fuelType = vehicleInfo.FuelType
+		return nil
 	}
 
-	driverSummary.totalMilesDriven += float64(tripInfo.TripMileage)
-	driverSummary.totalDurationDriven += tripInfo.TripDuration
+	es.registerVehicle(vehicleInfo)
 
 	return nil
 }
+
+// registerVehicle is the vehicle analogue of `registerDriver`.
+func (es *EventStore) registerVehicle(vehicleInfo *VehicleInfo) *vehicleSummary {
+	newVehicleSummary := &vehicleSummary{
+		class:    vehicleInfo.Class,
+		fuelType: vehicleInfo.FuelType,
+	}
+
+	es.vehicleSummaries[vehicleInfo.ID] = newVehicleSummary
+
+	return newVehicleSummary
+}
+
+// canonicalTripMileage converts `TripInfo.TripMileage` to the canonical unit (and representation) that
+// distances are stored in -- it's an `error` for the mileage not to be representable in it.
+//
+// The conversion is done on the `mathutils.FixedDecimal` that the mileage was given as (see
+// `mathutils.FixedDecimalFromFloat32`), so that it's exact rather than subject to floating-point rounding.
+func canonicalTripMileage(tripInfo *TripInfo) (mathutils.FixedDecimal, error) {
+	distance, err := mathutils.FixedDecimalFromFloat32(tripInfo.TripMileage)
+	if err != nil {
+		return 0, fmt.Errorf("invalid trip mileage: %w", err)
+	}
+
+	mileage, err := mathutils.ConvertDistance(distance, tripInfo.TripDistanceUnit, mathutils.Miles)
+	if err != nil {
+		return 0, fmt.Errorf("invalid trip mileage: %w", err)
+	}
+
+	return mileage, nil
+}
@@ -1,12 +1,19 @@
 package eventstore_test
 
 import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
 	"reflect"
 	"sort"
 	"testing"
+	"testing/quick"
 	"time"
 
 	"root.challenge/eventstore"
+	"root.challenge/logging"
+	"root.challenge/mathutils"
 )
 
 // `eventStoreMethodInvoker` and `eventStoreMethodInvocation` together provide a framework to invoke methods
@@ -25,28 +32,69 @@ func recordTripInvoker(eventStore *eventstore.EventStore, tripInfo interface{})
 	return eventStore.RecordTrip(tripInfo.(*eventstore.TripInfo))
 }
 
+func registerVehicleInvoker(eventStore *eventstore.EventStore, vehicleInfo interface{}) error {
+	return eventStore.RegisterVehicle(vehicleInfo.(*eventstore.VehicleInfo))
+}
+
+func deactivateDriverInvoker(eventStore *eventstore.EventStore, driverInfo interface{}) error {
+	return eventStore.DeactivateDriver(driverInfo.(*eventstore.DriverInfo))
+}
+
+func reactivateDriverInvoker(eventStore *eventstore.EventStore, driverInfo interface{}) error {
+	return eventStore.ReactivateDriver(driverInfo.(*eventstore.DriverInfo))
+}
+
+func renameDriverInvoker(eventStore *eventstore.EventStore, driverRenameInfo interface{}) error {
+	return eventStore.RenameDriver(driverRenameInfo.(*eventstore.DriverRenameInfo))
+}
+
+func mergeDriversInvoker(eventStore *eventstore.EventStore, driverMergeInfo interface{}) error {
+	return eventStore.MergeDrivers(driverMergeInfo.(*eventstore.DriverMergeInfo))
+}
+
+func cancelTripInvoker(eventStore *eventstore.EventStore, tripCancelInfo interface{}) error {
+	return eventStore.CancelTrip(tripCancelInfo.(*eventstore.TripCancelInfo))
+}
+
+func amendTripInvoker(eventStore *eventstore.EventStore, tripInfo interface{}) error {
+	return eventStore.AmendTrip(tripInfo.(*eventstore.TripInfo))
+}
+
+func discardTripInvoker(eventStore *eventstore.EventStore, tripInfo interface{}) error {
+	return eventStore.DiscardTrip(tripInfo.(*eventstore.TripInfo))
+}
+
+func discardTripAmendmentInvoker(eventStore *eventstore.EventStore, tripInfo interface{}) error {
+	return eventStore.DiscardTripAmendment(tripInfo.(*eventstore.TripInfo))
+}
+
 type eventStoreMethodInvocation struct {
 	invoker eventStoreMethodInvoker
 	params  interface{}
+	// For when the invocation is expected to return an `error`.
+	expectError bool
 }
 
 func TestEventStore(t *testing.T) {
 	tests := map[string]struct {
 		input          []eventStoreMethodInvocation
 		expectedOutput []eventstore.VisitableEntity
+		// Left nil by cases that don't involve vehicles.
+		expectedVehicleOutput []eventstore.VisitableVehicleEntity
 	}{
 		"OneDriverNoTrip": {
 			input: []eventStoreMethodInvocation{
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverA",
+						ID: "DriverA",
 					},
 				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 0 * time.Second,
 					TotalMilesDriven:    0.0,
 				},
@@ -57,15 +105,16 @@ func TestEventStore(t *testing.T) {
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    1 * time.Hour,
-						TripMileage:     20.0,
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
 					},
 				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 1 * time.Hour,
 					TotalMilesDriven:    20.0,
 				},
@@ -76,21 +125,22 @@ func TestEventStore(t *testing.T) {
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverA",
+						ID: "DriverA",
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    1 * time.Hour,
-						TripMileage:     20.0,
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
 					},
 				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 1 * time.Hour,
 					TotalMilesDriven:    20.0,
 				},
@@ -101,54 +151,731 @@ func TestEventStore(t *testing.T) {
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    1 * time.Hour,
-						TripMileage:     20.0,
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
 					},
 				},
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverA",
+						ID: "DriverA",
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+			},
+		},
+		"OneDriverTripsInMixedUnits": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:         "DriverA",
+						TripDuration:     1 * time.Hour,
+						TripMileage:      20.0,
+						TripDistanceUnit: mathutils.Miles,
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:         "DriverA",
+						TripDuration:     30 * time.Minute,
+						TripMileage:      16.09344,
+						TripDistanceUnit: mathutils.Kilometers,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 1*time.Hour + 30*time.Minute,
+					TotalMilesDriven:    30.0,
+				},
+			},
+		},
+		"OneDriverTripsInKilometersConvertedExactly": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:         "DriverA",
+						TripDuration:     1 * time.Hour,
+						TripMileage:      100.0,
+						TripDistanceUnit: mathutils.Kilometers,
+					},
+				},
+				{
+					// Exactly 1.5859375 miles, which rounds half away from zero.
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:         "DriverA",
+						TripDuration:     1 * time.Hour,
+						TripMileage:      2.552319,
+						TripDistanceUnit: mathutils.Kilometers,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 2 * time.Hour,
+					TotalMilesDriven:    63.723057,
+				},
+			},
+		},
+		"TripsWithAndWithoutVehicles": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: registerVehicleInvoker,
+					params: &eventstore.VehicleInfo{
+						ID:       "VehicleA",
+						Class:    "Sedan",
+						FuelType: "Petrol",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						VehicleID:    "VehicleA",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  15.5,
+						VehicleID:    "VehicleA",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 10 * time.Minute,
+						TripMileage:  5.0,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+				{
+					DriverID:            "DriverB",
+					DriverDisplayName:   "DriverB",
+					TotalDurationDriven: 40 * time.Minute,
+					TotalMilesDriven:    20.5,
+				},
+			},
+			expectedVehicleOutput: []eventstore.VisitableVehicleEntity{
+				{
+					VehicleID:           "VehicleA",
+					VehicleClass:        "Sedan",
+					VehicleFuelType:     "Petrol",
+					TotalDurationDriven: 1*time.Hour + 30*time.Minute,
+					TotalMilesDriven:    35.5,
+				},
+			},
+		},
+		"TripBeforeVehicleRegistration": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						VehicleID:    "VehicleB",
+					},
+				},
+				{
+					invoker: registerVehicleInvoker,
+					params: &eventstore.VehicleInfo{
+						ID:       "VehicleA",
+						Class:    "Van",
+						FuelType: "Diesel",
+					},
+				},
+				{
+					invoker: registerVehicleInvoker,
+					params: &eventstore.VehicleInfo{
+						ID:       "VehicleB",
+						Class:    "Sedan",
+						FuelType: "Electric",
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+			},
+			expectedVehicleOutput: []eventstore.VisitableVehicleEntity{
+				{
+					VehicleID:       "VehicleA",
+					VehicleClass:    "Van",
+					VehicleFuelType: "Diesel",
+				},
+				{
+					VehicleID:           "VehicleB",
+					VehicleClass:        "Sedan",
+					VehicleFuelType:     "Electric",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+			},
+		},
+		"DeactivateAndReactivateDrivers": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{invoker: deactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: deactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: deactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{invoker: reactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{invoker: deactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverC"}, expectError: true},
+				{invoker: reactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverC"}, expectError: true},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+					DriverInactive:      true,
+				},
+				{
+					DriverID:          "DriverB",
+					DriverDisplayName: "DriverB",
+				},
+			},
+		},
+		"RenameDrivers": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB", DisplayName: "NameB"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+					},
+				},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverA", NewDisplayName: "NameC"}},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverA", NewDisplayName: "NameD"}},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverA", NewDisplayName: "NameD"}},
+				// Display names needn't be unique.
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverB", NewDisplayName: "NameD"}},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverA", NewDisplayName: ""}, expectError: true},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverE", NewDisplayName: "NameF"}, expectError: true},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  10.0,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "NameD",
+					TotalDurationDriven: 1*time.Hour + 30*time.Minute,
+					TotalMilesDriven:    30.0,
+					DriverPreviousNames: []string{"DriverA", "NameC"},
+				},
+				{
+					DriverID:            "DriverB",
+					DriverDisplayName:   "NameD",
+					DriverPreviousNames: []string{"NameB"},
+				},
+			},
+		},
+		"MergeDrivers": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA", DisplayName: "NameA"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverX"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverX",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  10.5,
+					},
+				},
+				{invoker: renameDriverInvoker, params: &eventstore.DriverRenameInfo{DriverID: "DriverX", NewDisplayName: "NameY"}},
+				{invoker: deactivateDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverX"}},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverX", IntoDriverID: "DriverA"}},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverA", IntoDriverID: "DriverA"}, expectError: true},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverX", IntoDriverID: "DriverA"}, expectError: true},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverB", IntoDriverID: "DriverC"}, expectError: true},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "NameA",
+					TotalDurationDriven: 1*time.Hour + 30*time.Minute,
+					TotalMilesDriven:    30.5,
+					DriverPreviousNames: []string{"DriverX", "NameY"},
+				},
+				{
+					DriverID:          "DriverB",
+					DriverDisplayName: "DriverB",
+				},
+			},
+		},
+		"SameDisplayNameDifferentIDs": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "r1", DisplayName: "Raphael"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "r2", DisplayName: "Raphael"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "r2",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:          "r1",
+					DriverDisplayName: "Raphael",
+				},
+				{
+					DriverID:            "r2",
+					DriverDisplayName:   "Raphael",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+			},
+		},
+		"DisplayNameAfterLazyRegistration": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "r1",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+					},
+				},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "r1", DisplayName: "Raphael"}},
+				// Ignored, since "r1" now has a display name of their own.
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "r1", DisplayName: "Raff"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{DisplayName: "NoID"}, expectError: true},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "r1",
+					DriverDisplayName:   "Raphael",
+					TotalDurationDriven: 1 * time.Hour,
+					TotalMilesDriven:    20.0,
+				},
+			},
+		},
+		"CancelTrips": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.1,
+						VehicleID:    "VehicleA",
+						TripID:       "Trip1",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  10.3,
+						VehicleID:    "VehicleA",
+						TripID:       "Trip2",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  10.3,
+						TripID:       "Trip2",
+					},
+					expectError: true,
+				},
+				{invoker: cancelTripInvoker, params: &eventstore.TripCancelInfo{TripID: "Trip1"}},
+				{invoker: cancelTripInvoker, params: &eventstore.TripCancelInfo{TripID: "Trip1"}, expectError: true},
+				{invoker: cancelTripInvoker, params: &eventstore.TripCancelInfo{TripID: "Trip3"}, expectError: true},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 30 * time.Minute,
+					TotalMilesDriven:    10.3,
+				},
+			},
+			expectedVehicleOutput: []eventstore.VisitableVehicleEntity{
+				{
+					VehicleID:           "VehicleA",
+					TotalDurationDriven: 30 * time.Minute,
+					TotalMilesDriven:    10.3,
+				},
+			},
+		},
+		"AmendTrips": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.1,
+						VehicleID:    "VehicleA",
+						TripID:       "Trip1",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  30.0,
+						TripID:       "Trip2",
+					},
+				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration:     2 * time.Hour,
+						TripMileage:      16.09344,
+						TripDistanceUnit: mathutils.Kilometers,
+						VehicleID:        "VehicleB",
+						TripID:           "Trip1",
+					},
+				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  15.0,
+						TripID:       "Trip2",
 					},
 				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverC",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  15.0,
+						TripID:       "Trip2",
+					},
+					expectError: true,
+				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration: 30 * time.Minute,
+						TripMileage:  15.0,
+						TripID:       "Trip3",
+					},
+					expectError: true,
+				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					TotalDurationDriven: 2 * time.Hour,
+					TotalMilesDriven:    10.0,
+				},
+				{
+					DriverID:            "DriverB",
+					DriverDisplayName:   "DriverB",
+					TotalDurationDriven: 30 * time.Minute,
+					TotalMilesDriven:    15.0,
+				},
+			},
+			expectedVehicleOutput: []eventstore.VisitableVehicleEntity{
+				{
+					VehicleID: "VehicleA",
+				},
+				{
+					VehicleID:           "VehicleB",
+					TotalDurationDriven: 2 * time.Hour,
+					TotalMilesDriven:    10.0,
+				},
+			},
+		},
+		"CancelTripAfterMerge": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						TripID:       "Trip1",
+					},
+				},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverB", IntoDriverID: "DriverA"}},
+				{invoker: cancelTripInvoker, params: &eventstore.TripCancelInfo{TripID: "Trip1"}},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					DriverPreviousNames: []string{"DriverB"},
+				},
+			},
+		},
+		"TripsAfterMerge": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverC"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						TripID:       "Trip1",
+					},
+				},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverB", IntoDriverID: "DriverA"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  10.0,
+						TripID:       "Trip2",
+					},
+				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  12.0,
+						TripID:       "Trip2",
+					},
+				},
+				{invoker: cancelTripInvoker, params: &eventstore.TripCancelInfo{TripID: "Trip2"}},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  5.0,
+						TripID:       "Trip3",
+					},
+				},
+				// Drivers that were merged away aren't registered anew.
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB", DisplayName: "NameB"}},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverA", IntoDriverID: "DriverC"}},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverB", IntoDriverID: "DriverC"}, expectError: true},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 30 * time.Minute,
+						TripMileage:  1.0,
+					},
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverC",
+					DriverDisplayName:   "DriverC",
+					DriverPreviousNames: []string{"DriverB", "DriverA"},
+					TotalDurationDriven: 2 * time.Hour,
+					TotalMilesDriven:    26.0,
+				},
+			},
+		},
+		"UnrepresentableMileagesRejected": {
+			input: []eventStoreMethodInvocation{
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						TripID:       "Trip1",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  float32(math.NaN()),
+					},
+					expectError: true,
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  math.MaxFloat32,
+					},
+					expectError: true,
+				},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration: 1 * time.Hour,
+						TripMileage:  float32(math.Inf(+1)),
+						TripID:       "Trip1",
+					},
+					expectError: true,
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 1 * time.Hour,
 					TotalMilesDriven:    20.0,
 				},
 			},
 		},
+		"DiscardedTripsCanBeAmendedAfterMerge": {
+			input: []eventStoreMethodInvocation{
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverA"}},
+				{invoker: registerDriverInvoker, params: &eventstore.DriverInfo{ID: "DriverB"}},
+				{
+					invoker: discardTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  500.0,
+						TripID:       "Trip1",
+					},
+				},
+				{
+					invoker: recordTripInvoker,
+					params: &eventstore.TripInfo{
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
+						TripID:       "Trip2",
+					},
+				},
+				{
+					invoker: discardTripAmendmentInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration: 1 * time.Hour,
+						TripMileage:  500.0,
+						TripID:       "Trip2",
+					},
+				},
+				{invoker: mergeDriversInvoker, params: &eventstore.DriverMergeInfo{DriverID: "DriverB", IntoDriverID: "DriverA"}},
+				{
+					invoker: amendTripInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration: 30 * time.Minute,
+						TripMileage:  15.0,
+						TripID:       "Trip1",
+					},
+				},
+				{
+					invoker: discardTripAmendmentInvoker,
+					params: &eventstore.TripInfo{
+						TripDuration: 1 * time.Hour,
+						TripMileage:  500.0,
+						TripID:       "Trip3",
+					},
+					expectError: true,
+				},
+			},
+			expectedOutput: []eventstore.VisitableEntity{
+				{
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
+					DriverPreviousNames: []string{"DriverB"},
+					TotalDurationDriven: 30 * time.Minute,
+					TotalMilesDriven:    15.0,
+				},
+			},
+		},
 		"OneDriverMultipleTrips": {
 			input: []eventStoreMethodInvocation{
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverA",
+						ID: "DriverA",
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    1 * time.Hour,
-						TripMileage:     20.0,
+						DriverID:     "DriverA",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    10 * time.Minute,
-						TripMileage:     5.0,
+						DriverID:     "DriverA",
+						TripDuration: 10 * time.Minute,
+						TripMileage:  5.0,
 					},
 				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 1*time.Hour + 10*time.Minute,
 					TotalMilesDriven:    25.0,
 				},
@@ -159,67 +886,70 @@ func TestEventStore(t *testing.T) {
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverA",
+						ID: "DriverA",
 					},
 				},
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverB",
+						ID: "DriverB",
 					},
 				},
 				{
 					invoker: registerDriverInvoker,
 					params: &eventstore.DriverInfo{
-						FirstName: "DriverC",
+						ID: "DriverC",
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverB",
-						TripDuration:    1 * time.Hour,
-						TripMileage:     20.0,
+						DriverID:     "DriverB",
+						TripDuration: 1 * time.Hour,
+						TripMileage:  20.0,
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverC",
-						TripDuration:    10 * time.Minute,
-						TripMileage:     0.5,
+						DriverID:     "DriverC",
+						TripDuration: 10 * time.Minute,
+						TripMileage:  0.5,
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverB",
-						TripDuration:    45 * time.Minute,
-						TripMileage:     50.5,
+						DriverID:     "DriverB",
+						TripDuration: 45 * time.Minute,
+						TripMileage:  50.5,
 					},
 				},
 				{
 					invoker: recordTripInvoker,
 					params: &eventstore.TripInfo{
-						DriverFirstName: "DriverA",
-						TripDuration:    25 * time.Minute,
-						TripMileage:     35.0,
+						DriverID:     "DriverA",
+						TripDuration: 25 * time.Minute,
+						TripMileage:  35.0,
 					},
 				},
 			},
 			expectedOutput: []eventstore.VisitableEntity{
 				{
-					DriverFirstName:     "DriverA",
+					DriverID:            "DriverA",
+					DriverDisplayName:   "DriverA",
 					TotalDurationDriven: 25 * time.Minute,
 					TotalMilesDriven:    35.0,
 				},
 				{
-					DriverFirstName:     "DriverB",
+					DriverID:            "DriverB",
+					DriverDisplayName:   "DriverB",
 					TotalDurationDriven: 1*time.Hour + 45*time.Minute,
 					TotalMilesDriven:    70.5,
 				},
 				{
-					DriverFirstName:     "DriverC",
+					DriverID:            "DriverC",
+					DriverDisplayName:   "DriverC",
 					TotalDurationDriven: 10 * time.Minute,
 					TotalMilesDriven:    0.5,
 				},
@@ -230,22 +960,280 @@ func TestEventStore(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			es := eventstore.New()
-			for _, invocation := range tc.input {
-				invocation.invoker(es, invocation.params)
+			for i, invocation := range tc.input {
+				err := invocation.invoker(es, invocation.params)
+				switch {
+				case !invocation.expectError && err != nil:
+					t.Fatalf("invocation %d expected: no error, got: %v", i, err)
+				case invocation.expectError && err == nil:
+					t.Fatalf("invocation %d expected: error, got: no error", i)
+				}
 			}
 
 			r := eventstore.NewRecorder()
 			es.Visit(r)
+			es.VisitVehicles(r)
 
 			actualOutput := r.Entities
-			// The output of Visit() above is not guaranteed to be in any order, so sort by DriverFirstName to
+			// The output of Visit() above is not guaranteed to be in any order, so sort by DriverID to
 			// be able to work with something predictable (and comparable to tc.expectedOutput).
 			sort.Slice(actualOutput, func(i, j int) bool {
-				return actualOutput[i].DriverFirstName < actualOutput[j].DriverFirstName
+				return actualOutput[i].DriverID < actualOutput[j].DriverID
 			})
 			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
 				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
 			}
+
+			expectedVehicleOutput := tc.expectedVehicleOutput
+			if expectedVehicleOutput == nil {
+				expectedVehicleOutput = []eventstore.VisitableVehicleEntity{}
+			}
+
+			// Same deal as above with VisitVehicles() and VehicleID.
+			actualVehicleOutput := r.VehicleEntities
+			sort.Slice(actualVehicleOutput, func(i, j int) bool {
+				return actualVehicleOutput[i].VehicleID < actualVehicleOutput[j].VehicleID
+			})
+			if !reflect.DeepEqual(actualVehicleOutput, expectedVehicleOutput) {
+				t.Fatalf("expected: %#v, got: %#v", expectedVehicleOutput, actualVehicleOutput)
+			}
+		})
+	}
+}
+
+// Recording the same trips in any order must produce identical totals that are exact to the precision
+// that the trips' mileages were provided with (1 decimal place here), regardless of how many there are.
+func TestEventStoreTotalsAreExactAndOrderIndependent(t *testing.T) {
+	property := func(tenthsOfMiles []uint16, seed int64) bool {
+		var expectedTenths int64
+		tripInfos := make([]*eventstore.TripInfo, len(tenthsOfMiles))
+		for i, tenths := range tenthsOfMiles {
+			expectedTenths += int64(tenths)
+			tripInfos[i] = &eventstore.TripInfo{
+				DriverID:     "DriverA",
+				TripDuration: 1 * time.Minute,
+				TripMileage:  float32(float64(tenths) / 10),
+			}
+		}
+
+		totalMilesDriven := func() float64 {
+			es := eventstore.New()
+			es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA"})
+			for _, tripInfo := range tripInfos {
+				es.RecordTrip(tripInfo)
+			}
+
+			r := eventstore.NewRecorder()
+			es.Visit(r)
+			return r.Entities[0].TotalMilesDriven
+		}
+
+		inOrderTotal := totalMilesDriven()
+		rand.New(rand.NewSource(seed)).Shuffle(len(tripInfos), func(i, j int) {
+			tripInfos[i], tripInfos[j] = tripInfos[j], tripInfos[i]
 		})
+		shuffledTotal := totalMilesDriven()
+
+		return inOrderTotal == shuffledTotal && inOrderTotal == float64(expectedTenths)/10
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEventStoreReferentialIntegrity(t *testing.T) {
+	now := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		return now
+	}
+
+	tripInfo := func(driverID string) *eventstore.TripInfo {
+		return &eventstore.TripInfo{
+			DriverID:     driverID,
+			TripDuration: 1 * time.Hour,
+			TripMileage:  20.0,
+		}
+	}
+
+	totalMilesDrivenPerDriver := func(es *eventstore.EventStore) map[string]float64 {
+		r := eventstore.NewRecorder()
+		es.Visit(r)
+
+		totals := make(map[string]float64)
+		for _, ve := range r.Entities {
+			totals[ve.DriverID] = ve.TotalMilesDriven
+		}
+		return totals
 	}
+
+	t.Run("LazyRegistrationByDefault", func(t *testing.T) {
+		es := eventstore.New()
+
+		if err := es.RecordTrip(tripInfo("DriverA")); err != nil {
+			t.Fatalf("RecordTrip() expected: no error, got: %v", err)
+		}
+
+		if expected, actual := map[string]float64{"DriverA": 20.0}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	})
+
+	t.Run("StrictRegistrationRejectsUnregisteredDrivers", func(t *testing.T) {
+		es := eventstore.New(eventstore.WithStrictDriverRegistration())
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA"})
+
+		if err := es.RecordTrip(tripInfo("DriverA")); err != nil {
+			t.Fatalf("RecordTrip() expected: no error, got: %v", err)
+		}
+
+		err := es.RecordTrip(tripInfo("DriverB"))
+		var unregisteredDriverError *eventstore.UnregisteredDriverError
+		if !errors.As(err, &unregisteredDriverError) {
+			t.Fatalf("RecordTrip() expected: UnregisteredDriverError, got: %v", err)
+		}
+		if unregisteredDriverError.DriverID != "DriverB" || unregisteredDriverError.Parked {
+			t.Fatalf("RecordTrip() expected: UnregisteredDriverError for unparked DriverB, got: %#v", unregisteredDriverError)
+		}
+
+		// A later registration has no effect on the rejected trip.
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverB"})
+
+		if expected, actual := map[string]float64{"DriverA": 20.0, "DriverB": 0.0}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	})
+
+	t.Run("ParkedTripsRecordedUponRegistration", func(t *testing.T) {
+		es := eventstore.New(eventstore.WithParkedTrips(2))
+
+		for _, driverID := range []string{"DriverA", "DriverA", "DriverB"} {
+			if err := es.RecordTrip(tripInfo(driverID)); err != nil {
+				t.Fatalf("RecordTrip() expected: no error, got: %v", err)
+			}
+		}
+
+		if expected, actual := map[string]float64{}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA"})
+
+		if expected, actual := map[string]float64{"DriverA": 40.0}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+
+		if errs := es.ExpireParkedTrips(); len(errs) != 0 {
+			t.Fatalf("ExpireParkedTrips() expected: no errors, got: %v", errs)
+		}
+
+		errs := es.FlushParkedTrips()
+		if len(errs) != 1 {
+			t.Fatalf("FlushParkedTrips() expected: 1 error, got: %v", errs)
+		}
+
+		var unregisteredDriverError *eventstore.UnregisteredDriverError
+		if !errors.As(errs[0], &unregisteredDriverError) || unregisteredDriverError.DriverID != "DriverB" || !unregisteredDriverError.Parked {
+			t.Fatalf("FlushParkedTrips() expected: UnregisteredDriverError for parked DriverB, got: %v", errs[0])
+		}
+
+		// Flushed trips are gone for good.
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverB"})
+
+		if expected, actual := map[string]float64{"DriverA": 40.0, "DriverB": 0.0}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	})
+
+	t.Run("ParkedTripsCanBeCancelledAndAmended", func(t *testing.T) {
+		es := eventstore.New(eventstore.WithParkedTrips(2))
+
+		for _, tripID := range []string{"Trip1", "Trip2"} {
+			ti := tripInfo("DriverA")
+			ti.TripID = tripID
+			es.RecordTrip(ti)
+		}
+
+		if err := es.CancelTrip(&eventstore.TripCancelInfo{TripID: "Trip1"}); err != nil {
+			t.Fatalf("CancelTrip() expected: no error, got: %v", err)
+		}
+
+		if err := es.AmendTrip(&eventstore.TripInfo{TripID: "Trip2", TripDuration: 1 * time.Hour, TripMileage: 5.5}); err != nil {
+			t.Fatalf("AmendTrip() expected: no error, got: %v", err)
+		}
+
+		if err := es.AmendTrip(&eventstore.TripInfo{TripID: "Trip2", DriverID: "DriverB", TripDuration: 1 * time.Hour}); err == nil {
+			t.Fatalf("AmendTrip() expected: error, got: no error")
+		}
+
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA"})
+
+		if expected, actual := map[string]float64{"DriverA": 5.5}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+
+		// Trip2 is no longer parked, but is still known.
+		if err := es.CancelTrip(&eventstore.TripCancelInfo{TripID: "Trip2"}); err != nil {
+			t.Fatalf("CancelTrip() expected: no error, got: %v", err)
+		}
+
+		if err := es.CancelTrip(&eventstore.TripCancelInfo{TripID: "Trip1"}); !errors.Is(err, eventstore.ErrUnknownTrip) {
+			t.Fatalf("CancelTrip() expected: ErrUnknownTrip, got: %v", err)
+		}
+	})
+
+	t.Run("ParkedTripsExpire", func(t *testing.T) {
+		es := eventstore.New(eventstore.WithParkedTrips(2))
+
+		// Every call to `ExpireParkedTrips` counts an event.
+		es.RecordTrip(tripInfo("DriverA"))
+		if errs := es.ExpireParkedTrips(); len(errs) != 0 {
+			t.Fatalf("ExpireParkedTrips() expected: no errors, got: %v", errs)
+		}
+		es.RecordTrip(tripInfo("DriverB"))
+		if errs := es.ExpireParkedTrips(); len(errs) != 0 {
+			t.Fatalf("ExpireParkedTrips() expected: no errors, got: %v", errs)
+		}
+
+		errs := es.ExpireParkedTrips()
+		if len(errs) != 1 {
+			t.Fatalf("ExpireParkedTrips() expected: 1 error, got: %v", errs)
+		}
+
+		var unregisteredDriverError *eventstore.UnregisteredDriverError
+		if !errors.As(errs[0], &unregisteredDriverError) || unregisteredDriverError.DriverID != "DriverA" ||
+			unregisteredDriverError.ParkedForEvents != 2 {
+			t.Fatalf("ExpireParkedTrips() expected: UnregisteredDriverError for DriverA parked for 2 events, got: %v", errs[0])
+		}
+
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverA"})
+		es.RegisterDriver(&eventstore.DriverInfo{ID: "DriverB"})
+
+		if expected, actual := map[string]float64{"DriverA": 0.0, "DriverB": 20.0}, totalMilesDrivenPerDriver(es); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected: %v, got: %v", expected, actual)
+		}
+	})
+
+	t.Run("LogsWhatItDoesOnItsOwnAccord", func(t *testing.T) {
+		now = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		var buf bytes.Buffer
+		logger := logging.New(&buf, logging.LevelDebug, logging.WithClock(clock))
+
+		lazy := eventstore.New(eventstore.WithLogger(logger))
+		lazy.RecordTrip(tripInfo("DriverA"))
+
+		parking := eventstore.New(eventstore.WithParkedTrips(2),
+			eventstore.WithLogger(logger))
+		parking.RecordTrip(tripInfo("DriverB"))
+		parking.RegisterDriver(&eventstore.DriverInfo{ID: "DriverB"})
+		parking.RegisterDriver(&eventstore.DriverInfo{ID: "DriverC"})
+
+		expected := `{"time":"2021-01-01T00:00:00Z","level":"debug","msg":"registered driver lazily","driver":"DriverA"}` + "\n" +
+			`{"time":"2021-01-01T00:00:00Z","level":"debug","msg":"parked trip for unregistered driver","driver":"DriverB","trip_id":""}` + "\n" +
+			`{"time":"2021-01-01T00:00:00Z","level":"debug","msg":"recorded parked trips","driver":"DriverB","num_trips":1}` + "\n"
+		if buf.String() != expected {
+			t.Fatalf("expected: %q, got: %q", expected, buf.String())
+		}
+	})
 }
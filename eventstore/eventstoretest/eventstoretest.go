@@ -0,0 +1,18 @@
+// Package eventstoretest provides helpers for the tests of packages that process events into an
+// `eventstore.EventStore`.
+package eventstoretest
+
+import "root.challenge/eventstore"
+
+// TotalMilesByDriver returns the total miles driven by every driver in `eventStore`, by driver ID.
+func TotalMilesByDriver(eventStore *eventstore.EventStore) map[string]float64 {
+	r := eventstore.NewRecorder()
+	eventStore.Visit(r)
+
+	totals := make(map[string]float64, len(r.Entities))
+	for _, entity := range r.Entities {
+		totals[entity.DriverID] = entity.TotalMilesDriven
+	}
+
+	return totals
+}
@@ -0,0 +1,222 @@
+package eventstore
+
+import (
+	"fmt"
+	"sort"
+
+	"root.challenge/logging"
+)
+
+// driverRegistrationPolicy determines how `RecordTrip` deals with trips for drivers that aren't registered.
+type driverRegistrationPolicy int
+
+const (
+	lazilyRegisterDrivers driverRegistrationPolicy = iota
+	rejectUnregisteredDrivers
+	parkUnregisteredDrivers
+)
+
+// WithStrictDriverRegistration makes `RecordTrip` reject trips for drivers that aren't registered (with an
+// `*UnregisteredDriverError`), instead of lazily registering those drivers.
+func WithStrictDriverRegistration() Option {
+	return func(es *EventStore) {
+		es.driverRegistrationPolicy = rejectUnregisteredDrivers
+	}
+}
+
+// WithParkedTrips makes `RecordTrip` park trips for drivers that aren't registered (instead of lazily
+// registering those drivers), to be recorded as soon as `RegisterDriver` is called for those drivers.
+//
+// Trips wait for up to `maxEvents` events after the one that parked them, as counted by `ExpireParkedTrips`
+// -- which discards the trips whose drivers still aren't registered by then.
+//
+// Parked trips only last as long as the `EventStore` is in memory (see `Save`), so they're only meant for
+// processing an input in a single run.
+func WithParkedTrips(maxEvents int) Option {
+	return func(es *EventStore) {
+		es.driverRegistrationPolicy = parkUnregisteredDrivers
+		es.maxParkedEvents = maxEvents
+	}
+}
+
+// ParksTrips returns whether the `EventStore` parks trips for drivers that aren't registered (see
+// `WithParkedTrips`).
+func (es *EventStore) ParksTrips() bool {
+	return es.driverRegistrationPolicy == parkUnregisteredDrivers
+}
+
+// WithLogger makes the `EventStore` log what it does on its own accord -- like registering drivers lazily,
+// and parking (and later recording) trips -- to `logger`, at `logging.LevelDebug`.
+func WithLogger(logger *logging.Logger) Option {
+	return func(es *EventStore) {
+		es.logger = logger
+	}
+}
+
+// UnregisteredDriverError is the `error` that results from a trip referencing a driver that isn't
+// registered when lazy driver registration is disabled.
+type UnregisteredDriverError struct {
+	DriverID string
+	// Parked is false for trips that were rejected outright, and true for trips that expired after being
+	// parked (for `ParkedForEvents` events).
+	Parked          bool
+	ParkedForEvents int
+}
+
+// Conforms to `error`.
+func (ude *UnregisteredDriverError) Error() string {
+	if ude.Parked {
+		return fmt.Sprintf("trip for unregistered driver '%s' expired after being parked for %d events",
+			ude.DriverID, ude.ParkedForEvents)
+	}
+
+	return fmt.Sprintf("trip references unregistered driver '%s'", ude.DriverID)
+}
+
+// parkedTrip is a trip that's waiting on the registration of its driver.
+type parkedTrip struct {
+	tripInfo TripInfo
+	// contribution is what the trip will contribute once it's recorded.
+	contribution *tripContribution
+	// parkedAt is the number of events that had been counted by `ExpireParkedTrips` when the trip was parked.
+	parkedAt int
+	// sequenceNumber orders parked trips deterministically even when they're parked by the same event.
+	sequenceNumber uint64
+}
+
+func (es *EventStore) parkTrip(tripInfo *TripInfo, tc *tripContribution) {
+	es.parkedTrips[tripInfo.DriverID] = append(es.parkedTrips[tripInfo.DriverID], &parkedTrip{
+		// Copy `TripInfo` so that clients are free to reuse theirs.
+		tripInfo:       *tripInfo,
+		contribution:   tc,
+		parkedAt:       es.numEventsExpired,
+		sequenceNumber: es.numTripsParked,
+	})
+	es.numTripsParked++
+
+	es.logger.Debug("parked trip for unregistered driver", logging.String("driver", tripInfo.DriverID),
+		logging.String("trip_id", tripInfo.TripID))
+}
+
+// unparkTrips records every trip that's parked for a (newly-registered) driver, in the order they were parked.
+func (es *EventStore) unparkTrips(driverID string, driverSummary *driverSummary) {
+	parkedTrips := es.parkedTrips[driverID]
+	if len(parkedTrips) == 0 {
+		return
+	}
+
+	for _, pt := range parkedTrips {
+		es.recordTrip(driverSummary, pt.tripInfo.TripID, pt.contribution)
+	}
+
+	delete(es.parkedTrips, driverID)
+
+	es.logger.Debug("recorded parked trips", logging.String("driver", driverID),
+		logging.Int("num_trips", len(parkedTrips)))
+}
+
+// findParkedTrip returns the parked trip with the given (non-empty) ID, if any.
+func (es *EventStore) findParkedTrip(tripID string) *parkedTrip {
+	if tripID == "" {
+		return nil
+	}
+
+	for _, parkedTrips := range es.parkedTrips {
+		for _, pt := range parkedTrips {
+			if pt.tripInfo.TripID == tripID {
+				return pt
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeParkedTrip discards the parked trip with the given (non-empty) ID, if any, and returns it.
+func (es *EventStore) removeParkedTrip(tripID string) *parkedTrip {
+	pt := es.findParkedTrip(tripID)
+	if pt == nil {
+		return nil
+	}
+
+	driverID := pt.tripInfo.DriverID
+
+	remainingTrips := make([]*parkedTrip, 0, len(es.parkedTrips[driverID]))
+	for _, otherPT := range es.parkedTrips[driverID] {
+		if otherPT != pt {
+			remainingTrips = append(remainingTrips, otherPT)
+		}
+	}
+
+	if len(remainingTrips) == 0 {
+		delete(es.parkedTrips, driverID)
+	} else {
+		es.parkedTrips[driverID] = remainingTrips
+	}
+
+	return pt
+}
+
+// ExpireParkedTrips is meant to be called after every event is processed (whether or not it parked a trip),
+// and counts those calls as the events that parked trips have waited for -- it discards every parked trip
+// that has waited for as many events as provided to `WithParkedTrips`, and returns an
+// `*UnregisteredDriverError` for each of them (ordered by when they were parked).
+//
+// Counting events (rather than measuring time) makes expiry independent of how fast events are processed --
+// so a backlog that's replayed in seconds parks trips for exactly as long as it did when it was live.
+func (es *EventStore) ExpireParkedTrips() []error {
+	es.numEventsExpired++
+	return es.expireParkedTrips(func(pt *parkedTrip) bool {
+		return es.parkedForEvents(pt) >= es.maxParkedEvents
+	})
+}
+
+// parkedForEvents returns the number of events that `pt` has waited for since the event that parked it.
+func (es *EventStore) parkedForEvents(pt *parkedTrip) int {
+	// The event that parked `pt` was counted by `ExpireParkedTrips` after `pt` was parked.
+	return es.numEventsExpired - pt.parkedAt - 1
+}
+
+// FlushParkedTrips discards every parked trip regardless of how long it has been parked for (typically
+// because the input has been exhausted), and returns an `*UnregisteredDriverError` for each of them
+// (ordered by when they were parked).
+func (es *EventStore) FlushParkedTrips() []error {
+	return es.expireParkedTrips(func(*parkedTrip) bool {
+		return true
+	})
+}
+
+func (es *EventStore) expireParkedTrips(isExpired func(*parkedTrip) bool) []error {
+	expiredTrips := make([]*parkedTrip, 0)
+	for driverID, parkedTrips := range es.parkedTrips {
+		remainingTrips := parkedTrips[:0]
+		for _, pt := range parkedTrips {
+			if isExpired(pt) {
+				expiredTrips = append(expiredTrips, pt)
+			} else {
+				remainingTrips = append(remainingTrips, pt)
+			}
+		}
+
+		if len(remainingTrips) == 0 {
+			delete(es.parkedTrips, driverID)
+		} else {
+			es.parkedTrips[driverID] = remainingTrips
+		}
+	}
+
+	sort.Slice(expiredTrips, func(i, j int) bool {
+		return expiredTrips[i].sequenceNumber < expiredTrips[j].sequenceNumber
+	})
+
+	errs := make([]error, 0, len(expiredTrips))
+	for _, pt := range expiredTrips {
+		errs = append(errs, &UnregisteredDriverError{
+			DriverID:        pt.tripInfo.DriverID,
+			Parked:          true,
+			ParkedForEvents: es.parkedForEvents(pt),
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,197 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"root.challenge/mathutils"
+)
+
+// snapshotVersion is bumped on every incompatible change to the snapshot format, so that `Load` can reject
+// snapshots it can't read (rather than misread them).
+const snapshotVersion = 1
+
+// ============================================== Maintainer Notes ==============================================
+//
+// The *Snapshot structs below are the on-disk counterparts of the internal data structures of `EventStore`, and
+// are deliberately kept separate from them -- the internal data structures can keep changing at will, as long as
+// `Save` and `Load` keep translating to and from the snapshot format (bumping `snapshotVersion` when that's no
+// longer possible). Distances are stored as decimal strings, so that they round-trip exactly.
+
+type snapshot struct {
+	Version  int                         `json:"version"`
+	Drivers  map[string]*driverSnapshot  `json:"drivers"`
+	Vehicles map[string]*vehicleSnapshot `json:"vehicles"`
+	Trips    map[string]*tripSnapshot    `json:"trips"`
+	// DiscardedTrips was added without bumping `snapshotVersion`, since older snapshots simply have none.
+	DiscardedTrips map[string]*discardedTripSnapshot `json:"discarded_trips,omitempty"`
+	// MergedDrivers maps the IDs of drivers that were merged away to the IDs of the drivers they were merged
+	// into, and was added without bumping `snapshotVersion` for the same reason.
+	MergedDrivers map[string]string `json:"merged_drivers,omitempty"`
+}
+
+type driverSnapshot struct {
+	DisplayName         string        `json:"display_name,omitempty"`
+	TotalDurationDriven time.Duration `json:"total_duration_driven"`
+	TotalMilesDriven    string        `json:"total_miles_driven"`
+	Inactive            bool          `json:"inactive,omitempty"`
+	PreviousNames       []string      `json:"previous_names,omitempty"`
+}
+
+type vehicleSnapshot struct {
+	Class               string        `json:"class,omitempty"`
+	FuelType            string        `json:"fuel_type,omitempty"`
+	TotalDurationDriven time.Duration `json:"total_duration_driven"`
+	TotalMilesDriven    string        `json:"total_miles_driven"`
+}
+
+type tripSnapshot struct {
+	DriverID  string        `json:"driver_id"`
+	VehicleID string        `json:"vehicle_id,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Miles     string        `json:"miles"`
+}
+
+type discardedTripSnapshot struct {
+	DriverID string `json:"driver_id"`
+}
+
+// Save writes a snapshot of everything recorded in `es` to `w` (as JSON), from which `Load` can rebuild an
+// equivalent `EventStore` -- including the recorded trips that can still be cancelled or amended.
+//
+// Parked trips (see `WithParkedTrips`) are transient, and are thus not part of the snapshot; it's an
+// `error` to save an `EventStore` that still has any.
+func (es *EventStore) Save(w io.Writer) error {
+	if len(es.parkedTrips) > 0 {
+		return fmt.Errorf("can't save an EventStore with parked trips (flush them first)")
+	}
+
+	s := &snapshot{
+		Version:  snapshotVersion,
+		Drivers:  make(map[string]*driverSnapshot, len(es.driverSummaries)),
+		Vehicles: make(map[string]*vehicleSnapshot, len(es.vehicleSummaries)),
+		Trips:    make(map[string]*tripSnapshot, len(es.recordedTrips)),
+	}
+
+	if len(es.discardedTrips) > 0 {
+		s.DiscardedTrips = make(map[string]*discardedTripSnapshot, len(es.discardedTrips))
+		for tripID, driverID := range es.discardedTrips {
+			s.DiscardedTrips[tripID] = &discardedTripSnapshot{
+				DriverID: driverID,
+			}
+		}
+	}
+
+	if len(es.mergedDrivers) > 0 {
+		s.MergedDrivers = make(map[string]string, len(es.mergedDrivers))
+		for mergedDriverID, driverID := range es.mergedDrivers {
+			s.MergedDrivers[mergedDriverID] = driverID
+		}
+	}
+
+	for driverID, ds := range es.driverSummaries {
+		s.Drivers[driverID] = &driverSnapshot{
+			DisplayName:         ds.displayName,
+			TotalDurationDriven: ds.totalDurationDriven,
+			TotalMilesDriven:    ds.totalMilesDriven.String(),
+			Inactive:            ds.inactive,
+			PreviousNames:       ds.previousNames,
+		}
+	}
+
+	for vehicleID, vs := range es.vehicleSummaries {
+		s.Vehicles[vehicleID] = &vehicleSnapshot{
+			Class:               vs.class,
+			FuelType:            vs.fuelType,
+			TotalDurationDriven: vs.totalDurationDriven,
+			TotalMilesDriven:    vs.totalMilesDriven.String(),
+		}
+	}
+
+	for tripID, tc := range es.recordedTrips {
+		s.Trips[tripID] = &tripSnapshot{
+			DriverID:  tc.driverID,
+			VehicleID: tc.vehicleID,
+			Duration:  tc.duration,
+			Miles:     tc.mileage.String(),
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(s); err != nil {
+		return fmt.Errorf("error writing EventStore snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Load rebuilds an `EventStore` (customized by `opts`, which aren't part of the snapshot) from a snapshot
+// written by `Save`.
+func Load(r io.Reader, opts ...Option) (*EventStore, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("error reading EventStore snapshot: %w", err)
+	}
+
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported EventStore snapshot version %d (expecting %d)", s.Version, snapshotVersion)
+	}
+
+	es := New(opts...)
+
+	for driverID, d := range s.Drivers {
+		totalMilesDriven, err := mathutils.ParseFixedDecimal(d.TotalMilesDriven)
+		if err != nil {
+			return nil, fmt.Errorf("error reading EventStore snapshot: driver '%s': %w", driverID, err)
+		}
+
+		es.driverSummaries[driverID] = &driverSummary{
+			displayName:         d.DisplayName,
+			totalDurationDriven: d.TotalDurationDriven,
+			totalMilesDriven:    totalMilesDriven,
+			inactive:            d.Inactive,
+			previousNames:       d.PreviousNames,
+		}
+	}
+
+	for vehicleID, v := range s.Vehicles {
+		totalMilesDriven, err := mathutils.ParseFixedDecimal(v.TotalMilesDriven)
+		if err != nil {
+			return nil, fmt.Errorf("error reading EventStore snapshot: vehicle '%s': %w", vehicleID, err)
+		}
+
+		es.vehicleSummaries[vehicleID] = &vehicleSummary{
+			class:               v.Class,
+			fuelType:            v.FuelType,
+			totalDurationDriven: v.TotalDurationDriven,
+			totalMilesDriven:    totalMilesDriven,
+		}
+	}
+
+	for tripID, t := range s.Trips {
+		mileage, err := mathutils.ParseFixedDecimal(t.Miles)
+		if err != nil {
+			return nil, fmt.Errorf("error reading EventStore snapshot: trip '%s': %w", tripID, err)
+		}
+
+		es.recordedTrips[tripID] = &tripContribution{
+			driverID:  t.DriverID,
+			vehicleID: t.VehicleID,
+			duration:  t.Duration,
+			mileage:   mileage,
+		}
+	}
+
+	for tripID, t := range s.DiscardedTrips {
+		es.discardedTrips[tripID] = t.DriverID
+	}
+
+	for mergedDriverID, driverID := range s.MergedDrivers {
+		es.mergedDrivers[mergedDriverID] = driverID
+	}
+
+	return es, nil
+}
@@ -0,0 +1,113 @@
+package eventstore_test
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"root.challenge/eventstore"
+)
+
+// recordSorted records the contents of `eventStore`, in a deterministic order.
+func recordSorted(eventStore *eventstore.EventStore) *eventstore.Recorder {
+	recorder := eventstore.NewRecorder()
+	eventStore.Visit(recorder)
+	eventStore.VisitVehicles(recorder)
+
+	sort.Slice(recorder.Entities, func(i, j int) bool {
+		return recorder.Entities[i].DriverID < recorder.Entities[j].DriverID
+	})
+	sort.Slice(recorder.VehicleEntities, func(i, j int) bool {
+		return recorder.VehicleEntities[i].VehicleID < recorder.VehicleEntities[j].VehicleID
+	})
+
+	return recorder
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	original := eventstore.New()
+	for _, err := range []error{
+		original.RegisterDriver(&eventstore.DriverInfo{ID: "r1", DisplayName: "Raphael"}),
+		original.RegisterDriver(&eventstore.DriverInfo{ID: "d1", DisplayName: "Donatello"}),
+		original.RenameDriver(&eventstore.DriverRenameInfo{DriverID: "d1", NewDisplayName: "Don"}),
+		original.DeactivateDriver(&eventstore.DriverInfo{ID: "d1"}),
+		original.RegisterVehicle(&eventstore.VehicleInfo{ID: "V1", Class: "Sedan", FuelType: "Petrol"}),
+		original.RecordTrip(&eventstore.TripInfo{DriverID: "r1", TripDuration: 30 * time.Minute, TripMileage: 17.3,
+			VehicleID: "V1", TripID: "t1"}),
+		original.RecordTrip(&eventstore.TripInfo{DriverID: "r1", TripDuration: 1 * time.Hour, TripMileage: 42.1}),
+		original.DiscardTrip(&eventstore.TripInfo{DriverID: "d1", TripDuration: 1 * time.Hour, TripMileage: 900,
+			TripID: "t2"}),
+		original.RegisterDriver(&eventstore.DriverInfo{ID: "r2", DisplayName: "Raph"}),
+		original.MergeDrivers(&eventstore.DriverMergeInfo{DriverID: "r2", IntoDriverID: "r1"}),
+	} {
+		if err != nil {
+			t.Fatalf("expected: no error, got: %s", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := original.Save(&buf); err != nil {
+		t.Fatalf("expected: no error saving, got: %s", err)
+	}
+
+	loaded, err := eventstore.Load(&buf)
+	if err != nil {
+		t.Fatalf("expected: no error loading, got: %s", err)
+	}
+
+	if expected, actual := recordSorted(original), recordSorted(loaded); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %#v, got: %#v", expected, actual)
+	}
+
+	// Recorded (and discarded) trips survive the round trip, and can thus still be corrected -- as do the IDs
+	// of drivers that were merged away.
+	for _, eventStore := range []*eventstore.EventStore{original, loaded} {
+		if err := eventStore.RecordTrip(&eventstore.TripInfo{DriverID: "r2", TripDuration: 1 * time.Hour,
+			TripMileage: 12}); err != nil {
+			t.Fatalf("expected: no error recording trip, got: %s", err)
+		}
+		if err := eventStore.CancelTrip(&eventstore.TripCancelInfo{TripID: "t1"}); err != nil {
+			t.Fatalf("expected: no error cancelling trip, got: %s", err)
+		}
+		if err := eventStore.AmendTrip(&eventstore.TripInfo{TripDuration: 1 * time.Hour, TripMileage: 30,
+			TripID: "t2"}); err != nil {
+			t.Fatalf("expected: no error amending trip, got: %s", err)
+		}
+	}
+
+	if expected, actual := recordSorted(original), recordSorted(loaded); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %#v, got: %#v", expected, actual)
+	}
+}
+
+func TestLoadRejectsBadSnapshots(t *testing.T) {
+	tests := map[string]string{
+		"Malformed":            `{"version": 1`,
+		"UnsupportedVersion":   `{"version": 99}`,
+		"MissingVersion":       `{}`,
+		"MalformedDriverMiles": `{"version": 1, "drivers": {"r1": {"total_miles_driven": "many"}}}`,
+		"MalformedTripMiles":   `{"version": 1, "trips": {"t1": {"driver_id": "r1", "miles": ""}}}`,
+	}
+
+	for name, snapshot := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := eventstore.Load(strings.NewReader(snapshot)); err == nil {
+				t.Fatalf("expected: error, got: nil")
+			}
+		})
+	}
+}
+
+func TestSaveRejectsParkedTrips(t *testing.T) {
+	eventStore := eventstore.New(eventstore.WithParkedTrips(10))
+	if err := eventStore.RecordTrip(&eventstore.TripInfo{DriverID: "r1", TripDuration: time.Hour, TripMileage: 1}); err != nil {
+		t.Fatalf("expected: trip to be parked, got: %s", err)
+	}
+
+	if err := eventStore.Save(&bytes.Buffer{}); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
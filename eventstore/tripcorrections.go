@@ -0,0 +1,229 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"root.challenge/mathutils"
+)
+
+// tripContribution is everything that a single recorded trip contributed to the totals of its driver (and
+// vehicle), retained so that the trip can later be cancelled or amended exactly.
+type tripContribution struct {
+	driverID  string
+	vehicleID string
+
+	duration time.Duration
+	mileage  mathutils.FixedDecimal
+}
+
+func newTripContribution(tripInfo *TripInfo) (*tripContribution, error) {
+	mileage, err := canonicalTripMileage(tripInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tripContribution{
+		driverID:  tripInfo.DriverID,
+		vehicleID: tripInfo.VehicleID,
+		duration:  tripInfo.TripDuration,
+		mileage:   mileage,
+	}, nil
+}
+
+func (es *EventStore) applyTripContribution(driverSummary *driverSummary, tc *tripContribution) {
+	driverSummary.totalMilesDriven += tc.mileage
+	driverSummary.totalDurationDriven += tc.duration
+
+	if tc.vehicleID != "" {
+		// Register the Vehicle lazily, if needed (see the caveat on lazy Driver registration in `RecordTrip`,
+		// which applies equally here).
+		vehicleSummary := es.vehicleSummaries[tc.vehicleID]
+		if vehicleSummary == nil {
+			vehicleSummary = es.registerVehicle(&VehicleInfo{
+				ID: tc.vehicleID,
+			})
+		}
+
+		vehicleSummary.totalMilesDriven += tc.mileage
+		vehicleSummary.totalDurationDriven += tc.duration
+	}
+}
+
+// revertTripContribution is the exact inverse of `applyTripContribution` (which is guaranteed by distances
+// being `mathutils.FixedDecimal`s).
+func (es *EventStore) revertTripContribution(tc *tripContribution) {
+	if driverSummary := es.driverSummaries[tc.driverID]; driverSummary != nil {
+		driverSummary.totalMilesDriven -= tc.mileage
+		driverSummary.totalDurationDriven -= tc.duration
+	}
+
+	if vehicleSummary := es.vehicleSummaries[tc.vehicleID]; vehicleSummary != nil {
+		vehicleSummary.totalMilesDriven -= tc.mileage
+		vehicleSummary.totalDurationDriven -= tc.duration
+	}
+}
+
+// reattributeTrips points every recorded (or discarded) trip of one driver at another (typically after a
+// merge).
+func (es *EventStore) reattributeTrips(fromDriverID, toDriverID string) {
+	for _, tc := range es.recordedTrips {
+		if tc.driverID == fromDriverID {
+			tc.driverID = toDriverID
+		}
+	}
+
+	for tripID, driverID := range es.discardedTrips {
+		if driverID == fromDriverID {
+			es.discardedTrips[tripID] = toDriverID
+		}
+	}
+}
+
+// ErrUnknownTrip is returned (wrapped) by operations that reference a trip that was never recorded (or
+// that was already cancelled).
+var ErrUnknownTrip = errors.New("unknown trip")
+
+// CancelTrip reverses everything that a previously-recorded trip contributed to the totals of its driver
+// (and vehicle), as if it had never been recorded.
+//
+// Trips that are parked (see `WithParkedTrips`) can be cancelled too, in which case they're simply discarded
+// -- as can trips that were discarded (see `DiscardTrip`), for which there's nothing to reverse.
+func (es *EventStore) CancelTrip(tripCancelInfo *TripCancelInfo) error {
+	if tc, exists := es.recordedTrips[tripCancelInfo.TripID]; exists {
+		es.revertTripContribution(tc)
+		delete(es.recordedTrips, tripCancelInfo.TripID)
+		return nil
+	}
+
+	if es.removeParkedTrip(tripCancelInfo.TripID) != nil {
+		return nil
+	}
+
+	if _, exists := es.discardedTrips[tripCancelInfo.TripID]; exists {
+		delete(es.discardedTrips, tripCancelInfo.TripID)
+		return nil
+	}
+
+	return fmt.Errorf("trip '%s': %w", tripCancelInfo.TripID, ErrUnknownTrip)
+}
+
+// AmendTrip replaces everything that a previously-recorded trip (identified by `TripInfo.TripID`) contributed
+// to the totals of its driver (and vehicle) with the contribution of `tripInfo`.
+//
+// `TripInfo.DriverID` may be left empty to keep the trip attributed to its original driver, and must otherwise
+// reference a registered driver (or one that was merged away, see `MergeDrivers`). Parked trips (see
+// `WithParkedTrips`) can be amended too, and remain parked.
+//
+// Trips that were discarded (see `DiscardTrip`) can be amended too, in which case they're recorded (as per
+// `RecordTrip`) for the first time.
+func (es *EventStore) AmendTrip(tripInfo *TripInfo) error {
+	tripInfo = es.resolveTripDriver(tripInfo)
+	amendedTC, err := newTripContribution(tripInfo)
+	if err != nil {
+		return err
+	}
+
+	if driverID, exists := es.discardedTrips[tripInfo.TripID]; exists {
+		amendedTripInfo := *tripInfo
+		if amendedTripInfo.DriverID == "" {
+			amendedTripInfo.DriverID = driverID
+		}
+
+		delete(es.discardedTrips, tripInfo.TripID)
+		if err := es.RecordTrip(&amendedTripInfo); err != nil {
+			es.discardedTrips[tripInfo.TripID] = driverID
+			return err
+		}
+
+		return nil
+	}
+
+	if tc, exists := es.recordedTrips[tripInfo.TripID]; exists {
+		if amendedTC.driverID == "" {
+			amendedTC.driverID = tc.driverID
+		}
+
+		driverSummary, err := es.lookupDriver(amendedTC.driverID)
+		if err != nil {
+			return err
+		}
+
+		es.revertTripContribution(tc)
+		es.recordTrip(driverSummary, tripInfo.TripID, amendedTC)
+		return nil
+	}
+
+	if pt := es.findParkedTrip(tripInfo.TripID); pt != nil {
+		driverID := pt.tripInfo.DriverID
+		if tripInfo.DriverID != "" && tripInfo.DriverID != driverID {
+			return fmt.Errorf("can't reattribute parked trip '%s' to another driver", tripInfo.TripID)
+		}
+
+		pt.tripInfo = *tripInfo
+		pt.tripInfo.DriverID = driverID
+		pt.contribution = amendedTC
+		pt.contribution.driverID = driverID
+		return nil
+	}
+
+	return fmt.Errorf("trip '%s': %w", tripInfo.TripID, ErrUnknownTrip)
+}
+
+// DiscardTrip notes that a new trip (which `RecordTrip` would otherwise have been called with) was discarded
+// -- typically for being implausible. It contributes nothing to any totals, but remains referenceable by its
+// `TripInfo.TripID` (if it has one), so that it can later be amended (and thus recorded) or cancelled.
+func (es *EventStore) DiscardTrip(tripInfo *TripInfo) error {
+	if tripInfo.TripID == "" {
+		return nil
+	}
+
+	tripInfo = es.resolveTripDriver(tripInfo)
+
+	if es.isKnownTrip(tripInfo.TripID) {
+		return fmt.Errorf("trip '%s' is already recorded", tripInfo.TripID)
+	}
+
+	es.discardedTrips[tripInfo.TripID] = tripInfo.DriverID
+
+	return nil
+}
+
+// DiscardTripAmendment is the `AmendTrip` counterpart of `DiscardTrip` -- it reverses everything that a
+// previously-recorded trip contributed to the totals of its driver (and vehicle), like `CancelTrip`, but keeps
+// the trip referenceable by `TripInfo.TripID` (just like `DiscardTrip` does).
+//
+// Parked trips (see `WithParkedTrips`) stop being parked, and trips that were already discarded remain so.
+func (es *EventStore) DiscardTripAmendment(tripInfo *TripInfo) error {
+	if tc, exists := es.recordedTrips[tripInfo.TripID]; exists {
+		es.revertTripContribution(tc)
+		delete(es.recordedTrips, tripInfo.TripID)
+		es.discardedTrips[tripInfo.TripID] = tc.driverID
+		return nil
+	}
+
+	if pt := es.removeParkedTrip(tripInfo.TripID); pt != nil {
+		es.discardedTrips[tripInfo.TripID] = pt.tripInfo.DriverID
+		return nil
+	}
+
+	if _, exists := es.discardedTrips[tripInfo.TripID]; exists {
+		return nil
+	}
+
+	return fmt.Errorf("trip '%s': %w", tripInfo.TripID, ErrUnknownTrip)
+}
+
+// isKnownTrip returns whether a trip is either recorded, parked or discarded.
+func (es *EventStore) isKnownTrip(tripID string) bool {
+	if _, exists := es.recordedTrips[tripID]; exists {
+		return true
+	}
+
+	if _, exists := es.discardedTrips[tripID]; exists {
+		return true
+	}
+
+	return es.findParkedTrip(tripID) != nil
+}
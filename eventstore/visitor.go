@@ -1,8 +1,9 @@
 package eventstore
 
 import (
-	"log"
 	"time"
+
+	"root.challenge/logging"
 )
 
 // VisitableEntity provides the read-side contract that `EventStore` presents to the other components in
@@ -15,9 +16,14 @@ import (
 // is the responsibility of `Visit` to perform any translation required to map between this and the internal
 // storage format.
 type VisitableEntity struct {
-	DriverFirstName     string
+	DriverID string
+	// DriverDisplayName is never empty (it falls back to `DriverID` for drivers without a display name).
+	DriverDisplayName   string
 	TotalDurationDriven time.Duration
 	TotalMilesDriven    float64
+	DriverInactive      bool
+	// DriverPreviousNames is nil for drivers that have only ever been known by `DriverDisplayName`.
+	DriverPreviousNames []string
 }
 
 // VisitorInterface specifies the expectations of a client that wishes to make use of `Visit`.
@@ -30,27 +36,85 @@ type VisitorInterface interface {
 //
 // See https://en.wikipedia.org/wiki/Visitor_pattern for the benefits of the Visitor design pattern.
 func (es *EventStore) Visit(visitor VisitorInterface) {
-	for driverFirstName, driverSummary := range es.driverSummaries {
+	for driverID, driverSummary := range es.driverSummaries {
 		visitor.Visit(&VisitableEntity{
-			DriverFirstName:     driverFirstName,
+			DriverID:            driverID,
+			DriverDisplayName:   driverSummary.displayNameOr(driverID),
 			TotalDurationDriven: driverSummary.totalDurationDriven,
-			TotalMilesDriven:    driverSummary.totalMilesDriven,
+			TotalMilesDriven:    driverSummary.totalMilesDriven.Float64(),
+			DriverInactive:      driverSummary.inactive,
+			DriverPreviousNames: copyStrings(driverSummary.previousNames),
+		})
+	}
+}
+
+// copyStrings prevents visitors from being able to reach into (and mutate) internal storage.
+func copyStrings(strs []string) []string {
+	if strs == nil {
+		return nil
+	}
+
+	return append(make([]string, 0, len(strs)), strs...)
+}
+
+// VisitableVehicleEntity is the vehicle analogue of `VisitableEntity` (and is subject to the same Maintainer
+// Notes).
+type VisitableVehicleEntity struct {
+	VehicleID           string
+	VehicleClass        string
+	VehicleFuelType     string
+	TotalDurationDriven time.Duration
+	TotalMilesDriven    float64
+}
+
+// VehicleVisitorInterface specifies the expectations of a client that wishes to make use of `VisitVehicles`.
+type VehicleVisitorInterface interface {
+	VisitVehicle(*VisitableVehicleEntity)
+}
+
+// VisitVehicles is the vehicle analogue of `Visit`.
+func (es *EventStore) VisitVehicles(visitor VehicleVisitorInterface) {
+	for vehicleID, vehicleSummary := range es.vehicleSummaries {
+		visitor.VisitVehicle(&VisitableVehicleEntity{
+			VehicleID:           vehicleID,
+			VehicleClass:        vehicleSummary.class,
+			VehicleFuelType:     vehicleSummary.fuelType,
+			TotalDurationDriven: vehicleSummary.totalDurationDriven,
+			TotalMilesDriven:    vehicleSummary.totalMilesDriven.Float64(),
 		})
 	}
 }
 
-// Printer is a handy implementation of `VisitorInterface` to help with debugging during development.
-type Printer struct{}
+// Printer is a handy implementation of `VisitorInterface` to help with debugging during development -- it logs
+// every entity it visits (at `logging.LevelInfo`) to its `logging.Logger`.
+type Printer struct {
+	logger *logging.Logger
+}
 
-// NewPrinter creates a new `Printer`.
-func NewPrinter() *Printer {
-	return &Printer{}
+// NewPrinter creates a new `Printer` that logs to `logger`.
+func NewPrinter(logger *logging.Logger) *Printer {
+	return &Printer{logger: logger}
 }
 
 // Conforms to `VisitorInterface`.
 func (p Printer) Visit(visitableEntity *VisitableEntity) {
-	log.Printf("DriverFirstName: %s TotalDurationDriven: %v TotalMilesDriven: %v\n",
-		visitableEntity.DriverFirstName, visitableEntity.TotalDurationDriven, visitableEntity.TotalMilesDriven)
+	p.logger.Info("driver",
+		logging.String("driver", visitableEntity.DriverID),
+		logging.String("display_name", visitableEntity.DriverDisplayName),
+		logging.Duration("duration_driven", visitableEntity.TotalDurationDriven),
+		logging.Float("miles_driven", visitableEntity.TotalMilesDriven),
+		logging.Any("inactive", visitableEntity.DriverInactive),
+		logging.Any("previous_names", visitableEntity.DriverPreviousNames))
+}
+
+// Conforms to `VehicleVisitorInterface`.
+func (p Printer) VisitVehicle(visitableVehicleEntity *VisitableVehicleEntity) {
+	p.logger.Info("vehicle",
+		logging.String("vehicle", visitableVehicleEntity.VehicleID),
+		logging.String("class", visitableVehicleEntity.VehicleClass),
+		logging.String("fuel_type", visitableVehicleEntity.VehicleFuelType),
+		logging.Duration("duration_driven", visitableVehicleEntity.TotalDurationDriven),
+		logging.Float("miles_driven", visitableVehicleEntity.TotalMilesDriven))
 }
 
 // Recorder is a handy implementation of `VisitorInterface` to provide simple programmatic
@@ -58,13 +122,15 @@ func (p Printer) Visit(visitableEntity *VisitableEntity) {
 type Recorder struct {
 	// Eschew []*VisitableEntity since this is primarily meant for testability, and dealing with pointers
 	// makes error reporting unclear when the actual and the expected outputs don't match.
-	Entities []VisitableEntity
+	Entities        []VisitableEntity
+	VehicleEntities []VisitableVehicleEntity
 }
 
 // NewRecorder creates a new `Recorder`.
 func NewRecorder() *Recorder {
 	return &Recorder{
-		Entities: make([]VisitableEntity, 0),
+		Entities:        make([]VisitableEntity, 0),
+		VehicleEntities: make([]VisitableVehicleEntity, 0),
 	}
 }
 
@@ -72,3 +138,8 @@ func NewRecorder() *Recorder {
 func (r *Recorder) Visit(visitableEntity *VisitableEntity) {
 	r.Entities = append(r.Entities, *visitableEntity)
 }
+
+// Conforms to `VehicleVisitorInterface`.
+func (r *Recorder) VisitVehicle(visitableVehicleEntity *VisitableVehicleEntity) {
+	r.VehicleEntities = append(r.VehicleEntities, *visitableVehicleEntity)
+}
@@ -0,0 +1,222 @@
+package faas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/input"
+)
+
+// Record is a single record of a `Batch`, as delivered by a stream (like a Kinesis shard) to a function.
+//
+// `Data` holds one event per line (in the format read by the `Handler`, see `WithEventReader`), and is
+// base64-encoded in JSON.
+type Record struct {
+	// SequenceNumber uniquely identifies the record within its stream, and orders it within its partition.
+	SequenceNumber string `json:"sequenceNumber"`
+	PartitionKey   string `json:"partitionKey,omitempty"`
+	Data           []byte `json:"data"`
+}
+
+// Batch is the payload of a single invocation of a function -- the records of a single partition, in order.
+type Batch struct {
+	Records []*Record `json:"records"`
+}
+
+// BatchResponse reports which records of a `Batch` failed, in the shape of a partial batch response (so that
+// the stream only redelivers those records, rather than the whole batch).
+type BatchResponse struct {
+	BatchItemFailures []*BatchItemFailure `json:"batchItemFailures"`
+}
+
+// BatchItemFailure identifies a record that failed.
+type BatchItemFailure struct {
+	// ItemIdentifier is the `Record.SequenceNumber` of the record.
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// Handler is a function handler that processes a `Batch` into the `eventstore.EventStore` held by its `Store`.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// A record fails if any of its events fail to process. Streams like Kinesis redeliver a partition from its
+// earliest failed record onwards, so records that succeeded after a failed one are redelivered too -- which
+// is why every event without an ID of its own is given one derived from its record (see `recordEventID`),
+// and why an `eventprocessor.StagedDeduplicator` should be used in production (see
+// `WithStagedDeduplicator`): it makes redeliveries idempotent, and since failed events are recorded as seen
+// too, it keeps events that will never succeed from blocking their partition forever.
+//
+// Failures of the `Store` fail the invocation as a whole (rather than any records), since none of the
+// records can be considered processed if the store they were processed into can't be saved. That's also why
+// event IDs are only recorded as seen once the store is saved -- a deduplicator that records them as soon as
+// they're processed would drop every event of the redelivered batch, and their effects would be lost. An
+// instance crashing between saving the store and committing the event IDs still causes the batch to be
+// applied twice, which is no worse than not deduplicating at all.
+type Handler struct {
+	eventProcessor *eventprocessor.EventProcessor
+	store          Store
+	startReading   func(io.ReadCloser) <-chan *input.EventEnvelope
+	// observe is nil unless set by `WithErrorObserver`.
+	observe func(error)
+	// deduplicator is nil unless set by `WithStagedDeduplicator`.
+	deduplicator *eventprocessor.StagedDeduplicator
+}
+
+// Option customizes a `Handler` created by `NewHandler`.
+type Option func(*Handler)
+
+// WithEventReader makes the `Handler` read the events of records with `startReading` (like
+// `input.StartReadingJSON`), rather than with `input.StartReading`.
+func WithEventReader(startReading func(io.ReadCloser) <-chan *input.EventEnvelope) Option {
+	return func(h *Handler) {
+		h.startReading = startReading
+	}
+}
+
+// WithErrorObserver makes the `Handler` pass every `error` from processing events to `observe`, for example to
+// log it -- including `error`s that can't be attributed to any single record (like failures to commit the
+// event IDs of a batch, see `WithStagedDeduplicator`).
+func WithErrorObserver(observe func(error)) Option {
+	return func(h *Handler) {
+		h.observe = observe
+	}
+}
+
+// WithStagedDeduplicator makes the `Handler` commit the event IDs staged by `deduplicator` (which the
+// `eventprocessor.EventProcessor` of the `Handler` must deduplicate events with, see
+// `eventprocessor.WithDeduplicator`) once the `Store` is saved, and discard them if it can't be.
+//
+// Batches mustn't be handled concurrently then, since they'd commit (or discard) each other's event IDs.
+func WithStagedDeduplicator(deduplicator *eventprocessor.StagedDeduplicator) Option {
+	return func(h *Handler) {
+		h.deduplicator = deduplicator
+	}
+}
+
+// NewHandler creates a new `Handler` that processes events with `eventProcessor` into the
+// `eventstore.EventStore` held by `store`, customized by `opts`.
+func NewHandler(eventProcessor *eventprocessor.EventProcessor, store Store, opts ...Option) *Handler {
+	h := &Handler{
+		eventProcessor: eventProcessor,
+		store:          store,
+		startReading:   input.StartReading,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Handle processes every record of `batch` (in order) into the `eventstore.EventStore` of the `Store` of `h`,
+// and saves it back -- it's the entry point of the function.
+//
+// Records that aren't processed before `ctx` is done are reported as failed (along with the records whose
+// events failed), while an `error` is only returned if the `Store` fails.
+func (h *Handler) Handle(ctx context.Context, batch *Batch) (*BatchResponse, error) {
+	eventStore, err := h.store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading store: %w", err)
+	}
+
+	// Trips parked by a batch would be given up on at its end, without failing the record they're from.
+	if eventStore.ParksTrips() {
+		return nil, errors.New(
+			"error loading store: stores that park trips aren't supported, since every batch is processed on its own")
+	}
+
+	// Lines are numbered across the whole batch (rather than within each record), so that every `error` can
+	// be traced back to its record.
+	var lineRecords []int
+	firstUnprocessedRecord := len(batch.Records)
+
+	eventC := make(chan *input.EventEnvelope)
+	go func() {
+		defer close(eventC)
+
+		for i, record := range batch.Records {
+			if ctx.Err() != nil {
+				firstUnprocessedRecord = i
+				return
+			}
+
+			for eventEnvelope := range h.startReading(ioutil.NopCloser(bytes.NewReader(record.Data))) {
+				if eventEnvelope.ID == "" && eventEnvelope.Err == nil {
+					eventEnvelope.ID = recordEventID(record, eventEnvelope.Line)
+				}
+
+				lineRecords = append(lineRecords, i)
+				eventEnvelope.Line = len(lineRecords)
+
+				eventC <- eventEnvelope
+			}
+		}
+	}()
+
+	// Every record of a batch is from the same partition, which is what its events are logged as coming from.
+	var processOpts []eventprocessor.ProcessOption
+	if len(batch.Records) > 0 && batch.Records[0].PartitionKey != "" {
+		processOpts = append(processOpts, eventprocessor.WithSource(batch.Records[0].PartitionKey))
+	}
+
+	var failedLines []int
+	for err := range h.eventProcessor.Process(eventC, eventStore, processOpts...) {
+		if h.observe != nil {
+			h.observe(err)
+		}
+
+		var processingErr *eventprocessor.ProcessingError
+		if errors.As(err, &processingErr) && processingErr.Line > 0 {
+			failedLines = append(failedLines, processingErr.Line)
+		}
+	}
+
+	// `lineRecords` is only complete (and safe to read) once every event has been processed.
+	failedRecords := make(map[int]bool)
+	for _, line := range failedLines {
+		failedRecords[lineRecords[line-1]] = true
+	}
+
+	// Records processed before `ctx` was done still count, so the store is saved regardless.
+	if err := h.store.Save(eventStore); err != nil {
+		if h.deduplicator != nil {
+			h.deduplicator.Discard()
+		}
+
+		return nil, fmt.Errorf("error saving store: %w", err)
+	}
+
+	// The batch was processed for good by now, so failing to commit its event IDs doesn't fail it -- they're
+	// committed again with the next batch.
+	if h.deduplicator != nil {
+		if err := h.deduplicator.Commit(); err != nil && h.observe != nil {
+			h.observe(fmt.Errorf("error deduplicating batch: %w", err))
+		}
+	}
+
+	response := &BatchResponse{BatchItemFailures: make([]*BatchItemFailure, 0)}
+	for i, record := range batch.Records {
+		if failedRecords[i] || i >= firstUnprocessedRecord {
+			response.BatchItemFailures = append(response.BatchItemFailures,
+				&BatchItemFailure{ItemIdentifier: record.SequenceNumber})
+		}
+	}
+
+	return response, nil
+}
+
+// recordEventID returns the ID of the event on `line` (within `record`) that has no ID of its own -- the
+// sequence number of `record`, qualified by its partition key (since sequence numbers are only unique within
+// a shard, which every partition key maps to exactly one of) and by `line`.
+func recordEventID(record *Record, line int) string {
+	if record.PartitionKey == "" {
+		return fmt.Sprintf("%s/%d", record.SequenceNumber, line)
+	}
+
+	return fmt.Sprintf("%s/%s/%d", record.PartitionKey, record.SequenceNumber, line)
+}
@@ -0,0 +1,249 @@
+package faas_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/eventstore/eventstoretest"
+	"root.challenge/faas"
+	"root.challenge/input"
+)
+
+// newBatch creates a `faas.Batch` with a record for every one of `data`, numbered from 1.
+func newBatch(data ...string) *faas.Batch {
+	batch := &faas.Batch{}
+	for i, d := range data {
+		batch.Records = append(batch.Records, &faas.Record{
+			SequenceNumber: string(rune('1' + i)),
+			Data:           []byte(d),
+		})
+	}
+
+	return batch
+}
+
+func failedItems(response *faas.BatchResponse) []string {
+	items := make([]string, 0, len(response.BatchItemFailures))
+	for _, failure := range response.BatchItemFailures {
+		items = append(items, failure.ItemIdentifier)
+	}
+
+	return items
+}
+
+func TestHandle(t *testing.T) {
+	tests := map[string]struct {
+		opts                []faas.Option
+		batch               *faas.Batch
+		expectedFailedItems []string
+		expectedTotalMiles  map[string]float64
+		expectedNumObserved int
+	}{
+		"AllSucceed": {
+			batch:               newBatch("Driver Dan", "Trip Dan 07:15 07:45 17", "Trip Dan 08:00 09:00 40"),
+			expectedFailedItems: []string{},
+			expectedTotalMiles:  map[string]float64{"Dan": 57},
+		},
+		"PartialFailure": {
+			batch:               newBatch("Driver Dan", "Bogus Dan", "Trip Dan 07:15 07:45 17", "Trip Dan 07:15"),
+			expectedFailedItems: []string{"2", "4"},
+			expectedTotalMiles:  map[string]float64{"Dan": 17},
+			expectedNumObserved: 2,
+		},
+		"MultipleEventsPerRecord": {
+			batch:               newBatch("Driver Dan\nTrip Dan 07:15 07:45 17\n", "Driver Alex\nBogus\n"),
+			expectedFailedItems: []string{"2"},
+			expectedTotalMiles:  map[string]float64{"Dan": 17, "Alex": 0},
+			expectedNumObserved: 1,
+		},
+		"JSONRecords": {
+			opts: []faas.Option{faas.WithEventReader(input.StartReadingJSON)},
+			batch: newBatch(`{"type": "Driver", "args": ["Dan"]}`,
+				`{"type": "Trip", "args": ["Dan", "07:15", "07:45", "17"]}`, `{"type": "Trip"`),
+			expectedFailedItems: []string{"3"},
+			expectedTotalMiles:  map[string]float64{"Dan": 17},
+			expectedNumObserved: 1,
+		},
+		"EmptyBatch": {
+			batch:               newBatch(),
+			expectedFailedItems: []string{},
+			expectedTotalMiles:  map[string]float64{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var observed []error
+			store := faas.NewMemoryStore(eventstore.New())
+			handler := faas.NewHandler(eventprocessor.New(), store, append(tc.opts, faas.WithErrorObserver(func(err error) {
+				observed = append(observed, err)
+			}))...)
+
+			response, err := handler.Handle(context.Background(), tc.batch)
+			if err != nil {
+				t.Fatalf("expected: no error, got: %s", err)
+			}
+
+			if actual := failedItems(response); !reflect.DeepEqual(tc.expectedFailedItems, actual) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedFailedItems, actual)
+			}
+
+			eventStore, _ := store.Load()
+			if actual := eventstoretest.TotalMilesByDriver(eventStore); !reflect.DeepEqual(tc.expectedTotalMiles, actual) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedTotalMiles, actual)
+			}
+
+			if len(observed) != tc.expectedNumObserved {
+				t.Fatalf("expected: %d observed errors, got: %v", tc.expectedNumObserved, observed)
+			}
+		})
+	}
+}
+
+func TestHandleRedeliveriesAreIdempotent(t *testing.T) {
+	deduplicator, err := eventprocessor.NewWindowedDeduplicator(100)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	stagedDeduplicator := eventprocessor.NewStagedDeduplicator(deduplicator)
+	store := faas.NewMemoryStore(eventstore.New())
+	handler := faas.NewHandler(eventprocessor.New(eventprocessor.WithDeduplicator(stagedDeduplicator)), store,
+		faas.WithStagedDeduplicator(stagedDeduplicator))
+
+	batch := newBatch("Driver Dan", "Bogus Dan", "Trip Dan 07:15 07:45 17")
+	response, err := handler.Handle(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if expected, actual := []string{"2"}, failedItems(response); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+
+	// The stream redelivers from the failed record onwards -- which neither double-counts the trip, nor
+	// fails again.
+	redelivery := &faas.Batch{Records: batch.Records[1:]}
+	response, err = handler.Handle(context.Background(), redelivery)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if actual := failedItems(response); len(actual) != 0 {
+		t.Fatalf("expected: no failed items, got: %v", actual)
+	}
+
+	eventStore, _ := store.Load()
+	if expected, actual := map[string]float64{"Dan": 17},
+		eventstoretest.TotalMilesByDriver(eventStore); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestHandleCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	handler := faas.NewHandler(eventprocessor.New(), faas.NewMemoryStore(eventstore.New()))
+
+	response, err := handler.Handle(ctx, newBatch("Driver Dan", "Driver Alex"))
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	// Nothing was processed, so everything is redelivered.
+	actual := failedItems(response)
+	sort.Strings(actual)
+	if expected := []string{"1", "2"}; !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+// failingStore is a `faas.Store` that fails to save.
+type failingStore struct {
+	faas.MemoryStore
+}
+
+func (fs *failingStore) Save(*eventstore.EventStore) error {
+	return errors.New("disk full")
+}
+
+func TestHandleFailsOnStoreFailure(t *testing.T) {
+	store := &failingStore{*faas.NewMemoryStore(eventstore.New())}
+	handler := faas.NewHandler(eventprocessor.New(), store)
+
+	if _, err := handler.Handle(context.Background(), newBatch("Driver Dan")); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
+
+func TestHandleRejectsStoresThatParkTrips(t *testing.T) {
+	store := faas.NewMemoryStore(eventstore.New(eventstore.WithParkedTrips(10)))
+	handler := faas.NewHandler(eventprocessor.New(), store)
+
+	if _, err := handler.Handle(context.Background(), newBatch("Trip Dan 07:15 07:45 17.3")); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
+
+// flakyStore is a `faas.FileStore` that fails to save the first `numFailures` times.
+type flakyStore struct {
+	*faas.FileStore
+	numFailures int
+}
+
+func (fs *flakyStore) Save(eventStore *eventstore.EventStore) error {
+	if fs.numFailures > 0 {
+		fs.numFailures--
+		return errors.New("disk full")
+	}
+
+	return fs.FileStore.Save(eventStore)
+}
+
+func TestHandleRedeliveriesOfUnsavedBatchesAreProcessed(t *testing.T) {
+	deduplicator, err := eventprocessor.NewWindowedDeduplicator(100)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	stagedDeduplicator := eventprocessor.NewStagedDeduplicator(deduplicator)
+	store := &flakyStore{FileStore: faas.NewFileStore(filepath.Join(t.TempDir(), "store.json")), numFailures: 1}
+	handler := faas.NewHandler(eventprocessor.New(eventprocessor.WithDeduplicator(stagedDeduplicator)), store,
+		faas.WithStagedDeduplicator(stagedDeduplicator))
+
+	batch := newBatch("Driver Dan", "Trip Dan 07:15 07:45 17")
+	if _, err := handler.Handle(context.Background(), batch); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+
+	// The whole batch is redelivered -- and since none of it was saved, none of it is a duplicate.
+	response, err := handler.Handle(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if actual := failedItems(response); len(actual) != 0 {
+		t.Fatalf("expected: no failed items, got: %v", actual)
+	}
+
+	eventStore, err := store.Load()
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if expected, actual := map[string]float64{"Dan": 17},
+		eventstoretest.TotalMilesByDriver(eventStore); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+
+	// Once saved, the batch is a duplicate.
+	if _, err := handler.Handle(context.Background(), batch); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if numDuplicates := stagedDeduplicator.NumDuplicates(); numDuplicates != 2 {
+		t.Fatalf("expected: 2 duplicates, got: %d", numDuplicates)
+	}
+}
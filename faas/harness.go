@@ -0,0 +1,123 @@
+package faas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"root.challenge/input"
+)
+
+// Invoke is the raw entry point of a function -- it decodes the JSON `Batch` in `payload`, has `handler`
+// handle it, and encodes the resulting `BatchResponse` as JSON.
+func Invoke(ctx context.Context, handler *Handler, payload []byte) ([]byte, error) {
+	var batch Batch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return nil, fmt.Errorf("error decoding batch: %w", err)
+	}
+
+	response, err := handler.Handle(ctx, &batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(response)
+}
+
+// BatchResult is the outcome of a single invocation made by a `Harness`.
+type BatchResult struct {
+	Batch *Batch
+	// Response is nil if `Err` isn't.
+	Response *BatchResponse
+	Err      error
+}
+
+// Harness invokes a `Handler` locally, with simulated batches of the events of an input -- as if the input
+// had been written to a stream (one event per record), and the stream delivered it to the function.
+type Harness struct {
+	handler      *Handler
+	batchSize    int
+	partitionKey string
+	// nextSequenceNumber keeps increasing across calls to `Replay`, just like in a stream.
+	nextSequenceNumber uint64
+}
+
+// NewHarness creates a `Harness` that invokes `handler` with batches of (at most) `batchSize` records, all
+// from the partition identified by `partitionKey`.
+func NewHarness(handler *Handler, batchSize int, partitionKey string) (*Harness, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("expecting a positive batch size, got %d", batchSize)
+	}
+
+	return &Harness{
+		handler:            handler,
+		batchSize:          batchSize,
+		partitionKey:       partitionKey,
+		nextSequenceNumber: 1,
+	}, nil
+}
+
+// Replay invokes the `Handler` of `h` with every event of `r` (one per line), in batches, passing the
+// outcome of every invocation to `onBatch` -- it stops with an `error` if `r` can't be read, `ctx` is done,
+// or `onBatch` returns one (which is returned as-is).
+//
+// Every line of `r` becomes a record of its own, as split by `input.SplitRecords` (which skips blank lines,
+// and prepends the "#!" directives in the header of `r` to every record).
+//
+// Every batch goes through `Invoke` (and is thus encoded as JSON and back), so that it's handled exactly
+// like a batch delivered by a stream would be.
+func (h *Harness) Replay(ctx context.Context, r io.Reader, onBatch func(*BatchResult) error) error {
+	batch := &Batch{}
+	flush := func() error {
+		if len(batch.Records) == 0 {
+			return nil
+		}
+
+		result := h.invoke(ctx, batch)
+		batch = &Batch{}
+		if err := onBatch(result); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	}
+
+	if err := input.SplitRecords(r, func(data []byte) error {
+		batch.Records = append(batch.Records, &Record{
+			SequenceNumber: fmt.Sprintf("%020d", h.nextSequenceNumber),
+			PartitionKey:   h.partitionKey,
+			Data:           data,
+		})
+		h.nextSequenceNumber++
+
+		if len(batch.Records) == h.batchSize {
+			return flush()
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+func (h *Harness) invoke(ctx context.Context, batch *Batch) *BatchResult {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return &BatchResult{Batch: batch, Err: fmt.Errorf("error encoding batch: %w", err)}
+	}
+
+	responsePayload, err := Invoke(ctx, h.handler, payload)
+	if err != nil {
+		return &BatchResult{Batch: batch, Err: err}
+	}
+
+	var response BatchResponse
+	if err := json.Unmarshal(responsePayload, &response); err != nil {
+		return &BatchResult{Batch: batch, Err: fmt.Errorf("error decoding batch response: %w", err)}
+	}
+
+	return &BatchResult{Batch: batch, Response: &response}
+}
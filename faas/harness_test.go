@@ -0,0 +1,119 @@
+package faas_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/eventstore/eventstoretest"
+	"root.challenge/faas"
+)
+
+func TestHarnessReplay(t *testing.T) {
+	store := faas.NewMemoryStore(eventstore.New())
+	harness, err := faas.NewHarness(faas.NewHandler(eventprocessor.New(), store), 2, "p1")
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	input := "#!units km\n" +
+		"Driver Dan\n" +
+		"\n" +
+		"Trip Dan 07:15 07:45 16.09344\n" +
+		"Bogus\n" +
+		"Trip Dan 08:00 09:00 16.09344\n" +
+		"Trip Dan 10:00 11:00 1000\n"
+
+	var results []*faas.BatchResult
+	if err := harness.Replay(context.Background(), strings.NewReader(input), func(result *faas.BatchResult) error {
+		results = append(results, result)
+		return nil
+	}); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	var batchSizes []int
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("expected: no error, got: %s", result.Err)
+		}
+
+		batchSizes = append(batchSizes, len(result.Batch.Records))
+		failed = append(failed, failedItems(result.Response)...)
+	}
+
+	if expected := []int{2, 2, 1}; !reflect.DeepEqual(expected, batchSizes) {
+		t.Fatalf("expected: batches of %v, got: %v", expected, batchSizes)
+	}
+
+	// Sequence numbers skip blank lines (which aren't records), and the implausible trip is discarded
+	// (rather than failed).
+	if expected := []string{"00000000000000000003"}; !reflect.DeepEqual(expected, failed) {
+		t.Fatalf("expected: %v, got: %v", expected, failed)
+	}
+
+	// The header applies to every record.
+	eventStore, _ := store.Load()
+	if expected, actual := map[string]float64{"Dan": 20},
+		eventstoretest.TotalMilesByDriver(eventStore); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestNewHarnessRejectsBadBatchSize(t *testing.T) {
+	if _, err := faas.NewHarness(faas.NewHandler(eventprocessor.New(), faas.NewMemoryStore(eventstore.New())), 0,
+		"p1"); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
+
+func TestInvokeRejectsMalformedPayload(t *testing.T) {
+	handler := faas.NewHandler(eventprocessor.New(), faas.NewMemoryStore(eventstore.New()))
+
+	if _, err := faas.Invoke(context.Background(), handler, []byte(`{"records": [{"data": "not base64!"}]}`)); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
+
+// Every source replayed by `invoke` gets a `faas.Harness` of its own (with sequence numbers of its own), all
+// sharing a single deduplicator -- so their records must never pass for redeliveries of each other.
+func TestHarnessReplaySourcesWithDeduplication(t *testing.T) {
+	deduplicator, err := eventprocessor.NewWindowedDeduplicator(100)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	stagedDeduplicator := eventprocessor.NewStagedDeduplicator(deduplicator)
+	store := faas.NewMemoryStore(eventstore.New())
+	handler := faas.NewHandler(eventprocessor.New(eventprocessor.WithDeduplicator(stagedDeduplicator)), store,
+		faas.WithStagedDeduplicator(stagedDeduplicator))
+
+	for partitionKey, input := range map[string]string{
+		"a.txt": "Driver Dan\nTrip Dan 07:15 07:45 17\n",
+		"b.txt": "Driver Bob\nTrip Bob 07:15 07:45 20\n",
+	} {
+		harness, err := faas.NewHarness(handler, 10, partitionKey)
+		if err != nil {
+			t.Fatalf("expected: no error, got: %s", err)
+		}
+
+		if err := harness.Replay(context.Background(), strings.NewReader(input), func(result *faas.BatchResult) error {
+			return result.Err
+		}); err != nil {
+			t.Fatalf("expected: no error, got: %s", err)
+		}
+	}
+
+	eventStore, _ := store.Load()
+	if expected, actual := map[string]float64{"Dan": 17, "Bob": 20},
+		eventstoretest.TotalMilesByDriver(eventStore); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+	if numDuplicates := stagedDeduplicator.NumDuplicates(); numDuplicates != 0 {
+		t.Fatalf("expected: no duplicates, got: %d", numDuplicates)
+	}
+}
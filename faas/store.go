@@ -0,0 +1,95 @@
+package faas
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"root.challenge/eventstore"
+)
+
+// Store holds the `eventstore.EventStore` that a `Handler` processes batches into, across invocations --
+// it's what a function backs onto durable storage (like a DynamoDB table), since nothing else outlives an
+// invocation.
+//
+// A `Handler` calls `Load` at the start of every invocation, and `Save` (with whatever `Load` returned) at
+// the end of it.
+type Store interface {
+	Load() (*eventstore.EventStore, error)
+	Save(eventStore *eventstore.EventStore) error
+}
+
+// MemoryStore is a `Store` that keeps a single `eventstore.EventStore` in memory -- like a warm function
+// instance would, until it's recycled.
+type MemoryStore struct {
+	eventStore *eventstore.EventStore
+}
+
+// NewMemoryStore creates a `MemoryStore` holding `eventStore`.
+func NewMemoryStore(eventStore *eventstore.EventStore) *MemoryStore {
+	return &MemoryStore{eventStore: eventStore}
+}
+
+// Conforms to `Store`.
+func (ms *MemoryStore) Load() (*eventstore.EventStore, error) {
+	return ms.eventStore, nil
+}
+
+// Conforms to `Store`.
+func (ms *MemoryStore) Save(eventStore *eventstore.EventStore) error {
+	ms.eventStore = eventStore
+	return nil
+}
+
+// FileStore is a `Store` that loads (and saves) its `eventstore.EventStore` from (and to) a file on every
+// invocation (see `eventstore.EventStore.Save`) -- a local stand-in for durable storage.
+type FileStore struct {
+	path string
+	opts []eventstore.Option
+}
+
+// NewFileStore creates a `FileStore` backed by the file at `path` (which needn't exist yet), whose
+// `eventstore.EventStore`s are customized by `opts`.
+func NewFileStore(path string, opts ...eventstore.Option) *FileStore {
+	return &FileStore{path: path, opts: opts}
+}
+
+// Conforms to `Store`.
+//
+// A file that doesn't exist yet holds an empty `eventstore.EventStore`.
+func (fs *FileStore) Load() (*eventstore.EventStore, error) {
+	f, err := os.Open(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return eventstore.New(fs.opts...), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return eventstore.Load(f, fs.opts...)
+}
+
+// Conforms to `Store`.
+//
+// The file is replaced atomically, so that an invocation that fails midway doesn't corrupt it.
+func (fs *FileStore) Save(eventStore *eventstore.EventStore) error {
+	f, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := eventStore.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing store file %s: %w", f.Name(), err)
+	}
+
+	return os.Rename(f.Name(), fs.path)
+}
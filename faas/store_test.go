@@ -0,0 +1,70 @@
+package faas_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"root.challenge/eventstore"
+	"root.challenge/eventstore/eventstoretest"
+	"root.challenge/faas"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas")
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := faas.NewFileStore(filepath.Join(dir, "store.json"))
+
+	// A missing file holds an empty store.
+	eventStore, err := store.Load()
+	if err != nil {
+		t.Fatalf("expected: no error loading missing file, got: %s", err)
+	}
+	if actual := eventstoretest.TotalMilesByDriver(eventStore); len(actual) != 0 {
+		t.Fatalf("expected: empty store, got: %v", actual)
+	}
+
+	if err := eventStore.RecordTrip(&eventstore.TripInfo{DriverID: "Dan", TripDuration: time.Hour,
+		TripMileage: 40}); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if err := store.Save(eventStore); err != nil {
+		t.Fatalf("expected: no error saving, got: %s", err)
+	}
+
+	reloaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("expected: no error loading, got: %s", err)
+	}
+	if expected, actual := map[string]float64{"Dan": 40},
+		eventstoretest.TotalMilesByDriver(reloaded); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+
+	// No temporary files are left behind.
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected: 1 file, got: %d", len(entries))
+	}
+}
+
+func TestFileStoreRejectsCorruptFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "store")
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("{")
+	f.Close()
+
+	if _, err := faas.NewFileStore(f.Name()).Load(); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+}
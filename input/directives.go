@@ -0,0 +1,132 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"root.challenge/mathutils"
+)
+
+// directivePrefix marks a header line of text input that holds a directive (for example, "#!units km").
+const directivePrefix = "#!"
+
+// FormatVersion identifies the grammar that the lines of text input are written in.
+type FormatVersion string
+
+const (
+	// FormatV1 is the original grammar, where tokens are separated by whitespace and nothing else (see
+	// `strings.Fields`).
+	FormatV1 FormatVersion = "v1"
+	// FormatV2 extends `FormatV1` with double quotes, backslash escapes and comments (see `Tokenize`).
+	//
+	// It's the default, since it reads every `FormatV1` line without a `"`, `\` or leading `#` the same
+	// way -- files that do rely on those being plain characters can declare "#!format v1".
+	FormatV2 FormatVersion = "v2"
+)
+
+// Directives describe how to interpret the `Event`s of an input, as declared by the "#!<name> <value>"
+// lines in the header of text input (that is, before its first `Event`):
+//
+//	#!format v1           -- see `FormatVersion`
+//	#!units km            -- see `mathutils.ParseDistanceUnit`
+//
+// There's no "#!tz" directive, since clock times have no date -- the durations between them are the same in
+// every time zone, so declaring one couldn't change how any `Event` is interpreted (and is rejected rather
+// than silently ignored).
+//
+// The zero value (with an empty `Format`) describes an input without any directives.
+type Directives struct {
+	// Format is the grammar of the `Event`s, and is empty when not declared (which means `FormatV2`).
+	Format FormatVersion
+	// DistanceUnit is the unit of distances that aren't explicitly suffixed with one.
+	DistanceUnit mathutils.DistanceUnit
+}
+
+// FormatVersion returns the grammar of the `Event`s described by `d`, which may be nil.
+func (d *Directives) FormatVersion() FormatVersion {
+	if d == nil || d.Format == "" {
+		return FormatV2
+	}
+
+	return d.Format
+}
+
+// parseDirective applies the directive in `line` (which starts with `directivePrefix`) to `d`, unless it
+// was already declared (as tracked by `declared`).
+func (d *Directives) parseDirective(line string, declared map[string]bool) error {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), directivePrefix))
+	if len(fields) != 2 {
+		return fmt.Errorf("expecting a directive of the form '%s<name> <value>'; got '%s'", directivePrefix, line)
+	}
+
+	name, value := fields[0], fields[1]
+	if declared[name] {
+		return fmt.Errorf("directive '%s' is declared more than once", name)
+	}
+
+	switch name {
+	case "format":
+		switch version := FormatVersion(value); version {
+		case FormatV1, FormatV2:
+			d.Format = version
+		default:
+			return fmt.Errorf("unsupported format version '%s' (expecting '%s' or '%s')", value, FormatV1, FormatV2)
+		}
+	case "units":
+		distanceUnit, err := mathutils.ParseDistanceUnit(value)
+		if err != nil {
+			return fmt.Errorf("invalid units directive: %w", err)
+		}
+		d.DistanceUnit = distanceUnit
+	case "tz":
+		return fmt.Errorf("unsupported directive 'tz' (clock times have no date, so every time zone reads them the same)")
+	default:
+		return fmt.Errorf("unknown directive '%s'", name)
+	}
+
+	declared[name] = true
+	return nil
+}
+
+// SplitRecords calls `onRecord` with every non-blank line of the text input in `r` past its header, with the
+// directive lines of its header (see `Directives`) prepended -- every line ends with a newline -- so that
+// every record can be read on its own (as is the case when every line becomes a record of a stream). The
+// header ends where `StartReading` says it does, so comments in it are skipped rather than ending it. It
+// stops at the first `error` returned by `onRecord`, which is returned as-is.
+func SplitRecords(r io.Reader, onRecord func(data []byte) error) error {
+	var header strings.Builder
+	inHeader := true
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmedLine := strings.TrimSpace(line)
+		if trimmedLine == "" {
+			continue
+		}
+
+		if inHeader {
+			if strings.HasPrefix(trimmedLine, directivePrefix) {
+				header.WriteString(trimmedLine + "\n")
+				continue
+			}
+
+			if strings.HasPrefix(trimmedLine, string(commentChar)) {
+				continue
+			}
+		}
+		inHeader = false
+
+		if err := onRecord([]byte(header.String() + line + "\n")); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading input: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package input_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"root.challenge/input"
+	"root.challenge/mathutils"
+)
+
+func TestStartReadingWithDirectives(t *testing.T) {
+	tests := map[string]struct {
+		input              string
+		expectedDirectives *input.Directives
+		expectedBodies     []string
+		// expectedErrors is the number of `EventEnvelope`s holding errors.
+		expectedErrors int
+	}{
+		"NoDirectives": {
+			input:          "ABC DEF\nGHI",
+			expectedBodies: []string{"ABC DEF", "GHI"},
+		},
+		"AllDirectives": {
+			input:              "#!format v1\n#!units km\nABC DEF",
+			expectedDirectives: &input.Directives{Format: input.FormatV1, DistanceUnit: mathutils.Kilometers},
+			expectedBodies:     []string{"ABC DEF"},
+		},
+		"DirectivesAmongBlankLinesAndComments": {
+			input:              "# exported by partner X\n\n  #!units kilometres\nABC",
+			expectedDirectives: &input.Directives{DistanceUnit: mathutils.Kilometers},
+			expectedBodies:     []string{"# exported by partner X", "", "ABC"},
+		},
+		"DirectiveAfterFirstEvent": {
+			input:          "ABC\n#!units km\nDEF",
+			expectedBodies: []string{"ABC", "DEF"},
+			expectedErrors: 1,
+		},
+		"UnknownDirective": {
+			input:              "#!color blue\nABC",
+			expectedDirectives: &input.Directives{},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+		"DuplicateDirective": {
+			input:              "#!units km\n#!units mi\nABC",
+			expectedDirectives: &input.Directives{DistanceUnit: mathutils.Kilometers},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+		"UnsupportedFormatVersion": {
+			input:              "#!format v9\nABC",
+			expectedDirectives: &input.Directives{},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+		"InvalidUnits": {
+			input:              "#!units furlongs\nABC",
+			expectedDirectives: &input.Directives{},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+		"TimeZone": {
+			input:              "#!tz Europe/Berlin\nABC",
+			expectedDirectives: &input.Directives{},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+		"MalformedDirective": {
+			input:              "#!units\nABC",
+			expectedDirectives: &input.Directives{},
+			expectedBodies:     []string{"ABC"},
+			expectedErrors:     1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualBodies := make([]string, 0)
+			actualErrors := 0
+			for eventEnvelope := range input.StartReading(io.NopCloser(strings.NewReader(tc.input))) {
+				if eventEnvelope.Err != nil {
+					actualErrors++
+					continue
+				}
+
+				actualBodies = append(actualBodies, string(*eventEnvelope.Body))
+
+				// Blank lines and comments in the header may precede its directives, but they hold no
+				// `Event`s to interpret anyway.
+				if tokens, _ := eventEnvelope.Tokens(); len(tokens) == 0 {
+					continue
+				}
+
+				if !reflect.DeepEqual(eventEnvelope.Directives, tc.expectedDirectives) {
+					t.Fatalf("expected: %#v, got: %#v", tc.expectedDirectives, eventEnvelope.Directives)
+				}
+			}
+
+			if actualErrors != tc.expectedErrors {
+				t.Fatalf("expected: %d errors, got: %d", tc.expectedErrors, actualErrors)
+			}
+
+			if !reflect.DeepEqual(actualBodies, tc.expectedBodies) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedBodies, actualBodies)
+			}
+		})
+	}
+}
+
+func TestEventEnvelopeTokens(t *testing.T) {
+	tests := map[string]struct {
+		directives     *input.Directives
+		expectedOutput []string
+	}{
+		"NoDirectives": {
+			expectedOutput: []string{"Driver", "Mary Ann"},
+		},
+		"FormatV2": {
+			directives:     &input.Directives{Format: input.FormatV2},
+			expectedOutput: []string{"Driver", "Mary Ann"},
+		},
+		"FormatV1": {
+			directives:     &input.Directives{Format: input.FormatV1},
+			expectedOutput: []string{"Driver", `"Mary`, `Ann"`},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			eventEnvelope := input.NewEventEnvelopeForBody(input.NewEventFromString(`Driver "Mary Ann"`))
+			eventEnvelope.Directives = tc.directives
+
+			actualOutput, err := eventEnvelope.Tokens()
+			if err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestSplitRecords(t *testing.T) {
+	tests := map[string]struct {
+		input          string
+		expectedOutput []string
+	}{
+		"NoDirectives": {
+			input:          "ABC DEF\n\nGHI",
+			expectedOutput: []string{"ABC DEF\n", "GHI\n"},
+		},
+		"Directives": {
+			input:          "#!units km\n\n#!format v2\nABC\nDEF\n",
+			expectedOutput: []string{"#!units km\n#!format v2\nABC\n", "#!units km\n#!format v2\nDEF\n"},
+		},
+		"DirectivesAmongComments": {
+			input:          "# partner feed\n  #!units km\n# exported today\nABC\n# not in the header\n",
+			expectedOutput: []string{"#!units km\nABC\n", "#!units km\n# not in the header\n"},
+		},
+		"DirectiveAfterFirstEvent": {
+			input:          "ABC\n#!units km\n",
+			expectedOutput: []string{"ABC\n", "#!units km\n"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput := make([]string, 0)
+			if err := input.SplitRecords(strings.NewReader(tc.input), func(data []byte) error {
+				actualOutput = append(actualOutput, string(data))
+				return nil
+			}); err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package input
 
+import "strings"
+
 // Event represents an input event entering the system.
 //
 // While it's a simple `string` for now, having a type definition allows it to grow to be something
@@ -23,6 +25,27 @@ type EventEnvelope struct {
 	// Body is a pointer for future compatibility -- the type definition of `Event` can change and become
 	// meatier, and this allows that change to happen in the codebase in a minimally disruptive manner.
 	Body *Event
+
+	// ID optionally uniquely identifies `Body`, allowing re-deliveries of the same `Event` to be told
+	// apart from distinct (but otherwise identical) `Event`s -- it's empty for `Event`s without an ID.
+	ID string
+
+	// Line is the (1-based) line of the input that `Body` (or `Err`) came from, and is 0 for inputs that
+	// aren't line-based.
+	Line int
+
+	// Directives describe how to interpret `Body`, and is nil for inputs that don't declare any (or
+	// don't support declaring any).
+	Directives *Directives
+}
+
+// Tokens splits `Body` into its tokens according to the `FormatVersion` of `Directives`.
+func (ee *EventEnvelope) Tokens() ([]string, error) {
+	if ee.Directives.FormatVersion() == FormatV1 {
+		return strings.Fields(string(*ee.Body)), nil
+	}
+
+	return Tokenize(*ee.Body)
 }
 
 // NewEventEnvelopeForError is a helper to generate an `EventEnvelope` that contains an `error`.
@@ -38,3 +61,12 @@ func NewEventEnvelopeForBody(event *Event) *EventEnvelope {
 		Body: event,
 	}
 }
+
+// NewEventEnvelopeForBodyWithID is a helper to generate an `EventEnvelope` that contains an `Event`
+// uniquely identified by `id`.
+func NewEventEnvelopeForBodyWithID(event *Event, id string) *EventEnvelope {
+	return &EventEnvelope{
+		Body: event,
+		ID:   id,
+	}
+}
@@ -2,13 +2,43 @@ package input
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 )
 
+// eventIDPrefix marks the optional leading token of a text event that holds the ID of that event (for
+// example, "@e42 Trip Dan 07:15 07:45 17.3").
+const eventIDPrefix = "@"
+
 // StartReading scans `eventSource` for `Event`s in the background, and streams them out
 // (encapsulated in `EventEnvelope`s) over the returned channel.
+//
+// Each line of `eventSource` is an `Event`, optionally preceded by an "@<ID>" token that identifies it --
+// except for the "#!" lines in its header, which hold the `Directives` attached to every `EventEnvelope`.
 func StartReading(eventSource io.ReadCloser) <-chan *EventEnvelope {
+	return startScanning(eventSource, newTextParser().parseLine)
+}
+
+// StartReadingJSON is the equivalent of `StartReading` for structured input -- each line of `eventSource`
+// is a JSON object (see `StructuredEvent`) instead of plain text.
+func StartReadingJSON(eventSource io.ReadCloser) <-chan *EventEnvelope {
+	return startScanning(eventSource, parseJSONLine)
+}
+
+// StructuredEvent is the JSON representation of an `Event` consumed by `StartReadingJSON`, for example:
+//
+//	{"id": "e42", "type": "Trip", "args": ["Dan", "07:15", "07:45", "17.3"]}
+//
+// `ID` is optional, just as it is for text input.
+type StructuredEvent struct {
+	ID   string   `json:"id,omitempty"`
+	Type string   `json:"type"`
+	Args []string `json:"args,omitempty"`
+}
+
+func startScanning(eventSource io.ReadCloser, parseLine func(line string) *EventEnvelope) <-chan *EventEnvelope {
 	eventC := make(chan *EventEnvelope)
 
 	go func() {
@@ -18,9 +48,12 @@ func StartReading(eventSource io.ReadCloser) <-chan *EventEnvelope {
 		defer close(eventC)
 
 		scanner := bufio.NewScanner(eventSource)
-		for scanner.Scan() {
-			body := Event(scanner.Text())
-			eventC <- NewEventEnvelopeForBody(&body)
+		for line := 1; scanner.Scan(); line++ {
+			// Lines that aren't `Event`s (like directives) have no `EventEnvelope`.
+			if eventEnvelope := parseLine(scanner.Text()); eventEnvelope != nil {
+				eventEnvelope.Line = line
+				eventC <- eventEnvelope
+			}
 		}
 
 		if err := scanner.Err(); err != nil {
@@ -32,3 +65,95 @@ func StartReading(eventSource io.ReadCloser) <-chan *EventEnvelope {
 
 	return eventC
 }
+
+// textParser parses the lines of text input, keeping track of its header.
+type textParser struct {
+	// directives is nil until the first directive is declared, and is shared by every subsequent
+	// `EventEnvelope` (so it must not change once the header has ended).
+	directives *Directives
+	declared   map[string]bool
+	// inHeader is true until the first line that holds an `Event`.
+	inHeader bool
+}
+
+func newTextParser() *textParser {
+	return &textParser{
+		declared: make(map[string]bool),
+		inHeader: true,
+	}
+}
+
+func (tp *textParser) parseLine(line string) *EventEnvelope {
+	trimmedLine := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmedLine, directivePrefix) {
+		if !tp.inHeader {
+			return NewEventEnvelopeForError(
+				fmt.Errorf("error reading input: directive '%s' follows the first event", trimmedLine))
+		}
+
+		if tp.directives == nil {
+			tp.directives = &Directives{}
+		}
+		if err := tp.directives.parseDirective(trimmedLine, tp.declared); err != nil {
+			return NewEventEnvelopeForError(fmt.Errorf("error reading input: %w", err))
+		}
+
+		return nil
+	}
+
+	// Blank lines and comments can be interspersed with directives.
+	if trimmedLine != "" && !strings.HasPrefix(trimmedLine, string(commentChar)) {
+		tp.inHeader = false
+	}
+
+	eventEnvelope := parseTextLine(line)
+	eventEnvelope.Directives = tp.directives
+	return eventEnvelope
+}
+
+func parseTextLine(line string) *EventEnvelope {
+	trimmedLine := strings.TrimLeft(line, " \t")
+	if !strings.HasPrefix(trimmedLine, eventIDPrefix) {
+		body := Event(line)
+		return NewEventEnvelopeForBody(&body)
+	}
+
+	idToken, rest := trimmedLine, ""
+	if i := strings.IndexAny(trimmedLine, " \t"); i >= 0 {
+		idToken, rest = trimmedLine[:i], trimmedLine[i+1:]
+	}
+
+	id := strings.TrimPrefix(idToken, eventIDPrefix)
+	if id == "" {
+		return NewEventEnvelopeForError(fmt.Errorf("error reading input: empty event ID in line '%s'", line))
+	}
+
+	body := Event(rest)
+	return NewEventEnvelopeForBodyWithID(&body, id)
+}
+
+func parseJSONLine(line string) *EventEnvelope {
+	// Tolerate blank lines, in keeping with text input.
+	if strings.TrimSpace(line) == "" {
+		body := Event(line)
+		return NewEventEnvelopeForBody(&body)
+	}
+
+	var structuredEvent StructuredEvent
+	if err := json.Unmarshal([]byte(line), &structuredEvent); err != nil {
+		return NewEventEnvelopeForError(fmt.Errorf("error reading input: malformed JSON event '%s': %w", line, err))
+	}
+
+	if structuredEvent.Type == "" {
+		return NewEventEnvelopeForError(fmt.Errorf("error reading input: JSON event '%s' has no type", line))
+	}
+
+	// Args are quoted as needed so that they're tokenized back exactly as they were given (see `Tokenize`).
+	tokens := []string{QuoteToken(structuredEvent.Type)}
+	for _, arg := range structuredEvent.Args {
+		tokens = append(tokens, QuoteToken(arg))
+	}
+
+	body := Event(strings.Join(tokens, " "))
+	return NewEventEnvelopeForBodyWithID(&body, structuredEvent.ID)
+}
@@ -30,6 +30,32 @@ func TestStartReading(t *testing.T) {
 				input.NewEventEnvelopeForBody(input.NewEventFromString("ABC DEF  GHI  JKL")),
 			},
 		},
+		"InputWithEventID": {
+			input: "@e1 ABC DEF",
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC DEF"), "e1"),
+			},
+		},
+		"InputWithIndentedEventID": {
+			input: "  @e1\tABC DEF",
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC DEF"), "e1"),
+			},
+		},
+		"InputWithOnlyEventID": {
+			input: "@e1",
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString(""), "e1"),
+			},
+		},
+		"InputWithMixOfEventsWithAndWithoutIDs": {
+			input: "@e1 ABC\nDEF\n@e2 GHI",
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC"), "e1"),
+				input.NewEventEnvelopeForBody(input.NewEventFromString("DEF")),
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("GHI"), "e2"),
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -39,9 +65,110 @@ func TestStartReading(t *testing.T) {
 				actualOutput = append(actualOutput, eventEnvelope)
 			}
 
+			checkAndClearLines(t, actualOutput)
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+
+	t.Run("EmptyEventID", func(t *testing.T) {
+		for eventEnvelope := range input.StartReading(io.NopCloser(strings.NewReader("@ ABC DEF"))) {
+			if eventEnvelope.Err == nil {
+				t.Fatalf("expected: error, got: %#v", eventEnvelope)
+			}
+		}
+	})
+}
+
+func TestStartReadingJSON(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		// For when an `input.EventEnvelope` with an `error` is expected at that position.
+		expectedErrorIndices []int
+		expectedOutput       []*input.EventEnvelope
+	}{
+		"EmptyInput": {
+			input:          "",
+			expectedOutput: []*input.EventEnvelope{},
+		},
+		"EmptyLineAsInput": {
+			input: "      ",
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBody(input.NewEventFromString("      ")),
+			},
+		},
+		"EventWithoutID": {
+			input: `{"type": "ABC", "args": ["DEF", "GHI"]}`,
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBody(input.NewEventFromString("ABC DEF GHI")),
+			},
+		},
+		"EventWithID": {
+			input: `{"id": "e1", "type": "ABC", "args": ["DEF"]}`,
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC DEF"), "e1"),
+			},
+		},
+		"EventWithArgsThatNeedQuoting": {
+			input: `{"type": "ABC", "args": ["D E", "", "#F", "G\\\"H"]}`,
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBody(input.NewEventFromString(`ABC "D E" "" "#F" "G\\\"H"`)),
+			},
+		},
+		"EventWithoutArgs": {
+			input: `{"id": "e1", "type": "ABC"}`,
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC"), "e1"),
+			},
+		},
+		"MalformedEventsAmongWellFormedOnes": {
+			input:                "{\"id\": \"e1\", \"type\": \"ABC\"}\n{\"id\": \"e2\"\n{\"id\": \"e3\"}\n{\"type\": \"DEF\"}",
+			expectedErrorIndices: []int{1, 2},
+			expectedOutput: []*input.EventEnvelope{
+				input.NewEventEnvelopeForBodyWithID(input.NewEventFromString("ABC"), "e1"),
+				nil,
+				nil,
+				input.NewEventEnvelopeForBody(input.NewEventFromString("DEF")),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput := make([]*input.EventEnvelope, 0)
+			for eventEnvelope := range input.StartReadingJSON(io.NopCloser(strings.NewReader(tc.input))) {
+				actualOutput = append(actualOutput, eventEnvelope)
+			}
+
+			checkAndClearLines(t, actualOutput)
+
+			// Errors are checked for presence rather than content, and blanked out before the comparison.
+			for _, i := range tc.expectedErrorIndices {
+				if i >= len(actualOutput) || actualOutput[i].Err == nil {
+					t.Fatalf("expected: error at index %d, got: %#v", i, actualOutput)
+				}
+				actualOutput[i] = nil
+			}
+
 			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
 				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
 			}
 		})
 	}
 }
+
+// checkAndClearLines checks that every `input.EventEnvelope` in `eventEnvelopes` (each of which is expected to
+// come from its own line) has the right line number, and clears it so that the rest of each envelope can be
+// compared as a whole.
+func checkAndClearLines(t *testing.T, eventEnvelopes []*input.EventEnvelope) {
+	t.Helper()
+
+	for i, eventEnvelope := range eventEnvelopes {
+		if eventEnvelope.Line != i+1 {
+			t.Fatalf("expected: line %d at index %d, got: line %d", i+1, i, eventEnvelope.Line)
+		}
+		eventEnvelope.Line = 0
+	}
+}
@@ -0,0 +1,86 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	quoteChar   = '"'
+	escapeChar  = '\\'
+	commentChar = '#'
+)
+
+// Tokenize splits a text `Event` into its tokens (its `EventType` followed by its args).
+//
+// Tokens are separated by runs of spaces and tabs, like `strings.Fields`, with a few extensions that
+// allow tokens to hold any text (for example, "Driver "Mary Ann"" has 2 tokens):
+//
+//   - Text between double quotes is part of the current token, whitespace and all -- quotes can appear
+//     mid-token (for example, name="Mary Ann"), and "" is an empty token.
+//   - A backslash makes the character following it part of the current token as-is, both inside and outside
+//     of double quotes (for example, \" or \\).
+//   - A "#" at the start of a token comments out the rest of the line.
+//
+// Since none of `"`, `\` and a leading `#` have ever been legitimate in events, the tokens of every `Event`
+// without them are exactly what `strings.Fields` returns.
+//
+// It returns an `error` for unterminated double quotes, and for a backslash at the end of `event`.
+func Tokenize(event Event) ([]string, error) {
+	tokens := make([]string, 0)
+
+	var token strings.Builder
+	// inToken distinguishes an empty token (like "") from the absence of one.
+	inToken := false
+	inQuotes := false
+	escaped := false
+
+	for _, r := range string(event) {
+		switch {
+		case escaped:
+			token.WriteRune(r)
+			escaped = false
+		case r == escapeChar:
+			inToken = true
+			escaped = true
+		case r == quoteChar:
+			inToken = true
+			inQuotes = !inQuotes
+		case inQuotes:
+			token.WriteRune(r)
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		case r == commentChar && !inToken:
+			return tokens, nil
+		default:
+			inToken = true
+			token.WriteRune(r)
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, fmt.Errorf("dangling escape character at the end of event '%s'", event)
+	case inQuotes:
+		return nil, fmt.Errorf("unterminated double quotes in event '%s'", event)
+	case inToken:
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens, nil
+}
+
+// QuoteToken returns `token` in a form that `Tokenize` reads back as the single token `token` -- as-is if
+// possible, and double-quoted (with its double quotes and backslashes escaped) otherwise.
+func QuoteToken(token string) string {
+	if token != "" && !strings.ContainsAny(token, " \t\"\\") && token[0] != commentChar {
+		return token
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(token)
+	return string(quoteChar) + escaped + string(quoteChar)
+}
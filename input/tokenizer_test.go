@@ -0,0 +1,114 @@
+package input_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"root.challenge/input"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := map[string]struct {
+		input          string
+		expectError    bool
+		expectedOutput []string
+	}{
+		"Empty": {
+			input:          "",
+			expectedOutput: []string{},
+		},
+		"OnlyWhitespace": {
+			input:          " \t ",
+			expectedOutput: []string{},
+		},
+		"PlainTokens": {
+			input:          "  Trip\tDan 07:15  07:45 17.3 ",
+			expectedOutput: []string{"Trip", "Dan", "07:15", "07:45", "17.3"},
+		},
+		"QuotedToken": {
+			input:          `Driver "Mary Ann"`,
+			expectedOutput: []string{"Driver", "Mary Ann"},
+		},
+		"QuotesMidToken": {
+			input:          `Driver Mary name="Mary Ann"x`,
+			expectedOutput: []string{"Driver", "Mary", "name=Mary Annx"},
+		},
+		"EmptyQuotedToken": {
+			input:          `A "" B`,
+			expectedOutput: []string{"A", "", "B"},
+		},
+		"EscapesOutsideQuotes": {
+			input:          `A Mary\ Ann \"x\\`,
+			expectedOutput: []string{"A", "Mary Ann", `"x\`},
+		},
+		"EscapesInsideQuotes": {
+			input:          `A "say \"hi\" \\o/"`,
+			expectedOutput: []string{"A", `say "hi" \o/`},
+		},
+		"Comment": {
+			input:          "Driver Dan # registered late",
+			expectedOutput: []string{"Driver", "Dan"},
+		},
+		"CommentedOutLine": {
+			input:          "# Driver Dan",
+			expectedOutput: []string{},
+		},
+		"HashMidToken": {
+			input:          `Driver Dan#1 "#2" \#3`,
+			expectedOutput: []string{"Driver", "Dan#1", "#2", "#3"},
+		},
+		"UnterminatedQuotes": {
+			input:       `Driver "Mary Ann`,
+			expectError: true,
+		},
+		"DanglingEscape": {
+			input:       `Driver Dan\`,
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := input.Tokenize(input.Event(tc.input))
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: %#v", actualOutput)
+			}
+
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+
+			// Events without any of the special characters tokenize exactly like they always have.
+			if !strings.ContainsAny(tc.input, `"\#`) {
+				if fields := strings.Fields(tc.input); !reflect.DeepEqual(actualOutput, fields) {
+					t.Fatalf("expected: %#v (like strings.Fields), got: %#v", fields, actualOutput)
+				}
+			}
+		})
+	}
+}
+
+func TestQuoteToken(t *testing.T) {
+	for _, token := range []string{"Dan", "", "Mary Ann", "a\tb", `say "hi"`, `\o/`, "#1", "Dan#1", "=", `"`} {
+		t.Run(token, func(t *testing.T) {
+			tokens, err := input.Tokenize(input.Event("A " + input.QuoteToken(token)))
+			if err != nil {
+				t.Fatalf("expected: no error, got: %v", err)
+			}
+
+			if expectedOutput := []string{"A", token}; !reflect.DeepEqual(tokens, expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", expectedOutput, tokens)
+			}
+		})
+	}
+
+	if quoted := input.QuoteToken("Dan"); quoted != "Dan" {
+		t.Fatalf("expected: plain tokens to stay as-is, got: %s", quoted)
+	}
+}
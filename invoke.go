@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"root.challenge/eventstore"
+	"root.challenge/faas"
+	"root.challenge/logging"
+)
+
+// runInvoke implements the "invoke" subcommand, which replays an input through the function handler of the
+// `faas` package locally -- as simulated batches of stream records -- and prints the resulting report.
+func runInvoke(args []string) int {
+	flags := newFlagSet("invoke", "[flags] [input file]",
+		"Replays every event in the input (standard input by default) through the serverless function handler, as simulated "+
+			"batches of stream records (one event per record), logs the records of every batch that failed, and prints a report "+
+			"of the drivers (or vehicles).")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	rf := addReportFlags(flags)
+	outputFlag := addOutputFlag(flags)
+	batchSizeFlag := flags.Int("batch-size", 100, "the (maximum) number of records in every batch")
+	storeFlag := flags.String("store", "",
+		"keep the store in this file between invocations (like 'report -save-store' does), instead of in memory")
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	// Event IDs are only recorded as seen once the batch they're in was saved (see `faas.Handler`).
+	pf.stageDeduplication = true
+	pf.batched = true
+	p, err := pf.newPipeline()
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	reportOutputs, err := rf.newReportOutputs(flags, *outputFlag, cfg)
+	if err != nil {
+		logger.Error("invalid report flags", logging.Err(err))
+		return exitFailure
+	}
+
+	var store faas.Store = faas.NewMemoryStore(eventstore.New(p.eventStoreOpts...))
+	if *storeFlag != "" {
+		store = faas.NewFileStore(*storeFlag, p.eventStoreOpts...)
+	}
+
+	inputSources, err := openInputSources(flags, *pf.format, cfg, true)
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+
+	numFailedRecords, ok := invokeSources(inputSources, p, store, *batchSizeFlag)
+	if !ok {
+		return exitFailure
+	}
+
+	eventStore, err := store.Load()
+	if err != nil {
+		logger.Error("failed to load store", logging.Err(err))
+		return exitFailure
+	}
+
+	if err := writeReports(reportOutputs, eventStore); err != nil {
+		logger.Error("failed to write reports", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(numFailedRecords)
+}
+
+// invokeSources replays every one of `inputSources` (in order, each as its own partition) through a
+// `faas.Handler` backed by `p` and `store`, and returns the number of records that failed -- along with
+// false if any invocation failed as a whole (in which case it stops).
+func invokeSources(inputSources []*inputSource, p *pipeline, store faas.Store, batchSize int) (int, bool) {
+	numFailedRecords := 0
+	for _, is := range inputSources {
+		handlerOpts := []faas.Option{
+			faas.WithEventReader(p.instrumentReader(is.startReading)),
+			faas.WithErrorObserver(func(err error) {
+				logProcessingError(err, logging.String("source", is.name))
+			}),
+		}
+		if p.stagedDeduplicator != nil {
+			handlerOpts = append(handlerOpts, faas.WithStagedDeduplicator(p.stagedDeduplicator))
+		}
+
+		handler := faas.NewHandler(p.eventProcessor, store, handlerOpts...)
+
+		harness, err := faas.NewHarness(handler, batchSize, is.name)
+		if err != nil {
+			logger.Error("failed to create harness", logging.Err(err))
+			return numFailedRecords, false
+		}
+
+		numBatches := 0
+		err = harness.Replay(context.Background(), is.file, func(result *faas.BatchResult) error {
+			numBatches++
+			if result.Err != nil {
+				return fmt.Errorf("error invoking handler with batch %d of %s: %w", numBatches, is.name, result.Err)
+			}
+
+			if numFailed := len(result.Response.BatchItemFailures); numFailed > 0 {
+				numFailedRecords += numFailed
+				logger.Warn("batch had failed records", logging.String("source", is.name),
+					logging.Int("batch", numBatches), logging.Int("num_failed", numFailed),
+					logging.Int("num_records", len(result.Batch.Records)))
+			}
+
+			return nil
+		})
+		is.file.Close()
+
+		if err != nil {
+			logger.Error("failed to invoke handler", logging.Err(err))
+			return numFailedRecords, false
+		}
+	}
+
+	return numFailedRecords, true
+}
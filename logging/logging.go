@@ -0,0 +1,239 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log entry -- a `Logger` only writes entries at (or above) its level.
+type Level int32
+
+const (
+	// LevelDebug is for the details of processing individual events, which are only of interest when
+	// debugging.
+	LevelDebug Level = iota
+	// LevelInfo is for the milestones of a run (like inputs being opened, or servers starting).
+	LevelInfo
+	// LevelWarn is for problems that don't stop a run, like events that fail to process.
+	LevelWarn
+	// LevelError is for problems that stop a run.
+	LevelError
+)
+
+// String returns the name of `l` (as accepted by `ParseLevel`).
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+}
+
+// ParseLevel returns the `Level` called `name` (case-insensitively) -- "debug", "info", "warn" (or
+// "warning") or "error".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level '%s' (expecting 'debug', 'info', 'warn' or 'error')", name)
+	}
+}
+
+// Field is a single key/value pair of a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates a `Field` with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int creates a `Field` with an integer value.
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Float creates a `Field` with a floating-point value.
+func Float(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration creates a `Field` with the value of `d` in (fractional) seconds.
+func Duration(key string, d time.Duration) Field {
+	return Field{Key: key, Value: d.Seconds()}
+}
+
+// Err creates the "error" `Field` with the message of `err` (or null, if `err` is nil).
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error"}
+	}
+
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// Any creates a `Field` with any value that `encoding/json` can marshal (anything else is logged as per
+// `fmt.Sprint`).
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger writes leveled, structured log entries to an `io.Writer`, as JSON objects (one per line) such as:
+//
+//	{"time":"2021-06-01T12:00:00Z","level":"warn","msg":"event failed","source":"input.txt","line":3}
+//
+// Entries hold "time", "level" and "msg", followed by the `Field`s of the `Logger` (see `With`) and then
+// those of the entry itself, in order.
+//
+// A nil `*Logger` is valid, and discards everything -- so that components can take an optional `*Logger`
+// without checking for nil. `Logger`s are safe for concurrent use.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// Every `Logger` derived (via `With`) from the same `New` shares its writer and level, so that (say)
+// `SetLevel` on the root `Logger` of a program applies to the per-event `Logger`s handed to handlers too, and
+// entries written concurrently never interleave.
+type Logger struct {
+	core   *core
+	fields []Field
+}
+
+// core is what every `Logger` derived from the same `New` shares.
+type core struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level int32
+	clock func() time.Time
+}
+
+// Option customizes a `Logger` created by `New`.
+type Option func(*core)
+
+// WithClock replaces the source of the time of entries (which is `time.Now` by default) -- this is primarily
+// meant for testing.
+func WithClock(clock func() time.Time) Option {
+	return func(c *core) {
+		c.clock = clock
+	}
+}
+
+// New creates a `Logger` that writes the entries at (or above) `level` to `w`.
+func New(w io.Writer, level Level, opts ...Option) *Logger {
+	c := &core{w: w, level: int32(level), clock: time.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return &Logger{core: c}
+}
+
+// With returns a `Logger` that adds `fields` to every entry (after the fields of `l`).
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	return &Logger{
+		core:   l.core,
+		fields: append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...),
+	}
+}
+
+// SetLevel changes the level of `l` -- along with that of every `Logger` that shares its writer (see the
+// Maintainer Notes on `Logger`).
+func (l *Logger) SetLevel(level Level) {
+	if l != nil {
+		atomic.StoreInt32(&l.core.level, int32(level))
+	}
+}
+
+// Enabled reports whether `l` writes entries at `level` -- which is useful to skip building expensive
+// `Field`s.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && int32(level) >= atomic.LoadInt32(&l.core.level)
+}
+
+// Debug writes an entry at `LevelDebug`.
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(LevelDebug, msg, fields)
+}
+
+// Info writes an entry at `LevelInfo`.
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(LevelInfo, msg, fields)
+}
+
+// Warn writes an entry at `LevelWarn`.
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(LevelWarn, msg, fields)
+}
+
+// Error writes an entry at `LevelError`.
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(LevelError, msg, fields)
+}
+
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, l.core.clock().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, msg)
+	for _, fieldList := range [][]Field{l.fields, fields} {
+		for _, field := range fieldList {
+			buf.WriteByte(',')
+			writeJSON(&buf, field.Key)
+			buf.WriteByte(':')
+			writeJSON(&buf, field.Value)
+		}
+	}
+	buf.WriteString("}\n")
+
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	// There's nowhere to report a failure to log to.
+	l.core.w.Write(buf.Bytes())
+}
+
+// writeJSON writes `value` to `buf` as JSON, falling back to the JSON string of `fmt.Sprint(value)` for values
+// that can't be marshaled (like errors and channels).
+func writeJSON(buf *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buf.Write(encoded)
+}
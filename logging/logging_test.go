@@ -0,0 +1,156 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"root.challenge/logging"
+)
+
+func newTestLogger(buf *bytes.Buffer, level logging.Level) *logging.Logger {
+	return logging.New(buf, level, logging.WithClock(func() time.Time {
+		return time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	}))
+}
+
+func TestLogger(t *testing.T) {
+	tests := map[string]struct {
+		level    logging.Level
+		log      func(l *logging.Logger)
+		expected string
+	}{
+		"Fields": {
+			level: logging.LevelDebug,
+			log: func(l *logging.Logger) {
+				l.Warn("event failed", logging.String("source", "input.txt"), logging.Int("line", 3),
+					logging.Err(errors.New(`bad "args"`)), logging.Float("mph", 12.5),
+					logging.Duration("elapsed", 1500*time.Millisecond), logging.Any("args", []string{"Dan"}))
+			},
+			expected: `{"time":"2021-06-01T12:00:00Z","level":"warn","msg":"event failed","source":"input.txt","line":3,` +
+				`"error":"bad \"args\"","mph":12.5,"elapsed":1.5,"args":["Dan"]}` + "\n",
+		},
+		"With": {
+			level: logging.LevelDebug,
+			log: func(l *logging.Logger) {
+				child := l.With(logging.String("source", "a.txt"))
+				child.With(logging.Int("line", 1)).Debug("handled event", logging.String("event_type", "Trip"))
+				child.Info("done")
+				l.Info("root")
+			},
+			expected: `{"time":"2021-06-01T12:00:00Z","level":"debug","msg":"handled event","source":"a.txt","line":1,"event_type":"Trip"}` + "\n" +
+				`{"time":"2021-06-01T12:00:00Z","level":"info","msg":"done","source":"a.txt"}` + "\n" +
+				`{"time":"2021-06-01T12:00:00Z","level":"info","msg":"root"}` + "\n",
+		},
+		"BelowLevel": {
+			level: logging.LevelWarn,
+			log: func(l *logging.Logger) {
+				l.Debug("debug")
+				l.Info("info")
+				l.Warn("warn")
+				l.Error("error")
+			},
+			expected: `{"time":"2021-06-01T12:00:00Z","level":"warn","msg":"warn"}` + "\n" +
+				`{"time":"2021-06-01T12:00:00Z","level":"error","msg":"error"}` + "\n",
+		},
+		"SetLevelAppliesToDerivedLoggers": {
+			level: logging.LevelError,
+			log: func(l *logging.Logger) {
+				child := l.With(logging.String("k", "v"))
+				child.Info("before")
+				l.SetLevel(logging.LevelInfo)
+				child.Info("after")
+			},
+			expected: `{"time":"2021-06-01T12:00:00Z","level":"info","msg":"after","k":"v"}` + "\n",
+		},
+		"UnmarshalableValue": {
+			level: logging.LevelInfo,
+			log: func(l *logging.Logger) {
+				l.Info("odd", logging.Float("mph", math.NaN()), logging.Err(nil))
+			},
+			expected: `{"time":"2021-06-01T12:00:00Z","level":"info","msg":"odd","mph":"NaN","error":null}` + "\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			test.log(newTestLogger(&buf, test.level))
+
+			if buf.String() != test.expected {
+				t.Fatalf("expected: %q, got: %q", test.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *logging.Logger
+
+	// None of these may panic.
+	l.With(logging.String("k", "v")).Error("error")
+	l.SetLevel(logging.LevelDebug)
+	if l.Enabled(logging.LevelError) {
+		t.Fatalf("expected: a nil logger to be disabled")
+	}
+}
+
+func TestConcurrentEntriesDontInterleave(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, logging.LevelInfo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.With(logging.Int("goroutine", i)).Info("entry", logging.Int("j", j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 1000 {
+		t.Fatalf("expected: 1000 entries, got: %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("expected: valid JSON, got: %q", line)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]struct {
+		name     string
+		expected logging.Level
+		err      bool
+	}{
+		"Debug":   {name: "debug", expected: logging.LevelDebug},
+		"Info":    {name: "INFO", expected: logging.LevelInfo},
+		"Warn":    {name: "warn", expected: logging.LevelWarn},
+		"Warning": {name: "Warning", expected: logging.LevelWarn},
+		"Error":   {name: "error", expected: logging.LevelError},
+		"Unknown": {name: "verbose", err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			level, err := logging.ParseLevel(test.name)
+			if (err != nil) != test.err {
+				t.Fatalf("expected error: %v, got: %v", test.err, err)
+			}
+
+			if level != test.expected {
+				t.Fatalf("expected: %v, got: %v", test.expected, level)
+			}
+		})
+	}
+}
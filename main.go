@@ -1,43 +1,255 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strings"
+	"text/tabwriter"
 
-	"root.challenge/eventprocessor"
-	"root.challenge/eventstore"
-	"root.challenge/input"
-	"root.challenge/output"
+	"root.challenge/eventhandler"
+	"root.challenge/logging"
 )
 
+// Exit codes of the program.
+const (
+	// exitOK means that everything went fine.
+	exitOK = 0
+	// exitEventErrors means that the subcommand ran to completion, but some events failed to process (or to
+	// validate).
+	exitEventErrors = 1
+	// exitFailure means that the subcommand couldn't run to completion (because of bad flags, an input file
+	// that can't be opened, etc.).
+	exitFailure = 2
+)
+
+// logger is the `logging.Logger` of the program, which writes to standard error -- only warnings and errors,
+// unless the -log-level flag (see `newFlagSet`) asks for more (or less).
+var logger = logging.New(os.Stderr, logging.LevelWarn)
+
+// logLevelGiven is true once the -log-level flag has set the level of `logger`, which nothing else should
+// override then.
+var logLevelGiven bool
+
+// subcommand is a mode of operation of the program, selected by its first arg.
+type subcommand struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// subcommands lists every `subcommand` -- "report" runs when the first arg names none of them, so that
+// invocations from before subcommands existed (like "main -units km input.txt") keep working.
+var subcommands = []*subcommand{
+	{name: "report", summary: "process events and print a report (the default when no subcommand is given)", run: runReport},
+	{name: "validate", summary: "check events without processing them", run: runValidate},
+	{name: "stats", summary: "process events and print statistics about the input and its processing", run: runStats},
+	{name: "replay", summary: "rebuild a store saved with -save-store, process more events into it, and print a report", run: runReplay},
+	{name: "invoke", summary: "replay events through the serverless function handler as simulated stream batches, and print a report", run: runInvoke},
+	{name: "produce", summary: "append events to a local partitioned log, keyed by the driver they're about", run: runProduce},
+	{name: "consume", summary: "consume new records of a local partitioned log into a checkpointed store, and print a report", run: runConsume},
+	{name: "serve", summary: "ingest events over HTTP into a live store, and serve reports of it", run: runServe},
+}
+
 func main() {
-	inputFile, err := openInputFile()
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the subcommand selected by `args`, and returns the exit code of the program.
+func run(args []string) int {
+	if len(args) == 0 {
+		return runReport(args)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		// "help <subcommand>" is equivalent to "<subcommand> -h".
+		if len(args) > 1 {
+			if sc := lookupSubcommand(args[1]); sc != nil {
+				return sc.run([]string{"-h"})
+			}
+		}
+
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	if sc := lookupSubcommand(args[0]); sc != nil {
+		return sc.run(args[1:])
+	}
+
+	return runReport(args)
+}
+
+func lookupSubcommand(name string) *subcommand {
+	for _, sc := range subcommands {
+		if sc.name == name {
+			return sc
+		}
+	}
+
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [subcommand] [flags] [input file]\n\n", os.Args[0])
+	fmt.Fprintln(w, "Subcommands:")
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, sc := range subcommands {
+		fmt.Fprintf(tw, "  %s\t%s\n", sc.name, sc.summary)
+	}
+	fmt.Fprintf(tw, "  %s\t%s\n", "help", "print this help (or, followed by a subcommand, the help of that subcommand)")
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nRun '%s <subcommand> -h' for the flags of a subcommand.\n", os.Args[0])
+	fmt.Fprintf(w, "\nExit codes: %d on success, %d if any events failed to process (or to validate), and %d if the "+
+		"subcommand couldn't run to completion (for example, because of bad flags).\n", exitOK, exitEventErrors, exitFailure)
+}
+
+// newFlagSet creates the `flag.FlagSet` of the subcommand called `name`, whose usage lists `argsUsage` and
+// `description` ahead of its flags.
+//
+// Every subcommand takes the -log-level flag, which sets the level of `logger`.
+func newFlagSet(name, argsUsage, description string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s %s\n\n%s\n\nFlags:\n", os.Args[0], name, argsUsage, description)
+		flags.PrintDefaults()
+	}
+	flags.Var(logLevelFlag{}, "log-level",
+		"log entries (as JSON, to standard error) at or above this `level`: 'debug', 'info', 'warn' (the default) or 'error'")
+
+	return flags
+}
+
+// logLevelFlag is the `flag.Value` of the -log-level flag.
+type logLevelFlag struct{}
+
+// Conforms to `flag.Value`.
+func (logLevelFlag) String() string {
+	return logging.LevelWarn.String()
+}
+
+// Conforms to `flag.Value`.
+func (logLevelFlag) Set(value string) error {
+	level, err := logging.ParseLevel(value)
 	if err != nil {
-		log.Fatalf("Error opening input file: %s", err)
-		return
+		return err
+	}
+
+	logger.SetLevel(level)
+	logLevelGiven = true
+	return nil
+}
+
+// parseFlags parses `args` with `flags`, and returns false (along with the exit code of the program) if the
+// subcommand shouldn't run -- because help was asked for, or because `args` are malformed.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+
+		return exitFailure, false
+	}
+
+	// Every subcommand takes (at most) a single input file.
+	if flags.NArg() > 1 {
+		fmt.Fprintf(flags.Output(), "expecting at most 1 input file, got %d: %s\n", flags.NArg(),
+			strings.Join(flags.Args(), " "))
+		flags.Usage()
+		return exitFailure, false
 	}
 
-	eventStore := eventstore.New()
+	return exitOK, true
+}
 
-	for err := range eventprocessor.New().Process(input.StartReading(inputFile), eventStore) {
-		log.Printf("Error processing events: %s", err)
+// exitCodeFor returns the exit code of a subcommand that ran to completion with `numErrors` event errors.
+func exitCodeFor(numErrors int) int {
+	if numErrors > 0 {
+		return exitEventErrors
 	}
 
-	reportGenerator := output.NewReportGenerator()
-	eventStore.Visit(reportGenerator)
-	for _, reportEntry := range reportGenerator.Generate() {
-		fmt.Println(reportEntry)
+	return exitOK
+}
+
+func addOutputFlag(flags *flag.FlagSet) *string {
+	return flags.String("o", "", "write the output to this file (overwriting it) instead of standard output")
+}
+
+// writeOutput calls `write` with the file at `path` (see `addOutputFlag`), or with standard output if `path`
+// is empty.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	return writeFile(path, write)
+}
+
+// writeFile calls `write` with the file at `path`, which is created (or truncated) first.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating output file %s: %w", path, err)
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing output file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func listHandlers(w io.Writer, r *eventhandler.Registry) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "EVENT TYPE\tALIASES\tHANDLER\tHOOKS\tDESCRIPTION")
+	for _, handlerInfo := range r.ListEventHandlers() {
+		hooks := strings.Join(handlerInfo.Hooks, ",")
+		if hooks == "" {
+			hooks = "-"
+		}
+
+		aliases := make([]string, 0, len(handlerInfo.Aliases))
+		for _, alias := range handlerInfo.Aliases {
+			aliases = append(aliases, string(alias))
+		}
+		aliasesStr := strings.Join(aliases, ",")
+		if aliasesStr == "" {
+			aliasesStr = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", handlerInfo.EventType, aliasesStr, handlerInfo.HandlerType, hooks,
+			handlerInfo.Description)
+	}
+	tw.Flush()
+
+	// Usage is only known for handlers that declare an `eventhandler.Schema`.
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "USAGE")
+	for _, handlerInfo := range r.ListEventHandlers() {
+		if handlerInfo.Usage != "" {
+			fmt.Fprintf(w, "  %s\n", handlerInfo.Usage)
+		}
 	}
 }
 
-func openInputFile() (*os.File, error) {
+// openInputFile opens the input file named by the positional `args` left over from parsing flags.
+func openInputFile(args []string) (*os.File, error) {
 	// Default to reading from standard input.
 	inputFile := os.Stdin
 
 	// Give preference to files explicitly specified as input on the command line.
-	if len(os.Args) == 2 {
-		inputFileName := os.Args[1]
+	if len(args) == 1 {
+		inputFileName := args[0]
 
 		f, err := os.Open(inputFileName)
 		if err != nil {
@@ -0,0 +1,136 @@
+package mathutils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FixedDecimal is a signed decimal number with exactly `FixedDecimalPlaces` fractional digits, stored as an
+// integer count of the smallest representable fraction.
+//
+// Unlike floating-point numbers, sums (and differences) of `FixedDecimal`s are exact, which makes them
+// independent of the order in which they're performed -- that makes this the right representation for
+// any long-running aggregate that's rounded for display, where floating-point drift would otherwise
+// eventually change the displayed value.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// With 6 fractional digits, an `int64` can hold magnitudes up to ~9.2 trillion, which is comfortably more
+// than any plausible aggregate of distances; bumping `FixedDecimalPlaces` trades that headroom for precision.
+type FixedDecimal int64
+
+// FixedDecimalPlaces is the number of fractional decimal digits that a `FixedDecimal` retains.
+const FixedDecimalPlaces = 6
+
+// fixedDecimalScale is 10^`FixedDecimalPlaces`.
+const fixedDecimalScale = 1000000
+
+// ParseFixedDecimal parses a plain decimal string (for example, "-17.25") into a `FixedDecimal`, rounding
+// half away from zero beyond `FixedDecimalPlaces` fractional digits.
+//
+// The parse is done digit-by-digit (and never goes through a floating-point intermediate), so it's exact.
+func ParseFixedDecimal(s string) (FixedDecimal, error) {
+	digits := s
+	negative := false
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		negative = digits[0] == '-'
+		digits = digits[1:]
+	}
+
+	integerPart, fractionalPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integerPart, fractionalPart = digits[:i], digits[i+1:]
+	}
+
+	if integerPart == "" && fractionalPart == "" {
+		return 0, fmt.Errorf("failed to parse '%s' as a decimal: no digits", s)
+	}
+
+	var magnitude int64
+	for _, r := range integerPart {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("failed to parse '%s' as a decimal: unexpected character '%c'", s, r)
+		}
+		magnitude = magnitude*10 + int64(r-'0')
+		if magnitude > (1<<63-1)/fixedDecimalScale-1 {
+			return 0, fmt.Errorf("failed to parse '%s' as a decimal: out of range", s)
+		}
+	}
+
+	for i := 0; i < FixedDecimalPlaces; i++ {
+		magnitude *= 10
+		if i < len(fractionalPart) {
+			r := fractionalPart[i]
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("failed to parse '%s' as a decimal: unexpected character '%c'", s, r)
+			}
+			magnitude += int64(r - '0')
+		}
+	}
+
+	if len(fractionalPart) > FixedDecimalPlaces {
+		for _, r := range fractionalPart[FixedDecimalPlaces:] {
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("failed to parse '%s' as a decimal: unexpected character '%c'", s, r)
+			}
+		}
+		if fractionalPart[FixedDecimalPlaces] >= '5' {
+			magnitude++
+		}
+	}
+
+	if negative {
+		return FixedDecimal(-magnitude), nil
+	}
+	return FixedDecimal(magnitude), nil
+}
+
+// FixedDecimalFromFloat64 converts a `float64` to the `FixedDecimal` nearest to the shortest decimal string
+// that uniquely identifies it (so 0.1 becomes exactly 0.1, and not 0.1000000000000000055511151231257827...).
+//
+// It's an `error` for `f` to be a NaN, an infinity or a magnitude beyond what a `FixedDecimal` can hold.
+func FixedDecimalFromFloat64(f float64) (FixedDecimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("failed to convert %v to a decimal: not a finite number", f)
+	}
+
+	// `strconv.FormatFloat` only ever produces digits (and a sign and decimal point) in the 'f' format, so
+	// `ParseFixedDecimal` can only fail on it for being out of range.
+	return ParseFixedDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// FixedDecimalFromFloat32 is the `float32` analogue of `FixedDecimalFromFloat64` -- notably, it goes by the
+// shortest decimal string that identifies the `float32` (so `float32(17.3)` becomes exactly 17.3).
+func FixedDecimalFromFloat32(f float32) (FixedDecimal, error) {
+	return FixedDecimalFromFloat64(WidenFloat32(f))
+}
+
+// Float64 returns the `float64` nearest to `d`.
+func (d FixedDecimal) Float64() float64 {
+	return float64(d) / fixedDecimalScale
+}
+
+// Conforms to `fmt.Stringer`.
+func (d FixedDecimal) String() string {
+	sign := ""
+	magnitude := int64(d)
+	if magnitude < 0 {
+		sign, magnitude = "-", -magnitude
+	}
+
+	return fmt.Sprintf("%s%d.%0*d", sign, magnitude/fixedDecimalScale, FixedDecimalPlaces, magnitude%fixedDecimalScale)
+}
+
+// WidenFloat32 converts a `float32` to the `float64` nearest to the shortest decimal string that uniquely
+// identifies the `float32`, rather than to the `float64` with the identical binary value -- for example,
+// `float32(17.3)` widens to `float64(17.3)`, and not 17.299999237060547.
+//
+// This preserves the precision that the `float32` was originally provided with (typically in textual
+// input), instead of promoting its binary representation error into the wider type.
+func WidenFloat32(f float32) float64 {
+	// `strconv.ParseFloat` can't fail on the output of `strconv.FormatFloat`.
+	f64, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return f64
+}
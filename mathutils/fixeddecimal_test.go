@@ -0,0 +1,237 @@
+package mathutils_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"root.challenge/mathutils"
+)
+
+func TestParseFixedDecimal(t *testing.T) {
+	tests := map[string]struct {
+		input          string
+		expectError    bool
+		expectedOutput mathutils.FixedDecimal
+	}{
+		"Zero":                   {input: "0", expectedOutput: 0},
+		"Integer":                {input: "42", expectedOutput: 42000000},
+		"Fraction":               {input: "17.3", expectedOutput: 17300000},
+		"LeadingDot":             {input: ".5", expectedOutput: 500000},
+		"TrailingDot":            {input: "5.", expectedOutput: 5000000},
+		"Negative":               {input: "-0.25", expectedOutput: -250000},
+		"ExplicitPositive":       {input: "+0.25", expectedOutput: 250000},
+		"AllPlacesUsed":          {input: "1.234567", expectedOutput: 1234567},
+		"ExtraPlacesRoundDown":   {input: "1.2345674", expectedOutput: 1234567},
+		"ExtraPlacesRoundUp":     {input: "1.2345675", expectedOutput: 1234568},
+		"NegativeRoundUp":        {input: "-1.2345675", expectedOutput: -1234568},
+		"Empty":                  {input: "", expectError: true},
+		"OnlySign":               {input: "-", expectError: true},
+		"OnlyDot":                {input: ".", expectError: true},
+		"Letters":                {input: "12a", expectError: true},
+		"LettersInExtraPlaces":   {input: "1.1234567a", expectError: true},
+		"Exponent":               {input: "1e5", expectError: true},
+		"OutOfRange":             {input: "10000000000000", expectError: true},
+		"MultipleDecimalPoints":  {input: "1.2.3", expectError: true},
+		"SignInsideOfTheNumber":  {input: "1-2", expectError: true},
+		"WhitespaceIsNotAllowed": {input: " 1", expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := mathutils.ParseFixedDecimal(tc.input)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error (%v)", actualOutput)
+			}
+
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestFixedDecimalFromFloat64(t *testing.T) {
+	tests := map[string]struct {
+		input          float64
+		expectError    bool
+		expectedOutput mathutils.FixedDecimal
+	}{
+		"OneDecimalPlace":    {input: 0.1, expectedOutput: 100000},
+		"Negative":           {input: -2.5, expectedOutput: -2500000},
+		"LargestMagnitude":   {input: 9223372036853, expectedOutput: 9223372036853000000},
+		"NaN":                {input: math.NaN(), expectError: true},
+		"PositiveInfinity":   {input: math.Inf(+1), expectError: true},
+		"NegativeInfinity":   {input: math.Inf(-1), expectError: true},
+		"OutOfRange":         {input: 1e13, expectError: true},
+		"NegativeOutOfRange": {input: -1e13, expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := mathutils.FixedDecimalFromFloat64(tc.input)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error (%v)", actualOutput)
+			}
+
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestFixedDecimalFromFloat32(t *testing.T) {
+	tests := map[string]struct {
+		input          float32
+		expectError    bool
+		expectedOutput mathutils.FixedDecimal
+	}{
+		"OneDecimalPlace":  {input: 17.3, expectedOutput: 17300000},
+		"TwoDecimalPlaces": {input: 0.07, expectedOutput: 70000},
+		"Infinity":         {input: float32(math.Inf(+1)), expectError: true},
+		"MaxFloat32":       {input: math.MaxFloat32, expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := mathutils.FixedDecimalFromFloat32(tc.input)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error (%v)", actualOutput)
+			}
+
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestFixedDecimalString(t *testing.T) {
+	tests := map[string]struct {
+		input          mathutils.FixedDecimal
+		expectedOutput string
+	}{
+		"Zero":     {input: 0, expectedOutput: "0.000000"},
+		"Positive": {input: 17300000, expectedOutput: "17.300000"},
+		"Negative": {input: -250000, expectedOutput: "-0.250000"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actualOutput := tc.input.String(); actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestWidenFloat32(t *testing.T) {
+	tests := map[string]struct {
+		input          float32
+		expectedOutput float64
+	}{
+		"Zero":          {input: 0.0, expectedOutput: 0.0},
+		"Integer":       {input: 42.0, expectedOutput: 42.0},
+		"OneTenth":      {input: 0.1, expectedOutput: 0.1},
+		"Mileage":       {input: 17.3, expectedOutput: 17.3},
+		"NegativeValue": {input: -56.7, expectedOutput: -56.7},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if actualOutput := mathutils.WidenFloat32(tc.input); actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+// Summing `float32` mileages that were provided with 1 decimal place of precision must always be exact
+// (to that same precision), no matter the number of mileages nor the order in which they're summed.
+func TestFixedDecimalSummationIsExactAndOrderIndependent(t *testing.T) {
+	property := func(tenthsOfMiles []uint16, seed int64) bool {
+		var expectedTenths int64
+		mileages := make([]float32, len(tenthsOfMiles))
+		for i, tenths := range tenthsOfMiles {
+			expectedTenths += int64(tenths)
+			mileages[i] = float32(float64(tenths) / 10)
+		}
+
+		var inOrderSum mathutils.FixedDecimal
+		for _, mileage := range mileages {
+			d, err := mathutils.FixedDecimalFromFloat32(mileage)
+			if err != nil {
+				return false
+			}
+			inOrderSum += d
+		}
+
+		rand.New(rand.NewSource(seed)).Shuffle(len(mileages), func(i, j int) {
+			mileages[i], mileages[j] = mileages[j], mileages[i]
+		})
+
+		var shuffledSum mathutils.FixedDecimal
+		for _, mileage := range mileages {
+			d, err := mathutils.FixedDecimalFromFloat32(mileage)
+			if err != nil {
+				return false
+			}
+			shuffledSum += d
+		}
+
+		return inOrderSum == shuffledSum && inOrderSum == mathutils.FixedDecimal(expectedTenths*100000)
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Removing previously-added values must restore the prior sum exactly.
+func TestFixedDecimalSummationIsReversible(t *testing.T) {
+	property := func(base float32, addends []float32) bool {
+		start, err := mathutils.FixedDecimalFromFloat32(base)
+		if err != nil {
+			// Magnitudes beyond what a `FixedDecimal` can hold are of no interest here.
+			return true
+		}
+
+		decimals := make([]mathutils.FixedDecimal, 0, len(addends))
+		for _, addend := range addends {
+			if d, err := mathutils.FixedDecimalFromFloat32(addend); err == nil {
+				decimals = append(decimals, d)
+			}
+		}
+
+		sum := start
+		for _, d := range decimals {
+			sum += d
+		}
+		for _, d := range decimals {
+			sum -= d
+		}
+
+		return sum == start
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
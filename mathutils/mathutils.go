@@ -11,11 +11,20 @@ func RoundFloat64ToInt64(f float64) int64 {
 }
 
 // ComputeSpeedMph32 computes a `float32` speed in miles/hour given a `float32` mileage and a `time.Duration`.
+//
+// The computation itself is carried out in `float64` (with `mileage` widened via `WidenFloat32`), so the
+// only rounding that the result is subject to is the final narrowing to `float32`.
 func ComputeSpeedMph32(mileage float32, duration time.Duration) float32 {
-	return mileage / (float32(duration) / float32(time.Hour))
+	return float32(ComputeSpeedMph64(WidenFloat32(mileage), duration))
 }
 
 // ComputeSpeedMph64 computes a `float64` speed in miles/hour given a `float64` mileage and a `time.Duration`.
 func ComputeSpeedMph64(mileage float64, duration time.Duration) float64 {
-	return mileage / (float64(duration) / float64(time.Hour))
+	return ComputeSpeedPerHour64(mileage, duration)
+}
+
+// ComputeSpeedPerHour64 computes a `float64` speed given a `float64` distance and a `time.Duration` -- in the
+// unit of `distance` per hour, whichever `DistanceUnit` that is.
+func ComputeSpeedPerHour64(distance float64, duration time.Duration) float64 {
+	return distance / (float64(duration) / float64(time.Hour))
 }
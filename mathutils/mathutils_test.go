@@ -96,3 +96,25 @@ func TestComputeSpeedMph64(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeSpeedPerHour64(t *testing.T) {
+	tests := map[string]struct {
+		inputDistance  float64
+		inputDuration  time.Duration
+		expectedOutput float64
+	}{
+		"HundredInOneHour":     {inputDistance: 100.0, inputDuration: 1 * time.Hour, expectedOutput: 100.0},
+		"FiftyInThirtyMinutes": {inputDistance: 50.0, inputDuration: 30 * time.Minute, expectedOutput: 100.0},
+		"ZeroDistance":         {inputDistance: 0.0, inputDuration: 1 * time.Second, expectedOutput: 0.0},
+		"ZeroDuration":         {inputDistance: 100.0, inputDuration: 0 * time.Second, expectedOutput: float64(math.Inf(+1))},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput := mathutils.ComputeSpeedPerHour64(tc.inputDistance, tc.inputDuration)
+			if !floatsAreEqual64(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
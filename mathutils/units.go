@@ -0,0 +1,133 @@
+package mathutils
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// DistanceUnit identifies the unit that a distance is expressed in.
+//
+// The zero value is `Miles`, which keeps every pre-existing (unit-less) distance in the system meaning
+// exactly what it always has.
+type DistanceUnit int
+
+const (
+	Miles DistanceUnit = iota
+	Kilometers
+)
+
+// KilometersPerMile is the number of kilometers in an international mile (as fixed by the 1959 international
+// yard and pound agreement) -- every conversion between `DistanceUnit`s is derived from it.
+//
+// It's exact as a decimal, but not as a `float64` (which holds the nearest binary value to it) -- see
+// `ConvertDistance` for conversions that are exact.
+const KilometersPerMile = 1.609344
+
+// kilometersPerMileScaled / kilometersPerMileScale is exactly `KilometersPerMile`.
+const (
+	kilometersPerMileScaled = 1609344
+	kilometersPerMileScale  = 1000000
+)
+
+// ParseDistanceUnit maps the commonly-used spellings of a unit (for example, "mi", "miles", "km",
+// "kilometres") to a `DistanceUnit`, ignoring case.
+func ParseDistanceUnit(s string) (DistanceUnit, error) {
+	switch strings.ToLower(s) {
+	case "mi", "mile", "miles":
+		return Miles, nil
+	case "km", "kilometer", "kilometers", "kilometre", "kilometres":
+		return Kilometers, nil
+	}
+
+	return Miles, fmt.Errorf("unrecognized distance unit '%s'", s)
+}
+
+// Symbol returns the short symbol for the unit ("mi" or "km").
+func (u DistanceUnit) Symbol() string {
+	switch u {
+	case Kilometers:
+		return "km"
+	default:
+		return "mi"
+	}
+}
+
+// SpeedSymbol returns the short symbol for speeds measured in the unit per hour ("mph" or "kph").
+func (u DistanceUnit) SpeedSymbol() string {
+	switch u {
+	case Kilometers:
+		return "kph"
+	default:
+		return "mph"
+	}
+}
+
+// Conforms to `fmt.Stringer`.
+func (u DistanceUnit) String() string {
+	return u.Symbol()
+}
+
+// ConvertDistance64 converts a `float64` distance from one `DistanceUnit` to another.
+//
+// Converting between identical units returns `distance` untouched (so there's no floating-point noise
+// introduced for the overwhelmingly common case), and converting across units performs a single
+// multiplication or division by `KilometersPerMile` -- so the result is correctly rounded (and thus
+// deterministic), though not exact (see `ConvertDistance`).
+func ConvertDistance64(distance float64, from, to DistanceUnit) float64 {
+	switch {
+	case from == to:
+		return distance
+	case from == Miles && to == Kilometers:
+		return distance * KilometersPerMile
+	default:
+		return distance / KilometersPerMile
+	}
+}
+
+// ConvertDistance converts a `FixedDecimal` distance from one `DistanceUnit` to another, in integer arithmetic
+// on the exact decimal value of `KilometersPerMile` -- so the result is `distance` converted exactly, then
+// rounded half away from zero to `FixedDecimalPlaces` fractional digits (for example, 1.609344 km is exactly
+// 1 mile, and 100 km is 62.137119 miles).
+//
+// It's an `error` for the result to be beyond what a `FixedDecimal` can hold.
+func ConvertDistance(distance FixedDecimal, from, to DistanceUnit) (FixedDecimal, error) {
+	switch {
+	case from == to:
+		return distance, nil
+	case from == Miles && to == Kilometers:
+		return distance.mulDiv(kilometersPerMileScaled, kilometersPerMileScale)
+	default:
+		return distance.mulDiv(kilometersPerMileScale, kilometersPerMileScaled)
+	}
+}
+
+// mulDiv returns `d` * `numerator` / `denominator` (both of which must be positive), rounded half away from
+// zero -- the product is carried out in 128 bits, so only the result can overflow.
+func (d FixedDecimal) mulDiv(numerator, denominator uint64) (FixedDecimal, error) {
+	magnitude := uint64(d)
+	if d < 0 {
+		magnitude = uint64(-d)
+	}
+
+	hi, lo := bits.Mul64(magnitude, numerator)
+	// `bits.Div64` panics when the quotient doesn't fit in 64 bits.
+	if hi >= denominator {
+		return 0, fmt.Errorf("failed to convert %s: out of range", d)
+	}
+
+	quotient, remainder := bits.Div64(hi, lo, denominator)
+	if remainder >= denominator-remainder {
+		quotient++
+	}
+
+	if quotient > math.MaxInt64 {
+		return 0, fmt.Errorf("failed to convert %s: out of range", d)
+	}
+
+	if d < 0 {
+		return -FixedDecimal(quotient), nil
+	}
+	return FixedDecimal(quotient), nil
+}
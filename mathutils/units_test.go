@@ -0,0 +1,128 @@
+package mathutils_test
+
+import (
+	"testing"
+
+	"root.challenge/mathutils"
+)
+
+func TestParseDistanceUnit(t *testing.T) {
+	tests := map[string]struct {
+		input          string
+		expectError    bool
+		expectedOutput mathutils.DistanceUnit
+	}{
+		"Mi":         {input: "mi", expectedOutput: mathutils.Miles},
+		"Miles":      {input: "Miles", expectedOutput: mathutils.Miles},
+		"Km":         {input: "km", expectedOutput: mathutils.Kilometers},
+		"KM":         {input: "KM", expectedOutput: mathutils.Kilometers},
+		"Kilometres": {input: "kilometres", expectedOutput: mathutils.Kilometers},
+		"Empty":      {input: "", expectError: true},
+		"Unknown":    {input: "furlongs", expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput, err := mathutils.ParseDistanceUnit(tc.input)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestConvertDistance64(t *testing.T) {
+	tests := map[string]struct {
+		input          float64
+		from           mathutils.DistanceUnit
+		to             mathutils.DistanceUnit
+		expectedOutput float64
+	}{
+		// These comparisons are deliberately exact (and not tolerance-based) -- every conversion is a single
+		// correctly-rounded operation, so its result is fully determined, and these all round to the value
+		// they're compared against.
+		"MilesToMiles":               {input: 17.3, from: mathutils.Miles, to: mathutils.Miles, expectedOutput: 17.3},
+		"KilometersToKilometers":     {input: 17.3, from: mathutils.Kilometers, to: mathutils.Kilometers, expectedOutput: 17.3},
+		"OneMileToKilometers":        {input: 1.0, from: mathutils.Miles, to: mathutils.Kilometers, expectedOutput: 1.609344},
+		"OneMileOfKilometersToMiles": {input: 1.609344, from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: 1.0},
+		"MarathonToKilometers":       {input: 26.0, from: mathutils.Miles, to: mathutils.Kilometers, expectedOutput: 41.842944},
+		"HundredMilesOfKilometers":   {input: 160.9344, from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: 100.0},
+		"ZeroMiles":                  {input: 0.0, from: mathutils.Miles, to: mathutils.Kilometers, expectedOutput: 0.0},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actualOutput := mathutils.ConvertDistance64(tc.input, tc.from, tc.to)
+			if actualOutput != tc.expectedOutput {
+				t.Fatalf("expected: %v, got: %v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestConvertDistance(t *testing.T) {
+	tests := map[string]struct {
+		input          string
+		from           mathutils.DistanceUnit
+		to             mathutils.DistanceUnit
+		expectError    bool
+		expectedOutput string
+	}{
+		"MilesToMiles":               {input: "17.3", from: mathutils.Miles, to: mathutils.Miles, expectedOutput: "17.300000"},
+		"OneMileToKilometers":        {input: "1", from: mathutils.Miles, to: mathutils.Kilometers, expectedOutput: "1.609344"},
+		"OneMileOfKilometersToMiles": {input: "1.609344", from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: "1.000000"},
+		"HundredMilesOfKilometers":   {input: "160.9344", from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: "100.000000"},
+		"HundredKilometersToMiles":   {input: "100", from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: "62.137119"},
+		"NegativeKilometersToMiles":  {input: "-100", from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: "-62.137119"},
+		"SmallestMileToKilometers":   {input: "0.000001", from: mathutils.Miles, to: mathutils.Kilometers, expectedOutput: "0.000002"},
+		// 2.552319 km is exactly 1.5859375 miles, which rounds half away from zero (while the nearest `float64`
+		// to the quotient falls just short of the halfway point).
+		"HalfwayKilometersToMiles": {input: "2.552319", from: mathutils.Kilometers, to: mathutils.Miles, expectedOutput: "1.585938"},
+		"OutOfRange":               {input: "9000000000000", from: mathutils.Miles, to: mathutils.Kilometers, expectError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			input, err := mathutils.ParseFixedDecimal(tc.input)
+			if err != nil {
+				t.Fatalf("expected: no error parsing input, got: %v", err)
+			}
+
+			actualOutput, err := mathutils.ConvertDistance(input, tc.from, tc.to)
+			switch {
+			case tc.expectError && err != nil:
+				return
+			case !tc.expectError && err != nil:
+				t.Fatalf("expected: no error, got: %v", err)
+			case tc.expectError && err == nil:
+				t.Fatalf("expected: error, got: no error")
+			}
+
+			if actualOutput.String() != tc.expectedOutput {
+				t.Fatalf("expected: %s, got: %s", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
+
+func TestConvertDistance64RoundTrip(t *testing.T) {
+	for tenths := 0; tenths <= 100000; tenths++ {
+		miles := float64(tenths) / 10
+
+		roundTripped := mathutils.ConvertDistance64(
+			mathutils.ConvertDistance64(miles, mathutils.Miles, mathutils.Kilometers),
+			mathutils.Kilometers, mathutils.Miles)
+		if !floatsAreEqual64(roundTripped, miles) {
+			t.Fatalf("expected: %v, got: %v", miles, roundTripped)
+		}
+	}
+}
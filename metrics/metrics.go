@@ -0,0 +1,364 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format written by `Registry.WriteText`.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds a set of metrics, and exposes them in the Prometheus text exposition format.
+//
+// It (and every metric it holds) is safe for concurrent use.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// This is a deliberately small subset of what the Prometheus client library offers (counters, gauges and
+// histograms, with labels), which is all the system needs -- and keeps it free of dependencies. Metrics are
+// written in the order they were created, and their series in the order of their label values, so that the
+// output is stable.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// metric is a single named metric of a `Registry`.
+type metric interface {
+	name() string
+	writeText(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty `Registry`.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Names are chosen by the code creating metrics, so a clash is a bug.
+	if r.names[m.name()] {
+		panic(fmt.Sprintf("metric %s registered twice", m.name()))
+	}
+
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric of `r` to `w` in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.writeText(bw)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+
+	return nil
+}
+
+// Handler returns an `http.Handler` that serves the metrics of `r` (see `WriteText`), for Prometheus to
+// scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+// desc describes a metric, and the labels of its series.
+type desc struct {
+	metricName string
+	help       string
+	labelNames []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+func (d *desc) writeHeader(w *bufio.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, metricType)
+}
+
+// key joins `labelValues` into the key of their series, panicking unless there's one per label name.
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", d.metricName, len(d.labelNames),
+			len(labelValues)))
+	}
+
+	return strings.Join(labelValues, "\xff")
+}
+
+// labels renders the labels of the series with `key` (along with `extra` labels, if any) as "{...}", or as
+// "" if there are none.
+func (d *desc) labels(key string, extra ...string) string {
+	pairs := make([]string, 0, len(d.labelNames)+len(extra)/2)
+	if len(d.labelNames) > 0 {
+		for i, labelValue := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", d.labelNames[i], escapeLabelValue(labelValue)))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[i], escapeLabelValue(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a metric whose series only ever go up, like the number of events processed.
+type Counter struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates a `Counter` called `name` in `r`, whose series are told apart by `labelNames`.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{
+		desc:   desc{metricName: name, help: help, labelNames: labelNames},
+		values: make(map[string]float64),
+	}
+	r.register(c)
+
+	return c
+}
+
+// Inc adds 1 to the series of `c` with `labelValues` (one per label name, in order).
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds `delta` (which must not be negative) to the series of `c` with `labelValues`.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s can't go down (by %g)", c.metricName, -delta))
+	}
+
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += delta
+}
+
+// Value returns the value of the series of `c` with `labelValues` (0 if it was never added to).
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) writeText(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	// A counter without labels always has its single series, even before it's first added to.
+	if len(c.labelNames) == 0 {
+		fmt.Fprintf(w, "%s %s\n", c.metricName, formatValue(c.values[""]))
+		return
+	}
+
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labels(key), formatValue(c.values[key]))
+	}
+}
+
+// GaugeFunc is a metric without labels whose value can go up and down, like the number of events waiting to
+// be processed -- it's computed when the metric is written.
+type GaugeFunc struct {
+	desc
+	value func() float64
+}
+
+// NewGaugeFunc creates a `GaugeFunc` called `name` in `r`, whose value is returned by `value`.
+func (r *Registry) NewGaugeFunc(name, help string, value func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{metricName: name, help: help}, value: value}
+	r.register(g)
+
+	return g
+}
+
+func (g *GaugeFunc) writeText(w *bufio.Writer) {
+	g.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatValue(g.value()))
+}
+
+// DefBuckets are the upper bounds (in seconds) of the buckets of a `Histogram` of durations -- from 1µs to
+// about 1s, since handling a single event takes microseconds.
+var DefBuckets = ExponentialBuckets(0.000001, 4, 11)
+
+// ExponentialBuckets returns `count` bucket upper bounds, the first of which is `start`, each `factor` times
+// the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		buckets = append(buckets, start)
+		start *= factor
+	}
+
+	return buckets
+}
+
+// Histogram is a metric that samples observations (like durations) into buckets.
+type Histogram struct {
+	desc
+	// buckets are the (sorted) upper bounds of every bucket but the implicit "+Inf" one.
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries is a single series of a `Histogram`.
+type histogramSeries struct {
+	// counts are per bucket (not cumulative), with the "+Inf" bucket last.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a `Histogram` called `name` in `r`, with `buckets` (see `DefBuckets`), whose series
+// are told apart by `labelNames`.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	sortedBuckets := append([]float64(nil), buckets...)
+	sort.Float64s(sortedBuckets)
+
+	h := &Histogram{
+		desc:    desc{metricName: name, help: help, labelNames: labelNames},
+		buckets: sortedBuckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+
+	return h
+}
+
+// Observe adds `value` to the series of `h` with `labelValues` (one per label name, in order).
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+
+	s.counts[sort.SearchFloat64s(h.buckets, value)]++
+	s.sum += value
+	s.count++
+}
+
+// Count returns the number of values observed by the series of `h` with `labelValues`.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) writeText(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, count := range s.counts {
+			upperBound := math.Inf(1)
+			if i < len(h.buckets) {
+				upperBound = h.buckets[i]
+			}
+
+			cumulative += count
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labels(key, "le", formatValue(upperBound)),
+				cumulative)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labels(key), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labels(key), s.count)
+	}
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(labelValue string) string {
+	return labelValueEscaper.Replace(labelValue)
+}
@@ -0,0 +1,144 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"root.challenge/metrics"
+)
+
+func writeText(t *testing.T, r *metrics.Registry) string {
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	return sb.String()
+}
+
+func TestWriteText(t *testing.T) {
+	tests := map[string]struct {
+		record   func(r *metrics.Registry)
+		expected string
+	}{
+		"CounterWithoutLabels": {
+			record: func(r *metrics.Registry) {
+				c := r.NewCounter("things_total", "Things.")
+				c.Inc()
+				c.Add(2.5)
+			},
+			expected: "# HELP things_total Things.\n# TYPE things_total counter\nthings_total 3.5\n",
+		},
+		"UntouchedCounterWithoutLabels": {
+			record: func(r *metrics.Registry) {
+				r.NewCounter("things_total", "Things.")
+			},
+			expected: "# HELP things_total Things.\n# TYPE things_total counter\nthings_total 0\n",
+		},
+		"CounterWithLabels": {
+			record: func(r *metrics.Registry) {
+				c := r.NewCounter("things_total", "Things\\stuff\nmore.", "kind", "color")
+				c.Inc("b", "red")
+				c.Inc("a", `say "hi"`)
+				c.Inc("b", "red")
+			},
+			expected: "# HELP things_total Things\\\\stuff\\nmore.\n# TYPE things_total counter\n" +
+				"things_total{kind=\"a\",color=\"say \\\"hi\\\"\"} 1\nthings_total{kind=\"b\",color=\"red\"} 2\n",
+		},
+		"GaugeFunc": {
+			record: func(r *metrics.Registry) {
+				r.NewGaugeFunc("backlog", "Backlog.", func() float64 { return 7 })
+			},
+			expected: "# HELP backlog Backlog.\n# TYPE backlog gauge\nbacklog 7\n",
+		},
+		"Histogram": {
+			record: func(r *metrics.Registry) {
+				h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.5}, "op")
+				h.Observe(0.25, "read")
+				h.Observe(0.5, "read")
+				h.Observe(3, "read")
+			},
+			expected: "# HELP latency_seconds Latency.\n# TYPE latency_seconds histogram\n" +
+				"latency_seconds_bucket{op=\"read\",le=\"0.5\"} 2\n" +
+				"latency_seconds_bucket{op=\"read\",le=\"1\"} 2\n" +
+				"latency_seconds_bucket{op=\"read\",le=\"+Inf\"} 3\n" +
+				"latency_seconds_sum{op=\"read\"} 3.75\n" +
+				"latency_seconds_count{op=\"read\"} 3\n",
+		},
+		"InOrderOfCreation": {
+			record: func(r *metrics.Registry) {
+				r.NewGaugeFunc("b", "B.", func() float64 { return 1 })
+				r.NewGaugeFunc("a", "A.", func() float64 { return 2 })
+			},
+			expected: "# HELP b B.\n# TYPE b gauge\nb 1\n# HELP a A.\n# TYPE a gauge\na 2\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := metrics.NewRegistry()
+			tc.record(r)
+
+			if actual := writeText(t, r); actual != tc.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMisusePanics(t *testing.T) {
+	for name, misuse := range map[string]func(r *metrics.Registry){
+		"DuplicateName": func(r *metrics.Registry) {
+			r.NewCounter("things_total", "Things.")
+			r.NewGaugeFunc("things_total", "Things.", func() float64 { return 0 })
+		},
+		"MissingLabelValue": func(r *metrics.Registry) {
+			r.NewCounter("things_total", "Things.", "kind").Inc()
+		},
+		"NegativeDelta": func(r *metrics.Registry) {
+			r.NewCounter("things_total", "Things.").Add(-1)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected: panic, got: none")
+				}
+			}()
+
+			misuse(metrics.NewRegistry())
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.NewCounter("things_total", "Things.").Inc()
+
+	ts := httptest.NewServer(r.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != metrics.ContentType ||
+		string(body) != writeText(t, r) {
+		t.Fatalf("expected: metrics, got: %d %s\n%s", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	resp, err = http.Post(ts.URL, "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected: %d, got: %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
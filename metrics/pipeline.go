@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+// Pipeline holds the metrics of the processing pipeline -- from reading events, through processing them, to
+// their handlers -- and hooks into every stage of it to keep them:
+//
+//   - `InstrumentReader` wraps the readers of inputs (like `input.StartReading`)
+//   - `Pipeline` conforms to `eventprocessor.Observer` (see `eventprocessor.WithObserver`)
+//   - `ObserveHandling` is passed to `eventprocessor.Timing`
+//   - `ObserveDiscardedTrip` is passed to `trip.WithDiscardObserver`
+//
+// It's safe for concurrent use.
+type Pipeline struct {
+	eventsRead      *Counter
+	eventsProcessed *Counter
+	errors          *Counter
+	tripsDiscarded  *Counter
+	handlerDuration *Histogram
+
+	// numRead and numProcessed back the backlog gauge.
+	numRead      int64
+	numProcessed int64
+}
+
+// NewPipeline creates the metrics of the processing pipeline in `r`.
+func NewPipeline(r *Registry) *Pipeline {
+	p := &Pipeline{
+		eventsRead: r.NewCounter("events_read_total",
+			"Events read from inputs (including blank and malformed lines)."),
+		eventsProcessed: r.NewCounter("events_processed_total",
+			"Events processed, by event type (empty for lines without a well-formed event of a known type).",
+			"event_type"),
+		errors: r.NewCounter("processing_errors_total",
+			"Errors emitted by processing, by error class.", "class"),
+		tripsDiscarded: r.NewCounter("trips_discarded_total",
+			"Trips discarded for an implausible average speed, by the event type that held them.", "event_type"),
+		handlerDuration: r.NewHistogram("handler_duration_seconds",
+			"How long handlers take to handle events, by event type.", DefBuckets, "event_type"),
+	}
+
+	r.NewGaugeFunc("event_backlog",
+		"Events read from inputs that haven't been processed yet.", func() float64 {
+			return float64(atomic.LoadInt64(&p.numRead) - atomic.LoadInt64(&p.numProcessed))
+		})
+
+	return p
+}
+
+// InstrumentReader wraps `startReading` (like `input.StartReading`) so that it counts every
+// `input.EventEnvelope` it reads.
+func (p *Pipeline) InstrumentReader(
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope) func(io.ReadCloser) <-chan *input.EventEnvelope {
+	return func(eventSource io.ReadCloser) <-chan *input.EventEnvelope {
+		eventC := startReading(eventSource)
+		countedC := make(chan *input.EventEnvelope)
+
+		go func() {
+			defer close(countedC)
+
+			for eventEnvelope := range eventC {
+				p.eventsRead.Inc()
+				atomic.AddInt64(&p.numRead, 1)
+				countedC <- eventEnvelope
+			}
+		}()
+
+		return countedC
+	}
+}
+
+// Conforms to `eventprocessor.Observer`.
+func (p *Pipeline) EventProcessed(eventType eventhandler.EventType) {
+	p.eventsProcessed.Inc(string(eventType))
+	atomic.AddInt64(&p.numProcessed, 1)
+}
+
+// Conforms to `eventprocessor.Observer`.
+func (p *Pipeline) Error(err error) {
+	class := "unknown"
+
+	var processingErr *eventprocessor.ProcessingError
+	if errors.As(err, &processingErr) {
+		class = processingErr.Class.String()
+	}
+
+	p.errors.Inc(class)
+}
+
+// ObserveHandling records how long handling an event took (see `eventprocessor.Timing`).
+func (p *Pipeline) ObserveHandling(invocation *eventprocessor.Invocation, elapsed time.Duration, _ error) {
+	p.handlerDuration.Observe(elapsed.Seconds(), string(invocation.EventType))
+}
+
+// ObserveDiscardedTrip counts a trip discarded by its handler (see `trip.WithDiscardObserver`).
+func (p *Pipeline) ObserveDiscardedTrip(eventType eventhandler.EventType, _ *eventstore.TripInfo) {
+	p.tripsDiscarded.Inc(string(eventType))
+}
@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/metrics"
+)
+
+func TestPipeline(t *testing.T) {
+	r := metrics.NewRegistry()
+	p := metrics.NewPipeline(r)
+
+	registry := eventhandler.NewRegistry()
+	if err := eventprocessor.RegisterBuiltinHandlers(registry, trip.WithDiscardObserver(p.ObserveDiscardedTrip)); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	ep := eventprocessor.New(
+		eventprocessor.WithRegistry(registry),
+		eventprocessor.WithObserver(p),
+		eventprocessor.WithMiddleware(eventprocessor.Timing(p.ObserveHandling)))
+
+	events := strings.Join([]string{
+		"Driver Dan",
+		"",
+		"Trip Dan 07:15 07:45 17.3",
+		"Trip Dan 07:15 07:45 500",
+		"Bogus Dan",
+		"Trip Dan 07:15",
+	}, "\n")
+
+	eventC := p.InstrumentReader(input.StartReading)(ioutil.NopCloser(strings.NewReader(events)))
+	for range ep.Process(eventC, eventstore.New()) {
+	}
+
+	text := writeText(t, r)
+	for _, expectedLine := range []string{
+		"events_read_total 6",
+		// Unknown event types don't get labels of their own.
+		`events_processed_total{event_type=""} 2`,
+		`events_processed_total{event_type="Driver"} 1`,
+		`events_processed_total{event_type="Trip"} 3`,
+		`processing_errors_total{class="precondition"} 1`,
+		`processing_errors_total{class="unknown event type"} 1`,
+		`trips_discarded_total{event_type="Trip"} 1`,
+		`handler_duration_seconds_count{event_type="Driver"} 1`,
+		`handler_duration_seconds_count{event_type="Trip"} 2`,
+		"event_backlog 0",
+	} {
+		if !strings.Contains(text, expectedLine+"\n") {
+			t.Fatalf("expected: %q in metrics, got:\n%s", expectedLine, text)
+		}
+	}
+}
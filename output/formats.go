@@ -0,0 +1,157 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"root.challenge/mathutils"
+)
+
+// ReportEntry is a single (structured) entry of a report, as returned by `GenerateEntries`.
+type ReportEntry struct {
+	// ID uniquely identifies the entry (a driver ID or a vehicle ID).
+	ID string
+	// Label is the human-friendly name of the entry, suffixed with its ID if other entries share it.
+	Label string
+	// Distance is the total distance driven, in `DistanceUnit`.
+	Distance     float64
+	DistanceUnit mathutils.DistanceUnit
+	Duration     time.Duration
+	// AverageSpeed is in `DistanceUnit` per hour, and is 0 if `Duration` is.
+	AverageSpeed float64
+	// FlaggedInactive is only ever set for inactive drivers under `FlagInactiveDrivers`.
+	FlaggedInactive bool
+}
+
+// ReportFormat is a format that `WriteReport` can render a report in.
+type ReportFormat string
+
+const (
+	// TextFormat renders one line per entry (exactly the lines of a `GeneratedReport`).
+	TextFormat ReportFormat = "text"
+	// JSONFormat renders a JSON array with one object per entry.
+	JSONFormat ReportFormat = "json"
+	// CSVFormat renders a header row followed by one row per entry.
+	CSVFormat ReportFormat = "csv"
+)
+
+// ParseReportFormat parses the name of a `ReportFormat`.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch reportFormat := ReportFormat(s); reportFormat {
+	case TextFormat, JSONFormat, CSVFormat:
+		return reportFormat, nil
+	default:
+		return "", fmt.Errorf("unknown report format '%s' (expecting '%s', '%s' or '%s')", s, TextFormat, JSONFormat,
+			CSVFormat)
+	}
+}
+
+// FormatText renders `reportEntries` as a `GeneratedReport`.
+//
+// Distances and speeds are rounded to whole numbers, and speeds are left out for entries that were never
+// driven.
+func FormatText(reportEntries []*ReportEntry) GeneratedReport {
+	generatedReport := make(GeneratedReport, 0, len(reportEntries))
+	for _, re := range reportEntries {
+		// Only display a speed component in the `GeneratedReport` format if there was actually any driving.
+		var averageSpeedDisplayStr string
+		if re.Duration > 0 {
+			averageSpeedDisplayStr = fmt.Sprintf(" @ %v %s", mathutils.RoundFloat64ToInt64(re.AverageSpeed),
+				re.DistanceUnit.SpeedSymbol())
+		}
+
+		var annotation string
+		if re.FlaggedInactive {
+			annotation = " (inactive)"
+		}
+
+		generatedReport = append(generatedReport, fmt.Sprintf("%s: %v %s%s%s",
+			re.Label, mathutils.RoundFloat64ToInt64(re.Distance),
+			distanceDisplayName(re.DistanceUnit), averageSpeedDisplayStr, annotation))
+	}
+
+	return generatedReport
+}
+
+// distanceDisplayName returns how distances in `distanceUnit` are labeled in a `GeneratedReport` -- miles
+// retain their historical spelled-out label, while every other unit uses its symbol.
+func distanceDisplayName(distanceUnit mathutils.DistanceUnit) string {
+	if distanceUnit == mathutils.Miles {
+		return "miles"
+	}
+
+	return distanceUnit.Symbol()
+}
+
+// jsonReportEntry is how a `ReportEntry` is rendered in `JSONFormat` -- rounded exactly like in `TextFormat`,
+// so that every format reports the same numbers.
+type jsonReportEntry struct {
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	Distance int64  `json:"distance"`
+	Unit     string `json:"unit"`
+	// Speed is omitted for entries that were never driven.
+	Speed    *int64 `json:"speed,omitempty"`
+	Inactive bool   `json:"inactive,omitempty"`
+}
+
+// WriteReport renders `reportEntries` to `w` in `reportFormat`.
+func WriteReport(w io.Writer, reportEntries []*ReportEntry, reportFormat ReportFormat) error {
+	switch reportFormat {
+	case TextFormat:
+		for _, line := range FormatText(reportEntries) {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return fmt.Errorf("error writing report: %w", err)
+			}
+		}
+	case JSONFormat:
+		jsonEntries := make([]*jsonReportEntry, 0, len(reportEntries))
+		for _, re := range reportEntries {
+			jsonEntry := &jsonReportEntry{
+				ID:       re.ID,
+				Label:    re.Label,
+				Distance: mathutils.RoundFloat64ToInt64(re.Distance),
+				Unit:     re.DistanceUnit.Symbol(),
+				Inactive: re.FlaggedInactive,
+			}
+			if re.Duration > 0 {
+				speed := mathutils.RoundFloat64ToInt64(re.AverageSpeed)
+				jsonEntry.Speed = &speed
+			}
+			jsonEntries = append(jsonEntries, jsonEntry)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(jsonEntries); err != nil {
+			return fmt.Errorf("error writing report: %w", err)
+		}
+	case CSVFormat:
+		csvWriter := csv.NewWriter(w)
+		_ = csvWriter.Write([]string{"id", "label", "distance", "unit", "speed", "inactive"})
+		for _, re := range reportEntries {
+			var speed string
+			if re.Duration > 0 {
+				speed = strconv.FormatInt(mathutils.RoundFloat64ToInt64(re.AverageSpeed), 10)
+			}
+
+			_ = csvWriter.Write([]string{re.ID, re.Label,
+				strconv.FormatInt(mathutils.RoundFloat64ToInt64(re.Distance), 10), re.DistanceUnit.Symbol(), speed,
+				strconv.FormatBool(re.FlaggedInactive)})
+		}
+
+		// `csv.Writer` defers every write `error` to `Flush`.
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return fmt.Errorf("error writing report: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown report format '%s'", reportFormat)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package output_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"root.challenge/mathutils"
+	"root.challenge/output"
+)
+
+func TestWriteReport(t *testing.T) {
+	reportEntries := []*output.ReportEntry{
+		{ID: "r1", Label: "Raphael", Distance: 29.6, DistanceUnit: mathutils.Miles, Duration: 1 * time.Hour,
+			AverageSpeed: 29.6},
+		{ID: "d1", Label: "Donatello, PhD", DistanceUnit: mathutils.Miles, FlaggedInactive: true},
+	}
+
+	tests := map[string]struct {
+		reportFormat   output.ReportFormat
+		reportEntries  []*output.ReportEntry
+		expectedOutput string
+	}{
+		"Text": {
+			reportFormat:  output.TextFormat,
+			reportEntries: reportEntries,
+			expectedOutput: "Raphael: 30 miles @ 30 mph\n" +
+				"Donatello, PhD: 0 miles (inactive)\n",
+		},
+		"JSON": {
+			reportFormat:  output.JSONFormat,
+			reportEntries: reportEntries,
+			expectedOutput: `[
+  {
+    "id": "r1",
+    "label": "Raphael",
+    "distance": 30,
+    "unit": "mi",
+    "speed": 30
+  },
+  {
+    "id": "d1",
+    "label": "Donatello, PhD",
+    "distance": 0,
+    "unit": "mi",
+    "inactive": true
+  }
+]
+`,
+		},
+		"EmptyJSON": {
+			reportFormat:   output.JSONFormat,
+			reportEntries:  []*output.ReportEntry{},
+			expectedOutput: "[]\n",
+		},
+		"CSV": {
+			reportFormat:  output.CSVFormat,
+			reportEntries: reportEntries,
+			expectedOutput: "id,label,distance,unit,speed,inactive\n" +
+				"r1,Raphael,30,mi,30,false\n" +
+				"d1,\"Donatello, PhD\",0,mi,,true\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := output.WriteReport(&sb, tc.reportEntries, tc.reportFormat); err != nil {
+				t.Fatalf("expected: no error, got: %s", err)
+			}
+
+			if sb.String() != tc.expectedOutput {
+				t.Fatalf("expected: %q, got: %q", tc.expectedOutput, sb.String())
+			}
+		})
+	}
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		if err := output.WriteReport(&strings.Builder{}, reportEntries, "xml"); err == nil {
+			t.Fatalf("expected: error, got: nil")
+		}
+	})
+}
+
+func TestParseReportOptions(t *testing.T) {
+	for _, s := range []string{"text", "json", "csv"} {
+		if reportFormat, err := output.ParseReportFormat(s); err != nil || string(reportFormat) != s {
+			t.Fatalf("expected: %s, got: %s (%v)", s, reportFormat, err)
+		}
+	}
+	if _, err := output.ParseReportFormat("xml"); err == nil {
+		t.Fatalf("expected: error for unknown report format, got: nil")
+	}
+
+	for s, expected := range map[string]output.SortOrder{
+		"distance": output.SortByDistance,
+		"name":     output.SortByName,
+		"speed":    output.SortBySpeed,
+	} {
+		if sortOrder, err := output.ParseSortOrder(s); err != nil || sortOrder != expected {
+			t.Fatalf("expected: %v, got: %v (%v)", expected, sortOrder, err)
+		}
+	}
+	if _, err := output.ParseSortOrder("age"); err == nil {
+		t.Fatalf("expected: error for unknown sort order, got: nil")
+	}
+
+	for s, expected := range map[string]output.InactiveDriverPolicy{
+		"include": output.IncludeInactiveDrivers,
+		"exclude": output.ExcludeInactiveDrivers,
+		"flag":    output.FlagInactiveDrivers,
+	} {
+		if inactiveDriverPolicy, err := output.ParseInactiveDriverPolicy(s); err != nil || inactiveDriverPolicy != expected {
+			t.Fatalf("expected: %v, got: %v (%v)", expected, inactiveDriverPolicy, err)
+		}
+	}
+	if _, err := output.ParseInactiveDriverPolicy("hide"); err == nil {
+		t.Fatalf("expected: error for unknown inactive driver policy, got: nil")
+	}
+}
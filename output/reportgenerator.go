@@ -3,6 +3,7 @@ package output
 import (
 	"container/heap"
 	"fmt"
+	"time"
 
 	"root.challenge/eventstore"
 	"root.challenge/mathutils"
@@ -12,26 +13,134 @@ import (
 //
 // ============================================== Maintainer Notes ==============================================
 //
-// The implementation visits `EventStore` and maintains a max-heap of `eventstore.VisitableEntity`
-// objects (based on their `TotalMilesDriven` field) -- while a simple sort would suffice at small
-// scale, the technique used here should remain fairly performant even at medium scale, and at large
-// scale, the first thing to tweak will likely be capping the size of the max-heap (to control memory
-// usage) and leveraging disk space to store the entire working set, working on sub-sections as needed
-// (akin to an n-way merge sort); once the resource limits of a single machine are hit, the
-// implementation will need to substantially change to run a distributed algorithm.
+// The implementation visits `EventStore` and maintains a max-heap of `rankedEntry` objects (based on their
+// `totalMilesDriven` field by default, see `WithSortOrder`) -- while a simple sort would suffice at small
+// scale, the technique used here should remain fairly performant even at medium scale, and at large scale, the
+// first thing to tweak will likely be capping the size of the max-heap (to control memory usage) and leveraging
+// disk space to store the entire working set, working on sub-sections as needed (akin to an n-way merge sort);
+// once the resource limits of a single machine are hit, the implementation will need to substantially change
+// to run a distributed algorithm.
 type ReportGenerator struct {
-	heapElements []*eventstore.VisitableEntity
+	reportOptions
+
+	entries *rankedEntries
+}
+
+// reportOptions holds the customizations (made via `Option`s) shared by every kind of report generator.
+type reportOptions struct {
+	distanceUnit         mathutils.DistanceUnit
+	inactiveDriverPolicy InactiveDriverPolicy
+	sortOrder            SortOrder
+	topN                 int
+}
+
+// Option customizes the `GeneratedReport` produced by a `ReportGenerator` (or a `VehicleReportGenerator`).
+type Option func(*reportOptions)
+
+// WithDistanceUnit makes the `GeneratedReport` express distances (and speeds) in `distanceUnit`, rather
+// than in the default of miles (and miles/hour).
+func WithDistanceUnit(distanceUnit mathutils.DistanceUnit) Option {
+	return func(ro *reportOptions) {
+		ro.distanceUnit = distanceUnit
+	}
+}
+
+// InactiveDriverPolicy determines how a `ReportGenerator` treats drivers that are inactive.
+type InactiveDriverPolicy int
+
+const (
+	// IncludeInactiveDrivers reports inactive drivers exactly like active ones.
+	IncludeInactiveDrivers InactiveDriverPolicy = iota
+	// ExcludeInactiveDrivers leaves inactive drivers out of the report altogether.
+	ExcludeInactiveDrivers
+	// FlagInactiveDrivers reports inactive drivers, but marks them as such.
+	FlagInactiveDrivers
+)
+
+// WithInactiveDriverPolicy makes a `ReportGenerator` treat inactive drivers as per `inactiveDriverPolicy`,
+// rather than as per the default of `IncludeInactiveDrivers`.
+//
+// This has no effect on a `VehicleReportGenerator`.
+func WithInactiveDriverPolicy(inactiveDriverPolicy InactiveDriverPolicy) Option {
+	return func(ro *reportOptions) {
+		ro.inactiveDriverPolicy = inactiveDriverPolicy
+	}
+}
+
+// ParseInactiveDriverPolicy parses the name of an `InactiveDriverPolicy` ("include", "exclude" or "flag").
+func ParseInactiveDriverPolicy(s string) (InactiveDriverPolicy, error) {
+	switch s {
+	case "include":
+		return IncludeInactiveDrivers, nil
+	case "exclude":
+		return ExcludeInactiveDrivers, nil
+	case "flag":
+		return FlagInactiveDrivers, nil
+	default:
+		return 0, fmt.Errorf("unknown inactive driver policy '%s' (expecting 'include', 'exclude' or 'flag')", s)
+	}
+}
+
+// SortOrder determines the order of the entries of a `GeneratedReport`.
+type SortOrder int
+
+const (
+	// SortByDistance orders entries by descending total distance driven.
+	SortByDistance SortOrder = iota
+	// SortByName orders entries alphabetically by their labels (and then by their IDs).
+	SortByName
+	// SortBySpeed orders entries by descending average speed, with entries that were never driven last.
+	SortBySpeed
+)
+
+// ParseSortOrder parses the name of a `SortOrder` ("distance", "name" or "speed").
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch s {
+	case "distance":
+		return SortByDistance, nil
+	case "name":
+		return SortByName, nil
+	case "speed":
+		return SortBySpeed, nil
+	default:
+		return 0, fmt.Errorf("unknown sort order '%s' (expecting 'distance', 'name' or 'speed')", s)
+	}
+}
+
+// WithSortOrder orders the `GeneratedReport` as per `sortOrder`, rather than as per the default of
+// `SortByDistance`.
+func WithSortOrder(sortOrder SortOrder) Option {
+	return func(ro *reportOptions) {
+		ro.sortOrder = sortOrder
+	}
+}
+
+// WithTopN caps the `GeneratedReport` to its first `n` entries (in the order set by `WithSortOrder`), rather
+// than reporting every entry -- `n` <= 0 means no cap.
+func WithTopN(n int) Option {
+	return func(ro *reportOptions) {
+		ro.topN = n
+	}
 }
 
-// NewReportGenerator creates a new `ReportGenerator`.
+func newReportOptions(opts []Option) reportOptions {
+	var ro reportOptions
+	for _, opt := range opts {
+		opt(&ro)
+	}
+
+	return ro
+}
+
+// NewReportGenerator creates a new `ReportGenerator`, customized by `opts`.
 //
 // This is where the (empty) max-heap is initialized.
-func NewReportGenerator() *ReportGenerator {
+func NewReportGenerator(opts ...Option) *ReportGenerator {
 	rg := &ReportGenerator{
-		heapElements: make([]*eventstore.VisitableEntity, 0),
+		reportOptions: newReportOptions(opts),
 	}
 
-	heap.Init(rg)
+	rg.entries = newRankedEntries(rg.sortOrder)
 
 	return rg
 }
@@ -41,62 +150,160 @@ type GeneratedReport []string
 
 // Generate returns a `GeneratedReport` containing the desired output-ready summary information.
 //
-// This is where we pop from the max-heap, retrieving the elements in descending order of
-// `TotalMilesDriven`.
+// This is where we pop from the max-heap, retrieving the elements in the desired order (descending order of
+// `TotalMilesDriven` by default).
 func (rg *ReportGenerator) Generate() GeneratedReport {
-	generatedReport := make(GeneratedReport, 0)
+	return FormatText(rg.GenerateEntries())
+}
 
-	for len(rg.heapElements) > 0 {
-		ve := heap.Pop(rg).(*eventstore.VisitableEntity)
+// GenerateEntries returns the structured counterpart of what `Generate` returns, for rendering in formats
+// other than text (see `WriteReport`).
+//
+// Like `Generate`, it drains the entries visited so far, so only one of them can be called (once).
+func (rg *ReportGenerator) GenerateEntries() []*ReportEntry {
+	return rg.generate(rg.entries)
+}
 
-		// Only display a speed component in the `GeneratedReport` format if the driver actually drove.
-		var averageSpeedDisplayStr string
-		if ve.TotalDurationDriven > 0 {
-			averageSpeedDisplayStr = fmt.Sprintf(" @ %v mph", mathutils.RoundFloat64ToInt64(
-				mathutils.ComputeSpeedMph64(ve.TotalMilesDriven, ve.TotalDurationDriven)))
+// Conforms to `eventstore.VisitorInterface`.
+//
+// This is where we push to the max-heap, building up the sorted data structure.
+func (rg *ReportGenerator) Visit(visitableEntity *eventstore.VisitableEntity) {
+	var flaggedInactive bool
+	if visitableEntity.DriverInactive {
+		switch rg.inactiveDriverPolicy {
+		case ExcludeInactiveDrivers:
+			return
+		case FlagInactiveDrivers:
+			flaggedInactive = true
 		}
-
-		generatedReport = append(generatedReport, fmt.Sprintf("%s: %v miles%s",
-			ve.DriverFirstName, mathutils.RoundFloat64ToInt64(ve.TotalMilesDriven), averageSpeedDisplayStr))
 	}
 
-	return generatedReport
+	heap.Push(rg.entries, &rankedEntry{
+		id:                  visitableEntity.DriverID,
+		label:               visitableEntity.DriverDisplayName,
+		flaggedInactive:     flaggedInactive,
+		totalDurationDriven: visitableEntity.TotalDurationDriven,
+		totalMilesDriven:    visitableEntity.TotalMilesDriven,
+	})
 }
 
-// Conforms to `eventstore.VisitorInterface`.
+// generate drains `entries` (up to `ro.topN` of them) into `ReportEntry` objects, expressed as per `ro`.
 //
-// This is where we push to the max-heap, building up the sorted data structure.
-func (rg *ReportGenerator) Visit(visitableEntity *eventstore.VisitableEntity) {
-	heap.Push(rg, visitableEntity)
+// Entries that share a label (for example, two different drivers that are both called "Raphael") have their
+// IDs appended to their labels to tell them apart -- even if only one of them makes the cut of `ro.topN`.
+func (ro *reportOptions) generate(entries *rankedEntries) []*ReportEntry {
+	reportEntries := make([]*ReportEntry, 0)
+
+	numEntriesPerLabel := make(map[string]int)
+	for _, re := range entries.entries {
+		numEntriesPerLabel[re.label]++
+	}
+
+	for entries.Len() > 0 && (ro.topN <= 0 || len(reportEntries) < ro.topN) {
+		re := heap.Pop(entries).(*rankedEntry)
+
+		label := re.label
+		if numEntriesPerLabel[label] > 1 {
+			label = fmt.Sprintf("%s [%s]", label, re.id)
+		}
+
+		totalDistanceDriven := mathutils.ConvertDistance64(re.totalMilesDriven, mathutils.Miles, ro.distanceUnit)
+
+		var averageSpeed float64
+		if re.totalDurationDriven > 0 {
+			averageSpeed = mathutils.ComputeSpeedPerHour64(totalDistanceDriven, re.totalDurationDriven)
+		}
+
+		reportEntries = append(reportEntries, &ReportEntry{
+			ID:              re.id,
+			Label:           label,
+			Distance:        totalDistanceDriven,
+			DistanceUnit:    ro.distanceUnit,
+			Duration:        re.totalDurationDriven,
+			AverageSpeed:    averageSpeed,
+			FlaggedInactive: re.flaggedInactive,
+		})
+	}
+
+	return reportEntries
+}
+
+// rankedEntry is the common shape that everything ranked in a `GeneratedReport` is boiled down to.
+type rankedEntry struct {
+	// id uniquely identifies the entry, while its (human-friendly) label may not.
+	id    string
+	label string
+	// flaggedInactive marks the entry as inactive in the `GeneratedReport` (see `FlagInactiveDrivers`).
+	flaggedInactive     bool
+	totalDurationDriven time.Duration
+	totalMilesDriven    float64
+}
+
+// averageSpeed returns the average speed (in miles/hour) of `re`, and 0 if it was never driven.
+func (re *rankedEntry) averageSpeed() float64 {
+	if re.totalDurationDriven <= 0 {
+		return 0
+	}
+
+	return mathutils.ComputeSpeedMph64(re.totalMilesDriven, re.totalDurationDriven)
+}
+
+// rankedEntries is a max-heap of `rankedEntry` objects, ranked as per `sortOrder`.
+type rankedEntries struct {
+	entries   []*rankedEntry
+	sortOrder SortOrder
+}
+
+func newRankedEntries(sortOrder SortOrder) *rankedEntries {
+	re := &rankedEntries{
+		entries:   make([]*rankedEntry, 0),
+		sortOrder: sortOrder,
+	}
+
+	heap.Init(re)
+
+	return re
 }
 
 // Conforms to `heap.Interface`.
-func (rg ReportGenerator) Len() int {
-	return len(rg.heapElements)
+func (re *rankedEntries) Len() int {
+	return len(re.entries)
 }
 
 // Conforms to `heap.Interface`.
-func (rg ReportGenerator) Less(i, j int) bool {
-	// Maintain a max-heap (which is why the `Less` comparison uses '>') based on `TotalMilesDriven`.
-	return rg.heapElements[i].TotalMilesDriven > rg.heapElements[j].TotalMilesDriven
+func (re *rankedEntries) Less(i, j int) bool {
+	a, b := re.entries[i], re.entries[j]
+
+	// Maintain a max-heap (which is why the `Less` comparison uses '>' for the descending orders).
+	switch re.sortOrder {
+	case SortByName:
+		if a.label != b.label {
+			return a.label < b.label
+		}
+		return a.id < b.id
+	case SortBySpeed:
+		return a.averageSpeed() > b.averageSpeed()
+	default:
+		return a.totalMilesDriven > b.totalMilesDriven
+	}
 }
 
 // Conforms to `heap.Interface`.
-func (rg ReportGenerator) Swap(i, j int) {
-	rg.heapElements[i], rg.heapElements[j] = rg.heapElements[j], rg.heapElements[i]
+func (re *rankedEntries) Swap(i, j int) {
+	re.entries[i], re.entries[j] = re.entries[j], re.entries[i]
 }
 
 // Conforms to `heap.Interface`.
-func (rg *ReportGenerator) Push(x interface{}) {
-	rg.heapElements = append(rg.heapElements, x.(*eventstore.VisitableEntity))
+func (re *rankedEntries) Push(x interface{}) {
+	re.entries = append(re.entries, x.(*rankedEntry))
 }
 
 // Conforms to `heap.Interface`.
-func (rg *ReportGenerator) Pop() interface{} {
-	old := rg.heapElements
+func (re *rankedEntries) Pop() interface{} {
+	old := re.entries
 	n := len(old)
 	x := old[n-1]
 	old[n-1] = nil // Avoid memory leak.
-	rg.heapElements = old[0 : n-1]
+	re.entries = old[0 : n-1]
 	return x
 }
@@ -6,12 +6,14 @@ import (
 	"time"
 
 	"root.challenge/eventstore"
+	"root.challenge/mathutils"
 	"root.challenge/output"
 )
 
 func TestReportGenerator(t *testing.T) {
 	tests := map[string]struct {
 		input          []*eventstore.VisitableEntity
+		opts           []output.Option
 		expectedOutput output.GeneratedReport
 	}{
 		"EmptyInput": {
@@ -20,10 +22,10 @@ func TestReportGenerator(t *testing.T) {
 		},
 		"SimpleInput": {
 			input: []*eventstore.VisitableEntity{
-				{DriverFirstName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
-				{DriverFirstName: "DriverB", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
-				{DriverFirstName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
-				{DriverFirstName: "DriverD", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+				{DriverID: "DriverD", DriverDisplayName: "DriverD", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
 			},
 			expectedOutput: output.GeneratedReport{
 				"DriverB: 45 miles @ 15 mph",
@@ -34,10 +36,10 @@ func TestReportGenerator(t *testing.T) {
 		},
 		"AlreadySortedInput": {
 			input: []*eventstore.VisitableEntity{
-				{DriverFirstName: "DriverA", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
-				{DriverFirstName: "DriverB", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
-				{DriverFirstName: "DriverC", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
-				{DriverFirstName: "DriverD", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverD", DriverDisplayName: "DriverD", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
 			},
 			expectedOutput: output.GeneratedReport{
 				"DriverA: 45 miles @ 15 mph",
@@ -48,10 +50,10 @@ func TestReportGenerator(t *testing.T) {
 		},
 		"ReverseSortedInput": {
 			input: []*eventstore.VisitableEntity{
-				{DriverFirstName: "DriverA", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
-				{DriverFirstName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
-				{DriverFirstName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
-				{DriverFirstName: "DriverD", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 15 * time.Minute, TotalMilesDriven: 15.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+				{DriverID: "DriverD", DriverDisplayName: "DriverD", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
 			},
 			expectedOutput: output.GeneratedReport{
 				"DriverD: 45 miles @ 15 mph",
@@ -62,8 +64,8 @@ func TestReportGenerator(t *testing.T) {
 		},
 		"DriverWithNoTripEvents": {
 			input: []*eventstore.VisitableEntity{
-				{DriverFirstName: "DriverA", TotalDurationDriven: 0 * time.Second, TotalMilesDriven: 0.0},
-				{DriverFirstName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 0 * time.Second, TotalMilesDriven: 0.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
 			},
 			expectedOutput: output.GeneratedReport{
 				"DriverB: 30 miles @ 30 mph",
@@ -72,19 +74,147 @@ func TestReportGenerator(t *testing.T) {
 		},
 		"RoundingFractionalMileageAndSpeed": {
 			input: []*eventstore.VisitableEntity{
-				{DriverFirstName: "DriverA", TotalDurationDriven: 11 * time.Minute, TotalMilesDriven: 67.4},
-				{DriverFirstName: "DriverB", TotalDurationDriven: 1*time.Hour + 39*time.Minute, TotalMilesDriven: 72.9},
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 11 * time.Minute, TotalMilesDriven: 67.4},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1*time.Hour + 39*time.Minute, TotalMilesDriven: 72.9},
 			},
 			expectedOutput: output.GeneratedReport{
 				"DriverB: 73 miles @ 44 mph",
 				"DriverA: 67 miles @ 368 mph",
 			},
 		},
+		"ExplicitMiles": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+			opts: []output.Option{output.WithDistanceUnit(mathutils.Miles)},
+			expectedOutput: output.GeneratedReport{
+				"DriverA: 30 miles @ 30 mph",
+			},
+		},
+		"Kilometers": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 2 * time.Hour, TotalMilesDriven: 100.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 0 * time.Second, TotalMilesDriven: 0.0},
+			},
+			opts: []output.Option{output.WithDistanceUnit(mathutils.Kilometers)},
+			expectedOutput: output.GeneratedReport{
+				"DriverB: 161 km @ 80 kph",
+				"DriverA: 48 km @ 48 kph",
+				"DriverC: 0 km",
+			},
+		},
+		"DriversWithSameDisplayName": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "r1", DriverDisplayName: "Raphael", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "d1", DriverDisplayName: "Donatello", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 25.0},
+				{DriverID: "r2", DriverDisplayName: "Raphael", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+			},
+			expectedOutput: output.GeneratedReport{
+				"Raphael [r1]: 30 miles @ 30 mph",
+				"Donatello: 25 miles @ 25 mph",
+				"Raphael [r2]: 20 miles @ 20 mph",
+			},
+		},
+		"InactiveDriversIncludedByDefault": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0, DriverInactive: true},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+			},
+			expectedOutput: output.GeneratedReport{
+				"DriverA: 30 miles @ 30 mph",
+				"DriverB: 20 miles @ 20 mph",
+			},
+		},
+		"InactiveDriversExcluded": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0, DriverInactive: true},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+			},
+			opts: []output.Option{output.WithInactiveDriverPolicy(output.ExcludeInactiveDrivers)},
+			expectedOutput: output.GeneratedReport{
+				"DriverB: 20 miles @ 20 mph",
+			},
+		},
+		"InactiveDriversFlagged": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0, DriverInactive: true},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", DriverInactive: true},
+			},
+			opts: []output.Option{output.WithInactiveDriverPolicy(output.FlagInactiveDrivers)},
+			expectedOutput: output.GeneratedReport{
+				"DriverA: 30 miles @ 30 mph (inactive)",
+				"DriverB: 20 miles @ 20 mph",
+				"DriverC: 0 miles (inactive)",
+			},
+		},
+		"SortedByName": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "d2", DriverDisplayName: "Donatello", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "l1", DriverDisplayName: "Leonardo", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "d1", DriverDisplayName: "Donatello", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+				{DriverID: "a1", DriverDisplayName: "April", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 10.0},
+			},
+			opts: []output.Option{output.WithSortOrder(output.SortByName)},
+			expectedOutput: output.GeneratedReport{
+				"April: 10 miles @ 10 mph",
+				"Donatello [d1]: 20 miles @ 20 mph",
+				"Donatello [d2]: 30 miles @ 30 mph",
+				"Leonardo: 45 miles @ 45 mph",
+			},
+		},
+		"SortedBySpeed": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB"},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+				{DriverID: "DriverD", DriverDisplayName: "DriverD", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+			opts: []output.Option{output.WithSortOrder(output.SortBySpeed)},
+			expectedOutput: output.GeneratedReport{
+				"DriverC: 40 miles @ 120 mph",
+				"DriverD: 30 miles @ 30 mph",
+				"DriverA: 45 miles @ 15 mph",
+				"DriverB: 0 miles",
+			},
+		},
+		"TopN": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "DriverB", DriverDisplayName: "DriverB", TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{DriverID: "DriverC", DriverDisplayName: "DriverC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+			},
+			opts: []output.Option{output.WithTopN(2)},
+			expectedOutput: output.GeneratedReport{
+				"DriverB: 45 miles @ 15 mph",
+				"DriverC: 40 miles @ 120 mph",
+			},
+		},
+		"TopNLargerThanReport": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "DriverA", DriverDisplayName: "DriverA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+			opts: []output.Option{output.WithTopN(5)},
+			expectedOutput: output.GeneratedReport{
+				"DriverA: 30 miles @ 30 mph",
+			},
+		},
+		"TopNStillDisambiguatesSharedLabels": {
+			input: []*eventstore.VisitableEntity{
+				{DriverID: "r1", DriverDisplayName: "Raphael", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{DriverID: "r2", DriverDisplayName: "Raphael", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 20.0},
+			},
+			opts: []output.Option{output.WithTopN(1)},
+			expectedOutput: output.GeneratedReport{
+				"Raphael [r1]: 30 miles @ 30 mph",
+			},
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			rg := output.NewReportGenerator()
+			rg := output.NewReportGenerator(tc.opts...)
 			for _, ve := range tc.input {
 				rg.Visit(ve)
 			}
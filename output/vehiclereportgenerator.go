@@ -0,0 +1,63 @@
+package output
+
+import (
+	"container/heap"
+	"fmt"
+
+	"root.challenge/eventstore"
+)
+
+// VehicleReportGenerator is the per-vehicle analogue of `ReportGenerator` (and shares its implementation
+// strategy, as well as its `Option`s).
+type VehicleReportGenerator struct {
+	reportOptions
+
+	entries *rankedEntries
+}
+
+// NewVehicleReportGenerator creates a new `VehicleReportGenerator`, customized by `opts`.
+func NewVehicleReportGenerator(opts ...Option) *VehicleReportGenerator {
+	vrg := &VehicleReportGenerator{
+		reportOptions: newReportOptions(opts),
+	}
+
+	vrg.entries = newRankedEntries(vrg.sortOrder)
+
+	return vrg
+}
+
+// Generate returns a `GeneratedReport` containing the desired output-ready summary information, in the
+// desired order (descending order of `TotalMilesDriven` by default).
+func (vrg *VehicleReportGenerator) Generate() GeneratedReport {
+	return FormatText(vrg.GenerateEntries())
+}
+
+// GenerateEntries is the per-vehicle analogue of `ReportGenerator.GenerateEntries`.
+func (vrg *VehicleReportGenerator) GenerateEntries() []*ReportEntry {
+	return vrg.generate(vrg.entries)
+}
+
+// Conforms to `eventstore.VehicleVisitorInterface`.
+func (vrg *VehicleReportGenerator) VisitVehicle(visitableVehicleEntity *eventstore.VisitableVehicleEntity) {
+	heap.Push(vrg.entries, &rankedEntry{
+		id:                  visitableVehicleEntity.VehicleID,
+		label:               vehicleLabel(visitableVehicleEntity),
+		totalDurationDriven: visitableVehicleEntity.TotalDurationDriven,
+		totalMilesDriven:    visitableVehicleEntity.TotalMilesDriven,
+	})
+}
+
+// vehicleLabel identifies a vehicle in a `GeneratedReport` by its ID, followed by whichever of its
+// attributes are known (vehicles that are only ever referenced by trips have none).
+func vehicleLabel(visitableVehicleEntity *eventstore.VisitableVehicleEntity) string {
+	class, fuelType := visitableVehicleEntity.VehicleClass, visitableVehicleEntity.VehicleFuelType
+
+	switch {
+	case class != "" && fuelType != "":
+		return fmt.Sprintf("%s (%s, %s)", visitableVehicleEntity.VehicleID, class, fuelType)
+	case class != "" || fuelType != "":
+		return fmt.Sprintf("%s (%s%s)", visitableVehicleEntity.VehicleID, class, fuelType)
+	default:
+		return visitableVehicleEntity.VehicleID
+	}
+}
@@ -0,0 +1,71 @@
+package output_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"root.challenge/eventstore"
+	"root.challenge/mathutils"
+	"root.challenge/output"
+)
+
+func TestVehicleReportGenerator(t *testing.T) {
+	tests := map[string]struct {
+		input          []*eventstore.VisitableVehicleEntity
+		opts           []output.Option
+		expectedOutput output.GeneratedReport
+	}{
+		"EmptyInput": {
+			input:          []*eventstore.VisitableVehicleEntity{},
+			expectedOutput: output.GeneratedReport{},
+		},
+		"SimpleInput": {
+			input: []*eventstore.VisitableVehicleEntity{
+				{VehicleID: "VehicleA", VehicleClass: "Sedan", VehicleFuelType: "Petrol",
+					TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+				{VehicleID: "VehicleB", VehicleClass: "Van", VehicleFuelType: "Diesel",
+					TotalDurationDriven: 3 * time.Hour, TotalMilesDriven: 45.0},
+				{VehicleID: "VehicleC", TotalDurationDriven: 20 * time.Minute, TotalMilesDriven: 40.0},
+			},
+			expectedOutput: output.GeneratedReport{
+				"VehicleB (Van, Diesel): 45 miles @ 15 mph",
+				"VehicleC: 40 miles @ 120 mph",
+				"VehicleA (Sedan, Petrol): 30 miles @ 30 mph",
+			},
+		},
+		"PartiallyKnownAttributes": {
+			input: []*eventstore.VisitableVehicleEntity{
+				{VehicleID: "VehicleA", VehicleClass: "Sedan"},
+				{VehicleID: "VehicleB", VehicleFuelType: "Electric", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 1.0},
+			},
+			expectedOutput: output.GeneratedReport{
+				"VehicleB (Electric): 1 miles @ 1 mph",
+				"VehicleA (Sedan): 0 miles",
+			},
+		},
+		"Kilometers": {
+			input: []*eventstore.VisitableVehicleEntity{
+				{VehicleID: "VehicleA", TotalDurationDriven: 1 * time.Hour, TotalMilesDriven: 30.0},
+			},
+			opts: []output.Option{output.WithDistanceUnit(mathutils.Kilometers)},
+			expectedOutput: output.GeneratedReport{
+				"VehicleA: 48 km @ 48 kph",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vrg := output.NewVehicleReportGenerator(tc.opts...)
+			for _, vve := range tc.input {
+				vrg.VisitVehicle(vve)
+			}
+
+			actualOutput := vrg.Generate()
+			if !reflect.DeepEqual(actualOutput, tc.expectedOutput) {
+				t.Fatalf("expected: %#v, got: %#v", tc.expectedOutput, actualOutput)
+			}
+		})
+	}
+}
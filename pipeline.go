@@ -0,0 +1,439 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventhandler/eventhandlers/trip"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/logging"
+	"root.challenge/metrics"
+)
+
+// processingFlags are the flags shared by every subcommand that processes events (as opposed to only
+// validating them).
+type processingFlags struct {
+	format         *string
+	ignoreCase     *bool
+	strict         *bool
+	parkTrips      *int
+	dedupWindow    *int
+	dedupFile      *string
+	logEvents      *bool
+	rateLimit      *float64
+	circuitBreaker *int
+	minSpeed       *float64
+	maxSpeed       *float64
+	metricsAddr    *string
+	metricsFile    *string
+	// stageDeduplication makes `newPipeline` stage the event IDs seen by the deduplicator of its `pipeline`
+	// (see `pipeline.stagedDeduplicator`), for subcommands that commit them once their events were saved.
+	stageDeduplication bool
+	// batched is set by subcommands that process every batch of events on its own, which trips can't stay
+	// parked across (so `newPipeline` rejects -park-trips).
+	batched bool
+}
+
+func addProcessingFlags(flags *flag.FlagSet) *processingFlags {
+	return &processingFlags{
+		format:     addFormatFlag(flags),
+		ignoreCase: addIgnoreCaseFlag(flags),
+		strict: flags.Bool("strict", false,
+			"reject trips for drivers that haven't been registered (instead of registering them lazily)"),
+		parkTrips: flags.Int("park-trips", 0,
+			"with -strict, park trips for drivers that haven't been registered yet for up to this many subsequent events, instead of "+
+				"rejecting them"),
+		dedupWindow: flags.Int("dedup-window", 0, "drop events whose IDs were among the last this many event IDs seen"),
+		dedupFile: flags.String("dedup-file", "",
+			"drop events whose IDs are recorded in this file (by this or an earlier run), recording new IDs in it"),
+		logEvents: flags.Bool("log-events", false,
+			"log every event (and the outcome of handling it) to standard error, lowering the log level to info unless -log-level is given"),
+		rateLimit: flags.Float64("rate-limit", 0, "handle at most this many events per second (0 for no limit)"),
+		circuitBreaker: flags.Int("circuit-breaker", 0,
+			"stop handling an event type after its handler panics this many times in a row (0 to never stop)"),
+		minSpeed: flags.Float64("min-speed", trip.DefaultSpeedBounds.MinMph, "discard trips slower than this many mph"),
+		maxSpeed: flags.Float64("max-speed", trip.DefaultSpeedBounds.MaxMph, "discard trips faster than this many mph"),
+		metricsAddr: flags.String("metrics-addr", "",
+			"serve metrics (in the Prometheus text format) on /metrics at this address while running"),
+		metricsFile: flags.String("metrics-file", "",
+			"write metrics (in the Prometheus text format) to this file at exit ('-' for standard error)"),
+	}
+}
+
+// tripOpts returns the `trip.Option`s that `pf` asks for.
+func (pf *processingFlags) tripOpts() []trip.Option {
+	speedBounds := trip.SpeedBounds{MinMph: *pf.minSpeed, MaxMph: *pf.maxSpeed}
+	if speedBounds == trip.DefaultSpeedBounds {
+		return nil
+	}
+
+	return []trip.Option{trip.WithSpeedBounds(speedBounds)}
+}
+
+func addFormatFlag(flags *flag.FlagSet) *string {
+	return flags.String("format", "text", "format of the input: 'text' (one event per line) or 'json' (one JSON object per line)")
+}
+
+func addIgnoreCaseFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("ignore-case", false, "match event types (and their aliases) case-insensitively")
+}
+
+// eventReader returns the function that reads events in `format` (see `addFormatFlag`).
+func eventReader(format string) (func(io.ReadCloser) <-chan *input.EventEnvelope, error) {
+	switch format {
+	case "text":
+		return input.StartReading, nil
+	case "json":
+		return input.StartReadingJSON, nil
+	default:
+		return nil, fmt.Errorf("error parsing -format flag: expecting 'text' or 'json', got '%s'", format)
+	}
+}
+
+// newRegistry returns the `eventhandler.Registry` to process events with -- `eventhandler.GlobalRegistry()`,
+// unless `ignoreCase` calls for a case-insensitive one, or `tripOpts` for customized trip handlers.
+func newRegistry(ignoreCase bool, tripOpts ...trip.Option) (*eventhandler.Registry, error) {
+	if !ignoreCase && len(tripOpts) == 0 {
+		return eventhandler.GlobalRegistry(), nil
+	}
+
+	// Case-insensitivity is a property of a `Registry` as a whole (and the handlers of
+	// `eventhandler.GlobalRegistry()` can't be customized), so it needs one of its own.
+	var registryOpts []eventhandler.RegistryOption
+	if ignoreCase {
+		registryOpts = append(registryOpts, eventhandler.WithCaseInsensitiveEventTypes())
+	}
+
+	registry := eventhandler.NewRegistry(registryOpts...)
+	if err := eventprocessor.RegisterBuiltinHandlers(registry, tripOpts...); err != nil {
+		return nil, fmt.Errorf("error registering handlers: %w", err)
+	}
+
+	return registry, nil
+}
+
+// inputSource is an input to read events from.
+type inputSource struct {
+	name         string
+	file         io.ReadCloser
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope
+}
+
+// events starts reading the events of `is` (which can only be done once).
+func (is *inputSource) events() <-chan *input.EventEnvelope {
+	return is.startReading(is.file)
+}
+
+// openInputSources opens the inputs to read events from -- the input file named by the positional args
+// left over in `flags` if any, else the sources of `cfg` (which may be nil) if any, else standard input
+// (unless `stdinByDefault` is false, in which case there are none).
+//
+// Inputs are read in `format` (see `addFormatFlag`), except for sources of `cfg` with a format of their own
+// -- unless the -format flag was given explicitly, in which case it applies to every input.
+func openInputSources(flags *flag.FlagSet, format string, cfg *pipelineConfig,
+	stdinByDefault bool) ([]*inputSource, error) {
+	startReading, err := eventReader(format)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case flags.NArg() > 0:
+		inputFile, err := openInputFile(flags.Args())
+		if err != nil {
+			return nil, err
+		}
+
+		return []*inputSource{{name: inputFile.Name(), file: inputFile, startReading: startReading}}, nil
+	case cfg != nil && len(cfg.Sources) > 0:
+		formatExplicit := explicitFlags(flags)["format"]
+
+		inputSources := make([]*inputSource, 0, len(cfg.Sources))
+		for _, sc := range cfg.Sources {
+			sourceStartReading := startReading
+			if sc.Format != nil && !formatExplicit {
+				// Formats of sources are checked by `pipelineConfig.validate`.
+				sourceStartReading, _ = eventReader(*sc.Format)
+			}
+
+			inputFile, err := openInputFile([]string{sc.Path})
+			if err != nil {
+				for _, is := range inputSources {
+					is.file.Close()
+				}
+				return nil, err
+			}
+
+			inputSources = append(inputSources, &inputSource{name: sc.Path, file: inputFile,
+				startReading: sourceStartReading})
+		}
+
+		return inputSources, nil
+	case stdinByDefault:
+		return []*inputSource{{name: "standard input", file: os.Stdin, startReading: startReading}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// pipeline is everything needed to process an input, as configured by `processingFlags`.
+type pipeline struct {
+	eventStoreOpts []eventstore.Option
+	eventProcessor *eventprocessor.EventProcessor
+	// deduplicator is nil unless deduplication was asked for.
+	deduplicator eventprocessor.Deduplicator
+	// stagedDeduplicator is nil unless deduplication was asked for with `processingFlags.stageDeduplication`
+	// set, in which case it's `deduplicator`.
+	stagedDeduplicator *eventprocessor.StagedDeduplicator
+	// metrics is nil unless metrics were asked for.
+	metrics *metrics.Pipeline
+	closers []io.Closer
+}
+
+// newPipeline validates `pf` and builds the `pipeline` it describes, with `extraMiddlewares` wrapped around
+// (that is, outside of) the ones that `pf` asks for.
+func (pf *processingFlags) newPipeline(extraMiddlewares ...eventprocessor.Middleware) (*pipeline, error) {
+	if *pf.dedupWindow > 0 && *pf.dedupFile != "" {
+		return nil, errors.New("error parsing flags: -dedup-window and -dedup-file are mutually exclusive")
+	}
+	if *pf.parkTrips < 0 {
+		return nil, fmt.Errorf("error parsing flags: -park-trips expects a non-negative number, got %d", *pf.parkTrips)
+	}
+	if *pf.parkTrips > 0 && !*pf.strict {
+		return nil, errors.New("error parsing flags: -park-trips only applies with -strict")
+	}
+	if *pf.parkTrips > 0 && pf.batched {
+		return nil, errors.New("error parsing flags: -park-trips doesn't apply to batches, which are processed on their own " +
+			"(so trips would be given up on at the end of their batch)")
+	}
+
+	p := &pipeline{}
+
+	// Metrics are only kept when they're going somewhere.
+	var metricsRegistry *metrics.Registry
+	tripOpts := pf.tripOpts()
+	if *pf.metricsAddr != "" || *pf.metricsFile != "" {
+		metricsRegistry = metrics.NewRegistry()
+		p.metrics = metrics.NewPipeline(metricsRegistry)
+		tripOpts = append(tripOpts, trip.WithDiscardObserver(p.metrics.ObserveDiscardedTrip))
+	}
+
+	registry, err := newRegistry(*pf.ignoreCase, tripOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case *pf.parkTrips > 0:
+		p.eventStoreOpts = append(p.eventStoreOpts, eventstore.WithParkedTrips(*pf.parkTrips))
+	case *pf.strict:
+		p.eventStoreOpts = append(p.eventStoreOpts, eventstore.WithStrictDriverRegistration())
+	}
+
+	var deduplicator eventprocessor.LookupDeduplicator
+	switch {
+	case *pf.dedupWindow > 0:
+		if deduplicator, err = eventprocessor.NewWindowedDeduplicator(*pf.dedupWindow); err != nil {
+			return nil, fmt.Errorf("error creating deduplicator: %w", err)
+		}
+	case *pf.dedupFile != "":
+		persistentDeduplicator, err := eventprocessor.OpenPersistentDeduplicator(*pf.dedupFile)
+		if err != nil {
+			return nil, fmt.Errorf("error creating deduplicator: %w", err)
+		}
+
+		deduplicator = persistentDeduplicator
+		p.closers = append(p.closers, persistentDeduplicator)
+	}
+
+	switch {
+	case deduplicator != nil && pf.stageDeduplication:
+		p.stagedDeduplicator = eventprocessor.NewStagedDeduplicator(deduplicator)
+		p.deduplicator = p.stagedDeduplicator
+	case deduplicator != nil:
+		p.deduplicator = deduplicator
+	}
+
+	p.eventStoreOpts = append(p.eventStoreOpts, eventstore.WithLogger(logger))
+
+	eventProcessorOpts := []eventprocessor.Option{eventprocessor.WithRegistry(registry),
+		eventprocessor.WithLogger(logger)}
+	if p.deduplicator != nil {
+		eventProcessorOpts = append(eventProcessorOpts, eventprocessor.WithDeduplicator(p.deduplicator))
+	}
+
+	eventProcessorOpts = append(eventProcessorOpts, eventprocessor.WithMiddleware(extraMiddlewares...))
+
+	// Logging is outermost (of the middlewares asked for by flags), so that what it logs reflects the outcome
+	// of every other middleware.
+	if *pf.logEvents {
+		// Events are logged at the info level, which is quieter than the default -- unless -log-level asked
+		// for that explicitly, in which case only failed events are logged (at the warn level).
+		if !logLevelGiven && !logger.Enabled(logging.LevelInfo) {
+			logger.SetLevel(logging.LevelInfo)
+		}
+
+		eventProcessorOpts = append(eventProcessorOpts,
+			eventprocessor.WithMiddleware(eventprocessor.Logging(logger)))
+	}
+	if *pf.rateLimit > 0 {
+		eventProcessorOpts = append(eventProcessorOpts,
+			eventprocessor.WithMiddleware(eventprocessor.RateLimit(*pf.rateLimit, 1)))
+	}
+	if *pf.circuitBreaker > 0 {
+		eventProcessorOpts = append(eventProcessorOpts, eventprocessor.WithCircuitBreaker(*pf.circuitBreaker, 0))
+	}
+
+	// Timing is innermost, so that handler latency doesn't include the delays of any other middleware (like
+	// rate limiting).
+	if p.metrics != nil {
+		eventProcessorOpts = append(eventProcessorOpts,
+			eventprocessor.WithObserver(p.metrics),
+			eventprocessor.WithMiddleware(eventprocessor.Timing(p.metrics.ObserveHandling)))
+	}
+
+	p.eventProcessor = eventprocessor.New(eventProcessorOpts...)
+
+	if *pf.metricsAddr != "" {
+		metricsServer, err := serveMetrics(*pf.metricsAddr, metricsRegistry)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+
+		p.closers = append(p.closers, metricsServer)
+	}
+	if *pf.metricsFile != "" {
+		p.closers = append(p.closers, &metricsDump{path: *pf.metricsFile, registry: metricsRegistry})
+	}
+
+	return p, nil
+}
+
+// instrumentReader returns `startReading` (see `eventReader`), instrumented to keep the metrics of `p` (if
+// any).
+func (p *pipeline) instrumentReader(
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope) func(io.ReadCloser) <-chan *input.EventEnvelope {
+	if p.metrics == nil {
+		return startReading
+	}
+
+	return p.metrics.InstrumentReader(startReading)
+}
+
+// serveMetrics serves the metrics in `registry` on /metrics at `addr` in the background, until the returned
+// `io.Closer` is closed.
+func serveMetrics(addr string, registry *metrics.Registry) (io.Closer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error serving metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+	metricsServer := &http.Server{Handler: mux}
+
+	go func() {
+		if err := metricsServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("failed to serve metrics", logging.Err(err))
+		}
+	}()
+
+	return metricsServer, nil
+}
+
+// metricsDump writes the metrics in `registry` to the file at `path` (or to standard error, if `path` is
+// "-") when closed.
+type metricsDump struct {
+	path     string
+	registry *metrics.Registry
+}
+
+// Conforms to `io.Closer`.
+func (md *metricsDump) Close() error {
+	var err error
+	if md.path == "-" {
+		err = md.registry.WriteText(os.Stderr)
+	} else {
+		err = writeFile(md.path, md.registry.WriteText)
+	}
+
+	// `pipeline.Close` is deferred (so its `error` goes nowhere), and the dump is the last thing to happen.
+	if err != nil {
+		logger.Error("failed to write metrics", logging.Err(err))
+	}
+
+	return err
+}
+
+// process processes every event in `inputSources` (in order, passing the events of each one through `wrap`,
+// if not nil) into `eventStore`, logging (and passing to `observe`, if not nil) every `error` along the way,
+// and returns the number of `error`s.
+func (p *pipeline) process(inputSources []*inputSource, eventStore *eventstore.EventStore,
+	wrap func(<-chan *input.EventEnvelope) <-chan *input.EventEnvelope, observe func(error)) int {
+	numErrors := 0
+	for _, is := range inputSources {
+		eventC := p.instrumentReader(is.startReading)(is.file)
+		if wrap != nil {
+			eventC = wrap(eventC)
+		}
+
+		numErrors += p.processSource(is.name, eventC, eventStore, observe)
+	}
+
+	if p.deduplicator != nil && p.deduplicator.NumDuplicates() > 0 {
+		logger.Info("dropped duplicate events", logging.Int("num_duplicates", p.deduplicator.NumDuplicates()))
+	}
+
+	return numErrors
+}
+
+// logProcessingError logs `err` (emitted by processing events) as a warning, along with `fields` that tell
+// where the events came from.
+func logProcessingError(err error, fields ...logging.Field) {
+	fields = append(fields, eventprocessor.ErrorFields(err)...)
+
+	// Panics are bugs in the handlers, so the stack trace is as important as the error itself.
+	var panicErr *eventprocessor.PanicError
+	if errors.As(err, &panicErr) {
+		fields = append(fields, logging.String("stack", string(panicErr.Stack)))
+	}
+
+	logger.Warn("failed to process event", fields...)
+}
+
+// processSource is the part of `process` that processes the events of a single `inputSource`.
+func (p *pipeline) processSource(sourceName string, eventC <-chan *input.EventEnvelope,
+	eventStore *eventstore.EventStore, observe func(error)) int {
+	numErrors := 0
+	for err := range p.eventProcessor.Process(eventC, eventStore, eventprocessor.WithSource(sourceName)) {
+		numErrors++
+		logProcessingError(err, logging.String("source", sourceName))
+
+		if observe != nil {
+			observe(err)
+		}
+	}
+
+	return numErrors
+}
+
+// Close releases whatever `p` holds on to (such as the file of a persistent deduplicator).
+func (p *pipeline) Close() error {
+	var firstErr error
+	for _, closer := range p.closers {
+		if err := closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
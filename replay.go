@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+)
+
+// runReplay implements the "replay" subcommand, which rebuilds a store saved by "report -save-store" (see
+// `eventstore.Load`), processes the events of an input (if any) into it, and prints the resulting report --
+// so a report can be regenerated (or extended with new events) without reprocessing the original input.
+func runReplay(args []string) int {
+	flags := newFlagSet("replay", "-store <file> [flags] [input file]",
+		"Rebuilds the store saved (by 'report -save-store') in the -store file, processes every event in the input file (if any) into it, "+
+			"and prints a report of the drivers (or vehicles).")
+	storeFlag := flags.String("store", "", "the file to rebuild the store from (required)")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	rf := addReportFlags(flags)
+	outputFlag := addOutputFlag(flags)
+	saveStoreFlag := flags.String("save-store", "", "save the resulting store to this file (which may be the -store file)")
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	if *storeFlag == "" {
+		logger.Error("the -store flag is required")
+		flags.Usage()
+		return exitFailure
+	}
+
+	p, err := pf.newPipeline()
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	reportOutputs, err := rf.newReportOutputs(flags, *outputFlag, cfg)
+	if err != nil {
+		logger.Error("invalid report flags", logging.Err(err))
+		return exitFailure
+	}
+
+	eventStore, err := loadStore(*storeFlag, p.eventStoreOpts)
+	if err != nil {
+		logger.Error("failed to load store", logging.Err(err))
+		return exitFailure
+	}
+
+	// Unlike every other subcommand, the input is optional (rather than defaulting to standard input), since
+	// the store alone is enough for a report.
+	inputSources, err := openInputSources(flags, *pf.format, cfg, false)
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+
+	numErrors := p.process(inputSources, eventStore, nil, nil)
+
+	if *saveStoreFlag != "" {
+		if err := saveStore(*saveStoreFlag, eventStore); err != nil {
+			logger.Error("failed to save store", logging.Err(err))
+			return exitFailure
+		}
+	}
+
+	if err := writeReports(reportOutputs, eventStore); err != nil {
+		logger.Error("failed to write reports", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(numErrors)
+}
+
+// loadStore rebuilds the `eventstore.EventStore` saved in the file at `path` (see `saveStore`), customized
+// by `opts`.
+func loadStore(path string, opts []eventstore.Option) (*eventstore.EventStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return eventstore.Load(f, opts...)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+	"root.challenge/mathutils"
+	"root.challenge/output"
+)
+
+// reportFlags are the flags shared by every subcommand that prints a report.
+type reportFlags struct {
+	units        *string
+	by           *string
+	inactive     *string
+	reportFormat *string
+	sort         *string
+	top          *int
+}
+
+func addReportFlags(flags *flag.FlagSet) *reportFlags {
+	return &reportFlags{
+		units:        flags.String("units", "mi", "distance unit for the report: 'mi' (miles and mph) or 'km' (kilometers and kph)"),
+		by:           flags.String("by", "driver", "what the report aggregates by: 'driver' or 'vehicle'"),
+		inactive:     flags.String("inactive", "include", "how the report treats inactive drivers: 'include', 'exclude' or 'flag'"),
+		reportFormat: flags.String("report-format", "text", "format of the report: 'text', 'json' or 'csv'"),
+		sort:         flags.String("sort", "distance", "order of the report: 'distance' (descending), 'name' or 'speed' (descending)"),
+		top:          flags.Int("top", 0, "only report the first this many entries (0 for all of them)"),
+	}
+}
+
+// reporter writes the report described by `reportFlags`.
+type reporter struct {
+	byVehicle    bool
+	reportFormat output.ReportFormat
+	opts         []output.Option
+}
+
+// newReporter validates `rf` and builds the `reporter` it describes.
+func (rf *reportFlags) newReporter() (*reporter, error) {
+	byVehicle, err := parseBy(*rf.by)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -by flag: %w", err)
+	}
+
+	inactiveDriverPolicy, err := output.ParseInactiveDriverPolicy(*rf.inactive)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -inactive flag: %w", err)
+	}
+
+	reportDistanceUnit, err := mathutils.ParseDistanceUnit(*rf.units)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -units flag: %w", err)
+	}
+
+	reportFormat, err := output.ParseReportFormat(*rf.reportFormat)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -report-format flag: %w", err)
+	}
+
+	sortOrder, err := output.ParseSortOrder(*rf.sort)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing -sort flag: %w", err)
+	}
+
+	if *rf.top < 0 {
+		return nil, fmt.Errorf("error parsing -top flag: expecting a non-negative number, got %d", *rf.top)
+	}
+
+	return &reporter{
+		byVehicle:    byVehicle,
+		reportFormat: reportFormat,
+		opts: []output.Option{
+			output.WithDistanceUnit(reportDistanceUnit),
+			output.WithInactiveDriverPolicy(inactiveDriverPolicy),
+			output.WithSortOrder(sortOrder),
+			output.WithTopN(*rf.top),
+		},
+	}, nil
+}
+
+// parseBy parses what a report aggregates by, returning whether that's vehicles (rather than drivers).
+func parseBy(s string) (bool, error) {
+	switch s {
+	case "driver":
+		return false, nil
+	case "vehicle":
+		return true, nil
+	default:
+		return false, fmt.Errorf("expecting 'driver' or 'vehicle', got '%s'", s)
+	}
+}
+
+// withConfig returns a copy of `rf` with every flag that wasn't given explicitly (as per `explicit`) replaced
+// by its counterpart in `rc`, if set there.
+func (rf *reportFlags) withConfig(rc *reportConfig, explicit map[string]bool) *reportFlags {
+	pickString := func(name string, flagValue, configValue *string) *string {
+		if explicit[name] || configValue == nil {
+			return flagValue
+		}
+		return configValue
+	}
+
+	top := rf.top
+	if !explicit["top"] && rc.Top != nil {
+		top = rc.Top
+	}
+
+	return &reportFlags{
+		units:        pickString("units", rf.units, rc.Units),
+		by:           pickString("by", rf.by, rc.By),
+		inactive:     pickString("inactive", rf.inactive, rc.Inactive),
+		reportFormat: pickString("report-format", rf.reportFormat, rc.Format),
+		sort:         pickString("sort", rf.sort, rc.Sort),
+		top:          top,
+	}
+}
+
+// reportOutput is a report, along with the file it's written to (standard output if empty).
+type reportOutput struct {
+	path     string
+	reporter *reporter
+}
+
+// newReportOutputs builds the reports described by `rf` and `outputPath` (see `addOutputFlag`) -- or, if
+// `cfg` configures any reports, the reports of `cfg`, with the flags given explicitly in `flags` overriding
+// their counterparts in every one of them.
+func (rf *reportFlags) newReportOutputs(flags *flag.FlagSet, outputPath string,
+	cfg *pipelineConfig) ([]*reportOutput, error) {
+	if cfg == nil || len(cfg.Reports) == 0 {
+		r, err := rf.newReporter()
+		if err != nil {
+			return nil, err
+		}
+
+		return []*reportOutput{{path: outputPath, reporter: r}}, nil
+	}
+
+	// None of the report flags are set by `applyConfig`, so these were all given on the command line.
+	explicit := explicitFlags(flags)
+	if explicit["o"] && len(cfg.Reports) > 1 {
+		return nil, fmt.Errorf("-o can't override the outputs of the %d reports in the config file", len(cfg.Reports))
+	}
+
+	reportOutputs := make([]*reportOutput, 0, len(cfg.Reports))
+	for _, rc := range cfg.Reports {
+		r, err := rf.withConfig(rc, explicit).newReporter()
+		if err != nil {
+			return nil, err
+		}
+
+		path := rc.Output
+		if explicit["o"] {
+			path = outputPath
+		}
+
+		reportOutputs = append(reportOutputs, &reportOutput{path: path, reporter: r})
+	}
+
+	return reportOutputs, nil
+}
+
+// writeReports writes the report of everything in `eventStore` to every one of `reportOutputs`.
+func writeReports(reportOutputs []*reportOutput, eventStore *eventstore.EventStore) error {
+	for _, ro := range reportOutputs {
+		if err := writeOutput(ro.path, func(w io.Writer) error { return ro.reporter.write(w, eventStore) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write writes the report of everything in `eventStore` to `w`.
+func (r *reporter) write(w io.Writer, eventStore *eventstore.EventStore) error {
+	var reportEntries []*output.ReportEntry
+	if r.byVehicle {
+		vehicleReportGenerator := output.NewVehicleReportGenerator(r.opts...)
+		eventStore.VisitVehicles(vehicleReportGenerator)
+		reportEntries = vehicleReportGenerator.GenerateEntries()
+	} else {
+		reportGenerator := output.NewReportGenerator(r.opts...)
+		eventStore.Visit(reportGenerator)
+		reportEntries = reportGenerator.GenerateEntries()
+	}
+
+	return output.WriteReport(w, reportEntries, r.reportFormat)
+}
+
+// runReport implements the "report" subcommand (which is also what runs when no subcommand is given), which
+// processes an input and prints the resulting report.
+func runReport(args []string) int {
+	flags := newFlagSet("report", "[flags] [input file]",
+		"Processes every event in the input (standard input by default), and prints a report of the drivers (or vehicles).")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	rf := addReportFlags(flags)
+	outputFlag := addOutputFlag(flags)
+	saveStoreFlag := flags.String("save-store", "", "save the resulting store to this file (see the 'replay' subcommand)")
+	listHandlersFlag := flags.Bool("list-handlers", false, "list the event types this build supports (and their handlers), and exit")
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	if *listHandlersFlag {
+		registry, err := newRegistry(*pf.ignoreCase, pf.tripOpts()...)
+		if err != nil {
+			logger.Error("failed to create registry", logging.Err(err))
+			return exitFailure
+		}
+
+		listHandlers(os.Stdout, registry)
+		return exitOK
+	}
+
+	p, err := pf.newPipeline()
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	reportOutputs, err := rf.newReportOutputs(flags, *outputFlag, cfg)
+	if err != nil {
+		logger.Error("invalid report flags", logging.Err(err))
+		return exitFailure
+	}
+
+	inputSources, err := openInputSources(flags, *pf.format, cfg, true)
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+
+	eventStore := eventstore.New(p.eventStoreOpts...)
+	numErrors := p.process(inputSources, eventStore, nil, nil)
+
+	if *saveStoreFlag != "" {
+		if err := saveStore(*saveStoreFlag, eventStore); err != nil {
+			logger.Error("failed to save store", logging.Err(err))
+			return exitFailure
+		}
+	}
+
+	if err := writeReports(reportOutputs, eventStore); err != nil {
+		logger.Error("failed to write reports", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(numErrors)
+}
+
+// saveStore saves `eventStore` to the file at `path` (see `eventstore.EventStore.Save`).
+func saveStore(path string, eventStore *eventstore.EventStore) error {
+	return writeFile(path, eventStore.Save)
+}
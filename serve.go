@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"root.challenge/eventstore"
+	"root.challenge/logging"
+	"root.challenge/server"
+)
+
+// shutdownTimeout is how long the "serve" subcommand waits for requests in flight to finish when shutting down.
+const shutdownTimeout = 10 * time.Second
+
+// runServe implements the "serve" subcommand, which ingests events over HTTP into a live store (rather than
+// reading them from an input), and serves reports of it (see `server.Server`) until interrupted.
+func runServe(args []string) int {
+	flags := newFlagSet("serve", "[flags]",
+		"Serves HTTP until interrupted: POST events (one per line, or NDJSON with 'Content-Type: application/x-ndjson') to /events, "+
+			"and GET the report from /report (?format=text|json|csv&by=driver|vehicle&units=mi|km&inactive=...&sort=...&top=N), "+
+			"with health and readiness checks on /healthz and /readyz.")
+	addrFlag := flags.String("addr", ":8080", "the address to listen on")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	maxBodyBytesFlag := flags.Int64("max-body-bytes", server.DefaultMaxBodyBytes,
+		"reject batches of events larger than this many bytes")
+	storeFlag := flags.String("store", "", "rebuild the store saved (by 'report -save-store') in this file at startup")
+	saveStoreFlag := flags.String("save-store", "", "save the store to this file (which may be the -store file) at shutdown")
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	// Events only ever arrive over HTTP.
+	if flags.NArg() > 0 {
+		logger.Error("serve doesn't take an input file", logging.String("input", flags.Arg(0)))
+		flags.Usage()
+		return exitFailure
+	}
+
+	if _, err := applyConfig(flags, *configFlag); err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	// -format only sets the format of requests without a Content-Type.
+	startReading, err := eventReader(*pf.format)
+	if err != nil {
+		logger.Error("invalid flags", logging.Err(err))
+		return exitFailure
+	}
+
+	// Every request is a batch of its own (see `server.Server`).
+	pf.batched = true
+	p, err := pf.newPipeline()
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	eventStore := eventstore.New(p.eventStoreOpts...)
+	if *storeFlag != "" {
+		if eventStore, err = loadStore(*storeFlag, p.eventStoreOpts); err != nil {
+			logger.Error("failed to load store", logging.Err(err))
+			return exitFailure
+		}
+	}
+
+	s := server.New(p.eventProcessor, eventStore,
+		server.WithMaxBodyBytes(*maxBodyBytesFlag),
+		server.WithDefaultEventReader(startReading),
+		server.WithReaderInstrumentation(p.instrumentReader),
+		server.WithErrorObserver(func(err error) {
+			logProcessingError(err)
+		}))
+	httpServer := &http.Server{Addr: *addrFlag, Handler: s}
+
+	serveErrC := make(chan error, 1)
+	go func() {
+		serveErrC <- httpServer.ListenAndServe()
+	}()
+	logger.Info("serving", logging.String("addr", *addrFlag))
+
+	signalC := make(chan os.Signal, 1)
+	signal.Notify(signalC, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalC)
+
+	select {
+	case err := <-serveErrC:
+		logger.Error("failed to serve", logging.Err(err))
+		return exitFailure
+	case sig := <-signalC:
+		logger.Info("shutting down", logging.String("signal", sig.String()))
+	}
+
+	// Stop advertising readiness first, so that load balancers can stop sending traffic while requests in
+	// flight finish.
+	s.SetReady(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Error("failed to shut down", logging.Err(err))
+		return exitFailure
+	}
+	if err := <-serveErrC; !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("failed to serve", logging.Err(err))
+		return exitFailure
+	}
+
+	// Nothing touches the store once the server is shut down.
+	if *saveStoreFlag != "" {
+		if err := saveStore(*saveStoreFlag, eventStore); err != nil {
+			logger.Error("failed to save store", logging.Err(err))
+			return exitFailure
+		}
+	}
+
+	return exitOK
+}
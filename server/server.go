@@ -0,0 +1,432 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/mathutils"
+	"root.challenge/output"
+)
+
+// DefaultMaxBodyBytes is the largest batch of events (in bytes) that a `Server` not customized by
+// `WithMaxBodyBytes` accepts in a single request.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Server is an `http.Handler` that ingests events over HTTP into a live `eventstore.EventStore` (via an
+// `eventprocessor.EventProcessor`), and serves reports of whatever that store holds at the time:
+//
+//	POST /events   ingests a batch of events -- one per line, in the text format with a Content-Type of
+//	               "text/plain", or as NDJSON (one JSON object per line) with a Content-Type of
+//	               "application/x-ndjson" (see `WithDefaultEventReader` for requests without a Content-Type)
+//	GET  /report   renders a report (see `parseReportQuery` for its query parameters)
+//	GET  /healthz  responds with 200 for as long as the `Server` can respond at all
+//	GET  /readyz   responds with 200 while the `Server` is ready for traffic (see `SetReady`), and 503 otherwise
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// `eventstore.EventStore` isn't safe for concurrent use, so every batch is processed (and every report is
+// generated) while holding `mu` -- batches are thus applied one at a time, each in its entirety. Bodies are
+// read in full before `mu` is acquired, so that a slow client doesn't hold up everybody else.
+//
+// Every batch is a call to `eventprocessor.EventProcessor.Process` of its own, so per-run state (like the
+// setup of handlers) doesn't outlive a batch -- while state that's deliberately shared by every run (like
+// deduplication of event IDs) spans every batch. Trips parked while waiting on their drivers would be given
+// up on at the end of their batch, so `eventStore` mustn't park trips (see `eventstore.WithParkedTrips`).
+type Server struct {
+	eventProcessor *eventprocessor.EventProcessor
+
+	mu         sync.Mutex
+	eventStore *eventstore.EventStore
+
+	maxBodyBytes int64
+	// defaultStartReading reads the events of requests without a Content-Type.
+	defaultStartReading func(io.ReadCloser) <-chan *input.EventEnvelope
+	// observe is nil unless set by `WithErrorObserver`.
+	observe func(error)
+	// instrumentReader is nil unless set by `WithReaderInstrumentation`.
+	instrumentReader func(func(io.ReadCloser) <-chan *input.EventEnvelope) func(io.ReadCloser) <-chan *input.EventEnvelope
+	// ready is accessed atomically, and is 1 while ready.
+	ready int32
+
+	mux *http.ServeMux
+}
+
+// Option customizes a `Server` created by `New`.
+type Option func(*Server)
+
+// WithMaxBodyBytes makes the `Server` reject batches larger than `maxBodyBytes` (with a 413), rather than
+// batches larger than `DefaultMaxBodyBytes`.
+func WithMaxBodyBytes(maxBodyBytes int64) Option {
+	return func(s *Server) {
+		s.maxBodyBytes = maxBodyBytes
+	}
+}
+
+// WithDefaultEventReader makes the `Server` read the events of requests without a Content-Type with
+// `startReading` (like `input.StartReadingJSON`), rather than with `input.StartReading`.
+func WithDefaultEventReader(startReading func(io.ReadCloser) <-chan *input.EventEnvelope) Option {
+	return func(s *Server) {
+		s.defaultStartReading = startReading
+	}
+}
+
+// WithErrorObserver makes the `Server` pass every `error` from processing events to `observe` (in addition
+// to reporting it back to the client that sent the event), for example to log it.
+//
+// `observe` is only ever called by a single goroutine at a time.
+func WithErrorObserver(observe func(error)) Option {
+	return func(s *Server) {
+		s.observe = observe
+	}
+}
+
+// WithReaderInstrumentation makes the `Server` read the events of every request with the reader for its
+// Content-Type wrapped by `instrumentReader` (like `metrics.Pipeline.InstrumentReader`).
+func WithReaderInstrumentation(instrumentReader func(
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope) func(io.ReadCloser) <-chan *input.EventEnvelope) Option {
+	return func(s *Server) {
+		s.instrumentReader = instrumentReader
+	}
+}
+
+// New creates a new `Server` that processes events with `eventProcessor` into `eventStore`, customized by
+// `opts`.
+//
+// The `Server` starts out ready, and takes ownership of `eventStore` -- which must not be touched by anything
+// else while the `Server` may still be handling requests.
+func New(eventProcessor *eventprocessor.EventProcessor, eventStore *eventstore.EventStore, opts ...Option) *Server {
+	s := &Server{
+		eventProcessor:      eventProcessor,
+		eventStore:          eventStore,
+		maxBodyBytes:        DefaultMaxBodyBytes,
+		defaultStartReading: input.StartReading,
+		ready:               1,
+		mux:                 http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("/events", s.handleEvents)
+	s.mux.HandleFunc("/report", s.handleReport)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReadiness)
+
+	return s
+}
+
+// SetReady sets whether the `Server` reports being ready for traffic (on "/readyz") -- for example, so that
+// load balancers stop sending it traffic ahead of a shutdown.
+//
+// It has no effect on which requests are served.
+func (s *Server) SetReady(ready bool) {
+	var value int32
+	if ready {
+		value = 1
+	}
+
+	atomic.StoreInt32(&s.ready, value)
+}
+
+// Conforms to `http.Handler`.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// eventsResponse is the response to a batch of events.
+type eventsResponse struct {
+	// Events is the number of events in the batch (blank and commented-out lines aren't events, but malformed
+	// lines are).
+	Events int           `json:"events"`
+	Errors []*eventError `json:"errors"`
+}
+
+// eventError is a single `error` from processing a batch of events.
+type eventError struct {
+	// Line is the line of the batch that the event came from, and is omitted when unknown (or when the `error`
+	// isn't specific to a single event).
+	Line  int    `json:"line,omitempty"`
+	Class string `json:"class,omitempty"`
+	Error string `json:"error"`
+}
+
+func newEventError(err error) *eventError {
+	ee := &eventError{Error: err.Error()}
+
+	var processingErr *eventprocessor.ProcessingError
+	if errors.As(err, &processingErr) {
+		ee.Line = processingErr.Line
+		ee.Class = processingErr.Class.String()
+	}
+
+	return ee
+}
+
+// handleEvents processes the batch of events in the body of `r`, and responds with an `eventsResponse` --
+// with a 200 if every event was processed successfully, and a 422 otherwise.
+//
+// Events are applied regardless of whether other events in the batch fail, so a batch shouldn't be retried
+// just because it got a 422 (unless its events have IDs to deduplicate them by).
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("expecting a %s, got a %s", http.MethodPost, r.Method))
+		return
+	}
+
+	startReading, err := s.eventReader(r.Header.Get("Content-Type"))
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+	if s.instrumentReader != nil {
+		startReading = s.instrumentReader(startReading)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, s.maxBodyBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("error reading request body: %w", err))
+		return
+	}
+	if int64(len(body)) > s.maxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Errorf("request body is larger than the limit of %d bytes", s.maxBodyBytes))
+		return
+	}
+
+	response := s.process(startReading(ioutil.NopCloser(bytes.NewReader(body))))
+
+	statusCode := http.StatusOK
+	if len(response.Errors) > 0 {
+		statusCode = http.StatusUnprocessableEntity
+	}
+
+	writeJSON(w, statusCode, response)
+}
+
+// process processes every event in `eventC` into `s.eventStore`.
+func (s *Server) process(eventC <-chan *input.EventEnvelope) *eventsResponse {
+	response := &eventsResponse{Errors: make([]*eventError, 0)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for err := range s.eventProcessor.Process(countEvents(eventC, &response.Events), s.eventStore) {
+		response.Errors = append(response.Errors, newEventError(err))
+
+		if s.observe != nil {
+			s.observe(err)
+		}
+	}
+
+	return response
+}
+
+// countEvents passes every `input.EventEnvelope` in `eventC` through to the returned channel, counting the
+// ones that hold events in `numEvents` -- which is only safe to read once the returned channel is drained.
+func countEvents(eventC <-chan *input.EventEnvelope, numEvents *int) <-chan *input.EventEnvelope {
+	countedC := make(chan *input.EventEnvelope)
+
+	go func() {
+		defer close(countedC)
+
+		for eventEnvelope := range eventC {
+			// Blank (and commented-out) lines aren't events, but malformed ones still count as events.
+			if eventEnvelope.Err != nil {
+				*numEvents++
+			} else if tokens, err := eventEnvelope.Tokens(); err != nil || len(tokens) > 0 {
+				*numEvents++
+			}
+
+			countedC <- eventEnvelope
+		}
+	}()
+
+	return countedC
+}
+
+// eventReader returns the function that reads events in the format implied by `contentType` (which may be
+// empty, for the default format -- see `WithDefaultEventReader`).
+func (s *Server) eventReader(contentType string) (func(io.ReadCloser) <-chan *input.EventEnvelope, error) {
+	if contentType == "" {
+		return s.defaultStartReading, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Content-Type: %w", err)
+	}
+
+	switch mediaType {
+	case "text/plain":
+		return input.StartReading, nil
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return input.StartReadingJSON, nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Type '%s' (expecting 'text/plain' or 'application/x-ndjson')",
+			mediaType)
+	}
+}
+
+// reportQuery is the report asked for by the query parameters of a request to "/report".
+type reportQuery struct {
+	byVehicle    bool
+	reportFormat output.ReportFormat
+	opts         []output.Option
+}
+
+// parseReportQuery parses the query parameters of a request to "/report", each of which is optional:
+//
+//	format    'text' (the default), 'json' or 'csv'
+//	by        'driver' (the default) or 'vehicle'
+//	units     'mi' (the default) or 'km'
+//	inactive  'include' (the default), 'exclude' or 'flag'
+//	sort      'distance' (the default), 'name' or 'speed'
+//	top       the number of entries to cap the report to (0, the default, for all of them)
+func parseReportQuery(r *http.Request) (*reportQuery, error) {
+	query := r.URL.Query()
+	get := func(name, defaultValue string) string {
+		if value := query.Get(name); value != "" {
+			return value
+		}
+		return defaultValue
+	}
+
+	rq := &reportQuery{}
+
+	reportFormat, err := output.ParseReportFormat(get("format", string(output.TextFormat)))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing 'format' parameter: %w", err)
+	}
+	rq.reportFormat = reportFormat
+
+	switch by := get("by", "driver"); by {
+	case "driver":
+	case "vehicle":
+		rq.byVehicle = true
+	default:
+		return nil, fmt.Errorf("error parsing 'by' parameter: expecting 'driver' or 'vehicle', got '%s'", by)
+	}
+
+	distanceUnit, err := mathutils.ParseDistanceUnit(get("units", "mi"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing 'units' parameter: %w", err)
+	}
+
+	inactiveDriverPolicy, err := output.ParseInactiveDriverPolicy(get("inactive", "include"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing 'inactive' parameter: %w", err)
+	}
+
+	sortOrder, err := output.ParseSortOrder(get("sort", "distance"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing 'sort' parameter: %w", err)
+	}
+
+	top, err := strconv.Atoi(get("top", "0"))
+	if err != nil || top < 0 {
+		return nil, fmt.Errorf("error parsing 'top' parameter: expecting a non-negative number, got '%s'",
+			query.Get("top"))
+	}
+
+	rq.opts = []output.Option{
+		output.WithDistanceUnit(distanceUnit),
+		output.WithInactiveDriverPolicy(inactiveDriverPolicy),
+		output.WithSortOrder(sortOrder),
+		output.WithTopN(top),
+	}
+
+	return rq, nil
+}
+
+// contentTypes maps every `output.ReportFormat` to the Content-Type it's served with.
+var contentTypes = map[output.ReportFormat]string{
+	output.TextFormat: "text/plain; charset=utf-8",
+	output.JSONFormat: "application/json",
+	output.CSVFormat:  "text/csv; charset=utf-8",
+}
+
+// handleReport responds with the report asked for by the query parameters of `r` (see `parseReportQuery`).
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("expecting a %s, got a %s", http.MethodGet, r.Method))
+		return
+	}
+
+	rq, err := parseReportQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reportEntries := s.generateEntries(rq)
+
+	// The report is rendered in full before anything is written, so that a failure to render it can still
+	// be responded to with a 500.
+	var buf bytes.Buffer
+	if err := output.WriteReport(&buf, reportEntries, rq.reportFormat); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[rq.reportFormat])
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// generateEntries generates the entries of the report asked for by `rq`, from whatever `s.eventStore` holds
+// at the time.
+func (s *Server) generateEntries(rq *reportQuery) []*output.ReportEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rq.byVehicle {
+		vehicleReportGenerator := output.NewVehicleReportGenerator(rq.opts...)
+		s.eventStore.VisitVehicles(vehicleReportGenerator)
+		return vehicleReportGenerator.GenerateEntries()
+	}
+
+	reportGenerator := output.NewReportGenerator(rq.opts...)
+	s.eventStore.Visit(reportGenerator)
+	return reportGenerator.GenerateEntries()
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReadiness(w http.ResponseWriter, _ *http.Request) {
+	if atomic.LoadInt32(&s.ready) == 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// writeError responds with `err` (as JSON), and `statusCode`.
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
+
+// writeJSON responds with `v` (as JSON), and `statusCode`.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	// There's nobody left to report a failure to write the response to (the status code is already out).
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,349 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/server"
+)
+
+// eventsResponse mirrors the response to a batch of events.
+type eventsResponse struct {
+	Events int `json:"events"`
+	Errors []struct {
+		Line  int    `json:"line"`
+		Class string `json:"class"`
+		Error string `json:"error"`
+	} `json:"errors"`
+}
+
+func newTestServer(t *testing.T, opts ...server.Option) (*server.Server, *httptest.Server) {
+	s := server.New(eventprocessor.New(), eventstore.New(), opts...)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	return s, ts
+}
+
+// do sends a request to `ts`, and returns the status code and body of the response.
+func do(t *testing.T, ts *httptest.Server, method, path, contentType, body string) (int, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("expected: no error creating request, got: %s", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("expected: no error sending request, got: %s", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("expected: no error reading response, got: %s", err)
+	}
+
+	return resp.StatusCode, string(respBody)
+}
+
+func TestEvents(t *testing.T) {
+	tests := map[string]struct {
+		opts               []server.Option
+		contentType        string
+		body               string
+		expectedStatusCode int
+		expectedEvents     int
+		expectedErrorLines []int
+	}{
+		"SingleLine": {
+			body:               "Driver Dan",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     1,
+		},
+		"TextBatch": {
+			contentType:        "text/plain; charset=utf-8",
+			body:               "Driver Dan\n\n# comment\nTrip Dan 07:15 07:45 17.3\n",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     2,
+		},
+		"NDJSONBatch": {
+			contentType: "application/x-ndjson",
+			body: `{"type": "Driver", "args": ["Dan"]}` + "\n" +
+				`{"type": "Trip", "args": ["Dan", "07:15", "07:45", "17.3"]}` + "\n",
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     2,
+		},
+		"NDJSONByDefault": {
+			opts:               []server.Option{server.WithDefaultEventReader(input.StartReadingJSON)},
+			body:               `{"type": "Driver", "args": ["Dan"]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedEvents:     1,
+		},
+		"PartialFailure": {
+			body:               "Driver Dan\nBogus Dan\nTrip Dan 07:15 07:45\n",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedEvents:     3,
+			expectedErrorLines: []int{2, 3},
+		},
+		"MalformedNDJSON": {
+			contentType:        "application/x-ndjson",
+			body:               `{"type": "Driver", "args": ["Dan"]` + "\n",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedEvents:     1,
+			expectedErrorLines: []int{1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ts := newTestServer(t, tc.opts...)
+
+			statusCode, body := do(t, ts, http.MethodPost, "/events", tc.contentType, tc.body)
+			if statusCode != tc.expectedStatusCode {
+				t.Fatalf("expected: %d, got: %d (%s)", tc.expectedStatusCode, statusCode, body)
+			}
+
+			var response eventsResponse
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				t.Fatalf("expected: no error parsing response, got: %s (%s)", err, body)
+			}
+
+			if response.Events != tc.expectedEvents {
+				t.Fatalf("expected: %d events, got: %d", tc.expectedEvents, response.Events)
+			}
+
+			if len(response.Errors) != len(tc.expectedErrorLines) {
+				t.Fatalf("expected: %d errors, got: %+v", len(tc.expectedErrorLines), response.Errors)
+			}
+			for i, expectedLine := range tc.expectedErrorLines {
+				if response.Errors[i].Line != expectedLine || response.Errors[i].Class == "" {
+					t.Fatalf("expected: classified error on line %d, got: %+v", expectedLine, response.Errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEventsRejectsBadRequests(t *testing.T) {
+	tests := map[string]struct {
+		method             string
+		contentType        string
+		body               string
+		expectedStatusCode int
+	}{
+		"WrongMethod": {
+			method:             http.MethodGet,
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		"UnsupportedContentType": {
+			method:             http.MethodPost,
+			contentType:        "application/xml",
+			body:               "<Driver>Dan</Driver>",
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		"TooLarge": {
+			method:             http.MethodPost,
+			body:               strings.Repeat("Driver Dan\n", 10),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ts := newTestServer(t, server.WithMaxBodyBytes(100))
+
+			if statusCode, body := do(t, ts, tc.method, "/events", tc.contentType, tc.body); statusCode != tc.expectedStatusCode {
+				t.Fatalf("expected: %d, got: %d (%s)", tc.expectedStatusCode, statusCode, body)
+			}
+
+			// Nothing was processed.
+			if _, body := do(t, ts, http.MethodGet, "/report", "", ""); body != "" {
+				t.Fatalf("expected: empty report, got: %q", body)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	tests := map[string]struct {
+		query               string
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        string
+	}{
+		"Default": {
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "Alex: 42 miles @ 42 mph\nDan: 17 miles @ 35 mph\nKumi: 0 miles\n",
+		},
+		"JSON": {
+			query:               "?format=json&top=1",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody: "[\n  {\n    \"id\": \"Alex\",\n    \"label\": \"Alex\",\n    \"distance\": 42,\n    \"unit\": \"mi\",\n" +
+				"    \"speed\": 42\n  }\n]\n",
+		},
+		"CSVByVehicle": {
+			query:               "?format=csv&by=vehicle",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "id,label,distance,unit,speed,inactive\nV1,\"V1 (Sedan, Petrol)\",17,mi,35,false\n",
+		},
+		"SortedByNameInKilometers": {
+			query:               "?sort=name&units=km&inactive=exclude",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "Alex: 68 km @ 68 kph\nDan: 28 km @ 56 kph\n",
+		},
+		"BadParameter": {
+			query:               "?top=-1",
+			expectedStatusCode:  http.StatusBadRequest,
+			expectedContentType: "application/json",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ts := newTestServer(t)
+
+			// Events posted in separate batches all land in the same store.
+			for _, batch := range []string{
+				"Driver Dan\nDriver Alex\nVehicle V1 Sedan Petrol\n",
+				"Trip Dan 07:15 07:45 17.3 vehicle=V1\nTrip Alex 12:01 13:01 42.0\n",
+				"Driver Kumi\nDriverDeactivate Kumi\n",
+			} {
+				if statusCode, body := do(t, ts, http.MethodPost, "/events", "", batch); statusCode != http.StatusOK {
+					t.Fatalf("expected: %d, got: %d (%s)", http.StatusOK, statusCode, body)
+				}
+			}
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/report"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("expected: no error creating request, got: %s", err)
+			}
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("expected: no error sending request, got: %s", err)
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != tc.expectedStatusCode {
+				t.Fatalf("expected: %d, got: %d (%s)", tc.expectedStatusCode, resp.StatusCode, body)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != tc.expectedContentType {
+				t.Fatalf("expected: %s, got: %s", tc.expectedContentType, contentType)
+			}
+			if tc.expectedBody != "" && string(body) != tc.expectedBody {
+				t.Fatalf("expected: %q, got: %q", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if statusCode, body := do(t, ts, http.MethodGet, path, "", ""); statusCode != http.StatusOK {
+			t.Fatalf("expected: %d from %s, got: %d (%s)", http.StatusOK, path, statusCode, body)
+		}
+	}
+
+	s.SetReady(false)
+
+	// Health is unaffected by readiness.
+	if statusCode, body := do(t, ts, http.MethodGet, "/healthz", "", ""); statusCode != http.StatusOK {
+		t.Fatalf("expected: %d, got: %d (%s)", http.StatusOK, statusCode, body)
+	}
+	if statusCode, body := do(t, ts, http.MethodGet, "/readyz", "", ""); statusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected: %d, got: %d (%s)", http.StatusServiceUnavailable, statusCode, body)
+	}
+
+	s.SetReady(true)
+
+	if statusCode, body := do(t, ts, http.MethodGet, "/readyz", "", ""); statusCode != http.StatusOK {
+		t.Fatalf("expected: %d, got: %d (%s)", http.StatusOK, statusCode, body)
+	}
+}
+
+func TestConcurrentBatches(t *testing.T) {
+	var observed []error
+	_, ts := newTestServer(t, server.WithErrorObserver(func(err error) {
+		observed = append(observed, err)
+	}))
+
+	const numBatches = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < numBatches; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// `t.Fatalf` can't be called from here, so failures are left to the checks below.
+			batch := fmt.Sprintf("Driver D%02d\nTrip D%02d 07:00 08:00 10\nBogus\n", i, i)
+			if resp, err := ts.Client().Post(ts.URL+"/events", "text/plain", strings.NewReader(batch)); err == nil {
+				resp.Body.Close()
+			}
+			if resp, err := ts.Client().Get(ts.URL + "/report"); err == nil {
+				resp.Body.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, body := do(t, ts, http.MethodGet, "/report", "", "")
+	if numLines := strings.Count(body, "\n"); numLines != numBatches {
+		t.Fatalf("expected: %d report lines, got: %d (%s)", numBatches, numLines, body)
+	}
+
+	if len(observed) != numBatches {
+		t.Fatalf("expected: %d observed errors, got: %d", numBatches, len(observed))
+	}
+}
+
+func TestReaderInstrumentation(t *testing.T) {
+	var mu sync.Mutex
+	numRead := 0
+	_, ts := newTestServer(t, server.WithReaderInstrumentation(
+		func(startReading func(io.ReadCloser) <-chan *input.EventEnvelope) func(io.ReadCloser) <-chan *input.EventEnvelope {
+			return func(eventSource io.ReadCloser) <-chan *input.EventEnvelope {
+				countedC := make(chan *input.EventEnvelope)
+				go func() {
+					defer close(countedC)
+					for eventEnvelope := range startReading(eventSource) {
+						mu.Lock()
+						numRead++
+						mu.Unlock()
+						countedC <- eventEnvelope
+					}
+				}()
+
+				return countedC
+			}
+		}))
+
+	// Whatever the Content-Type, events are read by the instrumented reader.
+	do(t, ts, http.MethodPost, "/events", "", "Driver Dan\nDriver Alex\n")
+	do(t, ts, http.MethodPost, "/events", "application/x-ndjson", `{"type": "Driver", "args": ["Bob"]}`)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if numRead != 3 {
+		t.Fatalf("expected: 3 events read, got: %d", numRead)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"root.challenge/eventhandler"
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+	"root.challenge/logging"
+)
+
+// runStats implements the "stats" subcommand, which processes an input and prints statistics about the input,
+// its processing, and the resulting store (rather than a report).
+func runStats(args []string) int {
+	flags := newFlagSet("stats", "[flags] [input file]",
+		"Processes every event in the input (standard input by default), and prints statistics about it.")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	outputFlag := addOutputFlag(flags)
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	s := newStats()
+
+	p, err := pf.newPipeline(eventprocessor.Timing(s.observeHandling))
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	inputSources, err := openInputSources(flags, *pf.format, cfg, true)
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+
+	eventStore := eventstore.New(p.eventStoreOpts...)
+
+	start := time.Now()
+	p.process(inputSources, eventStore, s.countInput, s.errors.Add)
+	s.elapsed = time.Since(start)
+
+	if p.deduplicator != nil {
+		s.numDuplicates = p.deduplicator.NumDuplicates()
+	}
+
+	eventStore.Visit(s)
+	eventStore.VisitVehicles(s)
+
+	if err := writeOutput(*outputFlag, s.write); err != nil {
+		logger.Error("failed to write output", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(s.errors.Total())
+}
+
+// stats accumulates what the "stats" subcommand prints.
+//
+// Its fields are updated by the stages of the processing pipeline (each from its own goroutine), but each
+// field is only ever updated by a single stage, and only read once every stage is done.
+type stats struct {
+	// Updated by `countInput`.
+	numLines         int
+	numEvents        int
+	numEventsWithIDs int
+
+	// Updated by `observeHandling`.
+	numHandled map[eventhandler.EventType]int
+	numFailed  map[eventhandler.EventType]int
+
+	// Updated by the consumer of the `error`s of the pipeline.
+	errors        *eventprocessor.ErrorSummary
+	numDuplicates int
+	elapsed       time.Duration
+
+	// Updated by visiting the resulting `eventstore.EventStore`.
+	numDrivers          int
+	numInactiveDrivers  int
+	numVehicles         int
+	totalMilesDriven    float64
+	totalDurationDriven time.Duration
+}
+
+func newStats() *stats {
+	return &stats{
+		numHandled: make(map[eventhandler.EventType]int),
+		numFailed:  make(map[eventhandler.EventType]int),
+		errors:     eventprocessor.NewErrorSummary(),
+	}
+}
+
+// countInput passes every `input.EventEnvelope` in `eventC` (the events of a single input) through to the
+// returned channel, counting them along the way.
+func (s *stats) countInput(eventC <-chan *input.EventEnvelope) <-chan *input.EventEnvelope {
+	countedC := make(chan *input.EventEnvelope)
+
+	go func() {
+		defer close(countedC)
+
+		// Lines are numbered from scratch in every input.
+		numLines := 0
+		defer func() {
+			s.numLines += numLines
+		}()
+
+		for eventEnvelope := range eventC {
+			if eventEnvelope.Line > numLines {
+				numLines = eventEnvelope.Line
+			}
+
+			// Blank (and commented-out) lines aren't events, but malformed ones still count as events.
+			if eventEnvelope.Err == nil {
+				if tokens, err := eventEnvelope.Tokens(); err == nil && len(tokens) == 0 {
+					countedC <- eventEnvelope
+					continue
+				}
+			}
+
+			s.numEvents++
+			if eventEnvelope.ID != "" {
+				s.numEventsWithIDs++
+			}
+
+			countedC <- eventEnvelope
+		}
+	}()
+
+	return countedC
+}
+
+// observeHandling counts every event that reaches its handler (see `eventprocessor.Timing`).
+func (s *stats) observeHandling(invocation *eventprocessor.Invocation, _ time.Duration, err error) {
+	s.numHandled[invocation.EventType]++
+	if err != nil {
+		s.numFailed[invocation.EventType]++
+	}
+}
+
+// Conforms to `eventstore.VisitorInterface`.
+func (s *stats) Visit(visitableEntity *eventstore.VisitableEntity) {
+	s.numDrivers++
+	if visitableEntity.DriverInactive {
+		s.numInactiveDrivers++
+	}
+
+	s.totalMilesDriven += visitableEntity.TotalMilesDriven
+	s.totalDurationDriven += visitableEntity.TotalDurationDriven
+}
+
+// Conforms to `eventstore.VehicleVisitorInterface`.
+func (s *stats) VisitVehicle(*eventstore.VisitableVehicleEntity) {
+	s.numVehicles++
+}
+
+func (s *stats) write(w io.Writer) error {
+	eventTypes := make([]string, 0, len(s.numHandled))
+	for eventType := range s.numHandled {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	sort.Strings(eventTypes)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "INPUT")
+	fmt.Fprintf(tw, "  Lines\t%d\n", s.numLines)
+	fmt.Fprintf(tw, "  Events\t%d\n", s.numEvents)
+	fmt.Fprintf(tw, "  Events with IDs\t%d\n", s.numEventsWithIDs)
+	fmt.Fprintf(tw, "  Duplicates dropped\t%d\n", s.numDuplicates)
+
+	fmt.Fprintln(tw, "PROCESSING")
+	for _, eventType := range eventTypes {
+		numHandled, numFailed := s.numHandled[eventhandler.EventType(eventType)], s.numFailed[eventhandler.EventType(eventType)]
+		fmt.Fprintf(tw, "  %s events handled\t%d (%d failed)\n", eventType, numHandled, numFailed)
+	}
+	fmt.Fprintf(tw, "  Elapsed\t%s\n", s.elapsed)
+
+	fmt.Fprintln(tw, "STORE")
+	fmt.Fprintf(tw, "  Drivers\t%d (%d inactive)\n", s.numDrivers, s.numInactiveDrivers)
+	fmt.Fprintf(tw, "  Vehicles\t%d\n", s.numVehicles)
+	fmt.Fprintf(tw, "  Total distance driven\t%.1f miles\n", s.totalMilesDriven)
+	fmt.Fprintf(tw, "  Total duration driven\t%s\n", s.totalDurationDriven)
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("error writing stats: %w", err)
+	}
+
+	fmt.Fprintln(w, "ERRORS")
+	printErrorSummary(w, s.errors)
+
+	return nil
+}
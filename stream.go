@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"root.challenge/input"
+	"root.challenge/logging"
+	"root.challenge/streamlog"
+)
+
+// followPollInterval is how often "consume -follow" checks the log for new records once it has caught up.
+const followPollInterval = time.Second
+
+// runProduce implements the "produce" subcommand, which appends the events of an input to a local
+// partitioned log (see `streamlog.Log`), one event per record, keyed by the driver it's about (see
+// `streamlog.Router`).
+func runProduce(args []string) int {
+	flags := newFlagSet("produce", "[flags] [input file]",
+		"Appends every event in the input (standard input by default) to the partitioned log in the -log directory (creating it "+
+			"if needed), one event per record. Records are keyed by the ID of the driver their event is about (or of the driver "+
+			"of the trip it's about, or else of the vehicle it's about), so that the events of a driver all land in the same "+
+			"shard, in order. Events that can't be keyed (like events of unknown types, or corrections of unknown trips) are "+
+			"rejected.")
+	logFlag := flags.String("log", "", "the directory of the log (required)")
+	shardsFlag := flags.Int("shards", 0, "the number of shards of the log, when creating it (default 1)")
+	formatFlag := addFormatFlag(flags)
+	ignoreCaseFlag := addIgnoreCaseFlag(flags)
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	if *logFlag == "" {
+		logger.Error("the -log flag is required")
+		flags.Usage()
+		return exitFailure
+	}
+
+	startReading, err := eventReader(*formatFlag)
+	if err != nil {
+		logger.Error("invalid flags", logging.Err(err))
+		return exitFailure
+	}
+
+	registry, err := newRegistry(*ignoreCaseFlag)
+	if err != nil {
+		logger.Error("failed to create registry", logging.Err(err))
+		return exitFailure
+	}
+
+	l, err := streamlog.Open(*logFlag, streamlog.WithShards(*shardsFlag))
+	if err != nil {
+		logger.Error("failed to open log", logging.Err(err))
+		return exitFailure
+	}
+	defer l.Close()
+
+	// Corrections may be about trips appended by previous runs.
+	router := streamlog.NewRouter(registry, startReading)
+	if err := router.Seed(l); err != nil {
+		logger.Error("failed to read log", logging.Err(err))
+		return exitFailure
+	}
+
+	inputFile, err := openInputFile(flags.Args())
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+	defer inputFile.Close()
+
+	numRecords, numRejected, err := produce(l, inputFile, router)
+	logger.Info("appended records", logging.String("log", *logFlag), logging.Int("num_records", numRecords),
+		logging.Int("num_rejected", numRejected))
+	if err != nil {
+		logger.Error("failed to produce", logging.Err(err))
+		return exitFailure
+	}
+
+	if numRejected > 0 {
+		return exitEventErrors
+	}
+
+	return exitOK
+}
+
+// produce appends every event in `r` to `l`, keyed by `router`, and returns the number of records it
+// appended, along with the number of events it rejected (and logged) because `router` couldn't key them.
+//
+// Every line of `r` becomes a record of its own, as split by `input.SplitRecords` (which repeats the "#!"
+// directives in the header of `r` in every record, so that every record can be read on its own).
+func produce(l *streamlog.Log, r io.Reader, router *streamlog.Router) (int, int, error) {
+	numRecords, numRejected := 0, 0
+	err := input.SplitRecords(r, func(data []byte) error {
+		key, err := router.Key(data)
+		if err != nil {
+			numRejected++
+			logger.Warn("rejected event", logging.Int("record", numRecords+numRejected), logging.Err(err))
+			return nil
+		}
+
+		if _, err := l.Append(key, data); err != nil {
+			return fmt.Errorf("error appending to log: %w", err)
+		}
+		numRecords++
+
+		return nil
+	})
+
+	return numRecords, numRejected, err
+}
+
+// runConsume implements the "consume" subcommand, which consumes the records of a local partitioned log (see
+// `streamlog.Consumer`) from where it last left off, and prints a report of everything consumed so far.
+func runConsume(args []string) int {
+	flags := newFlagSet("consume", "[flags]",
+		"Consumes the records appended (by 'produce') to the partitioned log in the -log directory since the last run, committing "+
+			"the store along with the checkpoint of every shard to the -state file, and prints a report of the drivers (or "+
+			"vehicles) that reflects every record consumed so far. Each record is consumed exactly once, even across crashes.")
+	logFlag := flags.String("log", "", "the directory of the log (required)")
+	stateFlag := flags.String("state", "",
+		"the file that holds the checkpoints and the store between runs (required; created if needed)")
+	batchSizeFlag := flags.Int("batch-size", streamlog.DefaultBatchSize,
+		"the (maximum) number of records per shard to process between commits")
+	followFlag := flags.Bool("follow", false,
+		"keep consuming new records until interrupted, then print the report")
+	configFlag := addConfigFlag(flags)
+	pf := addProcessingFlags(flags)
+	rf := addReportFlags(flags)
+	outputFlag := addOutputFlag(flags)
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	// Events only ever come from the log.
+	if flags.NArg() > 0 {
+		logger.Error("consume doesn't take an input file", logging.String("input", flags.Arg(0)))
+		flags.Usage()
+		return exitFailure
+	}
+	if *logFlag == "" || *stateFlag == "" {
+		logger.Error("the -log and -state flags are required")
+		flags.Usage()
+		return exitFailure
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	startReading, err := eventReader(*pf.format)
+	if err != nil {
+		logger.Error("invalid flags", logging.Err(err))
+		return exitFailure
+	}
+
+	// Event IDs are only recorded as seen once the records they're in were committed (see
+	// `streamlog.Consumer`).
+	pf.stageDeduplication = true
+	pf.batched = true
+	p, err := pf.newPipeline()
+	if err != nil {
+		logger.Error("failed to create pipeline", logging.Err(err))
+		return exitFailure
+	}
+	defer p.Close()
+
+	reportOutputs, err := rf.newReportOutputs(flags, *outputFlag, cfg)
+	if err != nil {
+		logger.Error("invalid report flags", logging.Err(err))
+		return exitFailure
+	}
+
+	// The log must already exist (as created by "produce"), rather than be created empty by a typo.
+	if _, err := os.Stat(*logFlag); err != nil {
+		logger.Error("failed to open log", logging.Err(err))
+		return exitFailure
+	}
+
+	l, err := streamlog.Open(*logFlag)
+	if err != nil {
+		logger.Error("failed to open log", logging.Err(err))
+		return exitFailure
+	}
+	defer l.Close()
+
+	numErrors := 0
+	consumerOpts := []streamlog.ConsumerOption{
+		streamlog.WithEventReader(p.instrumentReader(startReading)),
+		streamlog.WithBatchSize(*batchSizeFlag),
+		streamlog.WithErrorObserver(func(shard int, err error) {
+			numErrors++
+			logProcessingError(err, logging.Int("shard", shard))
+		}),
+	}
+	if p.stagedDeduplicator != nil {
+		consumerOpts = append(consumerOpts, streamlog.WithStagedDeduplicator(p.stagedDeduplicator))
+	}
+
+	consumer, err := streamlog.NewConsumer(l, p.eventProcessor,
+		streamlog.NewFileCheckpointStore(*stateFlag, p.eventStoreOpts...), consumerOpts...)
+	if err != nil {
+		logger.Error("failed to create consumer", logging.Err(err))
+		return exitFailure
+	}
+
+	if err := consume(consumer, *followFlag); err != nil {
+		logger.Error("failed to consume", logging.Err(err))
+		return exitFailure
+	}
+
+	if err := writeReports(reportOutputs, consumer.EventStore()); err != nil {
+		logger.Error("failed to write reports", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(numErrors)
+}
+
+// consume consumes records with `consumer` until it has caught up with its log -- or, if `follow` is true,
+// until interrupted.
+func consume(consumer *streamlog.Consumer, follow bool) error {
+	if !follow {
+		for {
+			n, err := consumer.Poll()
+			if err != nil || n == 0 {
+				return err
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signalC := make(chan os.Signal, 1)
+	signal.Notify(signalC, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalC)
+
+	go func() {
+		select {
+		case sig := <-signalC:
+			logger.Info("stopping", logging.String("signal", sig.String()))
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Polls commit as they go, so stopping in between them loses nothing.
+	return consumer.Run(ctx, followPollInterval)
+}
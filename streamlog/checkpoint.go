@@ -0,0 +1,121 @@
+package streamlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"root.challenge/eventstore"
+)
+
+// CheckpointStore durably stores the progress of a `Consumer` -- the sequence number of the last record it
+// consumed from every shard, along with the `eventstore.EventStore` that those records were consumed into.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// Checkpoints and the `eventstore.EventStore` are committed together (rather than each on its own), since
+// that's what makes consumption exactly-once in effect: were the store saved first, a crash before the
+// checkpoint is committed would replay records into a store that already reflects them (and vice versa).
+type CheckpointStore interface {
+	// Load returns what was last committed -- an empty `eventstore.EventStore` and no checkpoints if nothing
+	// was ever committed.
+	Load() (*eventstore.EventStore, map[int]uint64, error)
+	// Commit atomically replaces what was last committed.
+	Commit(eventStore *eventstore.EventStore, checkpoints map[int]uint64) error
+}
+
+// checkpointVersion is bumped on every incompatible change to `checkpointFile`.
+const checkpointVersion = 1
+
+// checkpointFile is the contents of the file of a `FileCheckpointStore`.
+type checkpointFile struct {
+	Version     int            `json:"version"`
+	Checkpoints map[int]uint64 `json:"checkpoints"`
+	// Store is the snapshot of the `eventstore.EventStore` (see `eventstore.EventStore.Save`).
+	Store json.RawMessage `json:"store"`
+}
+
+// FileCheckpointStore is a `CheckpointStore` backed by a single file, which is replaced atomically on every
+// `Commit`.
+type FileCheckpointStore struct {
+	path string
+	opts []eventstore.Option
+}
+
+// NewFileCheckpointStore creates a `FileCheckpointStore` backed by the file at `path` (which needn't exist
+// yet), whose `eventstore.EventStore`s are customized by `opts`.
+func NewFileCheckpointStore(path string, opts ...eventstore.Option) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path, opts: opts}
+}
+
+// Conforms to `CheckpointStore`.
+func (fcs *FileCheckpointStore) Load() (*eventstore.EventStore, map[int]uint64, error) {
+	data, err := ioutil.ReadFile(fcs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return eventstore.New(fcs.opts...), make(map[int]uint64), nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading checkpoint file: %w", err)
+	}
+
+	var cf checkpointFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return nil, nil, fmt.Errorf("error parsing checkpoint file: %w", err)
+	}
+	if cf.Version != checkpointVersion {
+		return nil, nil, fmt.Errorf("unsupported checkpoint file version %d (expecting %d)", cf.Version,
+			checkpointVersion)
+	}
+
+	eventStore, err := eventstore.Load(bytes.NewReader(cf.Store), fcs.opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading store from checkpoint file: %w", err)
+	}
+
+	if cf.Checkpoints == nil {
+		cf.Checkpoints = make(map[int]uint64)
+	}
+
+	return eventStore, cf.Checkpoints, nil
+}
+
+// Conforms to `CheckpointStore`.
+func (fcs *FileCheckpointStore) Commit(eventStore *eventstore.EventStore, checkpoints map[int]uint64) error {
+	var store bytes.Buffer
+	if err := eventStore.Save(&store); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&checkpointFile{
+		Version:     checkpointVersion,
+		Checkpoints: checkpoints,
+		Store:       store.Bytes(),
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint file: %w", err)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(fcs.path), filepath.Base(fcs.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+
+	return os.Rename(f.Name(), fcs.path)
+}
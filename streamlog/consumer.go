@@ -0,0 +1,258 @@
+package streamlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/input"
+)
+
+// DefaultBatchSize is the maximum number of records per shard that a `Consumer` not customized by
+// `WithBatchSize` consumes in a single `Poll`.
+const DefaultBatchSize = 1000
+
+// Consumer consumes the records of every shard of a `Log` (each holding events, one per line) into an
+// `eventstore.EventStore`, picking up from where its `CheckpointStore` says it left off.
+//
+// It's not safe for concurrent use.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// Every `Poll` processes a batch of records into the `eventstore.EventStore`, and only once that's done
+// commits it along with the new checkpoints (see the Maintainer Notes on `CheckpointStore`) -- so a crash
+// at any point replays exactly the records that aren't reflected in the committed store, and nothing else.
+//
+// Every shard is processed by a call to `eventprocessor.EventProcessor.Process` of its own, so trips parked
+// while waiting on their drivers would be given up on at the end of every `Poll` -- which is why stores that
+// park trips (see `eventstore.WithParkedTrips`) are rejected. The events of a driver must all be in the same
+// shard, in order, so records should be keyed by driver (see `Router`).
+//
+// Event IDs can't be recorded as seen as soon as their events are processed either, or a `Poll` that failed
+// to commit would leave them behind, and their events would be dropped as duplicates when consumed again --
+// which is why deduplication goes through an `eventprocessor.StagedDeduplicator` (see
+// `WithStagedDeduplicator`), whose event IDs are discarded along with everything else that wasn't committed.
+type Consumer struct {
+	log            *Log
+	eventProcessor *eventprocessor.EventProcessor
+	checkpoints    CheckpointStore
+	startReading   func(io.ReadCloser) <-chan *input.EventEnvelope
+	batchSize      int
+	// observe is nil unless set by `WithErrorObserver`.
+	observe func(shard int, err error)
+	// deduplicator is nil unless set by `WithStagedDeduplicator`.
+	deduplicator *eventprocessor.StagedDeduplicator
+
+	eventStore *eventstore.EventStore
+	// committed holds the committed checkpoint of every shard (shards without one start from scratch).
+	committed map[int]uint64
+}
+
+// ConsumerOption customizes a `Consumer` created by `NewConsumer`.
+type ConsumerOption func(*Consumer)
+
+// WithEventReader makes the `Consumer` read the events of records with `startReading` (like
+// `input.StartReadingJSON`), rather than with `input.StartReading`.
+func WithEventReader(startReading func(io.ReadCloser) <-chan *input.EventEnvelope) ConsumerOption {
+	return func(c *Consumer) {
+		c.startReading = startReading
+	}
+}
+
+// WithBatchSize makes the `Consumer` consume at most `batchSize` records per shard in a single `Poll`, rather
+// than `DefaultBatchSize`.
+func WithBatchSize(batchSize int) ConsumerOption {
+	return func(c *Consumer) {
+		c.batchSize = batchSize
+	}
+}
+
+// WithErrorObserver makes the `Consumer` pass every `error` from processing events to `observe`, along with
+// the shard it arose in -- the `eventprocessor.ProcessingError.Line` of the `error` is the sequence number of
+// the record the event came from.
+//
+// Events that fail to process are still consumed (failing again on a replay wouldn't change anything).
+func WithErrorObserver(observe func(shard int, err error)) ConsumerOption {
+	return func(c *Consumer) {
+		c.observe = observe
+	}
+}
+
+// WithStagedDeduplicator makes the `Consumer` commit the event IDs staged by `deduplicator` (which the
+// `eventprocessor.EventProcessor` of the `Consumer` must deduplicate events with, see
+// `eventprocessor.WithDeduplicator`) once their records are committed, and discard them if they can't be.
+func WithStagedDeduplicator(deduplicator *eventprocessor.StagedDeduplicator) ConsumerOption {
+	return func(c *Consumer) {
+		c.deduplicator = deduplicator
+	}
+}
+
+// NewConsumer creates a `Consumer` of `l` that processes events with `eventProcessor`, and resumes from (and
+// commits to) `checkpoints`, customized by `opts`.
+func NewConsumer(l *Log, eventProcessor *eventprocessor.EventProcessor, checkpoints CheckpointStore,
+	opts ...ConsumerOption) (*Consumer, error) {
+	c := &Consumer{
+		log:            l,
+		eventProcessor: eventProcessor,
+		checkpoints:    checkpoints,
+		startReading:   input.StartReading,
+		batchSize:      DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.batchSize <= 0 {
+		return nil, fmt.Errorf("expecting a positive batch size, got %d", c.batchSize)
+	}
+
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	if c.eventStore.ParksTrips() {
+		return nil, errors.New("stores that park trips aren't supported, since every shard is processed on its own")
+	}
+
+	for shard, seq := range c.committed {
+		if shard < 0 || shard >= l.NumShards() || seq > l.LastSeq(shard) {
+			return nil, fmt.Errorf("checkpoint of shard %d (at %d) doesn't match the log", shard, seq)
+		}
+	}
+
+	return c, nil
+}
+
+// reload discards everything that hasn't been committed.
+func (c *Consumer) reload() error {
+	eventStore, committed, err := c.checkpoints.Load()
+	if err != nil {
+		return fmt.Errorf("error loading checkpoints: %w", err)
+	}
+
+	c.eventStore, c.committed = eventStore, committed
+	return nil
+}
+
+// EventStore returns the `eventstore.EventStore` that `c` consumes into, which reflects exactly the records
+// that `c` has committed -- it must not be modified.
+func (c *Consumer) EventStore() *eventstore.EventStore {
+	return c.eventStore
+}
+
+// Checkpoint returns the sequence number of the last record of `shard` that `c` has committed (0 if none).
+func (c *Consumer) Checkpoint(shard int) uint64 {
+	return c.committed[shard]
+}
+
+// Poll consumes (and commits) the next batch of records of every shard, and returns the number of records it
+// consumed -- 0 once `c` has caught up with the `Log`.
+//
+// If it fails, `c` reverts to what was last committed (so the records are consumed again by the next `Poll`)
+// -- unless only committing the event IDs of the records fails (see `WithStagedDeduplicator`), in which case
+// the records were consumed regardless, and their event IDs are committed again by the next `Poll`.
+func (c *Consumer) Poll() (int, error) {
+	checkpoints := make(map[int]uint64, len(c.committed))
+	for shard, seq := range c.committed {
+		checkpoints[shard] = seq
+	}
+
+	numRecords := 0
+	for shard := 0; shard < c.log.NumShards(); shard++ {
+		records, err := c.log.Read(shard, c.committed[shard], c.batchSize)
+		if err != nil {
+			return 0, c.revert(err)
+		}
+
+		if len(records) == 0 {
+			continue
+		}
+
+		c.process(shard, records)
+		checkpoints[shard] = records[len(records)-1].Seq
+		numRecords += len(records)
+	}
+
+	if numRecords == 0 {
+		return 0, nil
+	}
+
+	if err := c.checkpoints.Commit(c.eventStore, checkpoints); err != nil {
+		return 0, c.revert(fmt.Errorf("error committing checkpoints: %w", err))
+	}
+
+	c.committed = checkpoints
+
+	if c.deduplicator != nil {
+		if err := c.deduplicator.Commit(); err != nil {
+			return numRecords, fmt.Errorf("error committing event IDs: %w", err)
+		}
+	}
+
+	return numRecords, nil
+}
+
+// revert reverts `c` to what was last committed, after a `Poll` failed with `err` -- which it returns,
+// along with any `error` reverting.
+func (c *Consumer) revert(err error) error {
+	if c.deduplicator != nil {
+		c.deduplicator.Discard()
+	}
+
+	if reloadErr := c.reload(); reloadErr != nil {
+		return fmt.Errorf("%s (and then %w)", err, reloadErr)
+	}
+
+	return err
+}
+
+// process processes the events in `records` (all of which are of `shard`) into `c.eventStore`.
+func (c *Consumer) process(shard int, records []*Record) {
+	eventC := make(chan *input.EventEnvelope)
+	go func() {
+		defer close(eventC)
+
+		for _, record := range records {
+			for eventEnvelope := range c.startReading(ioutil.NopCloser(bytes.NewReader(record.Data))) {
+				// Records are the lines of their shards.
+				eventEnvelope.Line = int(record.Seq)
+				eventC <- eventEnvelope
+			}
+		}
+	}()
+
+	for err := range c.eventProcessor.Process(eventC, c.eventStore) {
+		if c.observe != nil {
+			c.observe(shard, err)
+		}
+	}
+}
+
+// Run keeps calling `Poll` (waiting `pollInterval` whenever `c` has caught up with the `Log`) until `ctx`
+// is done, or `Poll` fails.
+func (c *Consumer) Run(ctx context.Context, pollInterval time.Duration) error {
+	for ctx.Err() == nil {
+		n, err := c.Poll()
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+
+	return nil
+}
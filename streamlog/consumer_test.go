@@ -0,0 +1,267 @@
+package streamlog_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/eventstore"
+	"root.challenge/eventstore/eventstoretest"
+	"root.challenge/streamlog"
+)
+
+func newConsumer(t *testing.T, l *streamlog.Log, checkpoints streamlog.CheckpointStore,
+	opts ...streamlog.ConsumerOption) *streamlog.Consumer {
+	c, err := streamlog.NewConsumer(l, eventprocessor.New(), checkpoints, opts...)
+	if err != nil {
+		t.Fatalf("expected: no error creating consumer, got: %s", err)
+	}
+
+	return c
+}
+
+// drain polls `c` until it's caught up, and returns the number of records it consumed.
+func drain(t *testing.T, c *streamlog.Consumer) int {
+	total := 0
+	for {
+		n, err := c.Poll()
+		if err != nil {
+			t.Fatalf("expected: no error polling, got: %s", err)
+		}
+		if n == 0 {
+			return total
+		}
+		total += n
+	}
+}
+
+func TestConsume(t *testing.T) {
+	dir := tempDir(t)
+	l := openLog(t, filepath.Join(dir, "log"), streamlog.WithShards(3))
+	appendAll(t, l, "Dan", "Driver Dan", "Trip Dan 07:15 07:45 17", "Bogus Dan")
+	appendAll(t, l, "Bob", "Driver Bob", "Trip Bob 12:01 13:16 42")
+
+	type observed struct {
+		shard int
+		line  int
+	}
+	var actualObserved []observed
+	c := newConsumer(t, l, streamlog.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json")),
+		streamlog.WithBatchSize(2),
+		streamlog.WithErrorObserver(func(shard int, err error) {
+			var processingErr *eventprocessor.ProcessingError
+			if errors.As(err, &processingErr) {
+				actualObserved = append(actualObserved, observed{shard, processingErr.Line})
+			}
+		}))
+
+	if n := drain(t, c); n != 5 {
+		t.Fatalf("expected: 5 records consumed, got: %d", n)
+	}
+
+	if expected, actual := map[string]float64{"Dan": 17, "Bob": 42},
+		eventstoretest.TotalMilesByDriver(c.EventStore()); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+
+	// Failed events are reported against the sequence number of their record in their shard.
+	if expected := []observed{{l.ShardFor("Dan"), 3}}; !reflect.DeepEqual(expected, actualObserved) {
+		t.Fatalf("expected: %v, got: %v", expected, actualObserved)
+	}
+
+	if c.Checkpoint(l.ShardFor("Dan")) != 3 || c.Checkpoint(l.ShardFor("Bob")) != 2 {
+		t.Fatalf("expected: checkpoints at 3 and 2, got: %d and %d", c.Checkpoint(l.ShardFor("Dan")),
+			c.Checkpoint(l.ShardFor("Bob")))
+	}
+}
+
+func TestConsumeResumesFromCheckpoint(t *testing.T) {
+	dir := tempDir(t)
+	l := openLog(t, filepath.Join(dir, "log"))
+	checkpointsPath := filepath.Join(dir, "checkpoints.json")
+
+	appendAll(t, l, "Dan", "Driver Dan", "Trip Dan 07:15 07:45 17")
+	if n := drain(t, newConsumer(t, l, streamlog.NewFileCheckpointStore(checkpointsPath))); n != 2 {
+		t.Fatalf("expected: 2 records consumed, got: %d", n)
+	}
+
+	// A new consumer (say, after a restart) picks up where the last one committed, and nothing is applied
+	// twice.
+	appendAll(t, l, "Dan", "Trip Dan 08:00 09:00 40")
+	c := newConsumer(t, l, streamlog.NewFileCheckpointStore(checkpointsPath))
+	if n := drain(t, c); n != 1 {
+		t.Fatalf("expected: 1 record consumed, got: %d", n)
+	}
+
+	if expected, actual := map[string]float64{"Dan": 57},
+		eventstoretest.TotalMilesByDriver(c.EventStore()); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+// flakyCheckpointStore is a `streamlog.CheckpointStore` whose commits fail while `fail` is set.
+type flakyCheckpointStore struct {
+	fail        bool
+	eventStore  *eventstore.EventStore
+	checkpoints map[int]uint64
+}
+
+func (fcs *flakyCheckpointStore) Load() (*eventstore.EventStore, map[int]uint64, error) {
+	// Hand out a copy, just like a durable store would.
+	eventStore := eventstore.New()
+	if fcs.eventStore != nil {
+		eventStore = fcs.eventStore
+	}
+
+	checkpoints := make(map[int]uint64)
+	for shard, seq := range fcs.checkpoints {
+		checkpoints[shard] = seq
+	}
+
+	return eventStore, checkpoints, nil
+}
+
+func (fcs *flakyCheckpointStore) Commit(eventStore *eventstore.EventStore, checkpoints map[int]uint64) error {
+	if fcs.fail {
+		return errors.New("disk full")
+	}
+
+	fcs.eventStore, fcs.checkpoints = eventStore, checkpoints
+	return nil
+}
+
+func TestConsumeRevertsFailedCommits(t *testing.T) {
+	l := openLog(t, tempDir(t))
+	appendAll(t, l, "Dan", "Trip Dan 07:15 07:45 17")
+
+	checkpoints := &flakyCheckpointStore{fail: true}
+	c := newConsumer(t, l, checkpoints)
+
+	if _, err := c.Poll(); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+
+	// Nothing that failed to commit lingers.
+	if actual := eventstoretest.TotalMilesByDriver(c.EventStore()); len(actual) != 0 {
+		t.Fatalf("expected: empty store, got: %v", actual)
+	}
+	if c.Checkpoint(0) != 0 {
+		t.Fatalf("expected: no checkpoint, got: %d", c.Checkpoint(0))
+	}
+
+	// The records are consumed (exactly once) once committing works again.
+	checkpoints.fail = false
+	if n := drain(t, c); n != 1 {
+		t.Fatalf("expected: 1 record consumed, got: %d", n)
+	}
+
+	if expected, actual := map[string]float64{"Dan": 17},
+		eventstoretest.TotalMilesByDriver(c.EventStore()); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestConsumeWithDeduplicatorRevertsFailedCommits(t *testing.T) {
+	l := openLog(t, tempDir(t))
+	appendAll(t, l, "Dan", "Driver Dan", "@e1 Trip Dan 07:00 08:00 30", "@e1 Trip Dan 07:00 08:00 30")
+
+	deduplicator, err := eventprocessor.NewWindowedDeduplicator(10)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+
+	stagedDeduplicator := eventprocessor.NewStagedDeduplicator(deduplicator)
+	checkpoints := &flakyCheckpointStore{fail: true}
+	c, err := streamlog.NewConsumer(l, eventprocessor.New(eventprocessor.WithDeduplicator(stagedDeduplicator)),
+		checkpoints, streamlog.WithStagedDeduplicator(stagedDeduplicator))
+	if err != nil {
+		t.Fatalf("expected: no error creating consumer, got: %s", err)
+	}
+
+	if _, err := c.Poll(); err == nil {
+		t.Fatalf("expected: error, got: nil")
+	}
+
+	// The event IDs of records that failed to commit are forgotten along with everything else, so the
+	// records count exactly once (and their duplicates don't) when they're consumed again.
+	checkpoints.fail = false
+	if n := drain(t, c); n != 3 {
+		t.Fatalf("expected: 3 records consumed, got: %d", n)
+	}
+
+	if expected, actual := map[string]float64{"Dan": 30},
+		eventstoretest.TotalMilesByDriver(c.EventStore()); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+	if !deduplicator.Seen("e1") {
+		t.Fatalf("expected: e1 recorded once committed, got: not recorded")
+	}
+}
+
+func TestNewConsumerRejectsMismatchedCheckpoints(t *testing.T) {
+	l := openLog(t, tempDir(t))
+	appendAll(t, l, "Dan", "Driver Dan")
+
+	for name, checkpoints := range map[string]map[int]uint64{
+		"UnknownShard":  {1: 1},
+		"BeyondLastSeq": {0: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := streamlog.NewConsumer(l, eventprocessor.New(),
+				&flakyCheckpointStore{checkpoints: checkpoints}); err == nil {
+				t.Fatalf("expected: error, got: nil")
+			}
+		})
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(tempDir(t), "checkpoints.json")
+	fcs := streamlog.NewFileCheckpointStore(path)
+
+	eventStore, checkpoints, err := fcs.Load()
+	if err != nil {
+		t.Fatalf("expected: no error loading missing file, got: %s", err)
+	}
+	if len(checkpoints) != 0 || len(eventstoretest.TotalMilesByDriver(eventStore)) != 0 {
+		t.Fatalf("expected: nothing committed, got: %v", checkpoints)
+	}
+
+	if err := eventStore.RegisterDriver(&eventstore.DriverInfo{ID: "Dan"}); err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	if err := fcs.Commit(eventStore, map[int]uint64{0: 4, 2: 7}); err != nil {
+		t.Fatalf("expected: no error committing, got: %s", err)
+	}
+
+	eventStore, checkpoints, err = fcs.Load()
+	if err != nil {
+		t.Fatalf("expected: no error loading, got: %s", err)
+	}
+	if expected := map[int]uint64{0: 4, 2: 7}; !reflect.DeepEqual(expected, checkpoints) {
+		t.Fatalf("expected: %v, got: %v", expected, checkpoints)
+	}
+
+	drivers := make([]string, 0)
+	for driverID := range eventstoretest.TotalMilesByDriver(eventStore) {
+		drivers = append(drivers, driverID)
+	}
+	sort.Strings(drivers)
+	if expected := []string{"Dan"}; !reflect.DeepEqual(expected, drivers) {
+		t.Fatalf("expected: %v, got: %v", expected, drivers)
+	}
+}
+
+func TestConsumerRejectsStoresThatParkTrips(t *testing.T) {
+	dir := tempDir(t)
+	l := openLog(t, filepath.Join(dir, "log"))
+
+	checkpoints := streamlog.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json"),
+		eventstore.WithParkedTrips(10))
+	if _, err := streamlog.NewConsumer(l, eventprocessor.New(), checkpoints); err == nil {
+		t.Fatalf("expected: error creating consumer, got: nil")
+	}
+}
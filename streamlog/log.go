@@ -0,0 +1,440 @@
+package streamlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metadataFileName is the file (in the directory of a `Log`) that holds its `metadata`.
+const metadataFileName = "streamlog.json"
+
+// metadataVersion is bumped on every incompatible change to the layout of a `Log` on disk.
+const metadataVersion = 1
+
+// segmentExt is the extension of segment files.
+const segmentExt = ".seg"
+
+// DefaultSegmentSize is the number of records per segment of a `Log` not customized by `WithSegmentSize`.
+const DefaultSegmentSize = 10000
+
+// Record is a single record of a `Log`.
+type Record struct {
+	// Shard is the shard the record was appended to (as per its `Key`).
+	Shard int `json:"-"`
+	// Seq is the sequence number of the record within its shard -- sequence numbers start at 1, and increase
+	// by 1 with every record.
+	Seq  uint64 `json:"seq"`
+	Key  string `json:"key,omitempty"`
+	Data []byte `json:"data"`
+}
+
+// Log is a local, partitioned, append-only log of records -- a stand-in for a stream like Kinesis, where
+// records are spread across shards by their keys, and are ordered (by their sequence numbers) within a shard.
+//
+// It's safe for concurrent use.
+//
+// ============================================== Maintainer Notes ==============================================
+//
+// A `Log` is a directory holding a metadata file (which fixes the number of shards), and a directory per
+// shard holding its segments -- files of JSON-encoded records, one per line, each named after the sequence
+// number of its first record. Segments are only ever appended to, and a new segment is started once the
+// last one holds `segmentSize` records, so that old records could one day be expired by deleting whole
+// segments.
+//
+// A crash mid-append can leave a partial record at the end of the last segment of a shard, which is
+// truncated away when the `Log` is next opened (it was never acknowledged to the appender, so nothing can
+// have consumed it).
+type Log struct {
+	dir         string
+	segmentSize int
+
+	mu     sync.Mutex
+	shards []*shard
+}
+
+// metadata is the contents of the metadata file of a `Log`.
+type metadata struct {
+	Version int `json:"version"`
+	Shards  int `json:"shards"`
+}
+
+// shard is the state of a single shard of a `Log`.
+type shard struct {
+	dir string
+	// lastSeq is 0 for shards without any records.
+	lastSeq uint64
+	// segment is the last segment, and is nil for shards without any records.
+	segment *os.File
+	// segmentRecords is the number of records in `segment`.
+	segmentRecords int
+}
+
+// Option customizes a `Log` opened by `Open`.
+type Option func(*options)
+
+type options struct {
+	numShards   int
+	segmentSize int
+}
+
+// WithShards makes a `Log` created by `Open` have `numShards` shards, rather than 1 -- it's an error to ask
+// for a different number of shards than an existing `Log` has.
+func WithShards(numShards int) Option {
+	return func(o *options) {
+		o.numShards = numShards
+	}
+}
+
+// WithSegmentSize makes the `Log` start a new segment every `segmentSize` records, rather than every
+// `DefaultSegmentSize` records.
+func WithSegmentSize(segmentSize int) Option {
+	return func(o *options) {
+		o.segmentSize = segmentSize
+	}
+}
+
+// Open opens the `Log` in the directory at `dir`, creating it (and `dir`) if it doesn't exist yet.
+func Open(dir string, opts ...Option) (*Log, error) {
+	o := options{segmentSize: DefaultSegmentSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.numShards < 0 {
+		return nil, fmt.Errorf("expecting a positive number of shards, got %d", o.numShards)
+	}
+	if o.segmentSize <= 0 {
+		return nil, fmt.Errorf("expecting a positive segment size, got %d", o.segmentSize)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating log directory: %w", err)
+	}
+
+	md, err := loadOrCreateMetadata(dir, o.numShards)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{dir: dir, segmentSize: o.segmentSize}
+	for i := 0; i < md.Shards; i++ {
+		s, err := openShard(filepath.Join(dir, fmt.Sprintf("shard-%04d", i)))
+		if err != nil {
+			l.Close()
+			return nil, fmt.Errorf("error opening shard %d: %w", i, err)
+		}
+
+		l.shards = append(l.shards, s)
+	}
+
+	return l, nil
+}
+
+// loadOrCreateMetadata loads the `metadata` of the `Log` in `dir`, creating it (with `numShards` shards, or
+// 1 if `numShards` is 0) if it doesn't exist yet.
+func loadOrCreateMetadata(dir string, numShards int) (*metadata, error) {
+	path := filepath.Join(dir, metadataFileName)
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		md := &metadata{Version: metadataVersion, Shards: numShards}
+		if md.Shards == 0 {
+			md.Shards = 1
+		}
+
+		data, err := json.Marshal(md)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding log metadata: %w", err)
+		}
+		if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+			return nil, fmt.Errorf("error writing log metadata: %w", err)
+		}
+
+		return md, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading log metadata: %w", err)
+	}
+
+	var md metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("error parsing log metadata: %w", err)
+	}
+	if md.Version != metadataVersion {
+		return nil, fmt.Errorf("unsupported log version %d (expecting %d)", md.Version, metadataVersion)
+	}
+	if md.Shards <= 0 {
+		return nil, fmt.Errorf("invalid log metadata: %d shards", md.Shards)
+	}
+	if numShards != 0 && numShards != md.Shards {
+		return nil, fmt.Errorf("log has %d shards, not %d", md.Shards, numShards)
+	}
+
+	return &md, nil
+}
+
+// openShard opens the shard in the directory at `dir` (creating it if needed), truncating any partial record
+// at the end of its last segment.
+func openShard(dir string) (*shard, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shard{dir: dir}
+	if len(segments) == 0 {
+		return s, nil
+	}
+
+	lastSegment := segments[len(segments)-1]
+	f, err := os.OpenFile(lastSegment.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lastSeq = lastSegment.firstSeq - 1
+	var goodSize int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// Anything after the last newline is a partial record.
+			break
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil || record.Seq != s.lastSeq+1 {
+			break
+		}
+
+		s.lastSeq = record.Seq
+		s.segmentRecords++
+		goodSize += int64(len(line))
+	}
+
+	if err := f.Truncate(goodSize); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error truncating partial record: %w", err)
+	}
+	if _, err := f.Seek(goodSize, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s.segment = f
+	return s, nil
+}
+
+// segmentInfo describes a single segment file.
+type segmentInfo struct {
+	path     string
+	firstSeq uint64
+}
+
+// listSegments lists the segments in the shard directory at `dir`, in order.
+func listSegments(dir string) ([]*segmentInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []*segmentInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed segment file name %s", name)
+		}
+
+		segments = append(segments, &segmentInfo{path: filepath.Join(dir, name), firstSeq: firstSeq})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].firstSeq < segments[j].firstSeq
+	})
+
+	return segments, nil
+}
+
+// NumShards returns the number of shards of `l`.
+func (l *Log) NumShards() int {
+	return len(l.shards)
+}
+
+// ShardFor returns the shard that records with `key` are appended to.
+func (l *Log) ShardFor(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(l.shards)))
+}
+
+// Append appends a record holding `data` to the shard of `key` (see `ShardFor`), and returns it -- once it
+// returns, the record is on disk.
+func (l *Log) Append(key string, data []byte) (*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	shardIndex := l.ShardFor(key)
+	s := l.shards[shardIndex]
+
+	if s.segment == nil || s.segmentRecords >= l.segmentSize {
+		if err := s.startSegment(); err != nil {
+			return nil, fmt.Errorf("error starting segment of shard %d: %w", shardIndex, err)
+		}
+	}
+
+	record := &Record{Shard: shardIndex, Seq: s.lastSeq + 1, Key: key, Data: data}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding record: %w", err)
+	}
+
+	// A single write, so that a crash leaves (at worst) a partial record at the end of the segment.
+	if _, err := s.segment.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("error appending to shard %d: %w", shardIndex, err)
+	}
+	if err := s.segment.Sync(); err != nil {
+		return nil, fmt.Errorf("error appending to shard %d: %w", shardIndex, err)
+	}
+
+	s.lastSeq = record.Seq
+	s.segmentRecords++
+
+	return record, nil
+}
+
+// startSegment closes the last segment of `s` (if any), and starts a new one.
+func (s *shard) startSegment() error {
+	if s.segment != nil {
+		if err := s.segment.Close(); err != nil {
+			return err
+		}
+		s.segment = nil
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, fmt.Sprintf("%020d%s", s.lastSeq+1, segmentExt)),
+		os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	s.segment = f
+	s.segmentRecords = 0
+
+	return nil
+}
+
+// Read returns the records of `shardIndex` that come after sequence number `afterSeq` (so 0 reads from the
+// start), in order -- at most `max` of them (or all of them, if `max` <= 0).
+//
+// Records are read from disk, so those appended by another process (say, a producer feeding a consumer) are
+// read too -- but a `Log` must only ever be appended to by a single process at a time.
+func (l *Log) Read(shardIndex int, afterSeq uint64, max int) ([]*Record, error) {
+	if shardIndex < 0 || shardIndex >= len(l.shards) {
+		return nil, fmt.Errorf("no shard %d (the log has %d shards)", shardIndex, len(l.shards))
+	}
+
+	// Appends are serialized with reads, so that reads never see a partial record (from this process).
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.shards[shardIndex]
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading shard %d: %w", shardIndex, err)
+	}
+
+	// Skip every segment that only holds records up to `afterSeq`.
+	start := 0
+	for i, segment := range segments {
+		if segment.firstSeq <= afterSeq+1 {
+			start = i
+		}
+	}
+
+	records := make([]*Record, 0)
+	for _, segment := range segments[start:] {
+		var err error
+		if records, err = readSegment(segment.path, shardIndex, afterSeq, max, records); err != nil {
+			return nil, fmt.Errorf("error reading shard %d: %w", shardIndex, err)
+		}
+
+		if max > 0 && len(records) >= max {
+			break
+		}
+	}
+
+	return records, nil
+}
+
+// readSegment appends the records of the segment at `path` that come after `afterSeq` to `records`, until it
+// holds `max` records (if `max` > 0).
+func readSegment(path string, shardIndex int, afterSeq uint64, max int, records []*Record) ([]*Record, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		// A line without a trailing newline is a record that another process is midway through appending.
+		if (max > 0 && len(records) >= max) || !bytes.HasSuffix(line, []byte("\n")) {
+			break
+		}
+
+		record := &Record{Shard: shardIndex}
+		if err := json.Unmarshal(line, record); err != nil {
+			return nil, fmt.Errorf("malformed record in %s: %w", filepath.Base(path), err)
+		}
+
+		if record.Seq > afterSeq {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// LastSeq returns the sequence number of the last record of `shardIndex` (0 if it has none).
+func (l *Log) LastSeq(shardIndex int) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.shards[shardIndex].lastSeq
+}
+
+// Close closes `l`, which must not be used afterwards.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var firstErr error
+	for _, s := range l.shards {
+		if s.segment != nil {
+			if err := s.segment.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			s.segment = nil
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,202 @@
+package streamlog_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"root.challenge/streamlog"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "streamlog")
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func openLog(t *testing.T, dir string, opts ...streamlog.Option) *streamlog.Log {
+	l, err := streamlog.Open(dir, opts...)
+	if err != nil {
+		t.Fatalf("expected: no error opening log, got: %s", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	return l
+}
+
+func appendAll(t *testing.T, l *streamlog.Log, key string, data ...string) {
+	for _, d := range data {
+		if _, err := l.Append(key, []byte(d)); err != nil {
+			t.Fatalf("expected: no error appending, got: %s", err)
+		}
+	}
+}
+
+// readData returns the data of the records of `shard` after `afterSeq`.
+func readData(t *testing.T, l *streamlog.Log, shard int, afterSeq uint64, max int) []string {
+	records, err := l.Read(shard, afterSeq, max)
+	if err != nil {
+		t.Fatalf("expected: no error reading, got: %s", err)
+	}
+
+	data := make([]string, 0, len(records))
+	for i, record := range records {
+		if record.Shard != shard || record.Seq != afterSeq+uint64(i)+1 {
+			t.Fatalf("expected: record %d of shard %d, got: %+v", afterSeq+uint64(i)+1, shard, record)
+		}
+		data = append(data, string(record.Data))
+	}
+
+	return data
+}
+
+func TestAppendAndRead(t *testing.T) {
+	tests := map[string]struct {
+		afterSeq     uint64
+		max          int
+		expectedData []string
+	}{
+		"FromStart":           {afterSeq: 0, max: 0, expectedData: []string{"a", "b", "c", "d", "e"}},
+		"FromMiddle":          {afterSeq: 2, max: 0, expectedData: []string{"c", "d", "e"}},
+		"AcrossSegments":      {afterSeq: 1, max: 3, expectedData: []string{"b", "c", "d"}},
+		"FromLastSegment":     {afterSeq: 4, max: 10, expectedData: []string{"e"}},
+		"CaughtUp":            {afterSeq: 5, max: 0, expectedData: []string{}},
+		"CappedWithinSegment": {afterSeq: 0, max: 1, expectedData: []string{"a"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			l := openLog(t, tempDir(t), streamlog.WithSegmentSize(2))
+			appendAll(t, l, "k", "a", "b", "c", "d", "e")
+
+			if actual := readData(t, l, 0, tc.afterSeq, tc.max); !reflect.DeepEqual(tc.expectedData, actual) {
+				t.Fatalf("expected: %v, got: %v", tc.expectedData, actual)
+			}
+		})
+	}
+}
+
+func TestSegmentsRoll(t *testing.T) {
+	dir := tempDir(t)
+	l := openLog(t, dir, streamlog.WithSegmentSize(2))
+	appendAll(t, l, "k", "a", "b", "c", "d", "e")
+
+	segments, err := filepath.Glob(filepath.Join(dir, "shard-0000", "*.seg"))
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	for i := range segments {
+		segments[i] = filepath.Base(segments[i])
+	}
+
+	if expected := []string{"00000000000000000001.seg", "00000000000000000003.seg",
+		"00000000000000000005.seg"}; !reflect.DeepEqual(expected, segments) {
+		t.Fatalf("expected: %v, got: %v", expected, segments)
+	}
+}
+
+func TestShards(t *testing.T) {
+	l := openLog(t, tempDir(t), streamlog.WithShards(4))
+
+	if l.NumShards() != 4 {
+		t.Fatalf("expected: 4 shards, got: %d", l.NumShards())
+	}
+
+	// Records with the same key land in the same shard, in order, and sequence numbers are per shard.
+	appendAll(t, l, "Dan", "Driver Dan", "Trip Dan 07:15 07:45 17.3")
+	shard := l.ShardFor("Dan")
+	if expected, actual := []string{"Driver Dan", "Trip Dan 07:15 07:45 17.3"}, readData(t, l, shard, 0, 0); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+
+	for otherShard := 0; otherShard < l.NumShards(); otherShard++ {
+		if otherShard != shard && l.LastSeq(otherShard) != 0 {
+			t.Fatalf("expected: shard %d to be empty, got: %d records", otherShard, l.LastSeq(otherShard))
+		}
+	}
+
+	if _, err := l.Read(4, 0, 0); err == nil {
+		t.Fatalf("expected: error reading missing shard, got: nil")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := tempDir(t)
+
+	l := openLog(t, dir, streamlog.WithShards(2), streamlog.WithSegmentSize(2))
+	appendAll(t, l, "k", "a", "b", "c")
+	shard := l.ShardFor("k")
+	l.Close()
+
+	// The number of shards is fixed once the log exists.
+	if _, err := streamlog.Open(dir, streamlog.WithShards(3)); err == nil {
+		t.Fatalf("expected: error reopening with a different number of shards, got: nil")
+	}
+
+	reopened := openLog(t, dir, streamlog.WithSegmentSize(2))
+	if reopened.NumShards() != 2 {
+		t.Fatalf("expected: 2 shards, got: %d", reopened.NumShards())
+	}
+
+	appendAll(t, reopened, "k", "d")
+	if expected, actual := []string{"a", "b", "c", "d"}, readData(t, reopened, shard, 0, 0); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestReopenTruncatesPartialRecord(t *testing.T) {
+	dir := tempDir(t)
+
+	l := openLog(t, dir)
+	appendAll(t, l, "k", "a", "b")
+	l.Close()
+
+	// Simulate a crash midway through appending a record.
+	segment := filepath.Join(dir, "shard-0000", "00000000000000000001.seg")
+	f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("expected: no error, got: %s", err)
+	}
+	f.WriteString(`{"seq": 3, "da`)
+	f.Close()
+
+	reopened := openLog(t, dir)
+	if reopened.LastSeq(0) != 2 {
+		t.Fatalf("expected: last sequence number 2, got: %d", reopened.LastSeq(0))
+	}
+
+	appendAll(t, reopened, "k", "c")
+	if expected, actual := []string{"a", "b", "c"}, readData(t, reopened, 0, 0, 0); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
+
+func TestOpenRejectsBadOptions(t *testing.T) {
+	for name, opts := range map[string][]streamlog.Option{
+		"NegativeShards":  {streamlog.WithShards(-1)},
+		"ZeroSegmentSize": {streamlog.WithSegmentSize(0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := streamlog.Open(tempDir(t), opts...); err == nil {
+				t.Fatalf("expected: error, got: nil")
+			}
+		})
+	}
+}
+
+func TestReadSeesAppendsOfOtherLogs(t *testing.T) {
+	dir := tempDir(t)
+	producer := openLog(t, dir)
+	consumer := openLog(t, dir)
+
+	appendAll(t, producer, "k", "a", "b")
+	if expected, actual := []string{"a", "b"}, readData(t, consumer, 0, 0, 0); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %v, got: %v", expected, actual)
+	}
+}
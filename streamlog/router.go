@@ -0,0 +1,139 @@
+package streamlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"root.challenge/eventhandler"
+	"root.challenge/input"
+)
+
+// ErrUnroutable is the `error` (possibly wrapped) returned by `Router.Key` for events it can't route.
+var ErrUnroutable = errors.New("event can't be routed")
+
+// Router keys the records of a `Log` by the driver their events are about, so that every event about a
+// driver lands in the same shard, in order -- which is what allows a `Consumer` to process every shard on its
+// own.
+//
+// What an event is about is declared by the `eventhandler.Role`s of the fields of its `eventhandler.Schema`,
+// so events whose handlers have no `eventhandler.Schema` can't be routed.
+//
+// It's not safe for concurrent use.
+type Router struct {
+	registry     eventhandler.HandlerLookup
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope
+	// tripDrivers maps the ID of every trip routed so far to the ID of its driver.
+	tripDrivers map[string]string
+}
+
+// NewRouter creates a `Router` for records whose events are read with `startReading`, and handled by the
+// handlers in `registry`.
+func NewRouter(registry eventhandler.HandlerLookup,
+	startReading func(io.ReadCloser) <-chan *input.EventEnvelope) *Router {
+	return &Router{
+		registry:     registry,
+		startReading: startReading,
+		tripDrivers:  make(map[string]string),
+	}
+}
+
+// Seed makes `r` aware of the trips in every record of `l`, so that corrections of those trips are routed to
+// their drivers -- it's meant to be called before appending more records to a `Log` that already has some.
+func (r *Router) Seed(l *Log) error {
+	for shard := 0; shard < l.NumShards(); shard++ {
+		afterSeq := uint64(0)
+		for {
+			records, err := l.Read(shard, afterSeq, DefaultBatchSize)
+			if err != nil {
+				return fmt.Errorf("error reading shard %d: %w", shard, err)
+			}
+
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				// Records that can't be routed hold no trips worth knowing about.
+				_, _ = r.Key(record.Data)
+			}
+			afterSeq = records[len(records)-1].Seq
+		}
+	}
+
+	return nil
+}
+
+// Key returns the key of the record holding `data` (a single event, see `input.SplitRecords`), which is the
+// ID of the first of these that the event has a field for:
+//
+//  1. the driver it's about (see `eventhandler.DriverRole`)
+//  2. the driver of the trip it's about (see `eventhandler.TripRole`), as per the event that recorded the trip
+//  3. the vehicle it's about (see `eventhandler.VehicleRole`) -- events that are only about a vehicle can be
+//     processed in any order relative to the events of drivers
+//
+// Records without an event (like comments) are keyed by "", and an `error` wrapping `ErrUnroutable` is
+// returned for events that can't be routed at all -- malformed events, events without a handler (or whose
+// handler has no fields with any of those roles), and events about trips that `r` hasn't routed before.
+func (r *Router) Key(data []byte) (string, error) {
+	var eventArgs []string
+	for eventEnvelope := range r.startReading(ioutil.NopCloser(bytes.NewReader(data))) {
+		if eventEnvelope.Err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnroutable, eventEnvelope.Err)
+		}
+
+		tokens, err := eventEnvelope.Tokens()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnroutable, err)
+		}
+
+		if len(tokens) > 0 && eventArgs == nil {
+			eventArgs = tokens
+		}
+	}
+
+	if eventArgs == nil {
+		return "", nil
+	}
+
+	eventType := eventhandler.EventType(eventArgs[0])
+	_, eventHandler, err := eventhandler.Lookup(r.registry, eventType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnroutable, err)
+	}
+
+	schemaProvider, ok := eventHandler.(eventhandler.SchemaProvider)
+	if !ok {
+		return "", fmt.Errorf("%w: the handler for eventType %s declares no schema", ErrUnroutable, eventType)
+	}
+
+	roleArgs, err := schemaProvider.ArgSchema().RoleArgs(eventArgs[1:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnroutable, err)
+	}
+
+	driverID, hasDriver := roleArgs[eventhandler.DriverRole]
+	tripID, hasTrip := roleArgs[eventhandler.TripRole]
+	switch {
+	case hasDriver:
+		if hasTrip {
+			r.tripDrivers[tripID] = driverID
+		}
+
+		return driverID, nil
+	case hasTrip:
+		if driverID, ok := r.tripDrivers[tripID]; ok {
+			return driverID, nil
+		}
+
+		return "", fmt.Errorf("%w: no trip with ID %s was routed before", ErrUnroutable, tripID)
+	}
+
+	if vehicleID, ok := roleArgs[eventhandler.VehicleRole]; ok {
+		return vehicleID, nil
+	}
+
+	return "", fmt.Errorf("%w: eventType %s isn't about a driver, trip or vehicle", ErrUnroutable, eventType)
+}
@@ -0,0 +1,96 @@
+package streamlog_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"root.challenge/eventhandler"
+	"root.challenge/input"
+	"root.challenge/streamlog"
+)
+
+func TestRouterKey(t *testing.T) {
+	type keyed struct {
+		data        string
+		expectedKey string
+		expectedErr error
+	}
+
+	tests := map[string]struct {
+		records []keyed
+	}{
+		"Drivers": {
+			records: []keyed{
+				{"Driver Dan\n", "Dan", nil},
+				{"Driver Dan id=D1\n", "D1", nil},
+				{"Trip Dan 07:15 07:45 17.3\n", "Dan", nil},
+				{"DriverRename D1 Daniel\n", "D1", nil},
+				{"DriverMerge D2 D1\n", "D2", nil},
+			},
+		},
+		"TripCorrections": {
+			records: []keyed{
+				{"Driver Amy\n", "Amy", nil},
+				{"Trip Amy 07:00 08:00 17.3 id=a\n", "Amy", nil},
+				{"TripCancel a\n", "Amy", nil},
+				{"TripAmend a 07:00 08:30 18.1\n", "Amy", nil},
+			},
+		},
+		"Vehicles": {
+			records: []keyed{
+				{"Vehicle V1 sedan gas\n", "V1", nil},
+				{"Trip Dan 07:15 07:45 17.3 vehicle=V1\n", "Dan", nil},
+			},
+		},
+		"WithDirectivesAndIDs": {
+			records: []keyed{
+				{"#!units km\n@e1 Trip Amy 07:00 08:00 17.3 id=a\n", "Amy", nil},
+				{"#!units km\n@e2 TripCancel a\n", "Amy", nil},
+			},
+		},
+		"Comment": {
+			records: []keyed{
+				{"# Dan's trips\n", "", nil},
+			},
+		},
+		"Unroutable": {
+			records: []keyed{
+				{"TripCancel a\n", "", streamlog.ErrUnroutable},
+				{"Bogus Dan\n", "", streamlog.ErrUnroutable},
+				{"Trip\n", "", streamlog.ErrUnroutable},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			router := streamlog.NewRouter(eventhandler.GlobalRegistry(), input.StartReading)
+			for _, record := range test.records {
+				key, err := router.Key([]byte(record.data))
+				if !errors.Is(err, record.expectedErr) {
+					t.Fatalf("expected: error %v keying %q, got: %v", record.expectedErr, record.data, err)
+				}
+
+				if key != record.expectedKey {
+					t.Fatalf("expected: key %q for %q, got: %q", record.expectedKey, record.data, key)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterSeed(t *testing.T) {
+	l := openLog(t, filepath.Join(tempDir(t), "log"), streamlog.WithShards(3))
+	appendAll(t, l, "Amy", "Driver Amy\n", "Trip Amy 07:00 08:00 17.3 id=a\n")
+
+	router := streamlog.NewRouter(eventhandler.GlobalRegistry(), input.StartReading)
+	if err := router.Seed(l); err != nil {
+		t.Fatalf("expected: no error seeding, got: %s", err)
+	}
+
+	// Corrections are routed to the drivers of the trips that were appended before.
+	if key, err := router.Key([]byte("TripCancel a\n")); err != nil || key != "Amy" {
+		t.Fatalf("expected: key Amy, got: %q (error: %v)", key, err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"root.challenge/eventprocessor"
+	"root.challenge/logging"
+)
+
+// maxLinesPerClass caps the number of lines listed for each class of error in the summary printed by
+// `runValidate`.
+const maxLinesPerClass = 10
+
+// runValidate implements the "validate" subcommand, which checks that an input is clean without processing
+// it (see `eventprocessor.EventProcessor.Validate`), and returns the exit code of the program.
+func runValidate(args []string) int {
+	flags := newFlagSet("validate", "[flags] [input file]",
+		"Checks every event in the input (standard input by default) without processing it.")
+	configFlag := addConfigFlag(flags)
+	formatFlag := addFormatFlag(flags)
+	ignoreCaseFlag := addIgnoreCaseFlag(flags)
+	quietFlag := flags.Bool("quiet", false, "only print the summary, not every error")
+	outputFlag := addOutputFlag(flags)
+	if exitCode, ok := parseFlags(flags, args); !ok {
+		return exitCode
+	}
+
+	cfg, err := applyConfig(flags, *configFlag)
+	if err != nil {
+		logger.Error("invalid config", logging.Err(err))
+		return exitFailure
+	}
+
+	registry, err := newRegistry(*ignoreCaseFlag)
+	if err != nil {
+		logger.Error("failed to create registry", logging.Err(err))
+		return exitFailure
+	}
+
+	inputSources, err := openInputSources(flags, *formatFlag, cfg, true)
+	if err != nil {
+		logger.Error("failed to open input", logging.Err(err))
+		return exitFailure
+	}
+
+	summary := eventprocessor.NewErrorSummary()
+	err = writeOutput(*outputFlag, func(w io.Writer) error {
+		ep := eventprocessor.New(eventprocessor.WithRegistry(registry))
+		for _, is := range inputSources {
+			for err := range ep.Validate(is.events()) {
+				summary.Add(err)
+				if !*quietFlag {
+					// Only name the input when there's more than one to tell apart.
+					if len(inputSources) > 1 {
+						fmt.Fprintf(w, "%s: ", is.name)
+					}
+					fmt.Fprintln(w, err)
+				}
+			}
+		}
+
+		printErrorSummary(w, summary)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to write output", logging.Err(err))
+		return exitFailure
+	}
+
+	return exitCodeFor(summary.Total())
+}
+
+// printErrorSummary prints the number of errors of each class in `summary` (and the lines they arose on).
+func printErrorSummary(w io.Writer, summary *eventprocessor.ErrorSummary) {
+	if summary.Total() == 0 {
+		fmt.Fprintln(w, "No errors found")
+		return
+	}
+
+	fmt.Fprintf(w, "Found %d errors:\n", summary.Total())
+	for _, class := range summary.Classes() {
+		lines := summary.Lines(class)
+
+		lineStrs := make([]string, 0, maxLinesPerClass+1)
+		for i, line := range lines {
+			if i == maxLinesPerClass {
+				lineStrs = append(lineStrs, fmt.Sprintf("and %d more", len(lines)-maxLinesPerClass))
+				break
+			}
+			lineStrs = append(lineStrs, fmt.Sprint(line))
+		}
+
+		if len(lineStrs) == 0 {
+			fmt.Fprintf(w, "  %s: %d\n", class, summary.Count(class))
+		} else {
+			fmt.Fprintf(w, "  %s: %d (lines %s)\n", class, summary.Count(class), strings.Join(lineStrs, ", "))
+		}
+	}
+}